@@ -2,10 +2,12 @@ import client from './client';
 import { ENDPOINTS } from '../utils/constants';
 
 export const streamsAPI = {
-  createStream: async (title, description) => {
+  // schedule: { scheduled_start_at, cover_image_url } - создать запланированный стрим
+  createStream: async (title, description, schedule = {}) => {
     const response = await client.post(ENDPOINTS.STREAMS, {
       title,
       description,
+      ...schedule,
     });
     return response.data;
   },
@@ -15,6 +17,11 @@ export const streamsAPI = {
     return response.data;
   },
 
+  getUpcomingStreams: async () => {
+    const response = await client.get(`${ENDPOINTS.STREAMS}/upcoming`);
+    return response.data;
+  },
+
   getStream: async (id) => {
     const response = await client.get(`${ENDPOINTS.STREAMS}/${id}`);
     return response.data;
@@ -35,4 +42,76 @@ export const streamsAPI = {
     const response = await client.delete(`${ENDPOINTS.STREAMS}/${id}`);
     return response.data;
   },
+
+  // Телеметрия эфира (только владелец): params - { broadcast_id, since, limit }
+  getStreamHealth: async (id, params = {}) => {
+    const response = await client.get(`${ENDPOINTS.STREAMS}/${id}/health`, { params });
+    return response.data;
+  },
+
+  // Расписание эфира (только владелец)
+  scheduleStream: async (id, scheduledStartAt, coverImageUrl) => {
+    const response = await client.put(`${ENDPOINTS.STREAMS}/${id}/schedule`, {
+      scheduled_start_at: scheduledStartAt,
+      cover_image_url: coverImageUrl,
+    });
+    return response.data;
+  },
+
+  cancelSchedule: async (id) => {
+    const response = await client.delete(`${ENDPOINTS.STREAMS}/${id}/schedule`);
+    return response.data;
+  },
+
+  // Ключи публикации (только владелец)
+  getStreamKeys: async (id) => {
+    const response = await client.get(`${ENDPOINTS.STREAMS}/${id}/keys`);
+    return response.data;
+  },
+
+  createStreamKey: async (id, name) => {
+    const response = await client.post(`${ENDPOINTS.STREAMS}/${id}/keys`, { name });
+    return response.data;
+  },
+
+  rotateStreamKey: async (id, keyId) => {
+    const response = await client.post(`${ENDPOINTS.STREAMS}/${id}/keys/${keyId}/rotate`);
+    return response.data;
+  },
+
+  revokeStreamKey: async (id, keyId) => {
+    const response = await client.delete(`${ENDPOINTS.STREAMS}/${id}/keys/${keyId}`);
+    return response.data;
+  },
+
+  // Выходы рестрима на внешние площадки (только владелец)
+  getDestinations: async (id) => {
+    const response = await client.get(`${ENDPOINTS.STREAMS}/${id}/destinations`);
+    return response.data;
+  },
+
+  createDestination: async (id, data) => {
+    const response = await client.post(`${ENDPOINTS.STREAMS}/${id}/destinations`, data);
+    return response.data;
+  },
+
+  updateDestination: async (id, destinationId, data) => {
+    const response = await client.put(`${ENDPOINTS.STREAMS}/${id}/destinations/${destinationId}`, data);
+    return response.data;
+  },
+
+  deleteDestination: async (id, destinationId) => {
+    const response = await client.delete(`${ENDPOINTS.STREAMS}/${id}/destinations/${destinationId}`);
+    return response.data;
+  },
+
+  startDestination: async (id, destinationId) => {
+    const response = await client.post(`${ENDPOINTS.STREAMS}/${id}/destinations/${destinationId}/start`);
+    return response.data;
+  },
+
+  stopDestination: async (id, destinationId) => {
+    const response = await client.post(`${ENDPOINTS.STREAMS}/${id}/destinations/${destinationId}/stop`);
+    return response.data;
+  },
 };
@@ -65,4 +65,80 @@ export const videosAPI = {
     const response = await client.post(`${API_URL}/videos/${id}/like`);
     return response.data;
   },
+
+  // Комментарии: from/to (секунды) - только привязанные к отрезку видео
+  getComments: async (id, { limit = 20, offset = 0, from, to } = {}) => {
+    const response = await client.get(`${API_URL}/videos/${id}/comments`, {
+      params: { limit, offset, from, to },
+    });
+    return response.data;
+  },
+
+  createComment: async (id, content, { timestampSeconds, parentId } = {}) => {
+    const response = await client.post(`${API_URL}/videos/${id}/comments`, {
+      content,
+      timestamp_seconds: timestampSeconds,
+      parent_id: parentId,
+    });
+    return response.data;
+  },
+
+  updateComment: async (id, commentId, content) => {
+    const response = await client.put(`${API_URL}/videos/${id}/comments/${commentId}`, { content });
+    return response.data;
+  },
+
+  deleteComment: async (id, commentId) => {
+    const response = await client.delete(`${API_URL}/videos/${id}/comments/${commentId}`);
+    return response.data;
+  },
+
+  // Клип: { stream_id, start_at, end_at } или { video_id, start_seconds, end_seconds }.
+  // Нарезка асинхронная: клип появляется со status 'pending'
+  createClip: async (data) => {
+    const response = await client.post(`${API_URL}/clips`, data);
+    return response.data;
+  },
+
+  // Последняя обрезка ({ status, trim_start_seconds, ... } или null) и главы видео
+  getVideoEdit: async (id) => {
+    const response = await client.get(`${API_URL}/videos/${id}/edit`);
+    return response.data;
+  },
+
+  // { trim_start_seconds, trim_end_seconds, chapters: [{ start_seconds, title }] }.
+  // Главы - на таймлайне после обрезки; обрезка асинхронная (edit.status 'processing')
+  editVideo: async (id, data) => {
+    const response = await client.put(`${API_URL}/videos/${id}/edit`, data);
+    return response.data;
+  },
+
+  // Загрузка файла: { filename, size, title, description, category, tags, visibility }.
+  // Возвращает { upload, video, upload_url, chunk_size }
+  createUpload: async (data) => {
+    const response = await client.post(`${API_URL}/videos/uploads`, data);
+    return response.data;
+  },
+
+  // Состояние загрузки: upload.offset - с какого байта продолжать после обрыва
+  getUpload: async (id) => {
+    const response = await client.get(`${API_URL}/videos/uploads/${id}`);
+    return response.data;
+  },
+
+  // Отправляет порцию файла с offset, возвращает новый offset
+  uploadChunk: async (id, offset, chunk) => {
+    const response = await client.patch(`${API_URL}/videos/uploads/${id}`, chunk, {
+      headers: {
+        'Content-Type': 'application/offset+octet-stream',
+        'Upload-Offset': String(offset),
+        'Tus-Resumable': '1.0.0',
+      },
+    });
+    return Number(response.headers['upload-offset']);
+  },
+
+  cancelUpload: async (id) => {
+    await client.delete(`${API_URL}/videos/uploads/${id}`);
+  },
 };
@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { X, Radio, AlertCircle } from 'lucide-react';
+import { X, Radio, AlertCircle, CalendarClock } from 'lucide-react';
 import { Button, Input } from '../Common';
 import { streamsAPI } from '../../api/streams';
 
@@ -7,6 +7,8 @@ export const CreateStreamModal = ({ isOpen, onClose, onSuccess }) => {
   const [formData, setFormData] = useState({
     title: '',
     description: '',
+    scheduledStartAt: '',
+    coverImageUrl: '',
   });
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
@@ -31,13 +33,23 @@ export const CreateStreamModal = ({ isOpen, onClose, onSuccess }) => {
     }
 
     try {
+      // Пустое время - обычный стрим, иначе анонс запланированного эфира
+      const schedule = {};
+      if (formData.scheduledStartAt) {
+        schedule.scheduled_start_at = new Date(formData.scheduledStartAt).toISOString();
+      }
+      if (formData.coverImageUrl) {
+        schedule.cover_image_url = formData.coverImageUrl;
+      }
+
       const response = await streamsAPI.createStream(
         formData.title,
-        formData.description
+        formData.description,
+        schedule
       );
       
       // Reset form
-      setFormData({ title: '', description: '' });
+      setFormData({ title: '', description: '', scheduledStartAt: '', coverImageUrl: '' });
       
       // Call success callback
       if (onSuccess) {
@@ -119,6 +131,36 @@ export const CreateStreamModal = ({ isOpen, onClose, onSuccess }) => {
             />
           </div>
 
+          {/* Schedule */}
+          <div>
+            <label className="block text-sm font-medium text-gray-300 mb-2 flex items-center gap-2">
+              <CalendarClock className="w-4 h-4" />
+              Scheduled start (optional)
+            </label>
+            <input
+              type="datetime-local"
+              name="scheduledStartAt"
+              value={formData.scheduledStartAt}
+              onChange={handleChange}
+              className="input-field"
+              disabled={loading}
+            />
+            <p className="text-xs text-gray-500 mt-1">
+              Scheduled streams are listed as upcoming until you go live.
+            </p>
+          </div>
+
+          {formData.scheduledStartAt && (
+            <Input
+              label="Cover Image URL (optional)"
+              name="coverImageUrl"
+              value={formData.coverImageUrl}
+              onChange={handleChange}
+              placeholder="https://example.com/cover.jpg"
+              disabled={loading}
+            />
+          )}
+
           {/* Actions */}
           <div className="flex gap-3 pt-4">
             <Button
@@ -1,17 +1,7 @@
 import React from 'react';
-import { Radio, Eye, Clock, Copy, Check, Key, MonitorPlay } from 'lucide-react'; // ✅ Добавить MonitorPlay
-
-export const StreamCard = ({ stream, showActions = false, onManage, onCopyKey }) => {
-    const [copied, setCopied] = React.useState(false);
-
-    const handleCopyKey = (e) => {
-        e.stopPropagation();
-        navigator.clipboard.writeText(stream.stream_key);
-        setCopied(true);
-        if (onCopyKey) onCopyKey();
-        setTimeout(() => setCopied(false), 2000);
-    };
+import { Radio, Eye, Clock, Key, MonitorPlay, CalendarClock } from 'lucide-react'; // ✅ Добавить MonitorPlay
 
+export const StreamCard = ({ stream, showActions = false, onManage }) => {
     const handleManage = (e) => {
         e.stopPropagation();
         if (onManage) {
@@ -50,11 +40,21 @@ export const StreamCard = ({ stream, showActions = false, onManage, onCopyKey })
     };
 
     const isLive = stream.status === 'live';
+    const isScheduled = stream.status === 'scheduled';
 
-    // Thumbnail URL через API вместо прямого MinIO
-    const thumbnailUrl = stream.id 
-        ? `http://localhost/api/streams/${stream.id}/thumbnail` 
-        : null;
+    const formatScheduled = (dateString) => new Date(dateString).toLocaleString('en-US', {
+        month: 'short',
+        day: 'numeric',
+        hour: '2-digit',
+        minute: '2-digit',
+    });
+
+    // Анонс показывает обложку, остальные - thumbnail через API вместо прямого MinIO
+    const thumbnailUrl = isScheduled && stream.cover_image_url
+        ? stream.cover_image_url
+        : stream.id
+            ? `http://localhost/api/streams/${stream.id}/thumbnail`
+            : null;
 
     return (
         <div className="bg-gray-800 rounded-lg shadow-lg overflow-hidden hover:shadow-xl transition-shadow border border-gray-700">
@@ -90,10 +90,17 @@ export const StreamCard = ({ stream, showActions = false, onManage, onCopyKey })
                             LIVE
                         </span>
                     </div>
+                ) : isScheduled ? (
+                    <div className="absolute top-2 left-2">
+                        <span className="bg-primary-600 text-white px-2 py-1 rounded text-xs font-medium flex items-center gap-1">
+                            <CalendarClock className="w-3 h-3" />
+                            {formatScheduled(stream.scheduled_start_at)}
+                        </span>
+                    </div>
                 ) : (
                     <div className="absolute top-2 left-2">
-                        <span className="bg-gray-700 text-gray-300 px-2 py-1 rounded text-xs">
-                            Offline
+                        <span className="bg-gray-700 text-gray-300 px-2 py-1 rounded text-xs capitalize">
+                            {stream.status || 'offline'}
                         </span>
                     </div>
                 )}
@@ -132,29 +139,16 @@ export const StreamCard = ({ stream, showActions = false, onManage, onCopyKey })
                     {stream.title}
                 </h3>
                 
-                {/* Stream Key вместо description */}
-                <div className="mb-3">
-                    <div className="flex items-center gap-2 text-xs text-gray-500 mb-1">
+                {/* Ключи публикации - в Manage, на карточке не показываем */}
+                {stream.description && (
+                    <p className="text-sm text-gray-400 mb-3 line-clamp-2">{stream.description}</p>
+                )}
+                {showActions && (
+                    <div className="flex items-center gap-2 text-xs text-gray-500 mb-3">
                         <Key className="w-3 h-3" />
-                        <span>Stream Key</span>
-                    </div>
-                    <div className="flex items-center gap-2">
-                        <code className="flex-1 text-xs text-gray-400 bg-gray-900 px-2 py-1 rounded font-mono truncate">
-                            {stream.stream_key}
-                        </code>
-                        <button
-                            onClick={handleCopyKey}
-                            className="text-gray-400 hover:text-white transition"
-                            title="Copy Stream Key"
-                        >
-                            {copied ? (
-                                <Check className="w-4 h-4 text-green-400" />
-                            ) : (
-                                <Copy className="w-4 h-4" />
-                            )}
-                        </button>
+                        <span>Stream keys are in Manage</span>
                     </div>
-                </div>
+                )}
 
                 {/* Stats */}
                 <div className="flex items-center justify-between text-sm text-gray-400 mb-3">
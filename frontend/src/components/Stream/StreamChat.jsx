@@ -0,0 +1,190 @@
+import { useEffect, useRef, useState } from 'react';
+import { Send, Trash2, Clock, Ban } from 'lucide-react';
+import { useAuth } from '../../hooks/useAuth';
+import { API_BASE_URL } from '../../utils/constants';
+
+const MAX_MESSAGE_LENGTH = 500;
+const RECONNECT_DELAY = 3000;
+const TIMEOUT_SECONDS = 600;
+
+// ws(s)://<host>/api/streams/:id/chat/ws - через Nginx или VITE_API_URL
+const chatSocketURL = (streamId, token) => {
+  const base = API_BASE_URL || window.location.origin;
+  const url = new URL(`${base}/api/streams/${streamId}/chat/ws`);
+  url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+  if (token) {
+    url.searchParams.set('access_token', token);
+  }
+  return url.toString();
+};
+
+export const StreamChat = ({ streamId }) => {
+  const { token } = useAuth();
+  const [messages, setMessages] = useState([]);
+  const [connected, setConnected] = useState(false);
+  const [readOnly, setReadOnly] = useState(true);
+  const [isOwner, setIsOwner] = useState(false);
+  const [slowMode, setSlowMode] = useState(0);
+  const [notice, setNotice] = useState(null);
+  const [input, setInput] = useState('');
+  const socketRef = useRef(null);
+  const bottomRef = useRef(null);
+
+  useEffect(() => {
+    let closed = false;
+    let reconnectTimer;
+
+    const handleEvent = (event) => {
+      switch (event.type) {
+        case 'history':
+          setMessages(event.messages || []);
+          setReadOnly(event.read_only);
+          setIsOwner(event.is_owner);
+          setSlowMode(event.slow_mode_seconds || 0);
+          break;
+        case 'message':
+          setMessages((prev) => [...prev.slice(-199), event.message]);
+          break;
+        case 'message_deleted':
+          setMessages((prev) => prev.filter((m) => m.id !== event.message_id));
+          break;
+        case 'user_timed_out':
+        case 'user_banned':
+          setMessages((prev) => prev.filter((m) => m.user_id !== event.user_id));
+          break;
+        case 'slow_mode':
+          setSlowMode(event.slow_mode_seconds || 0);
+          break;
+        case 'error':
+          setNotice(event.error);
+          break;
+        default:
+          break;
+      }
+    };
+
+    const connect = () => {
+      const socket = new WebSocket(chatSocketURL(streamId, token));
+      socketRef.current = socket;
+
+      socket.onopen = () => {
+        setConnected(true);
+        setNotice(null);
+      };
+      socket.onmessage = (e) => {
+        try {
+          handleEvent(JSON.parse(e.data));
+        } catch (err) {
+          console.error('Invalid chat event:', err);
+        }
+      };
+      socket.onclose = () => {
+        setConnected(false);
+        if (!closed) {
+          reconnectTimer = setTimeout(connect, RECONNECT_DELAY);
+        }
+      };
+    };
+
+    connect();
+
+    return () => {
+      closed = true;
+      clearTimeout(reconnectTimer);
+      socketRef.current?.close();
+    };
+  }, [streamId, token]);
+
+  useEffect(() => {
+    bottomRef.current?.scrollIntoView({ behavior: 'smooth' });
+  }, [messages]);
+
+  const sendCommand = (command) => {
+    if (socketRef.current?.readyState === WebSocket.OPEN) {
+      socketRef.current.send(JSON.stringify(command));
+    }
+  };
+
+  const handleSubmit = (e) => {
+    e.preventDefault();
+    const content = input.trim();
+    if (!content) return;
+    setNotice(null);
+    sendCommand({ type: 'message', content });
+    setInput('');
+  };
+
+  const toggleSlowMode = () => {
+    sendCommand({ type: 'slow_mode', seconds: slowMode > 0 ? 0 : 30 });
+  };
+
+  return (
+    <div className="bg-gray-800 rounded-lg h-[600px] flex flex-col">
+      <div className="px-4 py-3 border-b border-gray-700 flex items-center justify-between">
+        <h2 className="text-white font-semibold">Live Chat</h2>
+        <div className="flex items-center space-x-2 text-xs text-gray-400">
+          {slowMode > 0 && <span>Slow mode: {slowMode}s</span>}
+          {isOwner && (
+            <button onClick={toggleSlowMode} className="px-2 py-1 bg-gray-700 hover:bg-gray-600 rounded">
+              {slowMode > 0 ? 'Disable slow mode' : 'Slow mode'}
+            </button>
+          )}
+          <span className={`w-2 h-2 rounded-full ${connected ? 'bg-green-500' : 'bg-gray-500'}`} />
+        </div>
+      </div>
+
+      <div className="flex-1 overflow-y-auto px-4 py-2 space-y-1">
+        {messages.length === 0 && (
+          <p className="text-gray-500 text-sm text-center mt-4">No messages yet</p>
+        )}
+        {messages.map((message) => (
+          <div key={message.id} className="group flex items-start text-sm">
+            <p className="flex-1 break-words">
+              <span className="font-semibold text-blue-400">{message.username}</span>
+              <span className="text-gray-200">: {message.content}</span>
+            </p>
+            {isOwner && (
+              <div className="hidden group-hover:flex space-x-1 ml-2 text-gray-400">
+                <button title="Delete" onClick={() => sendCommand({ type: 'delete_message', message_id: message.id })}>
+                  <Trash2 className="w-4 h-4 hover:text-white" />
+                </button>
+                <button title="Timeout 10 min" onClick={() => sendCommand({ type: 'timeout', user_id: message.user_id, seconds: TIMEOUT_SECONDS })}>
+                  <Clock className="w-4 h-4 hover:text-white" />
+                </button>
+                <button title="Ban" onClick={() => sendCommand({ type: 'ban', user_id: message.user_id })}>
+                  <Ban className="w-4 h-4 hover:text-red-500" />
+                </button>
+              </div>
+            )}
+          </div>
+        ))}
+        <div ref={bottomRef} />
+      </div>
+
+      {notice && <p className="px-4 py-1 text-xs text-yellow-400">{notice}</p>}
+
+      {readOnly ? (
+        <p className="px-4 py-3 border-t border-gray-700 text-sm text-gray-400 text-center">
+          Sign in to chat
+        </p>
+      ) : (
+        <form onSubmit={handleSubmit} className="px-4 py-3 border-t border-gray-700 flex space-x-2">
+          <input
+            value={input}
+            onChange={(e) => setInput(e.target.value)}
+            maxLength={MAX_MESSAGE_LENGTH}
+            placeholder="Send a message"
+            className="flex-1 bg-gray-700 text-white rounded-lg px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"
+          />
+          <button
+            type="submit"
+            disabled={!connected || !input.trim()}
+            className="p-2 bg-blue-600 hover:bg-blue-700 disabled:opacity-50 rounded-lg transition"
+          >
+            <Send className="w-4 h-4 text-white" />
+          </button>
+        </form>
+      )}
+    </div>
+  );
+};
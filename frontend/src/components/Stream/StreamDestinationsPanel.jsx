@@ -0,0 +1,172 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { Play, Plus, Send, Square, Trash2 } from 'lucide-react';
+import { streamsAPI } from '../../api/streams';
+
+const POLL_INTERVAL = 5000;
+
+const STATUS_STYLES = {
+    live: 'bg-green-500/20 text-green-400',
+    connecting: 'bg-blue-500/20 text-blue-400',
+    reconnecting: 'bg-yellow-500/20 text-yellow-400',
+    idle: 'bg-gray-600 text-gray-300',
+    stopped: 'bg-gray-600 text-gray-400',
+};
+
+// Рестрим: входящий поток параллельно уходит на другие площадки по RTMP/SRT
+export const StreamDestinationsPanel = ({ streamId }) => {
+    const [destinations, setDestinations] = useState([]);
+    const [loading, setLoading] = useState(true);
+    const [busy, setBusy] = useState(null);
+    const [form, setForm] = useState({ name: '', url: '' });
+    const [error, setError] = useState('');
+
+    const loadDestinations = useCallback(async () => {
+        try {
+            const data = await streamsAPI.getDestinations(streamId);
+            setDestinations(data.destinations || []);
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to load destinations');
+        } finally {
+            setLoading(false);
+        }
+    }, [streamId]);
+
+    useEffect(() => {
+        loadDestinations();
+        const interval = setInterval(loadDestinations, POLL_INTERVAL);
+        return () => clearInterval(interval);
+    }, [loadDestinations]);
+
+    const run = async (id, action, fallback) => {
+        setError('');
+        setBusy(id);
+        try {
+            await action();
+            await loadDestinations();
+        } catch (err) {
+            setError(err.response?.data?.error || fallback);
+        } finally {
+            setBusy(null);
+        }
+    };
+
+    const handleCreate = async (e) => {
+        e.preventDefault();
+        if (!form.name.trim() || !form.url.trim()) return;
+
+        await run('new', async () => {
+            await streamsAPI.createDestination(streamId, { name: form.name.trim(), url: form.url.trim() });
+            setForm({ name: '', url: '' });
+        }, 'Failed to add destination');
+    };
+
+    const handleToggle = (destination) => run(
+        destination.id,
+        () => destination.enabled
+            ? streamsAPI.stopDestination(streamId, destination.id)
+            : streamsAPI.startDestination(streamId, destination.id),
+        'Failed to update destination',
+    );
+
+    const handleDelete = (destination) => {
+        if (!window.confirm(`Delete destination "${destination.name}"?`)) {
+            return;
+        }
+        run(destination.id, () => streamsAPI.deleteDestination(streamId, destination.id), 'Failed to delete destination');
+    };
+
+    return (
+        <div>
+            <label className="block text-sm font-medium text-gray-400 mb-2 flex items-center gap-2">
+                <Send className="w-4 h-4" />
+                Restream Destinations
+            </label>
+
+            {error && (
+                <div className="bg-red-500/10 border border-red-500 text-red-400 p-2 rounded-lg text-sm mb-2">
+                    {error}
+                </div>
+            )}
+
+            {loading ? (
+                <p className="text-sm text-gray-500">Loading destinations...</p>
+            ) : (
+                <div className="space-y-2">
+                    {destinations.length === 0 && (
+                        <p className="text-sm text-gray-500">No destinations. Add one to simulcast to other platforms.</p>
+                    )}
+
+                    {destinations.map((destination) => (
+                        <div key={destination.id} className="bg-gray-700 rounded-lg p-3">
+                            <div className="flex items-center justify-between gap-2">
+                                <div className="min-w-0">
+                                    <div className="flex items-center gap-2">
+                                        <span className="text-white font-medium">{destination.name}</span>
+                                        <span className={`text-xs px-2 py-0.5 rounded ${STATUS_STYLES[destination.status] || STATUS_STYLES.idle}`}>
+                                            {destination.status}
+                                        </span>
+                                    </div>
+                                    <p className="text-xs text-gray-400 font-mono truncate">
+                                        {destination.protocol.toUpperCase()} · {destination.url}
+                                    </p>
+                                </div>
+                                <div className="flex items-center gap-3 text-xs shrink-0">
+                                    <button
+                                        onClick={() => handleToggle(destination)}
+                                        disabled={busy === destination.id}
+                                        className="text-gray-300 hover:text-white transition flex items-center gap-1 disabled:opacity-50"
+                                    >
+                                        {destination.enabled ? <Square className="w-3 h-3" /> : <Play className="w-3 h-3" />}
+                                        {destination.enabled ? 'Stop' : 'Start'}
+                                    </button>
+                                    <button
+                                        onClick={() => handleDelete(destination)}
+                                        disabled={busy === destination.id}
+                                        className="text-red-400 hover:text-red-300 transition flex items-center gap-1 disabled:opacity-50"
+                                    >
+                                        <Trash2 className="w-3 h-3" />
+                                        Delete
+                                    </button>
+                                </div>
+                            </div>
+                            {destination.last_error && (
+                                <p className="text-xs text-yellow-400 mt-1 truncate">{destination.last_error}</p>
+                            )}
+                        </div>
+                    ))}
+
+                    <form onSubmit={handleCreate} className="flex flex-col sm:flex-row gap-2">
+                        <input
+                            type="text"
+                            value={form.name}
+                            onChange={(e) => setForm({ ...form, name: e.target.value })}
+                            maxLength={50}
+                            placeholder="Name (e.g. YouTube)"
+                            className="sm:w-40 px-3 py-1.5 bg-gray-700 border border-gray-600 rounded text-white text-sm focus:outline-none focus:ring-2 focus:ring-primary-500"
+                        />
+                        <input
+                            type="text"
+                            value={form.url}
+                            onChange={(e) => setForm({ ...form, url: e.target.value })}
+                            placeholder="rtmp://a.rtmp.youtube.com/live2/<key> or srt://host:port"
+                            className="flex-1 px-3 py-1.5 bg-gray-700 border border-gray-600 rounded text-white font-mono text-sm focus:outline-none focus:ring-2 focus:ring-primary-500"
+                        />
+                        <button
+                            type="submit"
+                            disabled={!form.name.trim() || !form.url.trim() || busy === 'new'}
+                            className="px-3 py-1.5 bg-gray-700 hover:bg-gray-600 border border-gray-600 rounded text-white text-sm transition flex items-center gap-1 disabled:opacity-50"
+                        >
+                            <Plus className="w-4 h-4" />
+                            Add
+                        </button>
+                    </form>
+                </div>
+            )}
+
+            <p className="text-xs text-gray-500 mt-2">
+                Enabled destinations receive the stream while you are live and reconnect automatically
+                if the platform drops the connection. The URL usually contains the platform's stream key.
+            </p>
+        </div>
+    );
+};
@@ -1,7 +1,10 @@
 import React, { useState } from 'react';
-import { X, Copy, Check, Edit2, Trash2, Radio, Eye, Clock, Video } from 'lucide-react';
+import { X, Copy, Check, Edit2, Trash2, Radio, Eye, Clock, CalendarClock } from 'lucide-react';
 import { Button } from '../Common';
 import { streamsAPI } from '../../api/streams';
+import { StreamKeysPanel } from './StreamKeysPanel';
+import { StreamHealthPanel } from './StreamHealthPanel';
+import { StreamDestinationsPanel } from './StreamDestinationsPanel';
 
 export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete }) => {
     const [isEditing, setIsEditing] = useState(false);
@@ -10,15 +13,13 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
         description: stream?.description || '',
     });
     const [copiedId, setCopiedId] = useState(false);
-    const [copiedKey, setCopiedKey] = useState(false);
     const [copiedUrl, setCopiedUrl] = useState(false);
-    const [copiedSrt, setCopiedSrt] = useState(false);
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState('');
 
     if (!isOpen || !stream) return null;
 
-    const srtUrl = `srt://localhost:6000?streamid=${stream.stream_key}`;
+    const hlsUrl = stream.hls_url || `http://localhost/live-streams/live-segments/${stream.id}/master.m3u8`;
 
     const handleCopyId = () => {
         navigator.clipboard.writeText(stream.id);
@@ -26,24 +27,12 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
         setTimeout(() => setCopiedId(false), 2000);
     };
 
-    const handleCopyKey = () => {
-        navigator.clipboard.writeText(stream.stream_key);
-        setCopiedKey(true);
-        setTimeout(() => setCopiedKey(false), 2000);
-    };
-
     const handleCopyUrl = () => {
-        navigator.clipboard.writeText(stream.hls_url || `http://localhost/hls/${stream.stream_key}/playlist.m3u8`);
+        navigator.clipboard.writeText(hlsUrl);
         setCopiedUrl(true);
         setTimeout(() => setCopiedUrl(false), 2000);
     };
 
-    const handleCopySrt = () => {
-        navigator.clipboard.writeText(srtUrl);
-        setCopiedSrt(true);
-        setTimeout(() => setCopiedSrt(false), 2000);
-    };
-
     const handleEdit = () => {
         setIsEditing(true);
         setFormData({
@@ -52,6 +41,20 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
         });
     };
 
+    const handleCancelSchedule = async () => {
+        setLoading(true);
+        setError('');
+
+        try {
+            await streamsAPI.cancelSchedule(stream.id);
+            onUpdate({ ...stream, status: 'offline' });
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to cancel schedule');
+        } finally {
+            setLoading(false);
+        }
+    };
+
     const handleUpdate = async () => {
         if (!formData.title) {
             setError('Title is required');
@@ -230,40 +233,22 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
                         </div>
                     </div>
 
-                    {/* SRT URL - ДОБАВЛЕНО */}
-                    <div>
-                        <label className="block text-sm font-medium text-gray-400 mb-2 flex items-center gap-2">
-                            <Video className="w-4 h-4" />
-                            SRT Server URL
-                        </label>
-                        <div className="flex gap-2">
-                            <input
-                                type="text"
-                                value={srtUrl}
-                                readOnly
-                                className="flex-1 px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white font-mono text-sm"
-                            />
+                    {/* Schedule */}
+                    {stream.status === 'scheduled' && stream.scheduled_start_at && (
+                        <div className="bg-gray-700 p-4 rounded-lg flex items-center justify-between">
+                            <div className="flex items-center gap-2 text-white">
+                                <CalendarClock className="w-5 h-5 text-primary-400" />
+                                Scheduled for {new Date(stream.scheduled_start_at).toLocaleString()}
+                            </div>
                             <button
-                                onClick={handleCopySrt}
-                                className="px-4 py-2 bg-gray-700 hover:bg-gray-600 border border-gray-600 rounded-lg text-white transition flex items-center gap-2"
+                                onClick={handleCancelSchedule}
+                                disabled={loading}
+                                className="text-sm text-red-400 hover:text-red-300 transition disabled:opacity-50"
                             >
-                                {copiedSrt ? (
-                                    <>
-                                        <Check className="w-4 h-4 text-green-400" />
-                                        Copied
-                                    </>
-                                ) : (
-                                    <>
-                                        <Copy className="w-4 h-4" />
-                                        Copy
-                                    </>
-                                )}
+                                Cancel schedule
                             </button>
                         </div>
-                        <p className="text-xs text-gray-500 mt-2">
-                            Use this URL in OBS Studio: Settings → Stream → Custom → Server URL
-                        </p>
-                    </div>
+                    )}
 
                     {/* Stream ID */}
                     <div>
@@ -296,39 +281,14 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
                         </div>
                     </div>
 
-                    {/* Stream Key */}
-                    <div>
-                        <label className="block text-sm font-medium text-gray-400 mb-2">
-                            Stream Key
-                        </label>
-                        <div className="flex gap-2">
-                            <input
-                                type="text"
-                                value={stream.stream_key}
-                                readOnly
-                                className="flex-1 px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white font-mono text-sm"
-                            />
-                            <button
-                                onClick={handleCopyKey}
-                                className="px-4 py-2 bg-gray-700 hover:bg-gray-600 border border-gray-600 rounded-lg text-white transition flex items-center gap-2"
-                            >
-                                {copiedKey ? (
-                                    <>
-                                        <Check className="w-4 h-4 text-green-400" />
-                                        Copied
-                                    </>
-                                ) : (
-                                    <>
-                                        <Copy className="w-4 h-4" />
-                                        Copy
-                                    </>
-                                )}
-                            </button>
-                        </div>
-                        <p className="text-xs text-gray-500 mt-2">
-                            Keep this key private. Do not share it publicly.
-                        </p>
-                    </div>
+                    {/* Stream Keys */}
+                    <StreamKeysPanel streamId={stream.id} />
+
+                    {/* Ingest health */}
+                    <StreamHealthPanel streamId={stream.id} />
+
+                    {/* Restreaming */}
+                    <StreamDestinationsPanel streamId={stream.id} />
 
                     {/* HLS URL */}
                     <div>
@@ -338,7 +298,7 @@ export const StreamDetailsModal = ({ stream, isOpen, onClose, onUpdate, onDelete
                         <div className="flex gap-2">
                             <input
                                 type="text"
-                                value={stream.hls_url || `http://localhost/hls/${stream.stream_key}/playlist.m3u8`}
+                                value={hlsUrl}
                                 readOnly
                                 className="flex-1 px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white font-mono text-sm"
                             />
@@ -0,0 +1,87 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { Activity } from 'lucide-react';
+import { streamsAPI } from '../../api/streams';
+
+const POLL_INTERVAL = 5000;
+
+const Metric = ({ label, value, warn }) => (
+    <div className="bg-gray-800 rounded p-2">
+        <div className="text-xs text-gray-400">{label}</div>
+        <div className={`text-sm font-semibold ${warn ? 'text-yellow-400' : 'text-white'}`}>{value}</div>
+    </div>
+);
+
+// Телеметрия эфира: канал SRT и прогресс транскодера, обновляется пока стрим в эфире
+export const StreamHealthPanel = ({ streamId }) => {
+    const [health, setHealth] = useState(null);
+    const [error, setError] = useState('');
+
+    const loadHealth = useCallback(async () => {
+        try {
+            const data = await streamsAPI.getStreamHealth(streamId, { limit: 60 });
+            setHealth(data);
+            setError('');
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to load stream health');
+        }
+    }, [streamId]);
+
+    useEffect(() => {
+        loadHealth();
+        const interval = setInterval(loadHealth, POLL_INTERVAL);
+        return () => clearInterval(interval);
+    }, [loadHealth]);
+
+    const samples = health?.samples || [];
+    const sample = health?.current || samples[samples.length - 1];
+    const link = sample?.link;
+    const encoder = sample?.encoder;
+
+    return (
+        <div>
+            <label className="block text-sm font-medium text-gray-400 mb-2 flex items-center gap-2">
+                <Activity className="w-4 h-4" />
+                Stream Health {health?.live ? '(live)' : '(last broadcast)'}
+            </label>
+
+            {error && <p className="text-sm text-red-400">{error}</p>}
+
+            {!error && !sample && (
+                <p className="text-sm text-gray-500">No telemetry yet. Health data appears once the stream goes live.</p>
+            )}
+
+            {sample && (
+                <div className="bg-gray-700 rounded-lg p-3 space-y-2">
+                    {link && (
+                        <div className="grid grid-cols-2 sm:grid-cols-4 gap-2">
+                            <Metric label="RTT" value={`${link.rtt_ms.toFixed(0)} ms`} warn={link.rtt_ms > 300} />
+                            <Metric label="Bitrate" value={`${(link.bitrate_kbps / 1000).toFixed(2)} Mbps`} />
+                            <Metric
+                                label="Packet loss"
+                                value={`${link.packet_loss_percent.toFixed(2)}%`}
+                                warn={link.packet_loss_percent > 1}
+                            />
+                            <Metric
+                                label="Retransmits / drops"
+                                value={`${link.packets_retransmitted} / ${link.packets_dropped}`}
+                                warn={link.packets_dropped > 0}
+                            />
+                        </div>
+                    )}
+                    {encoder && (
+                        <div className="grid grid-cols-2 sm:grid-cols-4 gap-2">
+                            <Metric label="FPS" value={encoder.fps.toFixed(1)} />
+                            <Metric label="Speed" value={`${encoder.speed.toFixed(2)}x`} warn={encoder.speed > 0 && encoder.speed < 0.95} />
+                            <Metric label="Dropped frames" value={encoder.dropped_frames} warn={encoder.dropped_frames > 0} />
+                            <Metric label="Duplicated frames" value={encoder.duplicate_frames} />
+                        </div>
+                    )}
+                    <p className="text-xs text-gray-500">
+                        Sampled {new Date(sample.sampled_at).toLocaleTimeString()}. Speed below 1.0x or growing
+                        packet loss usually means the encoder or the network can't keep up.
+                    </p>
+                </div>
+            )}
+        </div>
+    );
+};
@@ -0,0 +1,218 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import { Copy, Check, Eye, EyeOff, Key, Plus, RefreshCw, Trash2 } from 'lucide-react';
+import { streamsAPI } from '../../api/streams';
+
+const SRT_SERVER = 'srt://localhost:6000';
+
+const formatDate = (dateString) => {
+    if (!dateString) return 'never';
+    return new Date(dateString).toLocaleString('en-US', {
+        month: 'short',
+        day: 'numeric',
+        hour: '2-digit',
+        minute: '2-digit',
+    });
+};
+
+// Ключи публикации стрима: несколько именованных ключей, ротация и отзыв
+export const StreamKeysPanel = ({ streamId }) => {
+    const [keys, setKeys] = useState([]);
+    const [loading, setLoading] = useState(true);
+    const [busyKey, setBusyKey] = useState(null);
+    const [revealed, setRevealed] = useState({});
+    const [copied, setCopied] = useState(null);
+    const [newName, setNewName] = useState('');
+    const [error, setError] = useState('');
+
+    const loadKeys = useCallback(async () => {
+        try {
+            const data = await streamsAPI.getStreamKeys(streamId);
+            setKeys(data.keys || []);
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to load stream keys');
+        } finally {
+            setLoading(false);
+        }
+    }, [streamId]);
+
+    useEffect(() => {
+        loadKeys();
+    }, [loadKeys]);
+
+    const copy = (id, value) => {
+        navigator.clipboard.writeText(value);
+        setCopied(id);
+        setTimeout(() => setCopied(null), 2000);
+    };
+
+    const handleCreate = async (e) => {
+        e.preventDefault();
+        if (!newName.trim()) return;
+
+        setError('');
+        setBusyKey('new');
+        try {
+            await streamsAPI.createStreamKey(streamId, newName.trim());
+            setNewName('');
+            await loadKeys();
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to create stream key');
+        } finally {
+            setBusyKey(null);
+        }
+    };
+
+    const handleRotate = async (key) => {
+        if (!window.confirm(`Rotate "${key.name}"? Encoders using the current key will be disconnected.`)) {
+            return;
+        }
+
+        setError('');
+        setBusyKey(key.id);
+        try {
+            await streamsAPI.rotateStreamKey(streamId, key.id);
+            setRevealed({ ...revealed, [key.id]: true });
+            await loadKeys();
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to rotate stream key');
+        } finally {
+            setBusyKey(null);
+        }
+    };
+
+    const handleRevoke = async (key) => {
+        if (!window.confirm(`Revoke "${key.name}"? It will stop working immediately and cannot be restored.`)) {
+            return;
+        }
+
+        setError('');
+        setBusyKey(key.id);
+        try {
+            await streamsAPI.revokeStreamKey(streamId, key.id);
+            await loadKeys();
+        } catch (err) {
+            setError(err.response?.data?.error || 'Failed to revoke stream key');
+        } finally {
+            setBusyKey(null);
+        }
+    };
+
+    const activeKeys = keys.filter((key) => !key.revoked_at);
+    const revokedKeys = keys.filter((key) => key.revoked_at);
+
+    return (
+        <div>
+            <label className="block text-sm font-medium text-gray-400 mb-2 flex items-center gap-2">
+                <Key className="w-4 h-4" />
+                Stream Keys
+            </label>
+
+            {error && (
+                <div className="bg-red-500/10 border border-red-500 text-red-400 p-2 rounded-lg text-sm mb-2">
+                    {error}
+                </div>
+            )}
+
+            {loading ? (
+                <p className="text-sm text-gray-500">Loading keys...</p>
+            ) : (
+                <div className="space-y-2">
+                    {activeKeys.length === 0 && (
+                        <p className="text-sm text-gray-500">No active keys. Create one to start streaming.</p>
+                    )}
+
+                    {activeKeys.map((key) => {
+                        const srtUrl = `${SRT_SERVER}?streamid=${key.key}`;
+                        return (
+                            <div key={key.id} className="bg-gray-700 rounded-lg p-3">
+                                <div className="flex items-center justify-between mb-2">
+                                    <span className="text-white font-medium">{key.name}</span>
+                                    <span className="text-xs text-gray-400">
+                                        Last used: {formatDate(key.last_used_at)}
+                                    </span>
+                                </div>
+                                <div className="flex gap-2">
+                                    <input
+                                        type={revealed[key.id] ? 'text' : 'password'}
+                                        value={key.key}
+                                        readOnly
+                                        className="flex-1 px-3 py-1.5 bg-gray-800 border border-gray-600 rounded text-white font-mono text-sm"
+                                    />
+                                    <button
+                                        onClick={() => setRevealed({ ...revealed, [key.id]: !revealed[key.id] })}
+                                        className="px-2 text-gray-400 hover:text-white transition"
+                                        title={revealed[key.id] ? 'Hide' : 'Show'}
+                                    >
+                                        {revealed[key.id] ? <EyeOff className="w-4 h-4" /> : <Eye className="w-4 h-4" />}
+                                    </button>
+                                    <button
+                                        onClick={() => copy(key.id, key.key)}
+                                        className="px-2 text-gray-400 hover:text-white transition"
+                                        title="Copy key"
+                                    >
+                                        {copied === key.id ? <Check className="w-4 h-4 text-green-400" /> : <Copy className="w-4 h-4" />}
+                                    </button>
+                                </div>
+                                <div className="flex items-center gap-3 mt-2 text-xs">
+                                    <button
+                                        onClick={() => copy(`${key.id}-srt`, srtUrl)}
+                                        className="text-gray-400 hover:text-white transition flex items-center gap-1"
+                                    >
+                                        {copied === `${key.id}-srt` ? <Check className="w-3 h-3 text-green-400" /> : <Copy className="w-3 h-3" />}
+                                        Copy SRT URL
+                                    </button>
+                                    <button
+                                        onClick={() => handleRotate(key)}
+                                        disabled={busyKey === key.id}
+                                        className="text-yellow-400 hover:text-yellow-300 transition flex items-center gap-1 disabled:opacity-50"
+                                    >
+                                        <RefreshCw className="w-3 h-3" />
+                                        Rotate
+                                    </button>
+                                    <button
+                                        onClick={() => handleRevoke(key)}
+                                        disabled={busyKey === key.id}
+                                        className="text-red-400 hover:text-red-300 transition flex items-center gap-1 disabled:opacity-50"
+                                    >
+                                        <Trash2 className="w-3 h-3" />
+                                        Revoke
+                                    </button>
+                                </div>
+                            </div>
+                        );
+                    })}
+
+                    <form onSubmit={handleCreate} className="flex gap-2">
+                        <input
+                            type="text"
+                            value={newName}
+                            onChange={(e) => setNewName(e.target.value)}
+                            maxLength={50}
+                            placeholder="New key name (e.g. backup encoder)"
+                            className="flex-1 px-3 py-1.5 bg-gray-700 border border-gray-600 rounded text-white text-sm focus:outline-none focus:ring-2 focus:ring-primary-500"
+                        />
+                        <button
+                            type="submit"
+                            disabled={!newName.trim() || busyKey === 'new'}
+                            className="px-3 py-1.5 bg-gray-700 hover:bg-gray-600 border border-gray-600 rounded text-white text-sm transition flex items-center gap-1 disabled:opacity-50"
+                        >
+                            <Plus className="w-4 h-4" />
+                            Add key
+                        </button>
+                    </form>
+
+                    {revokedKeys.length > 0 && (
+                        <p className="text-xs text-gray-500">
+                            Revoked: {revokedKeys.map((key) => `${key.name} (${formatDate(key.revoked_at)})`).join(', ')}
+                        </p>
+                    )}
+                </div>
+            )}
+
+            <p className="text-xs text-gray-500 mt-2">
+                Use the SRT URL in OBS Studio: Settings → Stream → Custom → Server URL. Keep keys private;
+                rotate or revoke a key if it leaks.
+            </p>
+        </div>
+    );
+};
@@ -2,4 +2,8 @@ export { CreateStreamModal } from './CreateStreamModal';
 export { StreamCard } from './StreamCard';
 export { LiveStreamCard } from './LiveStreamCard';
 export { StreamDetailsModal } from './StreamDetailsModal';  // ← Добавьте
-export { LivePlayer } from './LivePlayer';
\ No newline at end of file
+export { LivePlayer } from './LivePlayer';
+export { StreamChat } from './StreamChat';
+export { StreamKeysPanel } from './StreamKeysPanel';
+export { StreamHealthPanel } from './StreamHealthPanel';
+export { StreamDestinationsPanel } from './StreamDestinationsPanel';
@@ -0,0 +1,180 @@
+import React, { useState } from 'react';
+import { Link } from 'react-router-dom';
+import { X, Scissors } from 'lucide-react';
+import { videosAPI } from '../../api/videos';
+
+const MIN_CLIP_SECONDS = 5;
+const MAX_CLIP_SECONDS = 60;
+const LIVE_CLIP_LENGTHS = [15, 30, 60];
+
+// Клип из live стрима (последние N секунд эфира) или из видео (отрезок start-end)
+export const CreateClipModal = ({ isOpen, onClose, streamId, video }) => {
+  const [title, setTitle] = useState('');
+  const [visibility, setVisibility] = useState('public');
+  const [liveLength, setLiveLength] = useState(30);
+  const [start, setStart] = useState(0);
+  const [end, setEnd] = useState(Math.min(30, video?.duration || 30));
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [clip, setClip] = useState(null);
+
+  const handleClose = () => {
+    setClip(null);
+    setError('');
+    onClose();
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+
+    const request = { title, visibility };
+    if (streamId) {
+      const endAt = new Date();
+      request.stream_id = streamId;
+      request.start_at = new Date(endAt.getTime() - liveLength * 1000).toISOString();
+      request.end_at = endAt.toISOString();
+    } else {
+      const length = Number(end) - Number(start);
+      if (length < MIN_CLIP_SECONDS || length > MAX_CLIP_SECONDS) {
+        setError(`Clip must be ${MIN_CLIP_SECONDS}-${MAX_CLIP_SECONDS} seconds long`);
+        return;
+      }
+      request.video_id = video.id;
+      request.start_seconds = Number(start);
+      request.end_seconds = Number(end);
+    }
+
+    setLoading(true);
+    try {
+      const data = await videosAPI.createClip(request);
+      setClip(data.clip);
+    } catch (err) {
+      console.error('❌ Failed to create clip:', err);
+      setError(err.response?.data?.error || 'Failed to create clip');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (!isOpen) return null;
+
+  const inputClass =
+    'w-full px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white placeholder-gray-400 focus:outline-none focus:ring-2 focus:ring-primary-600 focus:border-transparent';
+
+  return (
+    <div className="fixed inset-0 bg-black/70 flex items-center justify-center z-50 p-4">
+      <div className="bg-gray-800 rounded-xl max-w-md w-full">
+        <div className="flex items-center justify-between p-6 border-b border-gray-700">
+          <h2 className="text-2xl font-bold text-white flex items-center gap-2">
+            <Scissors className="w-6 h-6" />
+            Create Clip
+          </h2>
+          <button onClick={handleClose} className="text-gray-400 hover:text-white transition">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        {clip ? (
+          <div className="p-6 space-y-4">
+            <p className="text-gray-300">
+              Your clip is being processed. It will be playable in a minute.
+            </p>
+            <Link
+              to={`/video/${clip.id}`}
+              className="inline-flex items-center px-4 py-2 bg-indigo-600 hover:bg-indigo-700 text-white rounded-lg transition"
+            >
+              Open clip
+            </Link>
+          </div>
+        ) : (
+          <form onSubmit={handleSubmit} className="p-6 space-y-4">
+            {error && (
+              <div className="bg-red-600/20 border border-red-600 text-red-400 px-4 py-3 rounded-lg">
+                {error}
+              </div>
+            )}
+
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Title *</label>
+              <input
+                type="text"
+                value={title}
+                onChange={(e) => setTitle(e.target.value)}
+                required
+                minLength={3}
+                className={inputClass}
+                placeholder="Enter clip title"
+              />
+            </div>
+
+            {streamId ? (
+              <div>
+                <label className="block text-sm font-medium text-gray-300 mb-2">Length</label>
+                <select value={liveLength} onChange={(e) => setLiveLength(Number(e.target.value))} className={inputClass}>
+                  {LIVE_CLIP_LENGTHS.map((seconds) => (
+                    <option key={seconds} value={seconds}>
+                      Last {seconds} seconds
+                    </option>
+                  ))}
+                </select>
+              </div>
+            ) : (
+              <div className="grid grid-cols-2 gap-4">
+                <div>
+                  <label className="block text-sm font-medium text-gray-300 mb-2">Start (seconds)</label>
+                  <input
+                    type="number"
+                    min={0}
+                    step="0.1"
+                    value={start}
+                    onChange={(e) => setStart(e.target.value)}
+                    className={inputClass}
+                  />
+                </div>
+                <div>
+                  <label className="block text-sm font-medium text-gray-300 mb-2">End (seconds)</label>
+                  <input
+                    type="number"
+                    min={0}
+                    max={video?.duration || undefined}
+                    step="0.1"
+                    value={end}
+                    onChange={(e) => setEnd(e.target.value)}
+                    className={inputClass}
+                  />
+                </div>
+              </div>
+            )}
+
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Visibility</label>
+              <select value={visibility} onChange={(e) => setVisibility(e.target.value)} className={inputClass}>
+                <option value="public">Public - Anyone can watch</option>
+                <option value="unlisted">Unlisted - Only with link</option>
+                <option value="private">Private - Only you</option>
+              </select>
+            </div>
+
+            <div className="flex justify-end gap-3 pt-2">
+              <button
+                type="button"
+                onClick={handleClose}
+                className="px-4 py-2 bg-gray-700 hover:bg-gray-600 text-white rounded-lg transition"
+              >
+                Cancel
+              </button>
+              <button
+                type="submit"
+                disabled={loading}
+                className="px-4 py-2 bg-indigo-600 hover:bg-indigo-700 disabled:opacity-50 text-white rounded-lg transition"
+              >
+                {loading ? 'Creating...' : 'Create Clip'}
+              </button>
+            </div>
+          </form>
+        )}
+      </div>
+    </div>
+  );
+};
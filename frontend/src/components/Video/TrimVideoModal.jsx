@@ -0,0 +1,198 @@
+import React, { useEffect, useState } from 'react';
+import { X, ListOrdered, Plus, Trash2 } from 'lucide-react';
+import { videosAPI } from '../../api/videos';
+
+// Обрезка видео (точки in/out) и главы. Главы задаются на таймлайне после обрезки
+export const TrimVideoModal = ({ isOpen, onClose, video, onSaved }) => {
+  const [start, setStart] = useState(0);
+  const [end, setEnd] = useState(video?.duration || 0);
+  const [chapters, setChapters] = useState([]);
+  const [chaptersChanged, setChaptersChanged] = useState(false);
+  const [edit, setEdit] = useState(null);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [message, setMessage] = useState('');
+
+  useEffect(() => {
+    if (!isOpen || !video) return;
+
+    setStart(0);
+    setEnd(video.duration || 0);
+    setError('');
+    setMessage('');
+    videosAPI
+      .getVideoEdit(video.id)
+      .then((data) => {
+        setEdit(data.edit);
+        setChapters(data.chapters || []);
+        setChaptersChanged(false);
+      })
+      .catch((err) => setError(err.response?.data?.error || 'Failed to load video edit'));
+  }, [isOpen, video?.id]);
+
+  const changeChapters = (update) => {
+    setChapters(update);
+    setChaptersChanged(true);
+  };
+
+  const updateChapter = (index, field, value) => {
+    changeChapters((prev) => prev.map((chapter, i) => (i === index ? { ...chapter, [field]: value } : chapter)));
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    setMessage('');
+
+    const request = {};
+    const trimming = Number(start) > 0 || Number(end) < video.duration;
+    if (trimming) {
+      request.trim_start_seconds = Number(start);
+      request.trim_end_seconds = Number(end);
+    }
+    // Нетронутые главы сервер сам сдвигает вместе с обрезкой
+    if (!trimming || chaptersChanged) {
+      request.chapters = chapters.map((chapter) => ({
+        start_seconds: Number(chapter.start_seconds),
+        title: chapter.title,
+      }));
+    }
+
+    setLoading(true);
+    try {
+      const data = await videosAPI.editVideo(video.id, request);
+      if (data.edit) {
+        setEdit(data.edit);
+      }
+      setMessage(data.message);
+      onSaved?.(data);
+    } catch (err) {
+      console.error('❌ Failed to edit video:', err);
+      setError(err.response?.data?.error || 'Failed to edit video');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (!isOpen) return null;
+
+  const processing = edit?.status === 'processing';
+  const inputClass =
+    'w-full px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white placeholder-gray-400 focus:outline-none focus:ring-2 focus:ring-primary-600 focus:border-transparent';
+
+  return (
+    <div className="fixed inset-0 bg-black/70 flex items-center justify-center z-50 p-4">
+      <div className="bg-gray-800 rounded-xl max-w-lg w-full max-h-[90vh] overflow-y-auto">
+        <div className="flex items-center justify-between p-6 border-b border-gray-700">
+          <h2 className="text-2xl font-bold text-white flex items-center gap-2">
+            <ListOrdered className="w-6 h-6" />
+            Trim & Chapters
+          </h2>
+          <button onClick={onClose} className="text-gray-400 hover:text-white transition">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        <form onSubmit={handleSubmit} className="p-6 space-y-4">
+          {error && (
+            <div className="bg-red-600/20 border border-red-600 text-red-400 px-4 py-3 rounded-lg">{error}</div>
+          )}
+          {message && (
+            <div className="bg-green-600/20 border border-green-600 text-green-400 px-4 py-3 rounded-lg">{message}</div>
+          )}
+          {processing && (
+            <p className="text-sm text-yellow-400">The video is being trimmed. Edits are available when it is finished.</p>
+          )}
+          {edit?.status === 'failed' && <p className="text-sm text-red-400">Last trim failed: {edit.error}</p>}
+
+          <div className="grid grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Trim start (seconds)</label>
+              <input
+                type="number"
+                min={0}
+                step="0.1"
+                value={start}
+                onChange={(e) => setStart(e.target.value)}
+                className={inputClass}
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Trim end (seconds)</label>
+              <input
+                type="number"
+                min={0}
+                max={video?.duration || undefined}
+                step="0.1"
+                value={end}
+                onChange={(e) => setEnd(e.target.value)}
+                className={inputClass}
+              />
+            </div>
+          </div>
+
+          <div>
+            <label className="block text-sm font-medium text-gray-300 mb-2">Chapters</label>
+            <p className="text-xs text-gray-500 mb-2">Chapter start times refer to the video after trimming.</p>
+            <div className="space-y-2">
+              {chapters.map((chapter, index) => (
+                <div key={index} className="flex gap-2">
+                  <input
+                    type="number"
+                    min={0}
+                    step="0.1"
+                    value={chapter.start_seconds}
+                    onChange={(e) => updateChapter(index, 'start_seconds', e.target.value)}
+                    className={`${inputClass} w-28`}
+                  />
+                  <input
+                    type="text"
+                    value={chapter.title}
+                    onChange={(e) => updateChapter(index, 'title', e.target.value)}
+                    required
+                    maxLength={100}
+                    placeholder="Chapter title"
+                    className={inputClass}
+                  />
+                  <button
+                    type="button"
+                    onClick={() => changeChapters((prev) => prev.filter((_, i) => i !== index))}
+                    className="p-2 text-gray-400 hover:text-red-400 transition"
+                    title="Remove chapter"
+                  >
+                    <Trash2 className="w-5 h-5" />
+                  </button>
+                </div>
+              ))}
+            </div>
+            <button
+              type="button"
+              onClick={() => changeChapters((prev) => [...prev, { start_seconds: 0, title: '' }])}
+              className="mt-2 inline-flex items-center gap-1 text-sm text-indigo-400 hover:text-indigo-300 transition"
+            >
+              <Plus className="w-4 h-4" />
+              Add chapter
+            </button>
+          </div>
+
+          <div className="flex justify-end gap-3 pt-2">
+            <button
+              type="button"
+              onClick={onClose}
+              className="px-4 py-2 bg-gray-700 hover:bg-gray-600 text-white rounded-lg transition"
+            >
+              Close
+            </button>
+            <button
+              type="submit"
+              disabled={loading || processing}
+              className="px-4 py-2 bg-indigo-600 hover:bg-indigo-700 disabled:opacity-50 text-white rounded-lg transition"
+            >
+              {loading ? 'Saving...' : 'Save'}
+            </button>
+          </div>
+        </form>
+      </div>
+    </div>
+  );
+};
@@ -0,0 +1,257 @@
+import React, { useRef, useState } from 'react';
+import { X, Upload } from 'lucide-react';
+import { videosAPI } from '../../api/videos';
+
+const CHUNK_SIZE = 32 * 1024 * 1024; // совпадает с chunk_size сервера
+const RETRY_DELAY_MS = 3000;
+const MAX_RETRIES = 5;
+
+// Незавершённые загрузки запоминаются по файлу, чтобы продолжить их после обрыва или перезагрузки страницы
+const resumeKey = (file) => `vod-upload:${file.name}:${file.size}:${file.lastModified}`;
+
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+// Загрузка видеофайла порциями с продолжением с последнего полученного сервером байта
+export const UploadVideoModal = ({ isOpen, onClose, onUploaded }) => {
+  const [file, setFile] = useState(null);
+  const [formData, setFormData] = useState({
+    title: '',
+    description: '',
+    category: '',
+    visibility: 'public',
+  });
+  const [progress, setProgress] = useState(0);
+  const [uploading, setUploading] = useState(false);
+  const [error, setError] = useState('');
+  const [message, setMessage] = useState('');
+  const cancelled = useRef(false);
+  const uploadId = useRef(null);
+
+  const handleFileChange = (e) => {
+    const selected = e.target.files[0];
+    setFile(selected || null);
+    setProgress(0);
+    if (selected && !formData.title) {
+      setFormData((prev) => ({ ...prev, title: selected.name.replace(/\.[^.]+$/, '') }));
+    }
+  };
+
+  const handleChange = (e) => {
+    setFormData((prev) => ({ ...prev, [e.target.name]: e.target.value }));
+  };
+
+  // Создаёт загрузку или продолжает сохранённую; возвращает { id, offset, chunkSize }
+  const startUpload = async () => {
+    const savedId = localStorage.getItem(resumeKey(file));
+    if (savedId) {
+      try {
+        const data = await videosAPI.getUpload(savedId);
+        if (data.upload.status === 'uploading') {
+          return { id: savedId, offset: data.upload.offset, chunkSize: CHUNK_SIZE };
+        }
+      } catch (err) {
+        console.warn('⚠️ Saved upload is not available, starting a new one:', err);
+      }
+      localStorage.removeItem(resumeKey(file));
+    }
+
+    const data = await videosAPI.createUpload({
+      ...formData,
+      filename: file.name,
+      size: file.size,
+      tags: [],
+    });
+    localStorage.setItem(resumeKey(file), data.upload.id);
+    return { id: data.upload.id, offset: 0, chunkSize: data.chunk_size };
+  };
+
+  // После сетевой ошибки сервер может уже иметь часть порции - берём offset у него
+  const sendChunks = async (id, startOffset, chunkSize) => {
+    let offset = startOffset;
+    let retries = 0;
+
+    while (offset < file.size) {
+      if (cancelled.current) return false;
+      try {
+        const chunk = file.slice(offset, offset + chunkSize);
+        offset = await videosAPI.uploadChunk(id, offset, chunk);
+        retries = 0;
+      } catch (err) {
+        const status = err.response?.status;
+        if (status && status !== 409 && status !== 423 && status < 500) throw err;
+        if (++retries > MAX_RETRIES) throw err;
+
+        console.warn(`⚠️ Chunk upload failed (attempt ${retries}), resuming:`, err);
+        await sleep(RETRY_DELAY_MS);
+        const data = await videosAPI.getUpload(id);
+        if (data.upload.status !== 'uploading') break;
+        offset = data.upload.offset;
+      }
+      setProgress(Math.round((offset / file.size) * 100));
+    }
+    return true;
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    if (!file) return;
+
+    setError('');
+    setMessage('');
+    setUploading(true);
+    cancelled.current = false;
+
+    try {
+      const { id, offset, chunkSize } = await startUpload();
+      uploadId.current = id;
+      setProgress(Math.round((offset / file.size) * 100));
+
+      const finished = await sendChunks(id, offset, chunkSize || CHUNK_SIZE);
+      if (!finished) return;
+
+      localStorage.removeItem(resumeKey(file));
+      setMessage('Upload complete. The video will be available after processing.');
+      onUploaded?.();
+    } catch (err) {
+      console.error('❌ Failed to upload video:', err);
+      setError(err.response?.data?.error || 'Failed to upload video. Try again to resume.');
+    } finally {
+      setUploading(false);
+    }
+  };
+
+  const handleCancel = async () => {
+    if (uploading && uploadId.current) {
+      cancelled.current = true;
+      try {
+        await videosAPI.cancelUpload(uploadId.current);
+        localStorage.removeItem(resumeKey(file));
+      } catch (err) {
+        console.error('❌ Failed to cancel upload:', err);
+      }
+    }
+    onClose();
+  };
+
+  if (!isOpen) return null;
+
+  const inputClass =
+    'w-full px-4 py-2 bg-gray-700 border border-gray-600 rounded-lg text-white placeholder-gray-400 focus:outline-none focus:ring-2 focus:ring-primary-600 focus:border-transparent';
+
+  return (
+    <div className="fixed inset-0 bg-black/70 flex items-center justify-center z-50 p-4">
+      <div className="bg-gray-800 rounded-xl max-w-lg w-full max-h-[90vh] overflow-y-auto">
+        <div className="flex items-center justify-between p-6 border-b border-gray-700">
+          <h2 className="text-2xl font-bold text-white flex items-center gap-2">
+            <Upload className="w-6 h-6" />
+            Upload Video
+          </h2>
+          <button onClick={handleCancel} className="text-gray-400 hover:text-white transition">
+            <X className="w-6 h-6" />
+          </button>
+        </div>
+
+        <form onSubmit={handleSubmit} className="p-6 space-y-4">
+          {error && (
+            <div className="bg-red-600/20 border border-red-600 text-red-400 px-4 py-3 rounded-lg">{error}</div>
+          )}
+          {message && (
+            <div className="bg-green-600/20 border border-green-600 text-green-400 px-4 py-3 rounded-lg">{message}</div>
+          )}
+
+          <div>
+            <label className="block text-sm font-medium text-gray-300 mb-2">Video file</label>
+            <input
+              type="file"
+              accept="video/*"
+              onChange={handleFileChange}
+              disabled={uploading}
+              className="w-full text-gray-300"
+            />
+          </div>
+
+          <div>
+            <label className="block text-sm font-medium text-gray-300 mb-2">Title</label>
+            <input
+              type="text"
+              name="title"
+              value={formData.title}
+              onChange={handleChange}
+              required
+              minLength={3}
+              maxLength={255}
+              disabled={uploading}
+              className={inputClass}
+            />
+          </div>
+
+          <div>
+            <label className="block text-sm font-medium text-gray-300 mb-2">Description</label>
+            <textarea
+              name="description"
+              value={formData.description}
+              onChange={handleChange}
+              rows={3}
+              disabled={uploading}
+              className={inputClass}
+            />
+          </div>
+
+          <div className="grid grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Category</label>
+              <input
+                type="text"
+                name="category"
+                value={formData.category}
+                onChange={handleChange}
+                disabled={uploading}
+                className={inputClass}
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium text-gray-300 mb-2">Visibility</label>
+              <select
+                name="visibility"
+                value={formData.visibility}
+                onChange={handleChange}
+                disabled={uploading}
+                className={inputClass}
+              >
+                <option value="public">Public</option>
+                <option value="unlisted">Unlisted</option>
+                <option value="private">Private</option>
+              </select>
+            </div>
+          </div>
+
+          {(uploading || progress > 0) && (
+            <div>
+              <div className="w-full bg-gray-700 rounded-full h-2">
+                <div className="bg-indigo-600 h-2 rounded-full transition-all" style={{ width: `${progress}%` }} />
+              </div>
+              <p className="text-sm text-gray-400 mt-1">{progress}%</p>
+            </div>
+          )}
+
+          <div className="flex justify-end gap-3 pt-2">
+            <button
+              type="button"
+              onClick={handleCancel}
+              className="px-4 py-2 bg-gray-700 hover:bg-gray-600 text-white rounded-lg transition"
+            >
+              {uploading ? 'Cancel upload' : 'Close'}
+            </button>
+            <button
+              type="submit"
+              disabled={!file || uploading || Boolean(message)}
+              className="px-4 py-2 bg-indigo-600 hover:bg-indigo-700 disabled:opacity-50 text-white rounded-lg transition"
+            >
+              {uploading ? 'Uploading...' : 'Upload'}
+            </button>
+          </div>
+        </form>
+      </div>
+    </div>
+  );
+};
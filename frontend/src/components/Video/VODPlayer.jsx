@@ -40,6 +40,8 @@ export const VODPlayer = ({ videoUrl, autoplay = false, startTime = 0 }) => {
         html5: {
           vhs: {
             overrideNative: true,
+            // master.m3u8 приватного видео проверяет владельца по cookie
+            withCredentials: true,
           },
         },
       });
@@ -0,0 +1,215 @@
+import { useEffect, useState } from 'react';
+import { MessageSquare, Trash2, Edit2, CornerDownRight } from 'lucide-react';
+import { videosAPI } from '../../api/videos';
+import { useAuth } from '../../hooks/useAuth';
+
+const formatTimestamp = (seconds) => {
+  const total = Math.floor(seconds);
+  const m = Math.floor(total / 60);
+  const s = String(total % 60).padStart(2, '0');
+  return `${m}:${s}`;
+};
+
+// "1:23" или "83" → секунды; пустая строка → undefined
+const parseTimestamp = (value) => {
+  if (!value.trim()) return undefined;
+  const parts = value.trim().split(':').map(Number);
+  if (parts.some(Number.isNaN)) return NaN;
+  return parts.reduce((acc, part) => acc * 60 + part, 0);
+};
+
+const CommentItem = ({ comment, videoId, canModerate, userId, onChanged, onReply }) => {
+  const [editing, setEditing] = useState(false);
+  const [text, setText] = useState(comment.content);
+  const isAuthor = userId && comment.user_id === userId;
+
+  const handleSave = async () => {
+    await videosAPI.updateComment(videoId, comment.id, text);
+    setEditing(false);
+    onChanged();
+  };
+
+  const handleDelete = async () => {
+    if (!window.confirm('Delete this comment?')) return;
+    await videosAPI.deleteComment(videoId, comment.id);
+    onChanged();
+  };
+
+  if (comment.deleted) {
+    return <p className="text-gray-500 text-sm italic">Comment deleted</p>;
+  }
+
+  return (
+    <div className="text-sm">
+      <div className="flex items-center space-x-2 text-gray-400">
+        <span className="font-semibold text-white">{comment.username}</span>
+        {comment.timestamp_seconds != null && (
+          <span className="px-1.5 py-0.5 bg-blue-600/30 text-blue-300 rounded text-xs">
+            {formatTimestamp(comment.timestamp_seconds)}
+          </span>
+        )}
+        <span className="text-xs">{new Date(comment.created_at).toLocaleString()}</span>
+        {comment.edited_at && <span className="text-xs">(edited)</span>}
+      </div>
+
+      {editing ? (
+        <div className="flex space-x-2 mt-1">
+          <input
+            value={text}
+            onChange={(e) => setText(e.target.value)}
+            maxLength={2000}
+            className="flex-1 bg-gray-700 text-white rounded px-2 py-1"
+          />
+          <button onClick={handleSave} className="text-blue-400 hover:text-blue-300">Save</button>
+          <button onClick={() => setEditing(false)} className="text-gray-400 hover:text-white">Cancel</button>
+        </div>
+      ) : (
+        <p className="text-gray-200 whitespace-pre-wrap mt-1">{comment.content}</p>
+      )}
+
+      <div className="flex items-center space-x-3 mt-1 text-xs text-gray-400">
+        {onReply && userId && (
+          <button onClick={() => onReply(comment)} className="flex items-center hover:text-white">
+            <CornerDownRight className="w-3 h-3 mr-1" /> Reply
+          </button>
+        )}
+        {isAuthor && !editing && (
+          <button onClick={() => setEditing(true)} className="flex items-center hover:text-white">
+            <Edit2 className="w-3 h-3 mr-1" /> Edit
+          </button>
+        )}
+        {(isAuthor || canModerate) && (
+          <button onClick={handleDelete} className="flex items-center hover:text-red-400">
+            <Trash2 className="w-3 h-3 mr-1" /> Delete
+          </button>
+        )}
+      </div>
+    </div>
+  );
+};
+
+export const VideoComments = ({ videoId, isOwner }) => {
+  const { user } = useAuth();
+  const [comments, setComments] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [content, setContent] = useState('');
+  const [timestamp, setTimestamp] = useState('');
+  const [replyTo, setReplyTo] = useState(null);
+  const [error, setError] = useState(null);
+
+  const fetchComments = async () => {
+    try {
+      const data = await videosAPI.getComments(videoId, { limit: 50 });
+      setComments(data.comments || []);
+      setTotal(data.total || 0);
+    } catch (err) {
+      console.error('Failed to load comments:', err);
+    }
+  };
+
+  useEffect(() => {
+    fetchComments();
+  }, [videoId]);
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    if (!content.trim()) return;
+
+    const timestampSeconds = replyTo ? undefined : parseTimestamp(timestamp);
+    if (Number.isNaN(timestampSeconds)) {
+      setError('Timestamp must look like 1:23');
+      return;
+    }
+
+    try {
+      await videosAPI.createComment(videoId, content, {
+        timestampSeconds,
+        parentId: replyTo?.id,
+      });
+      setContent('');
+      setTimestamp('');
+      setReplyTo(null);
+      setError(null);
+      fetchComments();
+    } catch (err) {
+      setError(err.response?.data?.error || 'Failed to post comment');
+    }
+  };
+
+  return (
+    <div className="bg-gray-800 rounded-lg p-6 mt-4">
+      <h3 className="text-white font-semibold mb-4 flex items-center">
+        <MessageSquare className="w-5 h-5 mr-2" /> Comments ({total})
+      </h3>
+
+      {user ? (
+        <form onSubmit={handleSubmit} className="mb-6 space-y-2">
+          {replyTo && (
+            <p className="text-xs text-gray-400">
+              Replying to <span className="text-white">{replyTo.username}</span>{' '}
+              <button type="button" onClick={() => setReplyTo(null)} className="text-blue-400">cancel</button>
+            </p>
+          )}
+          <div className="flex space-x-2">
+            {!replyTo && (
+              <input
+                value={timestamp}
+                onChange={(e) => setTimestamp(e.target.value)}
+                placeholder="0:00"
+                title="Optional video timestamp"
+                className="w-20 bg-gray-700 text-white rounded-lg px-3 py-2 text-sm"
+              />
+            )}
+            <input
+              value={content}
+              onChange={(e) => setContent(e.target.value)}
+              maxLength={2000}
+              placeholder={replyTo ? 'Write a reply' : 'Add a comment'}
+              className="flex-1 bg-gray-700 text-white rounded-lg px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"
+            />
+            <button type="submit" className="px-4 py-2 bg-blue-600 hover:bg-blue-700 text-white text-sm rounded-lg">
+              Post
+            </button>
+          </div>
+          {error && <p className="text-xs text-red-400">{error}</p>}
+        </form>
+      ) : (
+        <p className="text-gray-400 text-sm mb-6">Sign in to comment</p>
+      )}
+
+      {comments.length === 0 ? (
+        <p className="text-gray-500 text-center py-8">No comments yet</p>
+      ) : (
+        <div className="space-y-4">
+          {comments.map((comment) => (
+            <div key={comment.id}>
+              <CommentItem
+                comment={comment}
+                videoId={videoId}
+                canModerate={isOwner}
+                userId={user?.id}
+                onChanged={fetchComments}
+                onReply={setReplyTo}
+              />
+              {comment.replies?.length > 0 && (
+                <div className="ml-6 mt-2 pl-4 border-l border-gray-700 space-y-3">
+                  {comment.replies.map((reply) => (
+                    <CommentItem
+                      key={reply.id}
+                      comment={reply}
+                      videoId={videoId}
+                      canModerate={isOwner}
+                      userId={user?.id}
+                      onChanged={fetchComments}
+                      onReply={() => setReplyTo(comment)}
+                    />
+                  ))}
+                </div>
+              )}
+            </div>
+          ))}
+        </div>
+      )}
+    </div>
+  );
+};
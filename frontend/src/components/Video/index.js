@@ -2,4 +2,8 @@ export { VideoList } from './VideoList';
 export { VideoCard } from './VideoCard';
 //export { VideoJS } from './VideoJS';
 export { EditVideoModal } from './EditVideoModal'; // ✅ Добавить
-export { VODPlayer } from './VODPlayer';
\ No newline at end of file
+export { VODPlayer } from './VODPlayer';
+export { VideoComments } from './VideoComments';
+export { CreateClipModal } from './CreateClipModal';
+export { TrimVideoModal } from './TrimVideoModal';
+export { UploadVideoModal } from './UploadVideoModal';
@@ -0,0 +1,58 @@
+import { useEffect, useState } from 'react';
+
+const HEARTBEAT_INTERVAL = 10000;
+
+// Сессия просмотра живёт, пока открыта вкладка
+const newSessionId = () =>
+  crypto.randomUUID
+    ? crypto.randomUUID()
+    : 'xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx'.replace(/[xy]/g, (c) => {
+        const r = (Math.random() * 16) | 0;
+        return (c === 'x' ? r : (r & 0x3) | 0x8).toString(16);
+      });
+
+// Отправляет heartbeat зрителя, пока стрим открыт, и возвращает текущее число зрителей
+export const useViewerHeartbeat = (streamId, enabled, initialCount = 0) => {
+  const [viewerCount, setViewerCount] = useState(initialCount);
+
+  useEffect(() => {
+    setViewerCount(initialCount);
+  }, [initialCount]);
+
+  useEffect(() => {
+    if (!streamId || !enabled) return;
+
+    const sessionId = newSessionId();
+    const url = `http://localhost/api/streams/${streamId}/heartbeat`;
+
+    const send = async (leaving = false) => {
+      try {
+        const response = await fetch(url, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ session_id: sessionId, leaving }),
+          keepalive: leaving,
+        });
+        if (!leaving && response.ok) {
+          const data = await response.json();
+          setViewerCount(data.viewer_count);
+        }
+      } catch (err) {
+        console.error('Heartbeat failed:', err);
+      }
+    };
+
+    send();
+    const timer = setInterval(send, HEARTBEAT_INTERVAL);
+    const handleUnload = () => send(true);
+    window.addEventListener('pagehide', handleUnload);
+
+    return () => {
+      clearInterval(timer);
+      window.removeEventListener('pagehide', handleUnload);
+      send(true);
+    };
+  }, [streamId, enabled]);
+
+  return viewerCount;
+};
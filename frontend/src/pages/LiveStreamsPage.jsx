@@ -1,12 +1,13 @@
 import React, { useState, useEffect } from 'react';
 import { Header } from '../components/Layout';
 import { SearchBar } from '../components/Common';
-import { LiveStreamCard } from '../components/Stream';
-import { Radio, RefreshCw, Filter } from 'lucide-react';
+import { LiveStreamCard, StreamCard } from '../components/Stream';
+import { Radio, RefreshCw, Filter, CalendarClock } from 'lucide-react';
 import { streamsAPI } from '../api/streams';
 
 export const LiveStreamsPage = () => {
   const [streams, setStreams] = useState([]);
+  const [upcoming, setUpcoming] = useState([]);
   const [filteredStreams, setFilteredStreams] = useState([]);
   const [searchQuery, setSearchQuery] = useState('');
   const [loading, setLoading] = useState(true);
@@ -25,6 +26,15 @@ export const LiveStreamsPage = () => {
     } finally {
       setLoading(false);
     }
+
+    // Анонсы не критичны: ошибка не мешает списку live
+    try {
+      const data = await streamsAPI.getUpcomingStreams();
+      setUpcoming(data.streams || []);
+    } catch (error) {
+      console.error('Failed to load upcoming streams:', error);
+      setUpcoming([]);
+    }
   };
 
   useEffect(() => {
@@ -166,6 +176,21 @@ export const LiveStreamsPage = () => {
             )}
           </div>
         )}
+
+        {/* Upcoming */}
+        {upcoming.length > 0 && (
+          <section className="mt-12">
+            <div className="flex items-center gap-2 mb-4">
+              <CalendarClock className="w-5 h-5 text-primary-400" />
+              <h2 className="text-2xl font-bold text-white">Upcoming</h2>
+            </div>
+            <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 xl:grid-cols-4 gap-6">
+              {upcoming.map((stream) => (
+                <StreamCard key={stream.id} stream={stream} />
+              ))}
+            </div>
+          </section>
+        )}
       </main>
     </div>
   );
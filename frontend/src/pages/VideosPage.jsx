@@ -3,7 +3,8 @@ import { Link, useNavigate } from 'react-router-dom';
 import { Header } from '../components/Layout';
 import { SearchBar } from '../components/Common';
 import { videosAPI } from '../api/videos';
-import { Video, Clock, Eye, Calendar, ThumbsUp, Lock, Play } from 'lucide-react';
+import { UploadVideoModal } from '../components/Video';
+import { Video, Clock, Eye, Calendar, ThumbsUp, Lock, Play, Upload } from 'lucide-react';
 import { useAuth } from '../hooks/useAuth';
 
 export const VideosPage = () => {
@@ -12,6 +13,7 @@ export const VideosPage = () => {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
   const [searchQuery, setSearchQuery] = useState('');
+  const [showUpload, setShowUpload] = useState(false);
   const navigate = useNavigate();
   const { isAuthenticated } = useAuth();
 
@@ -156,11 +158,20 @@ export const VideosPage = () => {
       <div className="min-h-screen bg-gray-900 pt-20">
         <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8 py-8">
           {/* Header */}
-          <div className="mb-8">
-            <h1 className="text-3xl font-bold text-white mb-2">My Videos</h1>
-            <p className="text-gray-400">
-              {filteredVideos.length} {filteredVideos.length === 1 ? 'video' : 'videos'}
-            </p>
+          <div className="mb-8 flex items-start justify-between gap-4">
+            <div>
+              <h1 className="text-3xl font-bold text-white mb-2">My Videos</h1>
+              <p className="text-gray-400">
+                {filteredVideos.length} {filteredVideos.length === 1 ? 'video' : 'videos'}
+              </p>
+            </div>
+            <button
+              onClick={() => setShowUpload(true)}
+              className="inline-flex items-center gap-2 px-4 py-2 bg-indigo-600 hover:bg-indigo-700 text-white rounded-lg transition"
+            >
+              <Upload className="w-5 h-5" />
+              Upload video
+            </button>
           </div>
 
           {/* Search Bar */}
@@ -223,6 +234,12 @@ export const VideosPage = () => {
           )}
         </div>
       </div>
+
+      <UploadVideoModal
+        isOpen={showUpload}
+        onClose={() => setShowUpload(false)}
+        onUploaded={fetchVideos}
+      />
     </>
   );
 };
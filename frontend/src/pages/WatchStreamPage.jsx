@@ -2,13 +2,20 @@ import { useState, useEffect } from 'react';
 import { useParams, Link } from 'react-router-dom';
 import { Header } from '../components/Layout';
 import { LivePlayer } from '../components/Stream/LivePlayer';
-import { Eye, Clock, Share2, Flag } from 'lucide-react';
+import { StreamChat } from '../components/Stream/StreamChat';
+import { CreateClipModal } from '../components/Video/CreateClipModal';
+import { Eye, Clock, Share2, Flag, Scissors } from 'lucide-react';
+import { useViewerHeartbeat } from '../hooks/useViewerHeartbeat';
+import { useAuth } from '../hooks/useAuth';
 
 export const WatchStreamPage = () => {
   const { id } = useParams();
   const [stream, setStream] = useState(null);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
+  const [showClipModal, setShowClipModal] = useState(false);
+  const { user } = useAuth();
+  const viewerCount = useViewerHeartbeat(id, Boolean(stream), stream?.viewer_count || 0);
 
   useEffect(() => {
     fetchStream();
@@ -109,7 +116,7 @@ export const WatchStreamPage = () => {
                     <div className="flex items-center space-x-4 text-gray-400">
                       <div className="flex items-center space-x-2">
                         <Eye className="w-5 h-5" />
-                        <span>{stream.viewer_count || 0} viewers</span>
+                        <span>{viewerCount} viewers</span>
                       </div>
                       {stream.started_at && (
                         <div className="flex items-center space-x-2">
@@ -127,6 +134,15 @@ export const WatchStreamPage = () => {
 
                   {/* Action Buttons */}
                   <div className="flex space-x-2">
+                    {user && (
+                      <button
+                        onClick={() => setShowClipModal(true)}
+                        className="p-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition"
+                        title="Clip the last seconds"
+                      >
+                        <Scissors className="w-5 h-5 text-white" />
+                      </button>
+                    )}
                     <button
                       onClick={handleShare}
                       className="p-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition"
@@ -150,13 +166,17 @@ export const WatchStreamPage = () => {
                   </div>
                 )}
               </div>
+
+              <CreateClipModal
+                isOpen={showClipModal}
+                onClose={() => setShowClipModal(false)}
+                streamId={id}
+              />
             </div>
 
             {/* Chat Sidebar */}
             <div className="lg:col-span-1">
-              <div className="bg-gray-800 rounded-lg p-6 h-[600px] flex items-center justify-center">
-                <p className="text-gray-400">Chat feature coming soon...</p>
-              </div>
+              <StreamChat streamId={id} />
             </div>
           </div>
         </div>
@@ -2,8 +2,11 @@ import { useState, useEffect } from 'react';
 import { useParams, Link, useNavigate } from 'react-router-dom';
 import { Header } from '../components/Layout';
 import { VODPlayer } from '../components/Video/VODPlayer';
+import { VideoComments } from '../components/Video/VideoComments';
+import { CreateClipModal } from '../components/Video/CreateClipModal';
+import { TrimVideoModal } from '../components/Video/TrimVideoModal';
 import { videosAPI } from '../api/videos';
-import { ArrowLeft, Calendar, Eye, Clock, Share2, Download, Trash2, ThumbsUp, Lock } from 'lucide-react';
+import { ArrowLeft, Calendar, Eye, Clock, Share2, Download, Trash2, ThumbsUp, Lock, Scissors, ListOrdered } from 'lucide-react';
 import { useAuth } from '../hooks/useAuth';
 
 export const WatchVideoPage = () => {
@@ -11,11 +14,14 @@ export const WatchVideoPage = () => {
   const navigate = useNavigate();
   const { user } = useAuth();
   const [video, setVideo] = useState(null);
+  const [hlsUrl, setHlsUrl] = useState(null);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
   const [liked, setLiked] = useState(false);
   const [likesCount, setLikesCount] = useState(0);
   const [viewsCount, setViewsCount] = useState(0);
+  const [showClipModal, setShowClipModal] = useState(false);
+  const [showTrimModal, setShowTrimModal] = useState(false);
 
   useEffect(() => {
     fetchVideo();
@@ -36,6 +42,14 @@ export const WatchVideoPage = () => {
       setVideo(videoData);
       setLikesCount(videoData.like_count || 0);
       setViewsCount(videoData.view_count || 0);
+
+      // Упакованное видео играется лестницей качеств (HLS master.m3u8), иначе - MP4 файлом
+      try {
+        const stream = await videosAPI.getStreamUrl(id);
+        setHlsUrl(stream.abr ? stream.hls_url : null);
+      } catch (err) {
+        console.error('Failed to load stream URLs:', err);
+      }
       
       // Увеличиваем счетчик просмотров
       try {
@@ -178,7 +192,9 @@ export const WatchVideoPage = () => {
 
   // ✅ ИСПРАВЛЕНО: Используем endpoint который вернет presigned URL
   // Backend проверит JWT в заголовке и сделает редирект на presigned URL от MinIO
-  const playUrl = video.video_url || `http://localhost/api/videos/${video.id}/play`;
+  // ?v= - версия файла: после обрезки файл меняется, а ответы /play кэшируются надолго
+  const fileVersion = Math.floor(new Date(video.updated_at).getTime() / 1000);
+  const playUrl = hlsUrl || video.video_url || `http://localhost/api/videos/${video.id}/play?v=${fileVersion}`;
   console.log('🎥 Play URL:', playUrl);
 
   const creatorName = video.username || 'Unknown Creator';
@@ -258,6 +274,16 @@ export const WatchVideoPage = () => {
                       <Share2 className="h-5 w-5 text-gray-300" />
                     </button>
 
+                    {user && video.status === 'ready' && (
+                      <button
+                        onClick={() => setShowClipModal(true)}
+                        className="p-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition"
+                        title="Create clip"
+                      >
+                        <Scissors className="h-5 w-5 text-gray-300" />
+                      </button>
+                    )}
+
                     <a
                       href={playUrl}
                       download
@@ -267,6 +293,16 @@ export const WatchVideoPage = () => {
                       <Download className="h-5 w-5 text-gray-300" />
                     </a>
 
+                    {isOwner && video.status === 'ready' && (
+                      <button
+                        onClick={() => setShowTrimModal(true)}
+                        className="p-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition"
+                        title="Trim & chapters"
+                      >
+                        <ListOrdered className="h-5 w-5 text-gray-300" />
+                      </button>
+                    )}
+
                     {isOwner && (
                       <button
                         onClick={handleDelete}
@@ -305,14 +341,40 @@ export const WatchVideoPage = () => {
                     </p>
                   </div>
                 )}
-              </div>
 
-              <div className="bg-gray-800 rounded-lg p-6 mt-4">
-                <h3 className="text-white font-semibold mb-4">Comments</h3>
-                <p className="text-gray-500 text-center py-8">
-                  Comments feature coming soon...
-                </p>
+                {video.chapters?.length > 0 && (
+                  <div className="mt-4 pt-4 border-t border-gray-700">
+                    <h3 className="text-white font-semibold mb-2">Chapters</h3>
+                    <ul className="space-y-1">
+                      {video.chapters.map((chapter) => (
+                        <li key={chapter.start_seconds} className="flex gap-3 text-sm">
+                          <span className="text-indigo-400 font-mono">
+                            {chapter.start_seconds >= 1 ? formatDuration(Math.floor(chapter.start_seconds)) : '0:00'}
+                          </span>
+                          <span className="text-gray-300">{chapter.title}</span>
+                        </li>
+                      ))}
+                    </ul>
+                  </div>
+                )}
               </div>
+
+              <VideoComments videoId={video.id} isOwner={isOwner} />
+
+              <CreateClipModal
+                isOpen={showClipModal}
+                onClose={() => setShowClipModal(false)}
+                video={video}
+              />
+
+              {isOwner && (
+                <TrimVideoModal
+                  isOpen={showTrimModal}
+                  onClose={() => setShowTrimModal(false)}
+                  video={video}
+                  onSaved={(data) => data.chapters && setVideo((prev) => ({ ...prev, chapters: data.chapters }))}
+                />
+              )}
             </div>
 
             <div className="lg:col-span-1">
@@ -0,0 +1,16 @@
+-- infrastructure/postgres/migrations/streams_db/000005_add_stream_abr_ladder.down.sql
+-- Rollback: Remove per-stream ABR ladder
+
+BEGIN;
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS valid_abr_ladder;
+
+ALTER TABLE streams DROP COLUMN IF EXISTS abr_ladder;
+ALTER TABLE streams DROP COLUMN IF EXISTS abr_preset;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000005: Removed per-stream ABR ladder';
+END $$;
+
+COMMIT;
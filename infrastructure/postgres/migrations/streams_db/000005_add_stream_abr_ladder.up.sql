@@ -0,0 +1,41 @@
+-- infrastructure/postgres/migrations/streams_db/000005_add_stream_abr_ladder.up.sql
+
+-- Migration: Per-stream ABR ladder
+-- Description: Store the configured quality ladder (preset + renditions) with each stream.
+-- available_qualities keeps what the transcoder actually produces.
+
+BEGIN;
+
+ALTER TABLE streams
+ADD COLUMN IF NOT EXISTS abr_preset VARCHAR(32) NOT NULL DEFAULT 'full';
+
+ALTER TABLE streams
+ADD COLUMN IF NOT EXISTS abr_ladder TEXT[] NOT NULL
+DEFAULT ARRAY['1080p', '720p', '480p', '360p'];
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'valid_abr_ladder' AND conrelid = 'streams'::regclass
+    ) THEN
+        ALTER TABLE streams
+        ADD CONSTRAINT valid_abr_ladder CHECK (
+            cardinality(abr_ladder) > 0
+            AND abr_ladder <@ ARRAY['360p', '480p', '720p', '1080p', '1440p', '4K']
+        );
+        RAISE NOTICE '✅ Constraint valid_abr_ladder created';
+    ELSE
+        RAISE NOTICE '⚠️ Constraint valid_abr_ladder already exists, skipping';
+    END IF;
+
+    RAISE NOTICE '✅ Migration 000005 completed: Added per-stream ABR ladder';
+END $$;
+
+COMMENT ON COLUMN streams.abr_preset IS
+'Name of the ABR preset chosen for the stream (full, standard, low, minimal or custom)';
+
+COMMENT ON COLUMN streams.abr_ladder IS
+'Configured quality ladder used by the transcoder (e.g., ["720p", "480p", "360p"])';
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- infrastructure/postgres/migrations/streams_db/000006_add_stream_low_latency.down.sql
+-- Rollback: Remove low-latency HLS flag
+
+BEGIN;
+
+ALTER TABLE streams DROP COLUMN IF EXISTS low_latency;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000006: Removed low_latency flag';
+END $$;
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- infrastructure/postgres/migrations/streams_db/000006_add_stream_low_latency.up.sql
+
+-- Migration: Low-latency HLS mode
+-- Description: Per-stream opt-in for LL-HLS output (fMP4 parts, blocking playlist reload).
+-- Applies to the next broadcast.
+
+BEGIN;
+
+ALTER TABLE streams
+ADD COLUMN IF NOT EXISTS low_latency BOOLEAN NOT NULL DEFAULT FALSE;
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000006 completed: Added low_latency flag';
+END $$;
+
+COMMENT ON COLUMN streams.low_latency IS
+'Produce LL-HLS (CMAF parts, EXT-X-PART, blocking reload) instead of regular MPEG-TS HLS';
+
+COMMIT;
@@ -0,0 +1,61 @@
+-- infrastructure/postgres/migrations/streams_db/000007_create_event_outbox.up.sql
+
+-- Migration: Transactional outbox for inter-service events
+-- Description: outbox_events is written in the same transaction as the state change and
+-- delivered by the service relay with retries; processed_events lets consumers
+-- dedupe redeliveries by event ID (Idempotency-Key) and replay the first response.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    source VARCHAR(50) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_key VARCHAR(255) NOT NULL,
+    destination VARCHAR(50) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    CONSTRAINT outbox_events_status_check CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+-- Relay picks due pending events of its service
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending
+ON outbox_events (source, next_attempt_at) WHERE status = 'pending';
+
+-- Events of one aggregate (stream, recording) are delivered in order
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate
+ON outbox_events (source, aggregate_key, seq) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer VARCHAR(50) NOT NULL,
+    event_id UUID NOT NULL,
+    response_status INTEGER NOT NULL,
+    response_body BYTEA,
+    processed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at
+ON processed_events (processed_at);
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000007 completed: Created outbox_events and processed_events';
+END $$;
+
+COMMENT ON TABLE outbox_events IS
+'Events to other services, written in the state-change transaction and delivered by the relay';
+COMMENT ON COLUMN outbox_events.aggregate_key IS
+'Delivery order key: a pending event blocks later events with the same key';
+COMMENT ON TABLE processed_events IS
+'Consumer-side dedupe of delivered events with the first response for replay';
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- infrastructure/postgres/migrations/streams_db/000008_add_stream_dvr_window.down.sql
+-- Rollback: Remove live DVR window
+
+BEGIN;
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_dvr_window_seconds_check;
+ALTER TABLE streams DROP COLUMN IF EXISTS dvr_window_seconds;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000008: Removed dvr_window_seconds';
+END $$;
+
+COMMIT;
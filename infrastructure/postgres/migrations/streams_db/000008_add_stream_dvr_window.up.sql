@@ -0,0 +1,27 @@
+-- infrastructure/postgres/migrations/streams_db/000008_add_stream_dvr_window.up.sql
+
+-- Migration: Live DVR window
+-- Description: Per-stream time-shift window. Live playlists keep only the last
+-- dvr_window_seconds of segments, older segments are removed from storage.
+-- 0 keeps the whole broadcast. Applies to the next broadcast.
+
+BEGIN;
+
+ALTER TABLE streams
+ADD COLUMN IF NOT EXISTS dvr_window_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE streams
+DROP CONSTRAINT IF EXISTS streams_dvr_window_seconds_check;
+
+ALTER TABLE streams
+ADD CONSTRAINT streams_dvr_window_seconds_check CHECK (dvr_window_seconds >= 0);
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000008 completed: Added dvr_window_seconds';
+END $$;
+
+COMMENT ON COLUMN streams.dvr_window_seconds IS
+'How many seconds of a live stream viewers can rewind (0 = whole broadcast)';
+
+COMMIT;
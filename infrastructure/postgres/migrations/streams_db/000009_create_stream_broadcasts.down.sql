@@ -0,0 +1,14 @@
+-- infrastructure/postgres/migrations/streams_db/000009_create_stream_broadcasts.down.sql
+-- Rollback: Remove per-broadcast viewer statistics
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_streams_live_viewers;
+DROP TABLE IF EXISTS stream_broadcasts;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000009: Dropped stream_broadcasts';
+END $$;
+
+COMMIT;
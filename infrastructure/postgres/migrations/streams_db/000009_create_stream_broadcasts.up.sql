@@ -0,0 +1,42 @@
+-- infrastructure/postgres/migrations/streams_db/000009_create_stream_broadcasts.up.sql
+
+-- Migration: Viewer statistics per broadcast
+-- Description: Every live session of a stream is a broadcast. stream-service samples
+-- concurrent viewers (player heartbeats) and keeps peak / average per broadcast.
+-- streams.viewer_count holds the current count of live streams.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stream_broadcasts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    peak_viewers INT DEFAULT 0 NOT NULL,
+    avg_viewers NUMERIC(12, 2) DEFAULT 0 NOT NULL,
+    viewer_samples INT DEFAULT 0 NOT NULL,
+
+    CONSTRAINT broadcast_viewers_positive CHECK (peak_viewers >= 0 AND avg_viewers >= 0),
+    CONSTRAINT broadcast_timestamps CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+-- У стрима не больше одного идущего эфира
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_broadcasts_open
+    ON stream_broadcasts(stream_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_stream_broadcasts_stream
+    ON stream_broadcasts(stream_id, started_at DESC);
+
+-- Live стримы сортируются по популярности
+CREATE INDEX IF NOT EXISTS idx_streams_live_viewers
+    ON streams(viewer_count DESC, started_at DESC) WHERE status = 'live';
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000009 completed: Created stream_broadcasts';
+END $$;
+
+COMMENT ON TABLE stream_broadcasts IS 'Live sessions of a stream with viewer statistics';
+COMMENT ON COLUMN stream_broadcasts.avg_viewers IS 'Average concurrent viewers over periodic samples';
+COMMENT ON COLUMN stream_broadcasts.viewer_samples IS 'Number of samples behind avg_viewers';
+
+COMMIT;
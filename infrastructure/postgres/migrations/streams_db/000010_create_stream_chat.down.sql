@@ -0,0 +1,17 @@
+-- infrastructure/postgres/migrations/streams_db/000010_create_stream_chat.down.sql
+-- Rollback: Remove live chat
+
+BEGIN;
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_chat_slow_mode_range;
+ALTER TABLE streams DROP COLUMN IF EXISTS chat_slow_mode_seconds;
+
+DROP TABLE IF EXISTS chat_bans;
+DROP TABLE IF EXISTS chat_messages;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000010: Dropped chat_messages, chat_bans';
+END $$;
+
+COMMIT;
@@ -0,0 +1,54 @@
+-- infrastructure/postgres/migrations/streams_db/000010_create_stream_chat.up.sql
+
+-- Migration: Live chat per stream
+-- Description: Chat messages are persisted for history and moderation. Stream owners
+-- can delete messages, time out or ban users and enable slow mode.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    content VARCHAR(500) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    deleted_by UUID,
+
+    CONSTRAINT chat_message_not_empty CHECK (length(btrim(content)) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_stream
+    ON chat_messages(stream_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user
+    ON chat_messages(stream_id, user_id) WHERE deleted_at IS NULL;
+
+-- Баны и таймауты: expires_at NULL - бессрочный бан
+CREATE TABLE IF NOT EXISTS chat_bans (
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (stream_id, user_id)
+);
+
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS chat_slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE streams
+    ADD CONSTRAINT streams_chat_slow_mode_range
+    CHECK (chat_slow_mode_seconds >= 0 AND chat_slow_mode_seconds <= 3600);
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000010 completed: Created chat_messages, chat_bans';
+END $$;
+
+COMMENT ON TABLE chat_messages IS 'Live chat messages; deleted messages are kept for moderation';
+COMMENT ON TABLE chat_bans IS 'Chat bans (expires_at NULL) and timeouts per stream';
+COMMENT ON COLUMN streams.chat_slow_mode_seconds IS 'Minimum interval between messages of one user (0 - disabled)';
+
+COMMIT;
@@ -0,0 +1,62 @@
+-- infrastructure/postgres/migrations/streams_db/000011_create_stream_keys.down.sql
+-- Rollback: Return single stream_key column on streams
+
+BEGIN;
+
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS stream_key VARCHAR(255);
+
+-- Самый старый действующий ключ стрима; стримы без ключей получают новый
+UPDATE streams s
+SET stream_key = COALESCE(
+    (SELECT k.key FROM stream_keys k
+     WHERE k.stream_id = s.id AND k.revoked_at IS NULL
+     ORDER BY k.created_at
+     LIMIT 1),
+    md5(random()::text || s.id::text)
+);
+
+UPDATE streams
+SET hls_url = replace(hls_url, '/live-segments/' || id::text || '/', '/live-segments/' || stream_key || '/'),
+    thumbnail_url = replace(thumbnail_url, '/live-segments/' || id::text || '/', '/live-segments/' || stream_key || '/');
+
+ALTER TABLE streams ALTER COLUMN stream_key SET NOT NULL;
+ALTER TABLE streams ADD CONSTRAINT streams_stream_key_key UNIQUE (stream_key);
+CREATE INDEX IF NOT EXISTS idx_streams_stream_key ON streams(stream_key);
+
+DROP TABLE IF EXISTS stream_keys;
+
+DROP MATERIALIZED VIEW IF EXISTS streams_with_users_cache CASCADE;
+
+CREATE MATERIALIZED VIEW streams_with_users_cache AS
+SELECT 
+    s.id,
+    s.user_id,
+    s.stream_key,
+    s.title,
+    s.description,
+    s.status,
+    s.started_at,
+    s.ended_at,
+    s.available_qualities,
+    s.viewer_count,
+    s.thumbnail_url,
+    s.hls_url,
+    s.created_at,
+    s.updated_at,
+    COALESCE(u.username, 'Unknown Streamer') as username,
+    COALESCE(u.email, '') as user_email
+FROM streams s
+LEFT JOIN users u ON s.user_id = u.id;
+
+CREATE UNIQUE INDEX idx_streams_cache_id ON streams_with_users_cache(id);
+CREATE INDEX idx_streams_cache_status ON streams_with_users_cache(status);
+CREATE INDEX idx_streams_cache_user_id ON streams_with_users_cache(user_id);
+CREATE INDEX idx_streams_cache_created_at ON streams_with_users_cache(created_at DESC);
+CREATE INDEX idx_streams_cache_live ON streams_with_users_cache(status, started_at DESC) WHERE status = 'live';
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000011: Dropped stream_keys, restored streams.stream_key';
+END $$;
+
+COMMIT;
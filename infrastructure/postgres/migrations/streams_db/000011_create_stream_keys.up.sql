@@ -0,0 +1,82 @@
+-- infrastructure/postgres/migrations/streams_db/000011_create_stream_keys.up.sql
+
+-- Migration: Named stream keys with rotation and revocation
+-- Description: Stream keys move to a separate table so a stream can have several
+-- named keys (main encoder, backup, ...) that are rotated and revoked independently.
+-- Storage paths use the stream ID instead of the secret key.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stream_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+
+    CONSTRAINT stream_key_name_not_empty CHECK (length(btrim(name)) > 0)
+);
+
+-- Имя уникально среди действующих ключей стрима; отозванные остаются для аудита
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_keys_active_name
+    ON stream_keys(stream_id, name) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_stream_keys_stream
+    ON stream_keys(stream_id, created_at);
+
+-- Существующие ключи становятся основными ключами стримов
+INSERT INTO stream_keys (stream_id, name, key, created_at)
+SELECT id, 'primary', stream_key, created_at
+FROM streams
+ON CONFLICT (key) DO NOTHING;
+
+-- Сегменты теперь лежат в live-segments/<stream id>/
+UPDATE streams
+SET hls_url = replace(hls_url, '/live-segments/' || stream_key || '/', '/live-segments/' || id::text || '/'),
+    thumbnail_url = replace(thumbnail_url, '/live-segments/' || stream_key || '/', '/live-segments/' || id::text || '/');
+
+-- Кэш ссылается на streams.stream_key - пересоздаём без ключа
+DROP MATERIALIZED VIEW IF EXISTS streams_with_users_cache CASCADE;
+
+ALTER TABLE streams DROP COLUMN IF EXISTS stream_key;
+
+CREATE MATERIALIZED VIEW streams_with_users_cache AS
+SELECT 
+    s.id,
+    s.user_id,
+    s.title,
+    s.description,
+    s.status,
+    s.started_at,
+    s.ended_at,
+    s.available_qualities,
+    s.viewer_count,
+    s.thumbnail_url,
+    s.hls_url,
+    s.created_at,
+    s.updated_at,
+    COALESCE(u.username, 'Unknown Streamer') as username,
+    COALESCE(u.email, '') as user_email
+FROM streams s
+LEFT JOIN users u ON s.user_id = u.id;
+
+CREATE UNIQUE INDEX idx_streams_cache_id ON streams_with_users_cache(id);
+CREATE INDEX idx_streams_cache_status ON streams_with_users_cache(status);
+CREATE INDEX idx_streams_cache_user_id ON streams_with_users_cache(user_id);
+CREATE INDEX idx_streams_cache_created_at ON streams_with_users_cache(created_at DESC);
+CREATE INDEX idx_streams_cache_live ON streams_with_users_cache(status, started_at DESC) WHERE status = 'live';
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000011 completed: Created stream_keys, dropped streams.stream_key';
+END $$;
+
+COMMENT ON TABLE stream_keys IS 'Named publish keys (SRT/RTMP/WHIP) per stream; revoked keys are kept for audit';
+COMMENT ON COLUMN stream_keys.last_used_at IS 'Last accepted publish with this key';
+COMMENT ON COLUMN stream_keys.rotated_at IS 'Last time the secret was replaced; created_at keeps when the key was added';
+COMMENT ON MATERIALIZED VIEW streams_with_users_cache IS 
+'Cached JOIN of streams with users from auth_db. Refresh periodically for best performance.';
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- infrastructure/postgres/migrations/streams_db/000012_add_stream_schedule.down.sql
+-- Rollback: Remove scheduled streams
+
+BEGIN;
+
+UPDATE streams SET status = 'offline' WHERE status = 'scheduled';
+
+DROP INDEX IF EXISTS idx_streams_upcoming;
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_scheduled_has_start;
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE streams
+    ADD CONSTRAINT valid_status
+    CHECK (status IN ('offline', 'live', 'starting', 'stopping', 'error'));
+
+ALTER TABLE streams
+    DROP COLUMN IF EXISTS cover_image_url,
+    DROP COLUMN IF EXISTS scheduled_start_at;
+
+COMMENT ON COLUMN streams.status IS 'Stream status: offline, live, starting, stopping, error';
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000012: Removed scheduled status, scheduled_start_at, cover_image_url';
+END $$;
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- infrastructure/postgres/migrations/streams_db/000012_add_stream_schedule.up.sql
+
+-- Migration: Scheduled streams
+-- Description: Streams can be scheduled with a planned start time and a cover image.
+-- Adds the 'scheduled' status; still-scheduled streams expire to 'offline' after the planned start.
+
+BEGIN;
+
+ALTER TABLE streams
+    ADD COLUMN IF NOT EXISTS scheduled_start_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS cover_image_url TEXT;
+
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE streams
+    ADD CONSTRAINT valid_status
+    CHECK (status IN ('scheduled', 'offline', 'starting', 'live', 'stopping', 'error'));
+
+-- У запланированного стрима всегда есть время начала
+ALTER TABLE streams
+    ADD CONSTRAINT streams_scheduled_has_start
+    CHECK (status <> 'scheduled' OR scheduled_start_at IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_streams_upcoming
+    ON streams(scheduled_start_at) WHERE status = 'scheduled';
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000012 completed: Added scheduled status, scheduled_start_at, cover_image_url';
+END $$;
+
+COMMENT ON COLUMN streams.status IS 'Stream status: scheduled, offline, starting, live, stopping, error';
+COMMENT ON COLUMN streams.scheduled_start_at IS 'Planned start of a scheduled broadcast';
+COMMENT ON COLUMN streams.cover_image_url IS 'Cover image shown while the stream is scheduled or offline';
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- infrastructure/postgres/migrations/streams_db/000013_create_stream_health_samples.down.sql
+-- Rollback: Remove ingest health telemetry
+
+BEGIN;
+
+DROP TABLE IF EXISTS stream_health_samples;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000013: Dropped stream_health_samples';
+END $$;
+
+COMMIT;
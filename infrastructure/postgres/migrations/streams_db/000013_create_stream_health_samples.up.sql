@@ -0,0 +1,43 @@
+-- infrastructure/postgres/migrations/streams_db/000013_create_stream_health_samples.up.sql
+
+-- Migration: Ingest health telemetry
+-- Description: stream-service periodically samples the ingest link (SRT: RTT, loss,
+-- retransmits, bitrate) and ffmpeg progress (fps, speed, dropped frames).
+-- Samples form a time series per broadcast and are shown to the stream owner.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stream_health_samples (
+    id BIGSERIAL PRIMARY KEY,
+    broadcast_id UUID NOT NULL REFERENCES stream_broadcasts(id) ON DELETE CASCADE,
+    sampled_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    -- Канал ingest (только SRT, для RTMP/WHIP - NULL)
+    rtt_ms NUMERIC(10, 2),
+    bitrate_kbps NUMERIC(12, 2),
+    packets_received BIGINT,
+    packets_lost BIGINT,
+    packets_retransmitted BIGINT,
+    packets_dropped BIGINT,
+
+    -- Прогресс ffmpeg
+    fps NUMERIC(8, 2),
+    speed NUMERIC(8, 3),
+    dropped_frames BIGINT,
+    duplicate_frames BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_health_samples_broadcast
+    ON stream_health_samples(broadcast_id, sampled_at);
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000013 completed: Created stream_health_samples';
+END $$;
+
+COMMENT ON TABLE stream_health_samples IS 'Ingest link and transcoder health samples per broadcast';
+COMMENT ON COLUMN stream_health_samples.packets_lost IS 'SRT packets detected as lost during the sample interval';
+COMMENT ON COLUMN stream_health_samples.speed IS 'ffmpeg processing speed relative to realtime (1.0 = realtime)';
+COMMENT ON COLUMN stream_health_samples.dropped_frames IS 'Frames dropped by ffmpeg since the start of the broadcast';
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- infrastructure/postgres/migrations/streams_db/000014_create_stream_destinations.down.sql
+-- Rollback: Remove restreaming destinations
+
+BEGIN;
+
+DROP TABLE IF EXISTS stream_destinations;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000014: Dropped stream_destinations';
+END $$;
+
+COMMIT;
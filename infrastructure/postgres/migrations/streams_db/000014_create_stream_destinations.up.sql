@@ -0,0 +1,36 @@
+-- infrastructure/postgres/migrations/streams_db/000014_create_stream_destinations.up.sql
+
+-- Migration: Restreaming destinations (simulcast)
+-- Description: Owners configure external outputs per stream (other platforms' RTMP
+-- ingest URLs, SRT callers). While the stream is published, stream-service pushes
+-- the incoming feed to every enabled destination alongside local transcoding.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stream_destinations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    stream_id UUID NOT NULL REFERENCES streams(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    protocol VARCHAR(10) NOT NULL,
+    url TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    CONSTRAINT stream_destination_name_not_empty CHECK (length(btrim(name)) > 0),
+    CONSTRAINT valid_destination_protocol CHECK (protocol IN ('rtmp', 'srt'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_destinations_stream
+    ON stream_destinations(stream_id, created_at);
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000014 completed: Created stream_destinations';
+END $$;
+
+COMMENT ON TABLE stream_destinations IS 'External restreaming outputs (RTMP/SRT) per stream';
+COMMENT ON COLUMN stream_destinations.url IS 'Full output URL including the remote stream key (rtmp://, rtmps://, srt://)';
+COMMENT ON COLUMN stream_destinations.enabled IS 'Owner start/stop; enabled destinations are pushed while the stream is published';
+
+COMMIT;
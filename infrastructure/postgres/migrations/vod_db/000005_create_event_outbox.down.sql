@@ -0,0 +1,14 @@
+-- infrastructure/postgres/migrations/vod_db/000005_create_event_outbox.down.sql
+-- Rollback: Remove event outbox tables
+
+BEGIN;
+
+DROP TABLE IF EXISTS processed_events;
+DROP TABLE IF EXISTS outbox_events;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000005: Removed outbox_events and processed_events';
+END $$;
+
+COMMIT;
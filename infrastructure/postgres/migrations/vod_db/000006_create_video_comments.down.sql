@@ -0,0 +1,13 @@
+-- infrastructure/postgres/migrations/vod_db/000006_create_video_comments.down.sql
+-- Rollback: Remove video comments
+
+BEGIN;
+
+DROP TABLE IF EXISTS video_comments;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000006: Dropped video_comments';
+END $$;
+
+COMMIT;
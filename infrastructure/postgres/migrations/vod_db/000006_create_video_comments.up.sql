@@ -0,0 +1,46 @@
+-- infrastructure/postgres/migrations/vod_db/000006_create_video_comments.up.sql
+
+-- Migration: Comments on videos
+-- Description: Top-level comments may be anchored to a playback position and have
+-- one level of replies. Deleted comments are kept (soft delete) so that threads
+-- with replies stay readable.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS video_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    parent_id UUID REFERENCES video_comments(id) ON DELETE CASCADE,
+    timestamp_seconds NUMERIC(10, 3),
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    deleted_by UUID,
+
+    CONSTRAINT comment_content_length CHECK (length(content) BETWEEN 1 AND 2000),
+    CONSTRAINT comment_timestamp_positive CHECK (timestamp_seconds IS NULL OR timestamp_seconds >= 0),
+    -- Момент видео указывается только у комментариев верхнего уровня
+    CONSTRAINT comment_reply_without_timestamp CHECK (parent_id IS NULL OR timestamp_seconds IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_comments_threads
+    ON video_comments(video_id, created_at DESC) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_video_comments_timeline
+    ON video_comments(video_id, timestamp_seconds)
+    WHERE parent_id IS NULL AND timestamp_seconds IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_video_comments_parent
+    ON video_comments(parent_id, created_at) WHERE parent_id IS NOT NULL;
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000006 completed: Created video_comments';
+END $$;
+
+COMMENT ON TABLE video_comments IS 'Video comments with optional playback timestamp and one level of replies';
+COMMENT ON COLUMN video_comments.timestamp_seconds IS 'Playback position the comment refers to (top-level comments only)';
+COMMENT ON COLUMN video_comments.deleted_by IS 'Author or video owner who deleted the comment';
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- infrastructure/postgres/migrations/vod_db/000007_add_video_clips.down.sql
+-- Rollback: Remove clips
+-- PostgreSQL cannot drop an enum value: 'clip' stays in video_source, clips are deleted
+
+BEGIN;
+
+DELETE FROM videos WHERE source = 'clip';
+
+DROP INDEX IF EXISTS idx_videos_parent_video_id;
+ALTER TABLE videos DROP COLUMN IF EXISTS parent_video_id;
+
+COMMENT ON COLUMN videos.source IS 'Source: recording (from stream), upload (direct), import (external)';
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000007: Removed clips and videos.parent_video_id';
+END $$;
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- infrastructure/postgres/migrations/vod_db/000007_add_video_clips.up.sql
+
+-- Migration: Clips
+-- Description: Short clips cut from a live stream's recent segments or from a video.
+-- A clip is a regular video with source 'clip', linked to the parent video
+-- (parent_video_id) or to the stream (stream_id). Clips are cut asynchronously:
+-- status is 'pending' until ffmpeg finishes.
+
+BEGIN;
+
+-- PostgreSQL 12+: ADD VALUE допустим в транзакции, пока новое значение в ней не используется
+ALTER TYPE video_source ADD VALUE IF NOT EXISTS 'clip';
+
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS parent_video_id UUID REFERENCES videos(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_videos_parent_video_id
+    ON videos(parent_video_id) WHERE parent_video_id IS NOT NULL;
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000007 completed: Added clip source and videos.parent_video_id';
+END $$;
+
+COMMENT ON COLUMN videos.source IS 'Source: recording (from stream), upload (direct), import (external), clip (cut from a stream or video)';
+COMMENT ON COLUMN videos.parent_video_id IS 'Clip: video the clip was cut from (NULL for clips of live streams)';
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- infrastructure/postgres/migrations/vod_db/000008_create_video_edits.down.sql
+-- Rollback: Remove trimming and chapters
+-- Already trimmed files stay trimmed
+
+BEGIN;
+
+DROP TABLE IF EXISTS video_edits;
+DROP TABLE IF EXISTS video_chapters;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000008: Dropped video_edits and video_chapters';
+END $$;
+
+COMMIT;
@@ -0,0 +1,48 @@
+-- infrastructure/postgres/migrations/vod_db/000008_create_video_edits.up.sql
+
+-- Migration: Trimming and chapters
+-- Description: Owners set named chapters and trim in/out points on a video.
+-- A trim is applied asynchronously: video_edits tracks the latest trim job,
+-- the trimmed file replaces file_path, duration and file_size when it is ready.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS video_chapters (
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    start_seconds NUMERIC(10, 3) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+
+    PRIMARY KEY (video_id, start_seconds),
+    CONSTRAINT chapter_start_positive CHECK (start_seconds >= 0),
+    CONSTRAINT chapter_title_length CHECK (length(title) BETWEEN 1 AND 100)
+);
+
+CREATE TABLE IF NOT EXISTS video_edits (
+    video_id UUID PRIMARY KEY REFERENCES videos(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    trim_start_seconds NUMERIC(10, 3) NOT NULL,
+    trim_end_seconds NUMERIC(10, 3) NOT NULL,
+    lossless BOOLEAN,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    CONSTRAINT edit_status_valid CHECK (status IN ('processing', 'done', 'failed')),
+    CONSTRAINT edit_trim_range CHECK (trim_start_seconds >= 0 AND trim_end_seconds > trim_start_seconds)
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_edits_processing
+    ON video_edits(updated_at) WHERE status = 'processing';
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000008 completed: Created video_chapters and video_edits';
+END $$;
+
+COMMENT ON TABLE video_chapters IS 'Named chapters of a video; a chapter ends where the next one starts';
+COMMENT ON TABLE video_edits IS 'Latest trim job of a video';
+COMMENT ON COLUMN video_edits.trim_start_seconds IS 'Trim in point on the timeline of the file before trimming';
+COMMENT ON COLUMN video_edits.trim_end_seconds IS 'Trim out point on the timeline of the file before trimming';
+COMMENT ON COLUMN video_edits.lossless IS 'Stream copy from a keyframe (true) or re-encode (false); NULL until the job decides';
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- infrastructure/postgres/migrations/vod_db/000009_create_video_uploads.down.sql
+-- Rollback: Remove direct video uploads
+-- Videos of unfinished uploads are deleted, processed uploads stay as videos
+
+BEGIN;
+
+DELETE FROM videos
+WHERE id IN (SELECT video_id FROM video_uploads WHERE status <> 'completed');
+
+DROP TABLE IF EXISTS video_uploads;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000009: Dropped video_uploads';
+END $$;
+
+COMMIT;
@@ -0,0 +1,46 @@
+-- infrastructure/postgres/migrations/vod_db/000009_create_video_uploads.up.sql
+
+-- Migration: Direct video uploads
+-- Description: Resumable (tus-style) uploads of user videos. The video record is
+-- created in 'pending' together with the upload; received bytes are appended to
+-- a part file on the vod-service volume. A complete upload is validated with
+-- ffprobe and normalized to the platform MP4 format by a background job, then the
+-- video becomes 'ready' or 'failed'. Abandoned uploads expire with their video.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS video_uploads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    video_id UUID NOT NULL UNIQUE REFERENCES videos(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    received_bytes BIGINT DEFAULT 0 NOT NULL,
+    status VARCHAR(20) DEFAULT 'uploading' NOT NULL,
+    error TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    CONSTRAINT upload_size_positive CHECK (size > 0),
+    CONSTRAINT upload_received_bytes_range CHECK (received_bytes BETWEEN 0 AND size),
+    CONSTRAINT upload_status_valid CHECK (status IN ('uploading', 'uploaded', 'processing', 'completed', 'failed'))
+);
+
+-- Очередь обработки и истечение брошенных загрузок
+CREATE INDEX IF NOT EXISTS idx_video_uploads_queue
+    ON video_uploads(updated_at) WHERE status = 'uploaded';
+CREATE INDEX IF NOT EXISTS idx_video_uploads_expires_at
+    ON video_uploads(expires_at) WHERE status = 'uploading';
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000009 completed: Created video_uploads';
+END $$;
+
+COMMENT ON TABLE video_uploads IS 'Resumable uploads of user videos (source upload)';
+COMMENT ON COLUMN video_uploads.received_bytes IS 'Upload offset: bytes stored in the part file so far';
+COMMENT ON COLUMN video_uploads.status IS 'uploading -> uploaded (queued) -> processing -> completed | failed';
+COMMENT ON COLUMN video_uploads.expires_at IS 'Unfinished uploads are removed with their video after this time';
+
+COMMIT;
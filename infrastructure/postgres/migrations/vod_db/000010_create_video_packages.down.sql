@@ -0,0 +1,14 @@
+-- infrastructure/postgres/migrations/vod_db/000010_create_video_packages.down.sql
+-- Rollback: Remove adaptive-bitrate HLS packages
+-- Packaged HLS objects stay in the bucket under hls/
+
+BEGIN;
+
+DROP TABLE IF EXISTS video_packages;
+
+DO $$
+BEGIN
+    RAISE NOTICE 'Rollback 000010: Dropped video_packages';
+END $$;
+
+COMMIT;
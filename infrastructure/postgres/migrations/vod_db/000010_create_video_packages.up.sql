@@ -0,0 +1,38 @@
+-- infrastructure/postgres/migrations/vod_db/000010_create_video_packages.up.sql
+
+-- Migration: Adaptive-bitrate HLS packages
+-- Description: Every ready video is packaged into an HLS ladder (fMP4 segments)
+-- under hls/<video_id>/<version>/ in the vod-videos bucket. A video needs a new
+-- package when it has none or its file changed (trim); the previous package
+-- keeps being served until the new one is ready.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS video_packages (
+    video_id UUID PRIMARY KEY REFERENCES videos(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    source_path TEXT NOT NULL,
+    hls_path TEXT,
+    hls_source_path TEXT,
+    renditions TEXT[] DEFAULT '{}' NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    CONSTRAINT package_status_valid CHECK (status IN ('pending', 'processing', 'ready', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_video_packages_pending
+    ON video_packages(updated_at) WHERE status IN ('pending', 'processing');
+
+DO $$
+BEGIN
+    RAISE NOTICE '✅ Migration 000010 completed: Created video_packages';
+END $$;
+
+COMMENT ON TABLE video_packages IS 'Latest HLS ABR packaging job of a video and its ready package';
+COMMENT ON COLUMN video_packages.source_path IS 'Video file of the latest packaging job';
+COMMENT ON COLUMN video_packages.hls_path IS 'Storage prefix of the ready package (master.m3u8 and rendition folders)';
+COMMENT ON COLUMN video_packages.hls_source_path IS 'Video file the ready package was made from; stale when it differs from videos.file_path';
+
+COMMIT;
@@ -31,17 +31,27 @@ func main() {
 	vodProxy := proxy.NewServiceProxy(cfg.Services.VODURL)
 
 	router := gin.Default()
+	// Gateway стоит за nginx, который перезаписывает X-Real-IP адресом клиента;
+	// левый X-Forwarded-For клиент подставляет сам
+	router.RemoteIPHeaders = []string{"X-Real-IP"}
 
 	// CORS Configuration
 	corsConfig := middleware.CORSConfig{
 		AllowedOrigins: cfg.CORS.AllowedOrigins,
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{
 			"Content-Type",
 			"Authorization",
 			"X-User-ID",
 			"X-Internal-API-Key",
+			"Upload-Offset", // загрузки видео (tus)
+			"Tus-Resumable",
+			"Range", // частичная загрузка видео и условные запросы
+			"If-Range",
+			"If-None-Match",
 		},
+		// WHIP и загрузки видео возвращают URL сессии в Location
+		ExposedHeaders:   []string{"Location", "Upload-Offset", "Upload-Length", "Tus-Resumable", "Content-Range", "Accept-Ranges", "ETag"},
 		AllowCredentials: cfg.CORS.AllowCredentials,
 		MaxAge:           3600,
 	}
@@ -113,7 +123,12 @@ func main() {
 			streamProxy.ProxyRequest(c, "/api")
 		})
 
-		streamPublic.GET("/by-key/:key", func(c *gin.Context) {
+		// Запланированные эфиры, ближайшие первыми
+		streamPublic.GET("/upcoming", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamPublic.GET("/abr-presets", func(c *gin.Context) {
 			streamProxy.ProxyRequest(c, "/api")
 		})
 
@@ -135,6 +150,49 @@ func main() {
 			log.Printf("🔄 Proxying GET /:id/qualities to stream-service")
 			streamProxy.ProxyRequest(c, "/api")
 		})
+
+		// WHIP: stream-service сам проверяет stream key или JWT из Authorization
+		streamPublic.POST("/:id/whip", func(c *gin.Context) {
+			log.Printf("🔄 Proxying WHIP offer to stream-service")
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamPublic.DELETE("/:id/whip/:session", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// LL-HLS: blocking playlist reload (_HLS_msn/_HLS_part) держит запрос до новой части
+		streamPublic.GET("/:id/llhls/master.m3u8", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamPublic.GET("/:id/llhls/:quality/:file", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// DVR: плейлисты live стрима с произвольного момента в пределах окна (?start=)
+		streamPublic.GET("/:id/dvr", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamPublic.GET("/:id/dvr/:quality/playlist.m3u8", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Heartbeat зрителя: счётчик зрителей live стрима. Сессии считаются по
+		// пользователю из JWT или по IP, поэтому токен (если есть) проверяется здесь
+		streamPublic.POST("/:id/heartbeat", authMiddleware.OptionalJWT(), func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Live чат: JWT из Authorization или ?access_token=, без токена - только чтение
+		streamPublic.GET("/:id/chat/ws", authMiddleware.ValidateWebSocketJWT(), func(c *gin.Context) {
+			streamProxy.ProxyWebSocket(c, "/api")
+		})
+
+		streamPublic.GET("/:id/chat/messages", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
 	}
 
 	streamProtected := router.Group("/api/streams")
@@ -167,6 +225,66 @@ func main() {
 		streamProtected.DELETE("/:id", func(c *gin.Context) {
 			streamProxy.ProxyRequest(c, "/api")
 		})
+
+		streamProtected.GET("/:id/broadcasts", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Телеметрия эфира: SRT канал и прогресс ffmpeg (только владелец)
+		streamProtected.GET("/:id/health", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Расписание эфира (только владелец)
+		streamProtected.PUT("/:id/schedule", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.DELETE("/:id/schedule", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Ключи публикации (только владелец)
+		streamProtected.GET("/:id/keys", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.POST("/:id/keys", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.POST("/:id/keys/:key_id/rotate", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.DELETE("/:id/keys/:key_id", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		// Выходы рестрима на внешние площадки (только владелец)
+		streamProtected.GET("/:id/destinations", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.POST("/:id/destinations", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.PUT("/:id/destinations/:destination_id", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.DELETE("/:id/destinations/:destination_id", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.POST("/:id/destinations/:destination_id/start", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
+
+		streamProtected.POST("/:id/destinations/:destination_id/stop", func(c *gin.Context) {
+			streamProxy.ProxyRequest(c, "/api")
+		})
 	}
 
 	// ============================================================
@@ -214,9 +332,29 @@ func main() {
 			vodProxy.ProxyRequest(c, "/api")
 		})
 
+		// HLS/DASH манифесты и CMAF сегменты фрагментированного MP4
+		vodPublic.GET("/:id/cmaf/:file", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		// HLS лестница качеств: master.m3u8 проверяет доступ, плейлисты и сегменты - по подписи в URL
+		vodPublic.GET("/:id/hls/*path", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
 		vodPublic.POST("/:id/view", func(c *gin.Context) {
 			vodProxy.ProxyRequest(c, "/api")
 		})
+
+		// Комментарии: ?from=&to= - отметки на таймлайне плеера
+		vodPublic.GET("/:id/comments", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		// Главы видео для плеера (WebVTT, track kind="chapters")
+		vodPublic.GET("/:id/chapters.vtt", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
 	}
 
 	vodProtected := router.Group("/api/videos")
@@ -231,6 +369,29 @@ func main() {
 			vodProxy.ProxyRequest(c, "/api")
 		})
 
+		// Возобновляемая загрузка видео (tus): POST создаёт загрузку,
+		// HEAD/GET - offset и статус, PATCH - порция файла, DELETE - отмена
+		vodProtected.POST("/uploads", func(c *gin.Context) {
+			log.Printf("🔄 Proxying POST /videos/uploads to vod-service")
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.GET("/uploads/:id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.HEAD("/uploads/:id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.PATCH("/uploads/:id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.DELETE("/uploads/:id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
 		vodProtected.PUT("/:id",
 			validator.ValidateStreamInput(), // ✅ Validation
 			func(c *gin.Context) {
@@ -245,6 +406,38 @@ func main() {
 		vodProtected.POST("/:id/like", func(c *gin.Context) {
 			vodProxy.ProxyRequest(c, "/api")
 		})
+
+		// Обрезка и главы (владелец)
+		vodProtected.GET("/:id/edit", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.PUT("/:id/edit", func(c *gin.Context) {
+			log.Printf("🔄 Proxying PUT /videos/:id/edit to vod-service")
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.POST("/:id/comments", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.PUT("/:id/comments/:comment_id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+
+		vodProtected.DELETE("/:id/comments/:comment_id", func(c *gin.Context) {
+			vodProxy.ProxyRequest(c, "/api")
+		})
+	}
+
+	// Клипы live стримов и видео (vod-service)
+	clipsProtected := router.Group("/api/clips")
+	clipsProtected.Use(authMiddleware.ValidateJWT())
+	{
+		clipsProtected.POST("", func(c *gin.Context) {
+			log.Printf("🔄 Proxying POST /clips to vod-service")
+			vodProxy.ProxyRequest(c, "/api")
+		})
 	}
 
 	log.Printf("✅ API Gateway running on port %s", cfg.Port)
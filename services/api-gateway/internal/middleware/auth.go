@@ -35,13 +35,7 @@ func (m *AuthMiddleware) ValidateJWT() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(m.jwtSecret), nil
-		})
-
+		token, err := m.parseToken(tokenString)
 		if err != nil || !token.Valid {
 			log.Printf("❌ Invalid token: %v", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -68,8 +62,62 @@ func (m *AuthMiddleware) ValidateJWT() gin.HandlerFunc {
 	}
 }
 
+// ValidateWebSocketJWT - для WebSocket: браузер не может передать Authorization
+// при подключении, поэтому токен принимается и из ?access_token=. Без токена
+// подключение анонимное, с неверным токеном - 401
+func (m *AuthMiddleware) ValidateWebSocketJWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" {
+			tokenString = c.Query("access_token")
+		}
+
+		// Клиент не может сам представиться пользователем
+		c.Request.Header.Del("X-User-ID")
+		c.Request.Header.Del("X-Username")
+
+		if tokenString == "" {
+			c.Next()
+			return
+		}
+
+		token, err := m.parseToken(tokenString)
+		if err != nil || !token.Valid {
+			log.Printf("❌ Invalid WebSocket token: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims["user_id"])
+		c.Set("username", claims["username"])
+		c.Next()
+	}
+}
+
+func (m *AuthMiddleware) parseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(m.jwtSecret), nil
+	})
+}
+
+// OptionalJWT - пользователь из токена, если он есть; без токена запрос анонимный
 func (m *AuthMiddleware) OptionalJWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Клиент не может сам представиться пользователем
+		c.Request.Header.Del("X-User-ID")
+		c.Request.Header.Del("X-Username")
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader != "" {
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
@@ -13,6 +13,7 @@ type CORSConfig struct {
 	AllowedOrigins   []string
 	AllowedMethods   []string
 	AllowedHeaders   []string
+	ExposedHeaders   []string
 	AllowCredentials bool
 	MaxAge           int
 }
@@ -55,6 +56,10 @@ func CORSMiddlewareWithConfig(config CORSConfig) gin.HandlerFunc {
 			c.Header("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
 			c.Header("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
 
+			if len(config.ExposedHeaders) > 0 {
+				c.Header("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
+
 			if config.MaxAge > 0 {
 				c.Header("Access-Control-Max-Age", fmt.Sprintf("%d", config.MaxAge))
 			}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -46,6 +48,9 @@ func (p *ServiceProxy) ProxyRequest(c *gin.Context, stripPrefix string) {
 		}
 	}
 
+	// IP клиента по мнению gateway: заголовок от клиента не доходит до сервисов
+	req.Header.Set("X-Real-IP", c.ClientIP())
+
 	// Добавляем user context из JWT (если есть)
 	if userID, exists := c.Get("user_id"); exists {
 		userIDStr := convertToString(userID)
@@ -86,6 +91,45 @@ func (p *ServiceProxy) ProxyRequest(c *gin.Context, stripPrefix string) {
 	io.Copy(c.Writer, resp.Body)
 }
 
+// ProxyWebSocket проксирует WebSocket upgrade: после 101 Switching Protocols
+// ReverseProxy копирует кадры в обе стороны до закрытия соединения
+func (p *ServiceProxy) ProxyWebSocket(c *gin.Context, stripPrefix string) {
+	target, err := url.Parse(p.targetURL)
+	if err != nil {
+		log.Printf("❌ Invalid proxy target %s: %v", p.targetURL, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create proxy request"})
+		return
+	}
+
+	// Токен из query уже проверен и не должен попасть в логи сервиса
+	query := c.Request.URL.Query()
+	query.Del("access_token")
+
+	reverseProxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = strings.TrimPrefix(c.Request.URL.Path, stripPrefix)
+			req.URL.RawQuery = query.Encode()
+			req.Host = target.Host
+
+			if userID, exists := c.Get("user_id"); exists {
+				req.Header.Set("X-User-ID", convertToString(userID))
+			}
+			if username, exists := c.Get("username"); exists {
+				req.Header.Set("X-Username", convertToString(username))
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Printf("❌ Failed to proxy WebSocket to %s: %v", req.URL, err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	log.Printf("🔌 Proxying WebSocket: %s -> %s", c.Request.URL.Path, p.targetURL)
+	reverseProxy.ServeHTTP(c.Writer, c.Request)
+}
+
 // convertToString безопасно конвертирует interface{} в string
 func convertToString(value interface{}) string {
 	if value == nil {
@@ -14,7 +14,8 @@ import (
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/monitor"
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/recorder"
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/recording-service/internal/storage"
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
+	"github.com/SerKKiT/streaming-platform/shared/storage"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -42,19 +43,27 @@ func main() {
 	log.Println("✅ Connected to vod_db successfully")
 
 	// Initialize storage
-	minioStorage, err := storage.NewMinIOStorage(
-		cfg.MinioEndpoint,
-		cfg.MinioAccessKey,
-		cfg.MinioSecretKey,
-		cfg.MinioBucketRecording,
-		cfg.MinioUseSSL,
-	)
+	storageConfig := storage.Config{
+		Backend:   cfg.StorageBackend,
+		Endpoint:  cfg.MinioEndpoint,
+		AccessKey: cfg.MinioAccessKey,
+		SecretKey: cfg.MinioSecretKey,
+		UseSSL:    cfg.MinioUseSSL,
+		LocalPath: cfg.StoragePath,
+	}
+
+	recordingsStorage, err := storage.Open(storageConfig, cfg.MinioBucketRecording, storage.Options{})
 	if err != nil {
-		log.Fatalf("Failed to initialize MinIO: %v", err)
+		log.Fatalf("Failed to initialize recordings storage: %v", err)
+	}
+
+	segmentsStorage, err := storage.Open(storageConfig, cfg.MinioBucketLiveStreams, storage.Options{})
+	if err != nil {
+		log.Fatalf("Failed to initialize live-streams storage: %v", err)
 	}
 
 	// Initialize recorder
-	ffmpegRecorder := recorder.NewFFmpegRecorder(cfg.RecordingsPath, minioStorage.GetClient(), cfg.MinioBucketLiveStreams)
+	ffmpegRecorder := recorder.NewFFmpegRecorder(cfg.RecordingsPath, segmentsStorage)
 
 	// Initialize repository
 	recordingRepo := repository.NewRecordingRepository(db)
@@ -65,13 +74,27 @@ func main() {
 		vodServiceURL = "http://vod-service:8084"
 	}
 
+	// События для stream-service и vod-service доставляются через outbox с повторами.
+	// Импорт в VOD транскодирует запись синхронно, поэтому таймаут большой
+	relay := outbox.NewRelay(db, repository.OutboxSource, map[string]outbox.Destination{
+		"stream-service": {BaseURL: cfg.StreamServiceURL},
+		"vod-service":    {BaseURL: vodServiceURL, Timeout: 15 * time.Minute},
+	})
+	if apiKey := os.Getenv("INTERNAL_API_KEY"); apiKey != "" {
+		relay.SetHeader("X-Internal-API-Key", apiKey)
+	}
+
+	// Повторные доставки событий от stream-service отбрасываются по ID
+	inbox := outbox.NewInbox(db, repository.OutboxSource)
+
 	// Initialize stream monitor
 	streamMonitor := monitor.NewStreamMonitor(
 		cfg.StreamServiceURL,
-		vodServiceURL, // ← Передаём VOD URL
 		ffmpegRecorder,
 		recordingRepo,
-		minioStorage,
+		relay,
+		segmentsStorage,
+		recordingsStorage,
 		time.Duration(cfg.MonitorInterval)*time.Second,
 	)
 
@@ -85,12 +108,14 @@ func main() {
 	router.GET("/recordings", recordingHandler.GetAllRecordings)
 	router.GET("/recordings/:id", recordingHandler.GetRecordingByID)
 	router.GET("/recording/:id", recordingHandler.GetRecordingByID) // Альтернативный путь (если используется)
-	router.POST("/webhook/stream", webhookHandler.HandleStreamEvent)
+	router.POST("/webhook/stream", inbox.Middleware(), webhookHandler.HandleStreamEvent)
 
 	// Start stream monitor in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	go relay.Start(ctx)
+	go inbox.Start(ctx)
 	go streamMonitor.Start(ctx)
 
 	// Start HTTP server
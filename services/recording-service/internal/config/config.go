@@ -9,6 +9,8 @@ type Config struct {
 	DatabaseURL            string
 	Port                   string
 	StreamServiceURL       string
+	StorageBackend         string // minio или local
+	StoragePath            string // корень local хранилища (общий со stream-service)
 	MinioEndpoint          string
 	MinioAccessKey         string
 	MinioSecretKey         string
@@ -35,18 +37,29 @@ func LoadConfig() (*Config, error) {
 		streamServiceURL = "http://stream-service:8082"
 	}
 
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = "minio"
+	}
+
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "/var/lib/streaming/storage"
+	}
+
 	minioEndpoint := os.Getenv("MINIO_ENDPOINT")
 	if minioEndpoint == "" {
 		minioEndpoint = "minio:9000"
 	}
 
+	// Ключи MinIO нужны только для backend minio
 	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY")
-	if minioAccessKey == "" {
+	if minioAccessKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_ACCESS_KEY is required")
 	}
 
 	minioSecretKey := os.Getenv("MINIO_SECRET_KEY")
-	if minioSecretKey == "" {
+	if minioSecretKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_SECRET_KEY is required")
 	}
 
@@ -56,6 +69,8 @@ func LoadConfig() (*Config, error) {
 		DatabaseURL:            dbURL,
 		Port:                   port,
 		StreamServiceURL:       streamServiceURL,
+		StorageBackend:         storageBackend,
+		StoragePath:            storagePath,
 		MinioEndpoint:          minioEndpoint,
 		MinioAccessKey:         minioAccessKey,
 		MinioSecretKey:         minioSecretKey,
@@ -6,6 +6,7 @@ import (
 
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/monitor"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type WebhookHandler struct {
@@ -19,10 +20,11 @@ func NewWebhookHandler(streamMonitor *monitor.StreamMonitor) *WebhookHandler {
 }
 
 type StreamEventPayload struct {
-	StreamKey string `json:"stream_key"`
-	Event     string `json:"event"`
-	HLSURL    string `json:"hls_url"`
-	Timestamp int64  `json:"timestamp"`
+	StreamID   uuid.UUID `json:"stream_id"`
+	StorageKey string    `json:"storage_key"` // live-segments/<storage key>/
+	Event      string    `json:"event"`
+	HLSURL     string    `json:"hls_url"`
+	Timestamp  int64     `json:"timestamp"`
 }
 
 func (h *WebhookHandler) HandleStreamEvent(c *gin.Context) {
@@ -33,24 +35,29 @@ func (h *WebhookHandler) HandleStreamEvent(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Received webhook: stream=%s, event=%s, hls=%s", payload.StreamKey, payload.Event, payload.HLSURL)
+	if payload.StreamID == uuid.Nil || payload.StorageKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stream_id and storage_key are required"})
+		return
+	}
+
+	log.Printf("Received webhook: stream=%s, event=%s, hls=%s", payload.StreamID, payload.Event, payload.HLSURL)
 
 	switch payload.Event {
 	case "started":
-		if err := h.streamMonitor.HandleWebhookStart(payload.StreamKey, payload.HLSURL); err != nil {
+		if err := h.streamMonitor.HandleWebhookStart(payload.StreamID, payload.StorageKey, payload.HLSURL); err != nil {
 			log.Printf("❌ Failed to start recording: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start recording"})
 			return
 		}
-		log.Printf("✅ Recording started for stream: %s", payload.StreamKey)
+		log.Printf("✅ Recording started for stream: %s", payload.StreamID)
 
 	case "stopped":
-		if err := h.streamMonitor.HandleWebhookStop(payload.StreamKey); err != nil {
+		if err := h.streamMonitor.HandleWebhookStop(payload.StreamID); err != nil {
 			log.Printf("❌ Failed to stop recording: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop recording"})
 			return
 		}
-		log.Printf("✅ Recording stopped for stream: %s", payload.StreamKey)
+		log.Printf("✅ Recording stopped for stream: %s", payload.StreamID)
 
 	default:
 		log.Printf("⚠️ Unknown webhook event: %s", payload.Event)
@@ -1,11 +1,9 @@
 package monitor
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -15,54 +13,61 @@ import (
 
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/recorder"
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/recording-service/internal/storage"
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
+	"github.com/SerKKiT/streaming-platform/shared/storage"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
 )
 
 type StreamInfo struct {
-	ID        uuid.UUID `json:"id"`
-	StreamKey string    `json:"stream_key"`
-	Status    string    `json:"status"`
-	HLSURL    string    `json:"hls_url"`
-	UserID    uuid.UUID `json:"user_id"`
-	Title     string    `json:"title"`
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+	HLSURL string    `json:"hls_url"`
+	UserID uuid.UUID `json:"user_id"`
+	Title  string    `json:"title"`
 }
 
+// importEventType - событие импорта записи в vod-service
+const importEventType = "recording.import"
+
+// segmentSyncInterval - как часто во время эфира забираем новые сегменты.
+// Должно быть заметно меньше минимального DVR окна stream-service (1 минута)
+const segmentSyncInterval = 5 * time.Second
+
 type StreamMonitor struct {
 	streamServiceURL string
-	vodServiceURL    string
 	recorder         *recorder.FFmpegRecorder
 	recordingRepo    *repository.RecordingRepository
-	minioStorage     *storage.MinIOStorage
+	relay            *outbox.Relay
+	segments         storage.Storage // live-streams
+	recordings       storage.Storage
 	activeRecordings map[uuid.UUID]context.CancelFunc
-	streamKeyToID    map[string]uuid.UUID
 	mu               sync.RWMutex
 	interval         time.Duration
 }
 
 func NewStreamMonitor(
 	streamServiceURL string,
-	vodServiceURL string,
 	recorder *recorder.FFmpegRecorder,
 	recordingRepo *repository.RecordingRepository,
-	minioStorage *storage.MinIOStorage,
+	relay *outbox.Relay,
+	segments storage.Storage,
+	recordings storage.Storage,
 	interval time.Duration,
 ) *StreamMonitor {
-	if vodServiceURL == "" {
-		vodServiceURL = "http://vod-service:8084"
-	}
-
-	return &StreamMonitor{
+	m := &StreamMonitor{
 		streamServiceURL: streamServiceURL,
-		vodServiceURL:    vodServiceURL,
 		recorder:         recorder,
 		recordingRepo:    recordingRepo,
-		minioStorage:     minioStorage,
+		relay:            relay,
+		segments:         segments,
+		recordings:       recordings,
 		activeRecordings: make(map[uuid.UUID]context.CancelFunc),
-		streamKeyToID:    make(map[string]uuid.UUID),
 		interval:         interval,
 	}
+
+	// Ответ vod-service на импорт содержит ID созданного видео
+	relay.OnDelivered(importEventType, m.handleImported)
+	return m
 }
 
 func (m *StreamMonitor) Start(ctx context.Context) {
@@ -106,7 +111,6 @@ func (m *StreamMonitor) checkStreams() {
 	currentStreams := make(map[uuid.UUID]StreamInfo)
 	for _, stream := range result.Streams {
 		currentStreams[stream.ID] = stream
-		m.streamKeyToID[stream.StreamKey] = stream.ID
 	}
 
 	// Останавливаем записи для стримов которые больше не live
@@ -118,31 +122,21 @@ func (m *StreamMonitor) checkStreams() {
 	}
 }
 
-// HandleWebhookStart обрабатывает webhook о начале стрима
-func (m *StreamMonitor) HandleWebhookStart(streamKey, hlsURL string) error {
+// HandleWebhookStart обрабатывает webhook о начале стрима.
+// storageKey - префикс сегментов стрима в live-streams (live-segments/<storage key>/)
+func (m *StreamMonitor) HandleWebhookStart(streamID uuid.UUID, storageKey, hlsURL string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Получаем stream_id из маппинга или запрашиваем у Stream Service
-	streamID, exists := m.streamKeyToID[streamKey]
-	if !exists {
-		streamInfo, err := m.getStreamInfoByKey(streamKey)
-		if err != nil {
-			return fmt.Errorf("failed to get stream info: %w", err)
-		}
-		streamID = streamInfo.ID
-		m.streamKeyToID[streamKey] = streamID
-	}
-
 	if _, alreadyRecording := m.activeRecordings[streamID]; alreadyRecording {
 		log.Printf("⚠️ Stream %s is already being recorded", streamID)
 		return nil
 	}
 
-	log.Printf("🎬 Starting recording for stream %s (key: %s)", streamID, streamKey)
+	log.Printf("🎬 Starting recording for stream %s (storage key: %s)", streamID, storageKey)
 
 	// Создаём запись в БД
-	recording, err := m.recordingRepo.CreateRecording(streamID, streamKey+".mp4")
+	recording, err := m.recordingRepo.CreateRecording(streamID, storageKey+".mp4")
 	if err != nil {
 		return fmt.Errorf("failed to create recording: %w", err)
 	}
@@ -151,22 +145,16 @@ func (m *StreamMonitor) HandleWebhookStart(streamKey, hlsURL string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	m.activeRecordings[streamID] = cancel
 
-	go m.processRecording(ctx, streamKey, recording.ID, streamID)
+	go m.processRecording(ctx, storageKey, recording.ID, streamID)
 
 	return nil
 }
 
 // HandleWebhookStop обрабатывает webhook об остановке стрима
-func (m *StreamMonitor) HandleWebhookStop(streamKey string) error {
+func (m *StreamMonitor) HandleWebhookStop(streamID uuid.UUID) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	streamID, exists := m.streamKeyToID[streamKey]
-	if !exists {
-		log.Printf("⚠️ Unknown stream key: %s", streamKey)
-		return nil
-	}
-
 	m.stopRecordingLocked(streamID)
 	return nil
 }
@@ -183,8 +171,8 @@ func (m *StreamMonitor) stopRecordingLocked(streamID uuid.UUID) {
 }
 
 // waitForSegmentUploadCompletion ждёт когда счётчик файлов стабилизируется
-func (m *StreamMonitor) waitForSegmentUploadCompletion(streamKey string, maxWaitTime time.Duration) int {
-	log.Printf("⏳ Waiting for segment upload completion for stream %s", streamKey)
+func (m *StreamMonitor) waitForSegmentUploadCompletion(storageKey string, maxWaitTime time.Duration) int {
+	log.Printf("⏳ Waiting for segment upload completion for stream %s", storageKey)
 
 	deadline := time.Now().Add(maxWaitTime)
 	ticker := time.NewTicker(500 * time.Millisecond)
@@ -197,27 +185,22 @@ func (m *StreamMonitor) waitForSegmentUploadCompletion(streamKey string, maxWait
 	for time.Now().Before(deadline) {
 		select {
 		case <-ticker.C:
-			client := m.minioStorage.GetClient()
-			bucketName := m.minioStorage.GetBucketName()
-
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			prefix := fmt.Sprintf("live-segments/%s/", streamKey)
+			prefix := fmt.Sprintf("live-segments/%s/", storageKey)
+
+			objects, err := m.segments.List(ctx, prefix)
+			cancel()
+			if err != nil {
+				log.Printf("⚠️ Failed to list segments: %v", err)
+				continue
+			}
 
 			count := 0
-			objectsCh := client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-				Prefix:    prefix,
-				Recursive: true,
-			})
-
-			for obj := range objectsCh {
-				if obj.Err != nil {
-					continue
-				}
-				if strings.HasSuffix(obj.Key, ".ts") || strings.HasSuffix(obj.Key, ".m3u8") {
+			for _, obj := range objects {
+				if strings.HasSuffix(obj.Key, ".ts") || strings.HasSuffix(obj.Key, ".m4s") || strings.HasSuffix(obj.Key, ".m3u8") {
 					count++
 				}
 			}
-			cancel()
 
 			if count == lastCount && count > 0 {
 				stableChecks++
@@ -241,23 +224,25 @@ func (m *StreamMonitor) waitForSegmentUploadCompletion(streamKey string, maxWait
 	return lastCount
 }
 
-func (m *StreamMonitor) processRecording(ctx context.Context, streamKey string, recordingID uuid.UUID, streamID uuid.UUID) {
-	<-ctx.Done()
-
-	fileCount := m.waitForSegmentUploadCompletion(streamKey, 15*time.Second)
-	log.Printf("📹 Processing completed recording %s (%d files)", recordingID, fileCount)
+func (m *StreamMonitor) processRecording(ctx context.Context, storageKey string, recordingID uuid.UUID, streamID uuid.UUID) {
+	collector, err := m.recorder.NewSegmentCollector(storageKey, recordingID.String())
+	if err != nil {
+		log.Printf("❌ Failed to start recording: %v", err)
+		m.finishRecording(recordingID, "failed", m.cleanupEvents(storageKey, streamID, false)...)
+		return
+	}
+	defer collector.Close()
 
-	outputPath, err := m.recorder.ProcessRecordingFromMinIO(context.Background(), streamKey, recordingID.String())
+	// Во время эфира забираем сегменты до того, как их удалит DVR окно
+	m.collectSegments(ctx, collector)
 
-	success := err == nil
-	defer func() {
-		log.Printf("🧹 Sending cleanup webhook (success: %v)...", success)
-		m.sendCleanupWebhook(streamKey, streamID, success)
-	}()
+	fileCount := m.waitForSegmentUploadCompletion(storageKey, 15*time.Second)
+	log.Printf("📹 Processing completed recording %s (%d files)", recordingID, fileCount)
 
+	outputPath, err := m.recorder.ProcessRecording(context.Background(), collector, recordingID.String())
 	if err != nil {
 		log.Printf("❌ Failed to process recording: %v", err)
-		m.recordingRepo.UpdateRecordingStatus(recordingID, "failed")
+		m.finishRecording(recordingID, "failed", m.cleanupEvents(storageKey, streamID, false)...)
 		return
 	}
 
@@ -275,23 +260,22 @@ func (m *StreamMonitor) processRecording(ctx context.Context, streamKey string,
 		thumbnailGenerated = true
 	}
 
-	log.Printf("📦 Uploading recording to MinIO: %s", outputPath)
+	log.Printf("📦 Uploading recording to %s: %s", m.recordings.Bucket(), outputPath)
 
-	if err := m.minioStorage.UploadRecording(context.Background(), outputPath, streamKey+".mp4"); err != nil {
+	if err := m.recordings.PutFile(context.Background(), storageKey+".mp4", outputPath, "video/mp4"); err != nil {
 		log.Printf("❌ Failed to upload recording: %v", err)
-		m.recordingRepo.UpdateRecordingStatus(recordingID, "failed")
-		success = false
+		m.finishRecording(recordingID, "failed", m.cleanupEvents(storageKey, streamID, false)...)
 		return
 	}
 
-	log.Printf("✅ Recording uploaded to MinIO: %s.mp4", streamKey)
+	log.Printf("✅ Recording uploaded: %s/%s.mp4", m.recordings.Bucket(), storageKey)
 
 	if thumbnailGenerated {
-		thumbnailObjectName := streamKey + ".jpg"
-		if err := m.minioStorage.UploadThumbnail(context.Background(), thumbnailPath, thumbnailObjectName); err != nil {
+		thumbnailObjectName := storageKey + ".jpg"
+		if err := m.recordings.PutFile(context.Background(), thumbnailObjectName, thumbnailPath, "image/jpeg"); err != nil {
 			log.Printf("⚠️ Failed to upload thumbnail: %v", err)
 		} else {
-			log.Printf("✅ Thumbnail uploaded to MinIO: %s", thumbnailObjectName)
+			log.Printf("✅ Thumbnail uploaded: %s", thumbnailObjectName)
 			if err := m.recordingRepo.UpdateThumbnailPath(recordingID, thumbnailObjectName); err != nil {
 				log.Printf("⚠️ Failed to update thumbnail path in DB: %v", err)
 			} else {
@@ -302,93 +286,65 @@ func (m *StreamMonitor) processRecording(ctx context.Context, streamKey string,
 	}
 
 	os.Remove(outputPath)
-	m.recordingRepo.UpdateRecordingStatus(recordingID, "completed")
-	log.Printf("✅ Recording %s completed successfully", recordingID)
-
-	go m.triggerVODImport(streamID, recordingID)
-}
-
-func (m *StreamMonitor) getStreamInfoByKey(streamKey string) (*StreamInfo, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/streams/by-key/%s", m.streamServiceURL, streamKey))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("stream service returned status %d", resp.StatusCode)
-	}
 
-	var streamInfo StreamInfo
-	if err := json.NewDecoder(resp.Body).Decode(&streamInfo); err != nil {
-		return nil, err
+	// Статус completed, очистка сегментов и импорт в VOD фиксируются вместе
+	events := m.cleanupEvents(storageKey, streamID, true)
+	if importEvent, err := m.newImportEvent(streamID, recordingID); err != nil {
+		log.Printf("❌ Failed to prepare VOD import for recording %s: %v", recordingID, err)
+	} else {
+		events = append(events, importEvent)
 	}
-
-	return &streamInfo, nil
-}
-
-func (m *StreamMonitor) GetStreamIDByKey(streamKey string) (uuid.UUID, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	id, exists := m.streamKeyToID[streamKey]
-	return id, exists
+	m.finishRecording(recordingID, "completed", events...)
+	log.Printf("✅ Recording %s completed successfully", recordingID)
 }
 
-func (m *StreamMonitor) sendCleanupWebhook(streamKey string, streamID uuid.UUID, success bool) {
-	streamServiceURL := os.Getenv("STREAM_SERVICE_URL")
-	if streamServiceURL == "" {
-		streamServiceURL = "http://stream-service:8080"
-	}
-
-	webhookURL := fmt.Sprintf("%s/webhooks/recording-complete", streamServiceURL)
+// collectSegments скачивает новые сегменты стрима до окончания эфира
+func (m *StreamMonitor) collectSegments(ctx context.Context, collector *recorder.SegmentCollector) {
+	ticker := time.NewTicker(segmentSyncInterval)
+	defer ticker.Stop()
 
-	payload := map[string]interface{}{
-		"stream_key": streamKey,
-		"stream_id":  streamID.String(),
-		"success":    success,
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := collector.Sync(ctx, false); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️ Failed to collect recording segments: %v", err)
+			}
+		}
 	}
+}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("❌ Failed to marshal webhook payload: %v", err)
+// finishRecording сохраняет итоговый статус записи вместе с событиями для других сервисов
+func (m *StreamMonitor) finishRecording(recordingID uuid.UUID, status string, events ...outbox.Event) {
+	if err := m.recordingRepo.UpdateRecordingStatusWithEvents(recordingID, status, events...); err != nil {
+		log.Printf("❌ Failed to update recording %s status: %v", recordingID, err)
 		return
 	}
+	m.relay.Notify()
+}
 
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("❌ Failed to create webhook request: %v", err)
-		return
+// cleanupEvents - событие для stream-service: удалить live сегменты стрима
+func (m *StreamMonitor) cleanupEvents(storageKey string, streamID uuid.UUID, success bool) []outbox.Event {
+	payload := map[string]interface{}{
+		"storage_key": storageKey,
+		"stream_id":   streamID.String(),
+		"success":     success,
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	event, err := outbox.NewEvent("recording.completed", streamID.String(), "stream-service", "/webhooks/recording-complete", payload)
 	if err != nil {
-		log.Printf("❌ Failed to send cleanup webhook: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("⚠️ Cleanup webhook returned non-OK status: %d", resp.StatusCode)
-	} else {
-		log.Printf("✅ Cleanup webhook sent successfully for stream %s", streamKey)
+		log.Printf("❌ Failed to create cleanup event: %v", err)
+		return nil
 	}
+	return []outbox.Event{event}
 }
 
-func (m *StreamMonitor) triggerVODImport(streamID uuid.UUID, recordingID uuid.UUID) {
-	log.Printf("📥 Starting VOD import for recording %s", recordingID)
-
+// newImportEvent - событие для vod-service: импортировать запись как видео владельца стрима
+func (m *StreamMonitor) newImportEvent(streamID uuid.UUID, recordingID uuid.UUID) (outbox.Event, error) {
 	recording, err := m.recordingRepo.GetByID(recordingID.String())
 	if err != nil {
-		log.Printf("❌ Failed to get recording %s: %v", recordingID, err)
-		return
-	}
-
-	if recording.Status != "completed" {
-		log.Printf("⚠️ Recording %s is not completed (status: %s), skipping import", recordingID, recording.Status)
-		return
+		return outbox.Event{}, fmt.Errorf("failed to get recording: %w", err)
 	}
 
 	streamInfo, err := m.getStreamInfoByID(streamID)
@@ -407,7 +363,7 @@ func (m *StreamMonitor) triggerVODImport(streamID uuid.UUID, recordingID uuid.UU
 		title = fmt.Sprintf("Stream Recording %s", recording.StartedAt.Format("2006-01-02 15:04"))
 	}
 
-	log.Printf("📤 Importing recording %s to VOD for user %s", recordingID, streamInfo.UserID)
+	log.Printf("📤 Queueing VOD import of recording %s for user %s", recordingID, streamInfo.UserID)
 
 	payload := map[string]interface{}{
 		"recording_id": recordingID.String(),
@@ -416,51 +372,47 @@ func (m *StreamMonitor) triggerVODImport(streamID uuid.UUID, recordingID uuid.UU
 		"visibility":   "public",
 	}
 
-	jsonData, err := json.Marshal(payload)
+	event, err := outbox.NewEvent(importEventType, recordingID.String(), "vod-service", "/videos/import-recording", payload)
 	if err != nil {
-		log.Printf("❌ Failed to marshal VOD import payload: %v", err)
-		return
+		return outbox.Event{}, err
 	}
+	event.Headers = map[string]string{"X-User-ID": streamInfo.UserID.String()}
+	return event, nil
+}
 
-	importURL := m.vodServiceURL + "/videos/import-recording"
-	req, err := http.NewRequest("POST", importURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("❌ Failed to create VOD import request: %v", err)
-		return
+// handleImported сохраняет ID видео, созданного vod-service из записи
+func (m *StreamMonitor) handleImported(ctx context.Context, event outbox.Event, body []byte) error {
+	var request struct {
+		RecordingID string `json:"recording_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &request); err != nil {
+		return fmt.Errorf("failed to parse import event: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-ID", streamInfo.UserID.String())
-	req.Header.Set("X-Internal-API-Key", os.Getenv("INTERNAL_API_KEY")) // ✅ ДОБАВЛЕНО
+	var result struct {
+		VideoID string `json:"video_id"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse import response: %w", err)
+	}
 
-	client := &http.Client{Timeout: 15 * time.Minute}
-	resp, err := client.Do(req)
+	videoID, err := uuid.Parse(result.VideoID)
 	if err != nil {
-		log.Printf("❌ Failed to send VOD import request: %v", err)
-		return
+		return fmt.Errorf("invalid video ID in import response: %w", err)
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ VOD import failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-		return
+	recording, err := m.recordingRepo.GetByID(request.RecordingID)
+	if err != nil {
+		return fmt.Errorf("failed to get recording: %w", err)
 	}
 
-	var result struct {
-		VideoID string `json:"video_id"`
-		Message string `json:"message"`
+	recording.VideoID = &videoID
+	if err := m.recordingRepo.UpdateRecording(recording); err != nil {
+		return fmt.Errorf("failed to save video ID: %w", err)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil {
-		log.Printf("✅ Recording %s imported to VOD as video %s", recordingID, result.VideoID)
-		videoID, _ := uuid.Parse(result.VideoID)
-		recording.VideoID = &videoID
-		m.recordingRepo.UpdateRecording(recording)
-	} else {
-		log.Printf("✅ Recording %s imported to VOD successfully", recordingID)
-	}
+	log.Printf("✅ Recording %s imported to VOD as video %s", request.RecordingID, videoID)
+	return nil
 }
 
 func (m *StreamMonitor) getStreamInfoByID(streamID uuid.UUID) (*StreamInfo, error) {
@@ -2,7 +2,9 @@ package recorder
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -11,120 +13,280 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/minio/minio-go/v7"
+	"github.com/SerKKiT/streaming-platform/shared/storage"
 )
 
 type FFmpegRecorder struct {
 	recordingsPath string
-	minioClient    *minio.Client
-	minioBucket    string
+	segments       storage.Storage // live-streams
 }
 
-func NewFFmpegRecorder(recordingsPath string, minioClient *minio.Client, segmentsBucket string) *FFmpegRecorder {
+func NewFFmpegRecorder(recordingsPath string, segments storage.Storage) *FFmpegRecorder {
 	os.MkdirAll(recordingsPath, 0755)
 	return &FFmpegRecorder{
 		recordingsPath: recordingsPath,
-		minioClient:    minioClient,
-		minioBucket:    segmentsBucket, // live-streams
+		segments:       segments,
 	}
 }
 
-// ProcessRecordingFromMinIO скачивает сегменты из MinIO и создает MP4
-func (r *FFmpegRecorder) ProcessRecordingFromMinIO(ctx context.Context, streamKey, recordingID string) (string, error) {
-	log.Printf("📹 Processing recording for stream %s", streamKey)
+// qualityPriority - запись берётся из лучшего качества, которое есть у стрима.
+// source - эфир без перекодирования (транскодер был перегружен)
+var qualityPriority = []string{"1080p", "720p", "480p", "360p", "source"}
+
+// SegmentCollector скачивает сегменты одного качества по мере их появления в MinIO.
+// У стримов с DVR окном старые сегменты удаляются во время эфира,
+// поэтому запись собирается на диске, а не одним проходом после эфира
+type SegmentCollector struct {
+	r          *FFmpegRecorder
+	storageKey string
+	tempDir    string
+	quality    string
+	inits      map[int]string    // номер первого сегмента → локальный init (новый после переподключения издателя)
+	downloaded map[string]string // имя сегмента → локальный путь
+}
 
-	// Создать временную директорию для сегментов
+// NewSegmentCollector создаёт временную директорию для сегментов записи
+func (r *FFmpegRecorder) NewSegmentCollector(storageKey, recordingID string) (*SegmentCollector, error) {
 	tempDir := filepath.Join(r.recordingsPath, "temp", recordingID)
-	os.MkdirAll(tempDir, 0755)
-	defer os.RemoveAll(tempDir) // Очистить после
-
-	// ✅ Скачать сегменты ОДНОГО качества (приоритет: 1080p -> 720p -> 480p -> 360p)
-	segmentFiles, selectedQuality, err := r.downloadSingleQualitySegments(ctx, streamKey, tempDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to download segments: %w", err)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
-	if len(segmentFiles) == 0 {
-		return "", fmt.Errorf("no segments found for stream %s", streamKey)
-	}
+	return &SegmentCollector{
+		r:          r,
+		storageKey: storageKey,
+		tempDir:    tempDir,
+		inits:      make(map[int]string),
+		downloaded: make(map[string]string),
+	}, nil
+}
 
-	log.Printf("✅ Downloaded %d segments from quality '%s' for stream %s", len(segmentFiles), selectedQuality, streamKey)
+// Close удаляет скачанные сегменты
+func (c *SegmentCollector) Close() {
+	os.RemoveAll(c.tempDir)
+}
 
-	// Создать concat file для FFmpeg
-	concatFile := filepath.Join(tempDir, "concat.txt")
-	err = r.createConcatFile(segmentFiles, concatFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to create concat file: %w", err)
+// Sync скачивает новые сегменты. До появления master.m3u8 качество не выбирается:
+// иначе запись могла бы начаться с качества, которое загрузилось первым.
+// final - эфир закончен, качество выбирается по тому, что есть в MinIO
+func (c *SegmentCollector) Sync(ctx context.Context, final bool) error {
+	if c.quality == "" {
+		quality, err := c.selectQuality(ctx, final)
+		if err != nil {
+			return err
+		}
+		if quality == "" {
+			return nil
+		}
+		c.quality = quality
+		log.Printf("🎯 Recording stream %s from quality '%s'", c.storageKey, quality)
 	}
 
-	// Конкатенировать сегменты в MP4
-	outputPath := filepath.Join(r.recordingsPath, fmt.Sprintf("%s.mp4", recordingID))
-	err = r.concatenateSegments(concatFile, outputPath)
+	prefix := fmt.Sprintf("live-segments/%s/%s/", c.storageKey, c.quality)
+	objects, err := c.r.segments.List(ctx, prefix)
 	if err != nil {
-		return "", fmt.Errorf("failed to concatenate segments: %w", err)
+		return fmt.Errorf("failed to list segments: %w", err)
 	}
 
-	log.Printf("✅ Recording completed: %s", outputPath)
-	return outputPath, nil
-}
+	for _, object := range objects {
+		// Скачать только .ts сегменты или CMAF сегменты с init (init.mp4 / init_N.mp4)
+		fileName := filepath.Base(object.Key)
+		isInit := strings.HasPrefix(fileName, "init") && strings.HasSuffix(fileName, ".mp4")
+		if !isInit && !strings.HasSuffix(fileName, ".ts") && !strings.HasSuffix(fileName, ".m4s") {
+			continue
+		}
 
-// downloadSingleQualitySegments скачивает сегменты ОДНОГО качества
-func (r *FFmpegRecorder) downloadSingleQualitySegments(ctx context.Context, streamKey, tempDir string) ([]string, string, error) {
-	// Приоритет качеств: 1080p (лучший) -> 1080p -> 480p -> 360p
-	qualityPriority := []string{"1080p", "720p", "480p", "360p"}
+		// init.mp4 действует с начала эфира, init_N.mp4 - с сегмента N
+		initStart := 0
+		if isInit && fileName != "init.mp4" {
+			initStart = extractSegmentNumber(fileName)
+		}
 
-	for _, quality := range qualityPriority {
-		prefix := fmt.Sprintf("live-segments/%s/%s/", streamKey, quality)
-		log.Printf("🔍 Checking for segments in: %s", prefix)
-
-		objectCh := r.minioClient.ListObjects(ctx, r.minioBucket, minio.ListObjectsOptions{
-			Prefix:    prefix,
-			Recursive: false,
-		})
-
-		var segmentFiles []string
-		for object := range objectCh {
-			if object.Err != nil {
-				log.Printf("⚠️ Error listing objects: %v", object.Err)
+		if isInit {
+			if _, exists := c.inits[initStart]; exists {
 				continue
 			}
+		} else if _, exists := c.downloaded[fileName]; exists {
+			continue
+		}
 
-			// Скачать только .ts файлы
-			if !strings.HasSuffix(object.Key, ".ts") {
-				continue
+		localPath := filepath.Join(c.tempDir, fileName)
+
+		// Сегмент мог уйти из DVR окна между List и Download
+		if err := c.r.segments.Download(ctx, object.Key, localPath); err != nil {
+			log.Printf("❌ Failed to download segment %s: %v", object.Key, err)
+			continue
+		}
+
+		if isInit {
+			c.inits[initStart] = localPath
+			continue
+		}
+
+		c.downloaded[fileName] = localPath
+		log.Printf("📥 Downloaded: %s", fileName)
+	}
+
+	return nil
+}
+
+// selectQuality выбирает лучшее качество из master.m3u8 стрима
+func (c *SegmentCollector) selectQuality(ctx context.Context, final bool) (string, error) {
+	object, err := c.r.segments.Get(ctx, fmt.Sprintf("live-segments/%s/master.m3u8", c.storageKey))
+	if err == nil {
+		master, readErr := io.ReadAll(object)
+		object.Close()
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read master playlist: %w", readErr)
+		}
+		for _, quality := range qualityPriority {
+			if strings.Contains(string(master), quality+"/playlist.m3u8") {
+				return quality, nil
 			}
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", fmt.Errorf("failed to get master playlist: %w", err)
+	}
 
-			fileName := filepath.Base(object.Key)
-			localPath := filepath.Join(tempDir, fileName)
+	if !final {
+		return "", nil
+	}
 
-			err := r.minioClient.FGetObject(ctx, r.minioBucket, object.Key, localPath, minio.GetObjectOptions{})
-			if err != nil {
-				log.Printf("❌ Failed to download segment %s: %v", object.Key, err)
-				continue
+	// master.m3u8 так и не появился - берём первое качество, в котором есть сегменты
+	for _, quality := range qualityPriority {
+		prefix := fmt.Sprintf("live-segments/%s/%s/", c.storageKey, quality)
+		objects, err := c.r.segments.List(ctx, prefix)
+		if err != nil {
+			log.Printf("⚠️ Error listing objects: %v", err)
+			continue
+		}
+		if len(objects) > 0 {
+			return quality, nil
+		}
+		log.Printf("⚠️ No segments found in quality '%s', trying next...", quality)
+	}
+
+	return "", nil
+}
+
+// segmentFiles возвращает скачанные сегменты по порядку
+func (c *SegmentCollector) segmentFiles() []string {
+	files := make([]string, 0, len(c.downloaded))
+	for _, path := range c.downloaded {
+		files = append(files, path)
+	}
+
+	// Сортировать по номеру сегмента (segment_000.ts, segment_001.ts, ...)
+	sort.Slice(files, func(i, j int) bool {
+		return extractSegmentNumber(files[i]) < extractSegmentNumber(files[j])
+	})
+	return files
+}
+
+// fragmentRun - init и сегменты одного запуска транскодера
+type fragmentRun struct {
+	init     string
+	segments []string
+}
+
+// fragmentRuns делит сегменты по init: после переподключения издателя транскодер
+// перезапускается, и сегменты с номера N ссылаются на init_N.mp4
+func (c *SegmentCollector) fragmentRuns(segmentFiles []string) []fragmentRun {
+	starts := make([]int, 0, len(c.inits))
+	for start := range c.inits {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+
+	var runs []fragmentRun
+	runStart := -1
+	for _, path := range segmentFiles {
+		number := extractSegmentNumber(path)
+
+		// Последний init, начинающийся не позже сегмента
+		start := starts[0]
+		for _, s := range starts {
+			if s <= number {
+				start = s
 			}
+		}
 
-			segmentFiles = append(segmentFiles, localPath)
-			log.Printf("📥 Downloaded: %s", fileName)
+		if len(runs) == 0 || start != runStart {
+			runs = append(runs, fragmentRun{init: c.inits[start]})
+			runStart = start
 		}
+		runs[len(runs)-1].segments = append(runs[len(runs)-1].segments, path)
+	}
+	return runs
+}
+
+// ProcessRecording докачивает последние сегменты и создает MP4
+func (r *FFmpegRecorder) ProcessRecording(ctx context.Context, collector *SegmentCollector, recordingID string) (string, error) {
+	storageKey := collector.storageKey
+	log.Printf("📹 Processing recording for stream %s", storageKey)
+
+	if err := collector.Sync(ctx, true); err != nil {
+		return "", fmt.Errorf("failed to download segments: %w", err)
+	}
 
-		// Если нашли сегменты в этом качестве - используем его
-		if len(segmentFiles) > 0 {
-			// Сортировать по номеру сегмента (segment_000.ts, segment_001.ts, ...)
-			sort.Slice(segmentFiles, func(i, j int) bool {
-				return extractSegmentNumber(segmentFiles[i]) < extractSegmentNumber(segmentFiles[j])
-			})
+	segmentFiles := collector.segmentFiles()
+	if len(segmentFiles) == 0 {
+		return "", fmt.Errorf("no segments found for stream %s", storageKey)
+	}
 
-			log.Printf("✅ Found %d segments in quality '%s'", len(segmentFiles), quality)
-			return segmentFiles, quality, nil
+	log.Printf("✅ Collected %d segments from quality '%s' for stream %s", len(segmentFiles), collector.quality, storageKey)
+
+	outputPath := filepath.Join(r.recordingsPath, fmt.Sprintf("%s.mp4", recordingID))
+
+	// Live стримы пишутся в CMAF (init + segment_*.m4s)
+	if strings.HasSuffix(segmentFiles[0], ".m4s") {
+		if len(collector.inits) == 0 {
+			return "", fmt.Errorf("no init segment found for stream %s", storageKey)
 		}
 
-		log.Printf("⚠️ No segments found in quality '%s', trying next...", quality)
+		runs := collector.fragmentRuns(segmentFiles)
+		if len(runs) == 1 {
+			if err := r.concatenateFragmented(runs[0].init, runs[0].segments, outputPath); err != nil {
+				return "", fmt.Errorf("failed to concatenate fMP4 segments: %w", err)
+			}
+		} else {
+			// Издатель переподключался: каждый запуск транскодера собирается отдельно,
+			// затем части склеиваются в одну запись
+			log.Printf("🔗 Recording of stream %s spans %d publisher reconnects", storageKey, len(runs)-1)
+			parts := make([]string, len(runs))
+			for i, run := range runs {
+				parts[i] = filepath.Join(collector.tempDir, fmt.Sprintf("part_%d.mp4", i))
+				if err := r.concatenateFragmented(run.init, run.segments, parts[i]); err != nil {
+					return "", fmt.Errorf("failed to concatenate fMP4 segments: %w", err)
+				}
+			}
+			if err := r.concatenateParts(parts, collector.tempDir, outputPath); err != nil {
+				return "", fmt.Errorf("failed to join recording parts: %w", err)
+			}
+		}
+
+		log.Printf("✅ Recording completed: %s", outputPath)
+		return outputPath, nil
+	}
+
+	// Создать concat file для FFmpeg
+	concatFile := filepath.Join(collector.tempDir, "concat.txt")
+	err := r.createConcatFile(segmentFiles, concatFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to create concat file: %w", err)
+	}
+
+	// Конкатенировать сегменты в MP4
+	err = r.concatenateSegments(concatFile, outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to concatenate segments: %w", err)
 	}
 
-	return nil, "", fmt.Errorf("no segments found in any quality for stream %s", streamKey)
+	log.Printf("✅ Recording completed: %s", outputPath)
+	return outputPath, nil
 }
 
-// extractSegmentNumber извлекает номер из имени сегмента (segment_123.ts -> 123)
+// extractSegmentNumber извлекает номер из имени сегмента (segment_123.ts / segment_00123.m4s -> 123)
 func extractSegmentNumber(filename string) int {
 	base := filepath.Base(filename)
 	// segment_000.ts -> 000
@@ -132,7 +294,7 @@ func extractSegmentNumber(filename string) int {
 	if len(parts) < 2 {
 		return 0
 	}
-	numStr := strings.TrimSuffix(parts[1], ".ts")
+	numStr := strings.TrimSuffix(parts[1], filepath.Ext(parts[1]))
 	num, _ := strconv.Atoi(numStr)
 	return num
 }
@@ -181,6 +343,88 @@ func (r *FFmpegRecorder) concatenateSegments(concatFile, outputPath string) erro
 	return nil
 }
 
+// concatenateFragmented склеивает init.mp4 и fMP4 сегменты в один файл и перепаковывает
+// во фрагментированный MP4 с sidx: vod-service раздаёт его фрагменты как HLS/DASH сегменты
+func (r *FFmpegRecorder) concatenateFragmented(initPath string, segmentFiles []string, outputPath string) error {
+	joinedPath := filepath.Join(filepath.Dir(initPath), "joined.mp4")
+	joined, err := os.Create(joinedPath)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range append([]string{initPath}, segmentFiles...) {
+		if err := appendFile(joined, path); err != nil {
+			joined.Close()
+			return fmt.Errorf("failed to append %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	if err := joined.Close(); err != nil {
+		return err
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-i", joinedPath,
+		"-c", "copy",
+		"-movflags", "+frag_keyframe+empty_moov+default_base_moof+global_sidx",
+		"-y",
+		outputPath,
+	}
+
+	log.Printf("🎬 Remuxing fMP4 segments: ffmpeg %v", args)
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg remux failed: %w", err)
+	}
+
+	return nil
+}
+
+// concatenateParts склеивает части записи (concat demuxer) в такой же фрагментированный MP4
+func (r *FFmpegRecorder) concatenateParts(parts []string, tempDir, outputPath string) error {
+	concatFile := filepath.Join(tempDir, "parts.txt")
+	if err := r.createConcatFile(parts, concatFile); err != nil {
+		return fmt.Errorf("failed to create concat file: %w", err)
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", concatFile,
+		"-c", "copy",
+		"-movflags", "+frag_keyframe+empty_moov+default_base_moof+global_sidx",
+		"-y",
+		outputPath,
+	}
+
+	log.Printf("🎬 Joining recording parts: ffmpeg %v", args)
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg concat failed: %w", err)
+	}
+
+	return nil
+}
+
+func appendFile(dst *os.File, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
 // GetFileDuration получает длительность видео через ffprobe
 func (r *FFmpegRecorder) GetFileDuration(filePath string) (int, error) {
 	cmd := exec.Command("ffprobe",
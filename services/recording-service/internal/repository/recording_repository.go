@@ -1,16 +1,21 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/SerKKiT/streaming-platform/recording-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
+// OutboxSource - имя сервиса в outbox_events.source
+const OutboxSource = "recording-service"
+
 type RecordingRepository struct {
 	db *sql.DB
 }
@@ -84,13 +89,24 @@ func (r *RecordingRepository) UpdateRecording(recording *models.Recording) error
 
 // UpdateRecordingStatus обновляет только статус
 func (r *RecordingRepository) UpdateRecordingStatus(id uuid.UUID, status string) error {
+	return r.updateRecordingStatus(r.db, id, status)
+}
+
+// UpdateRecordingStatusWithEvents обновляет статус и сохраняет события в outbox одной транзакцией
+func (r *RecordingRepository) UpdateRecordingStatusWithEvents(id uuid.UUID, status string, events ...outbox.Event) error {
+	return outbox.WithTx(context.Background(), r.db, OutboxSource, func(tx *sql.Tx) error {
+		return r.updateRecordingStatus(tx, id, status)
+	}, events...)
+}
+
+func (r *RecordingRepository) updateRecordingStatus(exec outbox.Execer, id uuid.UUID, status string) error {
 	now := time.Now()
 	query := `
 		UPDATE recordings
 		SET status = $1, completed_at = $2
 		WHERE id = $3
 	`
-	_, err := r.db.Exec(query, status, now, id)
+	_, err := exec.ExecContext(context.Background(), query, status, now, id)
 	return err
 }
 
@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	inboxCleanupInterval = 1 * time.Hour
+	inboxRetention       = 30 * 24 * time.Hour // дольше, чем relay повторяет доставку
+)
+
+// Inbox - дедупликация входящих событий на стороне получателя по ID события
+type Inbox struct {
+	db       *sql.DB
+	consumer string
+}
+
+func NewInbox(db *sql.DB, consumer string) *Inbox {
+	return &Inbox{
+		db:       db,
+		consumer: consumer,
+	}
+}
+
+// Middleware пропускает событие в обработчик один раз. Повторная доставка
+// (relay не получил ответ) получает сохранённый первый ответ без повторной обработки.
+// Запросы без Idempotency-Key обрабатываются как раньше
+func (i *Inbox) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		eventID, err := uuid.Parse(key)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid Idempotency-Key"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		status, body, found, err := i.lookup(ctx, eventID)
+		if err != nil {
+			log.Printf("❌ Failed to check processed event %s: %v", eventID, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check event"})
+			return
+		}
+		if found {
+			log.Printf("♻️ Duplicate %s event %s, replaying response", c.GetHeader(HeaderEventType), eventID)
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(status, "application/json; charset=utf-8", body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Неуспешные ответы не сохраняем: relay повторит и событие обработается заново
+		status = recorder.Status()
+		if status < 200 || status >= 300 {
+			return
+		}
+		if err := i.markProcessed(context.Background(), eventID, status, recorder.body.Bytes()); err != nil {
+			log.Printf("⚠️ Failed to mark event %s as processed: %v", eventID, err)
+		}
+	}
+}
+
+func (i *Inbox) lookup(ctx context.Context, eventID uuid.UUID) (int, []byte, bool, error) {
+	query := `
+		SELECT response_status, response_body
+		FROM processed_events
+		WHERE consumer = $1 AND event_id = $2
+	`
+
+	var status int
+	var body []byte
+	err := i.db.QueryRowContext(ctx, query, i.consumer, eventID).Scan(&status, &body)
+	if err == sql.ErrNoRows {
+		return 0, nil, false, nil
+	}
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return status, body, true, nil
+}
+
+func (i *Inbox) markProcessed(ctx context.Context, eventID uuid.UUID, status int, body []byte) error {
+	query := `
+		INSERT INTO processed_events (consumer, event_id, response_status, response_body)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (consumer, event_id) DO NOTHING
+	`
+	_, err := i.db.ExecContext(ctx, query, i.consumer, eventID, status, body)
+	return err
+}
+
+// Start периодически удаляет старые записи об обработанных событиях
+func (i *Inbox) Start(ctx context.Context) {
+	ticker := time.NewTicker(inboxCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			query := `
+				DELETE FROM processed_events
+				WHERE consumer = $1 AND processed_at < NOW() - make_interval(secs => $2)
+			`
+			if _, err := i.db.ExecContext(ctx, query, i.consumer, inboxRetention.Seconds()); err != nil {
+				log.Printf("⚠️ Failed to clean up processed events: %v", err)
+			}
+		}
+	}
+}
+
+// responseRecorder копирует тело ответа для повторной отдачи дубликатам
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,109 @@
+// Package outbox - надёжная доставка событий между сервисами: transactional outbox,
+// relay с повторами и дедупликация на стороне получателя по ID события
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Заголовки доставки
+const (
+	HeaderIdempotencyKey = "Idempotency-Key" // ID события
+	HeaderEventType      = "X-Event-Type"
+)
+
+// Event - событие для другого сервиса
+type Event struct {
+	ID           uuid.UUID
+	Type         string            // stream.started, recording.completed, ...
+	AggregateKey string            // события одного ключа доставляются строго по порядку
+	Destination  string            // имя получателя в настройках relay
+	Path         string            // HTTP endpoint получателя
+	Headers      map[string]string // дополнительные заголовки (X-User-ID, ...)
+	Payload      json.RawMessage
+	Attempts     int
+}
+
+// NewEvent создаёт событие с новым ID
+func NewEvent(eventType, aggregateKey, destination, path string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return Event{
+		ID:           uuid.New(),
+		Type:         eventType,
+		AggregateKey: aggregateKey,
+		Destination:  destination,
+		Path:         path,
+		Payload:      data,
+	}, nil
+}
+
+// Execer - *sql.DB или *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Enqueue сохраняет события в outbox. Вызывается в транзакции изменения состояния,
+// тогда событие уходит тогда и только тогда, когда изменение зафиксировано
+func Enqueue(ctx context.Context, exec Execer, source string, events ...Event) error {
+	query := `
+		INSERT INTO outbox_events (id, source, event_type, aggregate_key, destination, path, headers, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	for _, event := range events {
+		headers := event.Headers
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headersJSON, err := json.Marshal(headers)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event headers: %w", err)
+		}
+
+		_, err = exec.ExecContext(ctx, query,
+			event.ID,
+			source,
+			event.Type,
+			event.AggregateKey,
+			event.Destination,
+			event.Path,
+			headersJSON,
+			[]byte(event.Payload),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue %s event: %w", event.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// WithTx выполняет fn в транзакции и сохраняет события в той же транзакции
+func WithTx(ctx context.Context, db *sql.DB, source string, fn func(tx *sql.Tx) error, events ...Event) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := Enqueue(ctx, tx, source, events...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	relayPollInterval    = 1 * time.Second
+	relayBatchSize       = 50
+	relayCleanupInterval = 1 * time.Hour
+	relayRetention       = 7 * 24 * time.Hour // доставленные события храним неделю
+
+	defaultDeliveryTimeout = 30 * time.Second
+	maxDeliveryAttempts    = 30 // с backoff до 5 минут - около двух часов повторов
+	maxRetryBackoff        = 5 * time.Minute
+	maxResponseBodySize    = 1 << 20
+)
+
+// Destination - получатель событий
+type Destination struct {
+	BaseURL string
+	Timeout time.Duration // 0 - defaultDeliveryTimeout
+}
+
+// DeliveredFunc вызывается после успешной доставки с телом ответа получателя
+type DeliveredFunc func(ctx context.Context, event Event, body []byte) error
+
+// Relay доставляет события из outbox сервиса по HTTP с повторами.
+// Событие доставляется, только когда все более ранние события его aggregate_key
+// уже доставлены, поэтому started никогда не обгонит stopped
+type Relay struct {
+	db           *sql.DB
+	source       string
+	destinations map[string]Destination
+	headers      map[string]string
+	onDelivered  map[string]DeliveredFunc
+	client       *http.Client
+	wake         chan struct{}
+}
+
+func NewRelay(db *sql.DB, source string, destinations map[string]Destination) *Relay {
+	return &Relay{
+		db:           db,
+		source:       source,
+		destinations: destinations,
+		headers:      make(map[string]string),
+		onDelivered:  make(map[string]DeliveredFunc),
+		client:       &http.Client{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// SetHeader добавляет заголовок ко всем доставкам (например, X-Internal-API-Key)
+func (r *Relay) SetHeader(key, value string) {
+	r.headers[key] = value
+}
+
+// OnDelivered регистрирует обработчик ответа для типа события
+func (r *Relay) OnDelivered(eventType string, fn DeliveredFunc) {
+	r.onDelivered[eventType] = fn
+}
+
+// Notify будит relay сразу после записи события, не дожидаясь опроса
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start доставляет события до отмены ctx. События, не доставленные до
+// перезапуска, будут доставлены после него
+func (r *Relay) Start(ctx context.Context) {
+	log.Printf("📮 Outbox relay started for %s", r.source)
+
+	poll := time.NewTicker(relayPollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(relayCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("🛑 Outbox relay stopped for %s", r.source)
+			return
+		case <-poll.C:
+		case <-r.wake:
+		case <-cleanup.C:
+			r.cleanup(ctx)
+			continue
+		}
+
+		// Пока есть готовые события - доставляем без паузы
+		for ctx.Err() == nil {
+			delivered, err := r.deliverBatch(ctx)
+			if err != nil {
+				log.Printf("❌ Outbox relay error: %v", err)
+				break
+			}
+			if delivered == 0 {
+				break
+			}
+		}
+	}
+}
+
+// deliverBatch забирает готовые события (не больше одного на aggregate_key)
+// и доставляет их параллельно. Возвращает число забранных событий
+func (r *Relay) deliverBatch(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, event := range events {
+		wg.Add(1)
+		go func(event Event) {
+			defer wg.Done()
+			r.deliver(ctx, event)
+		}(event)
+	}
+	wg.Wait()
+
+	return len(events), nil
+}
+
+// claim резервирует события на время доставки, сдвигая next_attempt_at.
+// Если relay упадёт посреди доставки, событие снова станет доступно после lease
+func (r *Relay) claim(ctx context.Context) ([]Event, error) {
+	query := `
+		UPDATE outbox_events
+		SET next_attempt_at = NOW() + make_interval(secs => $3)
+		WHERE seq IN (
+			SELECT e.seq
+			FROM outbox_events e
+			WHERE e.source = $1
+			  AND e.status = 'pending'
+			  AND e.next_attempt_at <= NOW()
+			  AND NOT EXISTS (
+				SELECT 1 FROM outbox_events p
+				WHERE p.source = e.source
+				  AND p.aggregate_key = e.aggregate_key
+				  AND p.status = 'pending'
+				  AND p.seq < e.seq
+			  )
+			ORDER BY e.seq
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, aggregate_key, destination, path, headers, payload, attempts
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, r.source, relayBatchSize, r.lease().Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var headers, payload []byte
+		if err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateKey,
+			&event.Destination,
+			&event.Path,
+			&headers,
+			&payload,
+			&event.Attempts,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		if err := json.Unmarshal(headers, &event.Headers); err != nil {
+			return nil, fmt.Errorf("failed to parse headers of event %s: %w", event.ID, err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// lease - время, на которое событие резервируется: самый долгий таймаут получателя с запасом
+func (r *Relay) lease() time.Duration {
+	longest := defaultDeliveryTimeout
+	for _, destination := range r.destinations {
+		if destination.Timeout > longest {
+			longest = destination.Timeout
+		}
+	}
+	return longest + time.Minute
+}
+
+func (r *Relay) deliver(ctx context.Context, event Event) {
+	destination, ok := r.destinations[event.Destination]
+	if !ok {
+		r.markRetry(ctx, event, fmt.Errorf("unknown destination %q", event.Destination))
+		return
+	}
+
+	timeout := destination.Timeout
+	if timeout == 0 {
+		timeout = defaultDeliveryTimeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := destination.BaseURL + event.Path
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(event.Payload))
+	if err != nil {
+		r.markRetry(ctx, event, fmt.Errorf("failed to create request: %w", err))
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderIdempotencyKey, event.ID.String())
+	req.Header.Set(HeaderEventType, event.Type)
+	for key, value := range r.headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range event.Headers {
+		req.Header.Set(key, value)
+	}
+
+	log.Printf("📤 Delivering %s event %s to %s (attempt %d)", event.Type, event.ID, url, event.Attempts+1)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.markRetry(ctx, event, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		r.markRetry(ctx, event, fmt.Errorf("status %d: %s", resp.StatusCode, string(body)))
+		return
+	}
+
+	// Ошибка обработчика ответа не отменяет доставку: получатель уже обработал событие
+	if fn, ok := r.onDelivered[event.Type]; ok {
+		if err := fn(ctx, event, body); err != nil {
+			log.Printf("⚠️ Failed to handle response of %s event %s: %v", event.Type, event.ID, err)
+		}
+	}
+
+	r.markDelivered(ctx, event)
+}
+
+func (r *Relay) markDelivered(ctx context.Context, event Event) {
+	query := `
+		UPDATE outbox_events
+		SET status = 'delivered',
+		    attempts = attempts + 1,
+		    last_error = NULL,
+		    delivered_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, event.ID); err != nil {
+		// Событие будет доставлено повторно после lease - получатель отбросит дубликат
+		log.Printf("❌ Failed to mark event %s as delivered: %v", event.ID, err)
+		return
+	}
+
+	log.Printf("✅ Delivered %s event %s", event.Type, event.ID)
+}
+
+// markRetry планирует повтор с экспоненциальной задержкой или сдаётся после maxDeliveryAttempts
+func (r *Relay) markRetry(ctx context.Context, event Event, deliveryErr error) {
+	attempts := event.Attempts + 1
+
+	if attempts >= maxDeliveryAttempts {
+		query := `
+			UPDATE outbox_events
+			SET status = 'failed', attempts = $2, last_error = $3
+			WHERE id = $1
+		`
+		if _, err := r.db.ExecContext(ctx, query, event.ID, attempts, deliveryErr.Error()); err != nil {
+			log.Printf("❌ Failed to mark event %s as failed: %v", event.ID, err)
+		}
+		log.Printf("❌ Giving up on %s event %s after %d attempts: %v", event.Type, event.ID, attempts, deliveryErr)
+		return
+	}
+
+	backoff := retryBackoff(attempts)
+	query := `
+		UPDATE outbox_events
+		SET attempts = $2,
+		    last_error = $3,
+		    next_attempt_at = NOW() + make_interval(secs => $4)
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, event.ID, attempts, deliveryErr.Error(), backoff.Seconds()); err != nil {
+		log.Printf("❌ Failed to schedule retry of event %s: %v", event.ID, err)
+	}
+
+	log.Printf("⚠️ Failed to deliver %s event %s (attempt %d), retrying in %v: %v", event.Type, event.ID, attempts, backoff, deliveryErr)
+}
+
+// retryBackoff: 1s, 2s, 4s, ... до maxRetryBackoff
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// cleanup удаляет давно доставленные события
+func (r *Relay) cleanup(ctx context.Context) {
+	query := `
+		DELETE FROM outbox_events
+		WHERE source = $1
+		  AND status = 'delivered'
+		  AND delivered_at < NOW() - make_interval(secs => $2)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, r.source, relayRetention.Seconds())
+	if err != nil {
+		log.Printf("⚠️ Failed to clean up outbox: %v", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("🧹 Removed %d delivered outbox events", n)
+	}
+}
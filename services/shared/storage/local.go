@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStorage - bucket как директория локальной файловой системы.
+// Для тестов и single-node установок без MinIO
+type LocalStorage struct {
+	root       string
+	bucketName string
+}
+
+func NewLocalStorage(basePath, bucketName string) (*LocalStorage, error) {
+	if basePath == "" {
+		return nil, errors.New("local storage path is required")
+	}
+
+	root := filepath.Join(basePath, bucketName)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bucket directory: %w", err)
+	}
+
+	log.Printf("✅ Local storage bucket: %s", root)
+	return &LocalStorage{
+		root:       root,
+		bucketName: bucketName,
+	}, nil
+}
+
+// Root возвращает директорию bucket (например, для раздачи через HTTP)
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+func (s *LocalStorage) Bucket() string {
+	return s.bucketName
+}
+
+// path переводит ключ в путь внутри bucket, не выпуская за его пределы
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	// Пишем во временный файл и переименовываем: читатели не видят недописанный объект
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write %s: wrote %d of %d bytes", key, written, size)
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) PutFile(ctx context.Context, key, localPath, contentType string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", localPath, err)
+	}
+
+	return s.Put(ctx, key, file, stat.Size(), contentType)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (Object, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		return nil, s.wrapError(key, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, s.wrapError(key, err)
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, s.wrapError(key, fs.ErrNotExist)
+	}
+
+	return &localObject{File: file, info: s.objectInfo(key, stat)}, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	stat, err := os.Stat(target)
+	if err == nil && stat.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return ObjectInfo{}, s.wrapError(key, err)
+	}
+	return s.objectInfo(key, stat), nil
+}
+
+func (s *LocalStorage) Download(ctx context.Context, key, localPath string) error {
+	object, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", localPath, err)
+	}
+
+	_, err = io.Copy(file, object)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		stat, err := d.Info()
+		if err != nil {
+			// Файл удалён во время обхода
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		objects = append(objects, s.objectInfo(key, stat))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s/%s: %w", s.bucketName, prefix, err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Как в S3: удаление отсутствующего объекта не ошибка
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s/%s: %w", s.bucketName, key, err)
+	}
+	s.removeEmptyDirs(filepath.Dir(target))
+	return nil
+}
+
+func (s *LocalStorage) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, object := range objects {
+		if err := s.Delete(ctx, object.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (s *LocalStorage) Copy(ctx context.Context, src Storage, srcKey, dstKey string) error {
+	return copyObject(ctx, s, src, srcKey, dstKey)
+}
+
+// removeEmptyDirs убирает опустевшие "папки" префикса, как их нет и в S3
+func (s *LocalStorage) removeEmptyDirs(dir string) {
+	for dir != s.root && strings.HasPrefix(dir, s.root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (s *LocalStorage) objectInfo(key string, stat fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  ContentType(key),
+		ETag:         fmt.Sprintf("%x-%x", stat.ModTime().UnixNano(), stat.Size()),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func (s *LocalStorage) wrapError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s/%s: %w", s.bucketName, key, ErrNotFound)
+	}
+	return fmt.Errorf("%s/%s: %w", s.bucketName, key, err)
+}
+
+// localObject - открытый файл объекта
+type localObject struct {
+	*os.File
+	info ObjectInfo
+}
+
+func (o *localObject) Info() ObjectInfo {
+	return o.info
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinIOStorage - bucket в MinIO или любом S3-совместимом хранилище
+type MinIOStorage struct {
+	client     *minio.Client
+	bucketName string
+}
+
+func NewMinIOStorage(endpoint, accessKey, secretKey, bucketName string, useSSL bool, opts Options) (*MinIOStorage, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket: %w", err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+		log.Printf("✅ Bucket '%s' created", bucketName)
+	}
+
+	s := &MinIOStorage{
+		client:     client,
+		bucketName: bucketName,
+	}
+
+	if opts.PublicRead {
+		if err := s.setPublicReadPolicy(ctx); err != nil {
+			log.Printf("⚠️ Failed to set public policy for bucket %s: %v", bucketName, err)
+		}
+	}
+
+	log.Printf("✅ Connected to MinIO bucket: %s", bucketName)
+	return s, nil
+}
+
+// setPublicReadPolicy разрешает анонимное чтение объектов (только GET)
+func (s *MinIOStorage) setPublicReadPolicy(ctx context.Context) error {
+	bucketPolicy := fmt.Sprintf(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{
+				"Effect": "Allow",
+				"Principal": {"AWS": "*"},
+				"Action": ["s3:GetObject"],
+				"Resource": ["arn:aws:s3:::%s/*"]
+			}
+		]
+	}`, s.bucketName)
+
+	return s.client.SetBucketPolicy(ctx, s.bucketName, bucketPolicy)
+}
+
+func (s *MinIOStorage) Bucket() string {
+	return s.bucketName
+}
+
+func (s *MinIOStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if contentType == "" {
+		contentType = ContentType(key)
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucketName, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s/%s: %w", s.bucketName, key, err)
+	}
+	return nil
+}
+
+func (s *MinIOStorage) PutFile(ctx context.Context, key, localPath, contentType string) error {
+	if contentType == "" {
+		contentType = ContentType(key)
+	}
+
+	_, err := s.client.FPutObject(ctx, s.bucketName, key, localPath, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to %s/%s: %w", localPath, s.bucketName, key, err)
+	}
+	return nil
+}
+
+func (s *MinIOStorage) Get(ctx context.Context, key string) (Object, error) {
+	object, err := s.client.GetObject(ctx, s.bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.wrapError(key, err)
+	}
+
+	// GetObject ленивый: ошибки (в т.ч. отсутствие объекта) видны только после Stat
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, s.wrapError(key, err)
+	}
+
+	return &minioObject{Object: object, info: toObjectInfo(stat)}, nil
+}
+
+func (s *MinIOStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	stat, err := s.client.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s.wrapError(key, err)
+	}
+	return toObjectInfo(stat), nil
+}
+
+func (s *MinIOStorage) Download(ctx context.Context, key, localPath string) error {
+	if err := s.client.FGetObject(ctx, s.bucketName, key, localPath, minio.GetObjectOptions{}); err != nil {
+		return s.wrapError(key, err)
+	}
+	return nil
+}
+
+func (s *MinIOStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for object := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list %s/%s: %w", s.bucketName, prefix, object.Err)
+		}
+		objects = append(objects, toObjectInfo(object))
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *MinIOStorage) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucketName, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", s.bucketName, key, err)
+	}
+	return nil
+}
+
+func (s *MinIOStorage) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	// Сначала собираем список: удаление во время листинга пропускает объекты
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	if len(objects) == 0 {
+		return 0, nil
+	}
+
+	objectsCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectsCh)
+		for _, object := range objects {
+			objectsCh <- minio.ObjectInfo{Key: object.Key}
+		}
+	}()
+
+	deleted := len(objects)
+	var firstErr error
+	for result := range s.client.RemoveObjects(ctx, s.bucketName, objectsCh, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			log.Printf("❌ Failed to delete %s: %v", result.ObjectName, result.Err)
+			deleted--
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete %s/%s: %w", s.bucketName, result.ObjectName, result.Err)
+			}
+		}
+	}
+	return deleted, firstErr
+}
+
+func (s *MinIOStorage) Copy(ctx context.Context, src Storage, srcKey, dstKey string) error {
+	// Внутри одного MinIO копируем на стороне сервера
+	source, ok := src.(*MinIOStorage)
+	if !ok {
+		return copyObject(ctx, s, src, srcKey, dstKey)
+	}
+
+	_, err := s.client.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket: s.bucketName,
+		Object: dstKey,
+		UserMetadata: map[string]string{
+			"Content-Type": ContentType(dstKey),
+		},
+		ReplaceMetadata: true,
+	}, minio.CopySrcOptions{
+		Bucket: source.bucketName,
+		Object: srcKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s/%s to %s/%s: %w", source.bucketName, srcKey, s.bucketName, dstKey, source.wrapError(srcKey, err))
+	}
+	return nil
+}
+
+func (s *MinIOStorage) wrapError(key string, err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fmt.Errorf("%s/%s: %w", s.bucketName, key, ErrNotFound)
+	}
+	return fmt.Errorf("%s/%s: %w", s.bucketName, key, err)
+}
+
+func toObjectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
+
+// minioObject - minio.Object с метаданными, полученными при открытии
+type minioObject struct {
+	*minio.Object
+	info ObjectInfo
+}
+
+func (o *minioObject) Info() ObjectInfo {
+	return o.info
+}
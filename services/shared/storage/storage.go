@@ -0,0 +1,126 @@
+// Package storage - общее объектное хранилище сервисов: MinIO/S3 или локальная файловая система
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Backend-ы хранилища (STORAGE_BACKEND)
+const (
+	BackendMinIO = "minio"
+	BackendLocal = "local"
+)
+
+// ErrNotFound - объекта с таким ключом нет
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo - метаданные объекта
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Object - открытый для чтения объект: поддерживает Seek и ReadAt
+// (byte range, разбор MP4 без загрузки файла целиком)
+type Object interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Info() ObjectInfo
+}
+
+// Storage - один bucket объектного хранилища. Ключи - пути через "/"
+type Storage interface {
+	Bucket() string
+
+	// Put загружает size байт из r. contentType "" - определить по расширению
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// PutFile загружает локальный файл
+	PutFile(ctx context.Context, key, localPath, contentType string) error
+	// Get открывает объект; ErrNotFound, если его нет
+	Get(ctx context.Context, key string) (Object, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Download сохраняет объект в локальный файл
+	Download(ctx context.Context, key, localPath string) error
+	// List возвращает все объекты с префиксом (рекурсивно), отсортированные по ключу
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix удаляет все объекты с префиксом и возвращает их количество
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	// Copy копирует объект из другого bucket (или этого же)
+	Copy(ctx context.Context, src Storage, srcKey, dstKey string) error
+}
+
+// Config - настройки подключения, общие для всех bucket-ов сервиса
+type Config struct {
+	Backend string // minio (по умолчанию) или local
+
+	// MinIO/S3
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+
+	// Local: каждый bucket - поддиректория LocalPath
+	LocalPath string
+}
+
+// Options - настройки отдельного bucket
+type Options struct {
+	PublicRead bool // анонимное чтение (live сегменты раздаются напрямую через nginx)
+}
+
+// Open создаёт bucket выбранного backend-а, если его ещё нет
+func Open(cfg Config, bucket string, opts Options) (Storage, error) {
+	switch cfg.Backend {
+	case "", BackendMinIO:
+		return NewMinIOStorage(cfg.Endpoint, cfg.AccessKey, cfg.SecretKey, bucket, cfg.UseSSL, opts)
+	case BackendLocal:
+		return NewLocalStorage(cfg.LocalPath, bucket)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// ContentType определяет тип содержимого по расширению ключа
+func ContentType(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s":
+		return "video/iso.segment"
+	case ".mp4":
+		return "video/mp4"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".json":
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
+
+// copyObject копирует объект между разными backend-ами через поток
+func copyObject(ctx context.Context, dst, src Storage, srcKey, dstKey string) error {
+	object, err := src.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	info := object.Info()
+	return dst.Put(ctx, dstKey, object, info.Size, info.ContentType)
+}
@@ -10,13 +10,24 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/chat"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/config"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/handlers"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/health"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/ingest"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/llhls"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/middleware"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/restream"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/rtmp"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/schedule"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/srt"
-	"github.com/SerKKiT/streaming-platform/stream-service/internal/storage"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/viewers"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/whip"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/worker"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -44,50 +55,109 @@ func main() {
 	}
 	log.Println("✅ Successfully connected to database")
 
-	// Initialize MinIO storage with public policy
-	minioStorage, err := storage.NewMinIOStorage(
-		cfg.MinioEndpoint,
-		cfg.MinioAccessKey,
-		cfg.MinioSecretKey,
-		cfg.MinioBucketLive,
-		cfg.MinioUseSSL,
-	)
+	// Initialize live-streams storage (публичное чтение сегментов)
+	liveStorage, err := storage.Open(storage.Config{
+		Backend:   cfg.StorageBackend,
+		Endpoint:  cfg.MinioEndpoint,
+		AccessKey: cfg.MinioAccessKey,
+		SecretKey: cfg.MinioSecretKey,
+		UseSSL:    cfg.MinioUseSSL,
+		LocalPath: cfg.StoragePath,
+	}, cfg.MinioBucketLive, storage.Options{PublicRead: true})
 	if err != nil {
-		log.Fatalf("Failed to initialize MinIO: %v", err)
-	}
-	log.Println("✅ Successfully connected to MinIO")
-
-	// Set public read policy for live-streams bucket
-	if err := minioStorage.SetPublicReadPolicy(); err != nil {
-		log.Printf("⚠️  Warning: Failed to set public policy: %v", err)
+		log.Fatalf("Failed to initialize storage: %v", err)
 	}
+	log.Printf("✅ Successfully initialized %s storage", cfg.StorageBackend)
 
 	// Initialize components
 	streamRepo := repository.NewStreamRepository(db)
 
+	// Transcode worker: только задания ingest узла, эфиры в БД ему не принадлежат
+	if cfg.Role == config.RoleWorker {
+		runWorker(cfg, streamRepo, liveStorage)
+		return
+	}
+
+	// Эфиры, оборванные падением сервиса, не должны остаться в live
+	if recovered, err := streamRepo.RecoverInterruptedStreams(); err != nil {
+		log.Printf("⚠️ Failed to recover interrupted streams: %v", err)
+	} else if recovered > 0 {
+		log.Printf("⚠️ Marked %d interrupted streams as error", recovered)
+	}
+
+	// LL-HLS трансляции раздаются из памяти stream-service
+	llRegistry := llhls.NewRegistry()
+
+	// CPU слоты транскодирования: admission control и деградация лестницы качеств
+	scheduler := transcoder.NewScheduler(cfg.TranscoderSlots)
+	workerRegistry := worker.NewRegistry()
+	transcoderHandler := handlers.NewTranscoderHandler(scheduler, workerRegistry)
+	log.Printf("🎛️ Transcoder capacity: %d slots", cfg.TranscoderSlots)
+
 	// Create FFmpeg transcoder
-	ffmpegTranscoder, err := transcoder.NewFFmpegTranscoder(
+	ffmpegTranscoder := transcoder.NewFFmpegTranscoder(
 		"/var/www/hls",
-		cfg.MinioEndpoint,
-		cfg.MinioAccessKey,
-		cfg.MinioSecretKey,
-		cfg.MinioBucketLive,
-		cfg.MinioUseSSL,
+		liveStorage,
 		streamRepo,        // Передать repository
 		cfg.PublicBaseURL, // Передать public base URL
+		llRegistry,
+		scheduler,
 	)
 
-	if err != nil {
-		log.Fatalf("Failed to initialize FFmpeg transcoder: %v", err)
-	}
-
 	// Recording Service URL for webhooks
 	recordingServiceURL := os.Getenv("RECORDING_SERVICE_URL")
 	if recordingServiceURL == "" {
 		recordingServiceURL = "http://recording-service:8083"
 	}
 
-	srtHandler := srt.NewHandler(streamRepo, ffmpegTranscoder, recordingServiceURL)
+	// События жизненного цикла стримов доставляются через outbox с повторами
+	relay := outbox.NewRelay(db, repository.OutboxSource, map[string]outbox.Destination{
+		"recording-service": {BaseURL: recordingServiceURL},
+	})
+	if apiKey := os.Getenv("INTERNAL_API_KEY"); apiKey != "" {
+		relay.SetHeader("X-Internal-API-Key", apiKey)
+	}
+
+	// Повторные доставки событий от recording-service отбрасываются по ID
+	inbox := outbox.NewInbox(db, repository.OutboxSource)
+
+	// Зрители live стримов: heartbeat'ы в памяти, периодический сброс в БД
+	viewerTracker := viewers.NewTracker(streamRepo)
+	viewerHandler := handlers.NewViewerHandler(streamRepo, viewerTracker)
+
+	// Live чат: WebSocket на стрим, история и баны в БД
+	chatRepo := repository.NewChatRepository(db)
+	chatHandler := handlers.NewChatHandler(streamRepo, chatRepo, chat.NewHub(chatRepo))
+
+	// Телеметрия эфира: статистика SRT и прогресс ffmpeg по эфирам
+	healthRepo := repository.NewHealthRepository(db)
+	healthMonitor := health.NewMonitor(healthRepo)
+	healthHandler := handlers.NewHealthHandler(streamRepo, healthRepo, healthMonitor)
+
+	// Рестрим: входящий поток параллельно уходит на внешние RTMP/SRT площадки
+	destRepo := repository.NewDestinationRepository(db)
+	restreamManager := restream.NewManager(destRepo)
+	destinationHandler := handlers.NewDestinationHandler(streamRepo, destRepo, restreamManager)
+
+	// STREAM_ROLE=ingest: публикации транскодируют worker'ы, локально - только LL-HLS и WHIP
+	var pipeline ingest.Transcoder = ffmpegTranscoder
+	if cfg.Role == config.RoleIngest {
+		pipeline = worker.NewDispatcher(ffmpegTranscoder, workerRegistry, middleware.InternalAPIKey())
+		log.Printf("🛠️ Ingest role: broadcasts are dispatched to transcode workers")
+	}
+
+	// Общий pipeline публикации для SRT, RTMP и WHIP
+	publisher := ingest.NewPublisher(streamRepo, pipeline, relay, viewerTracker, healthMonitor, restreamManager, cfg.ReconnectGrace)
+
+	// Запланированные эфиры: анонсы без издателя снимаются после ExpireGrace
+	scheduleHandler := handlers.NewScheduleHandler(streamRepo)
+	scheduleExpirer := schedule.NewExpirer(streamRepo)
+
+	// Ключи публикации: ротация и отзыв отключают издателя с этим ключом
+	streamKeyRepo := repository.NewStreamKeyRepository(db)
+	streamKeyHandler := handlers.NewStreamKeyHandler(streamRepo, streamKeyRepo, publisher)
+
+	srtHandler := srt.NewHandler(streamRepo, publisher)
 
 	// Initialize SRT server
 	srtServer, err := srt.NewServer(&srt.Config{
@@ -98,31 +168,69 @@ func main() {
 		log.Fatalf("Failed to create SRT server: %v", err)
 	}
 
+	// Initialize RTMP server
+	rtmpHandler := rtmp.NewHandler(streamRepo, publisher)
+	rtmpServer, err := rtmp.NewServer(&rtmp.Config{
+		Address: ":" + cfg.RTMPPort,
+		App:     cfg.RTMPApp,
+	}, rtmpHandler)
+	if err != nil {
+		log.Fatalf("Failed to create RTMP server: %v", err)
+	}
+
+	// Initialize WHIP (WebRTC ingest из браузера)
+	whipHandler, err := whip.NewHandler(&whip.Config{
+		UDPPort:  cfg.WHIPUDPPort,
+		PublicIP: cfg.WHIPPublicIP,
+	}, streamRepo, publisher, cfg.JWTSecret)
+	if err != nil {
+		log.Fatalf("Failed to create WHIP handler: %v", err)
+	}
+
 	// Start SRT server in goroutine
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	go relay.Start(ctx)
+	go inbox.Start(ctx)
+	go viewerTracker.Start(ctx)
+	go scheduleExpirer.Start(ctx)
+
 	go func() {
 		if err := srtServer.Start(ctx); err != nil && err != context.Canceled {
 			log.Printf("❌ SRT server error: %v", err)
 		}
 	}()
 
+	// Start RTMP server in goroutine
+	go func() {
+		if err := rtmpServer.Start(ctx); err != nil && err != context.Canceled {
+			log.Printf("❌ RTMP server error: %v", err)
+		}
+	}()
+
 	// Setup HTTP API
 	streamHandler := handlers.NewStreamHandler(
 		streamRepo,
 		"localhost:"+cfg.SRTPort,
-		minioStorage,
+		"localhost:"+cfg.RTMPPort,
+		cfg.RTMPApp,
+		liveStorage,
 		cfg.PublicBaseURL, // ДОБАВЛЕНО: из конфига
 	)
 
 	// Cleanup handler
 	cleanupHandler := handlers.NewCleanupHandler(
 		streamRepo,
-		minioStorage,
+		liveStorage,
 		"/var/www/hls",
 	)
 
+	llhlsHandler := llhls.NewHandler(llRegistry)
+
+	// DVR: плейлисты с произвольного момента эфира в пределах окна стрима
+	dvrHandler := handlers.NewDVRHandler(streamRepo, liveStorage, cfg.PublicBaseURL)
+
 	router := gin.Default()
 
 	// Global middleware
@@ -132,16 +240,40 @@ func main() {
 	// Health check
 	router.GET("/health", streamHandler.Health)
 
+	// Local backend: live-streams раздаёт сам сервис (с MinIO - nginx напрямую из bucket)
+	if local, ok := liveStorage.(*storage.LocalStorage); ok {
+		router.Static("/"+cfg.MinioBucketLive, local.Root())
+	}
+
 	// Public routes (NO AUTH - no user_id required)
 	public := router.Group("/streams")
 	{
 		public.GET("/live", streamHandler.GetLiveStreams)
-		public.GET("/by-key/:key", streamHandler.GetStreamByKey)
+		public.GET("/upcoming", scheduleHandler.GetUpcomingStreams)
+		public.GET("/abr-presets", streamHandler.GetABRPresets)
 		public.GET("/:id/play", streamHandler.GetStreamPlaybackInfo)
 		public.GET("/:id/thumbnail", streamHandler.GetStreamThumbnail)
 		public.GET("/:id", streamHandler.GetStream)
 		public.GET("/:id/qualities", streamHandler.GetStreamQualities)
 
+		// WHIP: авторизация по stream key или JWT владельца внутри handler
+		public.POST("/:id/whip", whipHandler.HandleOffer)
+		public.DELETE("/:id/whip/:session", whipHandler.HandleDelete)
+
+		// LL-HLS: blocking playlist reload и части из памяти
+		public.GET("/:id/llhls/master.m3u8", llhlsHandler.GetMaster)
+		public.GET("/:id/llhls/:quality/:file", llhlsHandler.GetRenditionFile)
+
+		// DVR / time-shift: ?start= RFC3339 или unix seconds
+		public.GET("/:id/dvr", dvrHandler.GetMaster)
+		public.GET("/:id/dvr/:quality/playlist.m3u8", dvrHandler.GetPlaylist)
+
+		// Зрители: heartbeat плеера раз в ~10 секунд
+		public.POST("/:id/heartbeat", viewerHandler.Heartbeat)
+
+		// Чат: без X-User-ID подключение только читает
+		public.GET("/:id/chat/ws", chatHandler.ServeWS)
+		public.GET("/:id/chat/messages", chatHandler.GetMessages)
 	}
 
 	// Protected routes (require X-User-ID header from API Gateway)
@@ -152,10 +284,42 @@ func main() {
 		protected.GET("/user", streamHandler.GetUserStreams)
 		protected.PUT("/:id", streamHandler.UpdateStream)
 		protected.DELETE("/:id", streamHandler.DeleteStream)
+		protected.GET("/:id/broadcasts", viewerHandler.GetBroadcasts)
+		protected.GET("/:id/health", healthHandler.GetStreamHealth)
+
+		protected.PUT("/:id/schedule", scheduleHandler.ScheduleStream)
+		protected.DELETE("/:id/schedule", scheduleHandler.CancelSchedule)
+
+		protected.GET("/:id/keys", streamKeyHandler.ListKeys)
+		protected.POST("/:id/keys", streamKeyHandler.CreateKey)
+		protected.POST("/:id/keys/:key_id/rotate", streamKeyHandler.RotateKey)
+		protected.DELETE("/:id/keys/:key_id", streamKeyHandler.RevokeKey)
+
+		protected.GET("/:id/destinations", destinationHandler.ListDestinations)
+		protected.POST("/:id/destinations", destinationHandler.CreateDestination)
+		protected.PUT("/:id/destinations/:destination_id", destinationHandler.UpdateDestination)
+		protected.DELETE("/:id/destinations/:destination_id", destinationHandler.DeleteDestination)
+		protected.POST("/:id/destinations/:destination_id/start", destinationHandler.StartDestination)
+		protected.POST("/:id/destinations/:destination_id/stop", destinationHandler.StopDestination)
+	}
+
+	// Операторские endpoints (INTERNAL_API_KEY, через gateway не проксируются)
+	admin := router.Group("/admin")
+	admin.Use(middleware.InternalAuth())
+	{
+		admin.GET("/transcoder", transcoderHandler.GetUsage)
+		admin.GET("/workers", transcoderHandler.GetWorkers)
+	}
+
+	// Heartbeat'ы transcode worker'ов (INTERNAL_API_KEY)
+	internal := router.Group("/internal")
+	internal.Use(middleware.InternalAuth())
+	{
+		internal.POST("/workers/heartbeat", workerRegistry.HandleHeartbeat)
 	}
 
 	// ✅ НОВОЕ: Webhook endpoint (public - no auth)
-	router.POST("/webhooks/recording-complete", cleanupHandler.HandleRecordingComplete)
+	router.POST("/webhooks/recording-complete", inbox.Middleware(), cleanupHandler.HandleRecordingComplete)
 
 	// Start HTTP server in goroutine
 	go func() {
@@ -173,6 +337,8 @@ func main() {
 	log.Println("⏹️  Shutting down gracefully...")
 	cancel()
 	srtServer.Stop()
+	rtmpServer.Stop()
+	whipHandler.Close()
 	log.Println("✅ Server stopped")
 }
 
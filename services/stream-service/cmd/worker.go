@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/config"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/llhls"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/middleware"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// runWorker - STREAM_ROLE=worker: только транскодирование заданий ingest узла,
+// без ingest серверов и публичного API. Сегменты пишутся в общее хранилище
+func runWorker(cfg *config.Config, streamRepo *repository.StreamRepository, liveStorage storage.Storage) {
+	scheduler := transcoder.NewScheduler(cfg.TranscoderSlots)
+	ffmpegTranscoder := transcoder.NewFFmpegTranscoder(
+		"/var/www/hls",
+		liveStorage,
+		streamRepo,
+		cfg.PublicBaseURL,
+		llhls.NewRegistry(), // LL-HLS эфиры транскодирует ingest узел
+		scheduler,
+	)
+	server := worker.NewServer(ffmpegTranscoder, streamRepo)
+
+	workerURL := cfg.WorkerURL
+	if workerURL == "" {
+		advertised, err := worker.AdvertiseURL(cfg.IngestURL, cfg.WorkerPort)
+		if err != nil {
+			log.Fatalf("Failed to determine worker URL (set WORKER_URL): %v", err)
+		}
+		workerURL = advertised
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go server.Run(ctx)
+	go worker.NewAnnouncer(cfg.WorkerID, workerURL, cfg.IngestURL, middleware.InternalAPIKey(), scheduler).Run(ctx)
+
+	router := gin.Default()
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "role": config.RoleWorker, "worker_id": cfg.WorkerID})
+	})
+
+	jobs := router.Group("/jobs")
+	jobs.Use(middleware.InternalAuth())
+	{
+		jobs.POST("/:stream_id", server.HandleJob)
+		jobs.POST("/:stream_id/finish", server.HandleFinish)
+	}
+
+	go func() {
+		log.Printf("🛠️ Transcode worker %s starting on port %s (%d slots, ingest %s)",
+			cfg.WorkerID, cfg.WorkerPort, cfg.TranscoderSlots, cfg.IngestURL)
+		if err := router.Run(":" + cfg.WorkerPort); err != nil {
+			log.Fatalf("❌ Failed to start worker HTTP server: %v", err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("⏹️  Transcode worker shutting down...")
+}
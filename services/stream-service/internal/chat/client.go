@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxFrameSize   = 4096
+	sendBufferSize = 64
+)
+
+// User - автор сообщений. Анонимные зрители (nil) только читают чат
+type User struct {
+	ID       uuid.UUID
+	Username string
+}
+
+// client - одно WebSocket подключение к комнате
+type client struct {
+	conn *websocket.Conn
+	user *User
+	send chan []byte // закрывает комната при отключении клиента
+}
+
+func (c *client) anonymous() bool {
+	return c.user == nil
+}
+
+// readPump читает команды клиента до разрыва соединения
+func (c *client) readPump(r *room) {
+	c.conn.SetReadLimit(maxFrameSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("⚠️ Chat connection of stream %s closed: %v", r.streamID, err)
+			}
+			return
+		}
+
+		var cmd command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			r.reply(c, errorEvent("Invalid command format"))
+			continue
+		}
+		r.handle(c, &cmd)
+	}
+}
+
+// writePump отправляет события клиенту и держит соединение ping'ами
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
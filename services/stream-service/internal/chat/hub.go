@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// HistoryLimit - сколько последних сообщений получает новое подключение
+const HistoryLimit = 50
+
+// Hub держит комнаты чатов стримов, у которых есть подключения
+type Hub struct {
+	chatRepo *repository.ChatRepository
+
+	mu    sync.Mutex
+	rooms map[uuid.UUID]*room
+}
+
+func NewHub(chatRepo *repository.ChatRepository) *Hub {
+	return &Hub{
+		chatRepo: chatRepo,
+		rooms:    make(map[uuid.UUID]*room),
+	}
+}
+
+// Serve подключает WebSocket к чату стрима и блокируется до отключения.
+// user == nil - анонимный зритель, только чтение
+func (h *Hub) Serve(conn *websocket.Conn, stream *models.Stream, user *User) {
+	c := &client{
+		conn: conn,
+		user: user,
+		send: make(chan []byte, sendBufferSize),
+	}
+
+	r, err := h.join(stream, c)
+	if err != nil {
+		log.Printf("❌ Failed to join chat of stream %s: %v", stream.ID, err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "chat is unavailable"),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	go c.writePump()
+	c.readPump(r)
+	h.leave(r, c)
+}
+
+func (h *Hub) join(stream *models.Stream, c *client) (*room, error) {
+	history, err := h.chatRepo.GetRecentMessages(stream.ID, time.Time{}, HistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if r, ok := h.rooms[stream.ID]; ok {
+		r.add(c, history)
+		h.mu.Unlock()
+		return r, nil
+	}
+	h.mu.Unlock()
+
+	// Состояние новой комнаты загружается без блокировки hub: другие чаты не ждут БД
+	loaded, err := h.loadRoom(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// Подключение и закрытие пустой комнаты сериализуются через h.mu
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rooms[stream.ID]
+	if !ok {
+		r = loaded
+		h.rooms[stream.ID] = r
+	}
+	r.add(c, history)
+	return r, nil
+}
+
+func (h *Hub) leave(r *room, c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.remove(c) == 0 && h.rooms[r.streamID] == r {
+		delete(h.rooms, r.streamID)
+	}
+}
+
+func (h *Hub) loadRoom(stream *models.Stream) (*room, error) {
+	slowMode, err := h.chatRepo.GetSlowMode(stream.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	bans, err := h.chatRepo.GetActiveBans(stream.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat bans: %w", err)
+	}
+
+	r := &room{
+		hub:         h,
+		streamID:    stream.ID,
+		ownerID:     stream.UserID,
+		clients:     make(map[*client]struct{}),
+		slowMode:    time.Duration(slowMode) * time.Second,
+		bans:        make(map[uuid.UUID]time.Time, len(bans)),
+		lastMessage: make(map[uuid.UUID]time.Time),
+		limiters:    make(map[uuid.UUID]*rate.Limiter),
+	}
+	for _, ban := range bans {
+		if ban.ExpiresAt != nil {
+			r.bans[ban.UserID] = *ban.ExpiresAt
+		} else {
+			r.bans[ban.UserID] = time.Time{}
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,64 @@
+// Package chat - live чат стрима поверх WebSocket: комната на стрим, история в БД,
+// лимиты на пользователя, slow mode и модерация владельцем стрима
+package chat
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+)
+
+// Кадры от клиента
+const (
+	CommandMessage  = "message"        // {"type":"message","content":"..."}
+	CommandDelete   = "delete_message" // {"type":"delete_message","message_id":"..."}
+	CommandTimeout  = "timeout"        // {"type":"timeout","user_id":"...","seconds":600}
+	CommandBan      = "ban"            // {"type":"ban","user_id":"..."}
+	CommandUnban    = "unban"          // {"type":"unban","user_id":"..."}
+	CommandSlowMode = "slow_mode"      // {"type":"slow_mode","seconds":30}, 0 - выключить
+)
+
+// События от сервера
+const (
+	EventHistory        = "history"
+	EventMessage        = "message"
+	EventMessageDeleted = "message_deleted"
+	EventUserTimedOut   = "user_timed_out" // сообщения пользователя скрыты
+	EventUserBanned     = "user_banned"    // сообщения пользователя скрыты
+	EventUserUnbanned   = "user_unbanned"
+	EventSlowMode       = "slow_mode"
+	EventError          = "error"
+)
+
+// command - кадр от клиента
+type command struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	Seconds   int    `json:"seconds,omitempty"`
+}
+
+// event - кадр для клиентов. Заполняются только поля своего типа
+type event struct {
+	Type            string                `json:"type"`
+	Message         *models.ChatMessage   `json:"message,omitempty"`
+	Messages        []*models.ChatMessage `json:"messages,omitempty"`
+	MessageID       string                `json:"message_id,omitempty"`
+	UserID          string                `json:"user_id,omitempty"`
+	Until           *time.Time            `json:"until,omitempty"`
+	SlowModeSeconds *int                  `json:"slow_mode_seconds,omitempty"`
+	ReadOnly        *bool                 `json:"read_only,omitempty"`
+	IsOwner         *bool                 `json:"is_owner,omitempty"`
+	Error           string                `json:"error,omitempty"`
+}
+
+func (e *event) encode() []byte {
+	data, _ := json.Marshal(e)
+	return data
+}
+
+func errorEvent(message string) *event {
+	return &event{Type: EventError, Error: message}
+}
@@ -0,0 +1,316 @@
+package chat
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const (
+	MaxMessageLength = 500
+	MaxSlowMode      = time.Hour
+	MaxTimeout       = 14 * 24 * time.Hour
+
+	// Не больше 20 сообщений за 30 секунд, подряд - до 5
+	messageRate  = rate.Limit(20.0 / 30.0)
+	messageBurst = 5
+)
+
+// room - чат одного стрима. Живёт, пока есть подключения
+type room struct {
+	hub      *Hub
+	streamID uuid.UUID
+	ownerID  uuid.UUID
+
+	mu          sync.Mutex
+	clients     map[*client]struct{}
+	slowMode    time.Duration
+	bans        map[uuid.UUID]time.Time // user ID → конец таймаута (zero - бессрочный бан)
+	lastMessage map[uuid.UUID]time.Time
+	limiters    map[uuid.UUID]*rate.Limiter
+}
+
+func (r *room) isOwner(c *client) bool {
+	return !c.anonymous() && c.user.ID == r.ownerID
+}
+
+// add регистрирует клиента и первым событием отправляет ему историю и состояние чата
+func (r *room) add(c *client, history []*models.ChatMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slowMode := int(r.slowMode.Seconds())
+	readOnly := c.anonymous()
+	isOwner := r.isOwner(c)
+
+	r.clients[c] = struct{}{}
+	c.send <- (&event{
+		Type:            EventHistory,
+		Messages:        history,
+		SlowModeSeconds: &slowMode,
+		ReadOnly:        &readOnly,
+		IsOwner:         &isOwner,
+	}).encode()
+}
+
+// remove отключает клиента и возвращает число оставшихся
+func (r *room) remove(c *client) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drop(c)
+	return len(r.clients)
+}
+
+// drop - без блокировки, вызывающий держит r.mu
+func (r *room) drop(c *client) {
+	if _, ok := r.clients[c]; ok {
+		delete(r.clients, c)
+		close(c.send)
+	}
+}
+
+// broadcast рассылает событие всем. Клиенты, не успевающие читать, отключаются
+func (r *room) broadcast(e *event) {
+	data := e.encode()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		select {
+		case c.send <- data:
+		default:
+			log.Printf("⚠️ Chat client of stream %s is too slow, disconnecting", r.streamID)
+			r.drop(c)
+		}
+	}
+}
+
+// reply отправляет событие одному клиенту
+func (r *room) reply(c *client, e *event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clients[c]; !ok {
+		return
+	}
+	select {
+	case c.send <- e.encode():
+	default:
+		r.drop(c)
+	}
+}
+
+func (r *room) handle(c *client, cmd *command) {
+	if c.anonymous() {
+		r.reply(c, errorEvent("Sign in to chat"))
+		return
+	}
+
+	if cmd.Type == CommandMessage {
+		r.postMessage(c, cmd.Content)
+		return
+	}
+
+	if !r.isOwner(c) {
+		r.reply(c, errorEvent("Only the stream owner can moderate the chat"))
+		return
+	}
+
+	switch cmd.Type {
+	case CommandDelete:
+		r.deleteMessage(c, cmd.MessageID)
+	case CommandTimeout:
+		if cmd.Seconds <= 0 || time.Duration(cmd.Seconds)*time.Second > MaxTimeout {
+			r.reply(c, errorEvent(fmt.Sprintf("Timeout must be between 1 and %d seconds", int(MaxTimeout.Seconds()))))
+			return
+		}
+		r.banUser(c, cmd.UserID, time.Duration(cmd.Seconds)*time.Second)
+	case CommandBan:
+		r.banUser(c, cmd.UserID, 0)
+	case CommandUnban:
+		r.unbanUser(c, cmd.UserID)
+	case CommandSlowMode:
+		r.setSlowMode(c, cmd.Seconds)
+	default:
+		r.reply(c, errorEvent(fmt.Sprintf("Unknown command %q", cmd.Type)))
+	}
+}
+
+func (r *room) postMessage(c *client, content string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+	if utf8.RuneCountInString(content) > MaxMessageLength {
+		r.reply(c, errorEvent(fmt.Sprintf("Message is longer than %d characters", MaxMessageLength)))
+		return
+	}
+
+	if reason := r.admit(c); reason != "" {
+		r.reply(c, errorEvent(reason))
+		return
+	}
+
+	message := &models.ChatMessage{
+		StreamID: r.streamID,
+		UserID:   c.user.ID,
+		Username: c.user.Username,
+		Content:  content,
+	}
+	if err := r.hub.chatRepo.CreateMessage(message); err != nil {
+		log.Printf("❌ Failed to save chat message in stream %s: %v", r.streamID, err)
+		r.reply(c, errorEvent("Failed to send message"))
+		return
+	}
+
+	r.broadcast(&event{Type: EventMessage, Message: message})
+}
+
+// admit проверяет бан, лимит и slow mode. Пустая строка - сообщение можно отправить
+func (r *room) admit(c *client) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID := c.user.ID
+	now := time.Now()
+
+	if until, banned := r.bans[userID]; banned {
+		if until.IsZero() {
+			return "You are banned from this chat"
+		}
+		if now.Before(until) {
+			return fmt.Sprintf("You are timed out for %d more seconds", int(until.Sub(now).Seconds())+1)
+		}
+		delete(r.bans, userID)
+	}
+
+	// Владелец не ограничен
+	if r.isOwner(c) {
+		return ""
+	}
+
+	if r.slowMode > 0 {
+		if last, ok := r.lastMessage[userID]; ok && now.Sub(last) < r.slowMode {
+			return fmt.Sprintf("Slow mode: wait %d seconds", int((r.slowMode-now.Sub(last)).Seconds())+1)
+		}
+	}
+
+	limiter, ok := r.limiters[userID]
+	if !ok {
+		limiter = rate.NewLimiter(messageRate, messageBurst)
+		r.limiters[userID] = limiter
+	}
+	if !limiter.Allow() {
+		return "You are sending messages too fast"
+	}
+
+	r.lastMessage[userID] = now
+	return ""
+}
+
+func (r *room) deleteMessage(c *client, messageIDStr string) {
+	messageID, err := uuid.Parse(messageIDStr)
+	if err != nil {
+		r.reply(c, errorEvent("Invalid message ID"))
+		return
+	}
+
+	deleted, err := r.hub.chatRepo.DeleteMessage(r.streamID, messageID, c.user.ID)
+	if err != nil {
+		log.Printf("❌ Failed to delete chat message %s: %v", messageID, err)
+		r.reply(c, errorEvent("Failed to delete message"))
+		return
+	}
+	if !deleted {
+		r.reply(c, errorEvent("Message not found"))
+		return
+	}
+
+	r.broadcast(&event{Type: EventMessageDeleted, MessageID: messageID.String()})
+}
+
+// banUser банит (duration 0) или выдаёт таймаут и скрывает сообщения пользователя
+func (r *room) banUser(c *client, userIDStr string, duration time.Duration) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		r.reply(c, errorEvent("Invalid user ID"))
+		return
+	}
+	if userID == r.ownerID {
+		r.reply(c, errorEvent("You cannot ban yourself"))
+		return
+	}
+
+	var until time.Time
+	var expiresAt *time.Time
+	if duration > 0 {
+		until = time.Now().Add(duration)
+		expiresAt = &until
+	}
+
+	if err := r.hub.chatRepo.BanUser(r.streamID, userID, expiresAt, c.user.ID); err != nil {
+		log.Printf("❌ Failed to ban user %s in stream %s: %v", userID, r.streamID, err)
+		r.reply(c, errorEvent("Failed to ban user"))
+		return
+	}
+	if err := r.hub.chatRepo.DeleteUserMessages(r.streamID, userID, c.user.ID); err != nil {
+		log.Printf("⚠️ Failed to hide messages of user %s in stream %s: %v", userID, r.streamID, err)
+	}
+
+	r.mu.Lock()
+	r.bans[userID] = until
+	r.mu.Unlock()
+
+	if duration > 0 {
+		log.Printf("🔇 User %s timed out in stream %s for %v", userID, r.streamID, duration)
+		r.broadcast(&event{Type: EventUserTimedOut, UserID: userID.String(), Until: expiresAt})
+	} else {
+		log.Printf("🚫 User %s banned in stream %s", userID, r.streamID)
+		r.broadcast(&event{Type: EventUserBanned, UserID: userID.String()})
+	}
+}
+
+func (r *room) unbanUser(c *client, userIDStr string) {
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		r.reply(c, errorEvent("Invalid user ID"))
+		return
+	}
+
+	if err := r.hub.chatRepo.UnbanUser(r.streamID, userID); err != nil {
+		log.Printf("❌ Failed to unban user %s in stream %s: %v", userID, r.streamID, err)
+		r.reply(c, errorEvent("Failed to unban user"))
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.bans, userID)
+	r.mu.Unlock()
+
+	r.broadcast(&event{Type: EventUserUnbanned, UserID: userID.String()})
+}
+
+func (r *room) setSlowMode(c *client, seconds int) {
+	if seconds < 0 || time.Duration(seconds)*time.Second > MaxSlowMode {
+		r.reply(c, errorEvent(fmt.Sprintf("Slow mode must be between 0 and %d seconds", int(MaxSlowMode.Seconds()))))
+		return
+	}
+
+	if err := r.hub.chatRepo.SetSlowMode(r.streamID, seconds); err != nil {
+		log.Printf("❌ Failed to set slow mode in stream %s: %v", r.streamID, err)
+		r.reply(c, errorEvent("Failed to update slow mode"))
+		return
+	}
+
+	r.mu.Lock()
+	r.slowMode = time.Duration(seconds) * time.Second
+	r.mu.Unlock()
+
+	r.broadcast(&event{Type: EventSlowMode, SlowModeSeconds: &seconds})
+}
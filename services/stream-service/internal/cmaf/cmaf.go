@@ -0,0 +1,230 @@
+package cmaf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Флаг sample_is_non_sync_sample в sample_flags (ISO/IEC 14496-12)
+const nonSyncSampleFlag = 0x00010000
+
+// box - заголовок MP4 бокса и его содержимое
+type box struct {
+	kind    string
+	payload []byte
+}
+
+// readBoxes разбирает последовательность MP4 боксов одного уровня
+func readBoxes(data []byte) []box {
+	var boxes []box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		kind := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+
+		if size < header || size > uint64(len(data)) {
+			return boxes
+		}
+
+		boxes = append(boxes, box{kind: kind, payload: data[header:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func findBox(data []byte, kind string) []byte {
+	for _, b := range readBoxes(data) {
+		if b.kind == kind {
+			return b.payload
+		}
+	}
+	return nil
+}
+
+// TrackDefaults - что нужно знать из init сегмента для разбора частей
+type TrackDefaults struct {
+	VideoTrackID      uint32
+	DefaultSampleFlag uint32 // default_sample_flags из trex видео трека
+}
+
+// ParseInit находит видео трек и его флаги по умолчанию в init.mp4
+func ParseInit(init []byte) (TrackDefaults, bool) {
+	var defaults TrackDefaults
+
+	moov := findBox(init, "moov")
+	if moov == nil {
+		return defaults, false
+	}
+
+	found := false
+	for _, b := range readBoxes(moov) {
+		if b.kind != "trak" {
+			continue
+		}
+		hdlr := findBox(findBox(b.payload, "mdia"), "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			continue
+		}
+		tkhd := findBox(b.payload, "tkhd")
+		if len(tkhd) < 4 {
+			continue
+		}
+		// version 1 использует 64-битные времена создания/изменения
+		offset := 12
+		if tkhd[0] == 1 {
+			offset = 20
+		}
+		if len(tkhd) < offset+4 {
+			continue
+		}
+		defaults.VideoTrackID = binary.BigEndian.Uint32(tkhd[offset : offset+4])
+		found = true
+		break
+	}
+
+	if !found {
+		return defaults, false
+	}
+
+	for _, b := range readBoxes(findBox(moov, "mvex")) {
+		if b.kind != "trex" || len(b.payload) < 24 {
+			continue
+		}
+		if binary.BigEndian.Uint32(b.payload[4:8]) == defaults.VideoTrackID {
+			defaults.DefaultSampleFlag = binary.BigEndian.Uint32(b.payload[20:24])
+		}
+	}
+
+	return defaults, true
+}
+
+// IsIndependent проверяет начинается ли fMP4 часть с ключевого кадра видео
+func IsIndependent(part []byte, defaults TrackDefaults) bool {
+	for _, b := range readBoxes(part) {
+		if b.kind != "moof" {
+			continue
+		}
+		for _, traf := range readBoxes(b.payload) {
+			if traf.kind != "traf" {
+				continue
+			}
+			if flags, ok := firstSampleFlags(traf.payload, defaults); ok {
+				return flags&nonSyncSampleFlag == 0
+			}
+		}
+	}
+	return false
+}
+
+// firstSampleFlags возвращает флаги первого сэмпла видео трека во фрагменте
+func firstSampleFlags(traf []byte, defaults TrackDefaults) (uint32, bool) {
+	tfhd := findBox(traf, "tfhd")
+	if len(tfhd) < 8 || binary.BigEndian.Uint32(tfhd[4:8]) != defaults.VideoTrackID {
+		return 0, false
+	}
+
+	sampleFlags := defaults.DefaultSampleFlag
+	tfhdFlags := binary.BigEndian.Uint32(tfhd[0:4]) & 0xffffff
+	offset := 8
+	if tfhdFlags&0x01 != 0 { // base_data_offset
+		offset += 8
+	}
+	if tfhdFlags&0x02 != 0 { // sample_description_index
+		offset += 4
+	}
+	if tfhdFlags&0x08 != 0 { // default_sample_duration
+		offset += 4
+	}
+	if tfhdFlags&0x10 != 0 { // default_sample_size
+		offset += 4
+	}
+	if tfhdFlags&0x20 != 0 && len(tfhd) >= offset+4 { // default_sample_flags
+		sampleFlags = binary.BigEndian.Uint32(tfhd[offset : offset+4])
+	}
+
+	trun := findBox(traf, "trun")
+	if len(trun) < 8 {
+		return sampleFlags, true
+	}
+
+	trunFlags := binary.BigEndian.Uint32(trun[0:4]) & 0xffffff
+	offset = 8
+	if trunFlags&0x01 != 0 { // data_offset
+		offset += 4
+	}
+	if trunFlags&0x04 != 0 { // first_sample_flags
+		if len(trun) < offset+4 {
+			return sampleFlags, true
+		}
+		return binary.BigEndian.Uint32(trun[offset : offset+4]), true
+	}
+
+	if trunFlags&0x400 != 0 { // per-sample flags
+		if trunFlags&0x100 != 0 { // sample_duration
+			offset += 4
+		}
+		if trunFlags&0x200 != 0 { // sample_size
+			offset += 4
+		}
+		if len(trun) >= offset+4 {
+			return binary.BigEndian.Uint32(trun[offset : offset+4]), true
+		}
+	}
+
+	return sampleFlags, true
+}
+
+// Codecs возвращает строку кодеков треков init сегмента (RFC 6381) для DASH
+func Codecs(init []byte) string {
+	var codecs []string
+	for _, b := range readBoxes(findBox(init, "moov")) {
+		if b.kind != "trak" {
+			continue
+		}
+		stbl := findBox(findBox(findBox(b.payload, "mdia"), "minf"), "stbl")
+		stsd := findBox(stbl, "stsd")
+		if len(stsd) < 8 {
+			continue
+		}
+		// version/flags + entry_count, дальше sample entries
+		entries := readBoxes(stsd[8:])
+		if len(entries) == 0 {
+			continue
+		}
+		codecs = append(codecs, sampleEntryCodec(entries[0]))
+	}
+	return strings.Join(codecs, ",")
+}
+
+func sampleEntryCodec(entry box) string {
+	switch entry.kind {
+	case "avc1", "avc3":
+		// VisualSampleEntry занимает 78 байт до дочерних боксов
+		if len(entry.payload) < 78 {
+			return entry.kind
+		}
+		avcC := findBox(entry.payload[78:], "avcC")
+		if len(avcC) < 4 {
+			return entry.kind
+		}
+		// profile, constraint flags, level
+		return fmt.Sprintf("%s.%02x%02x%02x", entry.kind, avcC[1], avcC[2], avcC[3])
+	case "mp4a":
+		return "mp4a.40.2" // транскодер всегда кодирует AAC-LC
+	case "Opus":
+		return "opus"
+	}
+	return entry.kind
+}
@@ -3,15 +3,37 @@ package config
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"strconv"
+	"time"
+)
+
+// Роли процесса stream-service (STREAM_ROLE)
+const (
+	RoleStandalone = "standalone" // ingest и транскодирование в одном процессе
+	RoleIngest     = "ingest"     // ingest и API, транскодирование на worker'ах
+	RoleWorker     = "worker"     // только транскодирование заданий ingest узла
 )
 
 type Config struct {
+	Role            string // STREAM_ROLE: standalone, ingest или worker
+	IngestURL       string // worker: адрес ingest узла для heartbeat'ов
+	WorkerID        string // worker: имя в реестре ingest узла
+	WorkerPort      string // worker: порт HTTP заданий
+	WorkerURL       string // worker: адрес для заданий (по умолчанию - IP маршрута к ingest)
 	DatabaseURL     string
 	JWTSecret       string
 	Port            string
 	SRTPort         string
 	SRTLatency      uint // миллисекунды
+	RTMPPort        string
+	RTMPApp         string
+	WHIPUDPPort     string        // единый UDP порт для всего WebRTC (ICE) трафика
+	WHIPPublicIP    string        // внешний IP для ICE кандидатов (NAT 1:1, docker)
+	ReconnectGrace  time.Duration // сколько эфир ждёт переподключения издателя (0 - не ждёт)
+	TranscoderSlots int           // CPU слоты транскодера (слот ≈ одно качество 360p30)
+	StorageBackend  string        // minio или local
+	StoragePath     string        // корень local хранилища
 	MinioEndpoint   string
 	MinioAccessKey  string
 	MinioSecretKey  string
@@ -50,23 +72,95 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	rtmpPort := os.Getenv("RTMP_PORT")
+	if rtmpPort == "" {
+		rtmpPort = "1935"
+	}
+
+	rtmpApp := os.Getenv("RTMP_APP")
+	if rtmpApp == "" {
+		rtmpApp = "live"
+	}
+
+	whipUDPPort := os.Getenv("WHIP_UDP_PORT")
+	if whipUDPPort == "" {
+		whipUDPPort = "8189"
+	}
+
+	whipPublicIP := os.Getenv("WHIP_PUBLIC_IP")
+
+	reconnectGrace := 30 * time.Second // default: короткие обрывы Wi-Fi не завершают эфир
+	if value := os.Getenv("RECONNECT_GRACE_SECONDS"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("RECONNECT_GRACE_SECONDS must be a non-negative number of seconds")
+		}
+		reconnectGrace = time.Duration(seconds) * time.Second
+	}
+
+	// default: ядро тянет около 720p30 x264 veryfast, то есть 4 слота
+	transcoderSlots := runtime.NumCPU() * 4
+	if value := os.Getenv("TRANSCODER_SLOTS"); value != "" && value != "0" {
+		slots, err := strconv.Atoi(value)
+		if err != nil || slots < 1 {
+			return nil, fmt.Errorf("TRANSCODER_SLOTS must be a positive number (0 - by CPU count)")
+		}
+		transcoderSlots = slots
+	}
+
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = "minio"
+	}
+
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "/var/lib/streaming/storage"
+	}
+
 	minioEndpoint := os.Getenv("MINIO_ENDPOINT")
 	if minioEndpoint == "" {
 		minioEndpoint = "minio:9000"
 	}
 
+	// Ключи MinIO нужны только для backend minio
 	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY")
-	if minioAccessKey == "" {
+	if minioAccessKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_ACCESS_KEY is required")
 	}
 
 	minioSecretKey := os.Getenv("MINIO_SECRET_KEY")
-	if minioSecretKey == "" {
+	if minioSecretKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_SECRET_KEY is required")
 	}
 
 	minioUseSSL := os.Getenv("MINIO_USE_SSL") == "true"
 
+	role := os.Getenv("STREAM_ROLE")
+	if role == "" {
+		role = RoleStandalone
+	}
+	if role != RoleStandalone && role != RoleIngest && role != RoleWorker {
+		return nil, fmt.Errorf("STREAM_ROLE must be one of: standalone, ingest, worker")
+	}
+
+	ingestURL := os.Getenv("INGEST_URL")
+	if ingestURL == "" {
+		ingestURL = "http://stream-service:8082"
+	}
+
+	workerPort := os.Getenv("WORKER_PORT")
+	if workerPort == "" {
+		workerPort = "8090"
+	}
+
+	// Несколько worker'ов на одной машине различаются портом
+	workerID := os.Getenv("WORKER_ID")
+	if workerID == "" {
+		hostname, _ := os.Hostname()
+		workerID = hostname + ":" + workerPort
+	}
+
 	// ДОБАВЛЕНО: Public base URL для HLS endpoints
 	publicBaseURL := os.Getenv("PUBLIC_BASE_URL")
 	if publicBaseURL == "" {
@@ -74,11 +168,24 @@ func LoadConfig() (*Config, error) {
 	}
 
 	return &Config{
+		Role:            role,
+		IngestURL:       ingestURL,
+		WorkerID:        workerID,
+		WorkerPort:      workerPort,
+		WorkerURL:       os.Getenv("WORKER_URL"),
 		DatabaseURL:     dbURL,
 		JWTSecret:       jwtSecret,
 		Port:            port,
 		SRTPort:         srtPort,
 		SRTLatency:      srtLatency,
+		RTMPPort:        rtmpPort,
+		RTMPApp:         rtmpApp,
+		WHIPUDPPort:     whipUDPPort,
+		WHIPPublicIP:    whipPublicIP,
+		ReconnectGrace:  reconnectGrace,
+		TranscoderSlots: transcoderSlots,
+		StorageBackend:  storageBackend,
+		StoragePath:     storagePath,
 		MinioEndpoint:   minioEndpoint,
 		MinioAccessKey:  minioAccessKey,
 		MinioSecretKey:  minioSecretKey,
@@ -0,0 +1,182 @@
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"time"
+)
+
+// timescale сегментов в SegmentTimeline (миллисекунды)
+const timescale = 1000
+
+// Representation - одно качество в MPD. Сегменты и init общие с HLS,
+// пути задаются относительно manifest.mpd
+type Representation struct {
+	ID             string
+	Bandwidth      int
+	Width          int
+	Height         int
+	Codecs         string
+	Initialization string
+	Media          string // шаблон с $Number$, например 720p/segment_$Number%03d$.m4s
+	StartNumber    int64
+	StartTime      float64   // время первого сегмента от начала эфира в секундах (DVR окно)
+	Durations      []float64 // длительности сегментов в секундах, начиная со StartNumber
+}
+
+// Manifest - MPD для live (dynamic) или завершённой (static) трансляции
+type Manifest struct {
+	Live                  bool
+	AvailabilityStartTime time.Time
+	SegmentDuration       float64 // целевая длительность сегмента
+	TimeShiftBufferDepth  float64 // DVR окно в секундах (0 - весь эфир)
+	Representations       []Representation
+}
+
+type mpd struct {
+	XMLName                    xml.Name `xml:"MPD"`
+	Xmlns                      string   `xml:"xmlns,attr"`
+	Profiles                   string   `xml:"profiles,attr"`
+	Type                       string   `xml:"type,attr"`
+	AvailabilityStartTime      string   `xml:"availabilityStartTime,attr,omitempty"`
+	PublishTime                string   `xml:"publishTime,attr,omitempty"`
+	MinimumUpdatePeriod        string   `xml:"minimumUpdatePeriod,attr,omitempty"`
+	SuggestedPresentationDelay string   `xml:"suggestedPresentationDelay,attr,omitempty"`
+	TimeShiftBufferDepth       string   `xml:"timeShiftBufferDepth,attr,omitempty"`
+	MediaPresentationDuration  string   `xml:"mediaPresentationDuration,attr,omitempty"`
+	MinBufferTime              string   `xml:"minBufferTime,attr"`
+	Period                     period   `xml:"Period"`
+}
+
+type period struct {
+	ID            string        `xml:"id,attr"`
+	Start         string        `xml:"start,attr"`
+	AdaptationSet adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	ContentType      string           `xml:"contentType,attr"`
+	MimeType         string           `xml:"mimeType,attr"`
+	SegmentAlignment bool             `xml:"segmentAlignment,attr"`
+	StartWithSAP     int              `xml:"startWithSAP,attr"`
+	Representations  []representation `xml:"Representation"`
+}
+
+type representation struct {
+	ID              string          `xml:"id,attr"`
+	Bandwidth       int             `xml:"bandwidth,attr"`
+	Width           int             `xml:"width,attr,omitempty"`
+	Height          int             `xml:"height,attr,omitempty"`
+	Codecs          string          `xml:"codecs,attr,omitempty"`
+	SegmentTemplate segmentTemplate `xml:"SegmentTemplate"`
+}
+
+type segmentTemplate struct {
+	Timescale       int             `xml:"timescale,attr"`
+	Initialization  string          `xml:"initialization,attr"`
+	Media           string          `xml:"media,attr"`
+	StartNumber     int64           `xml:"startNumber,attr"`
+	SegmentTimeline segmentTimeline `xml:"SegmentTimeline"`
+}
+
+type segmentTimeline struct {
+	Segments []timelineSegment `xml:"S"`
+}
+
+type timelineSegment struct {
+	T *int64 `xml:"t,attr"`
+	D int64  `xml:"d,attr"`
+	R int    `xml:"r,attr,omitempty"`
+}
+
+// Render формирует manifest.mpd
+func (m *Manifest) Render() ([]byte, error) {
+	doc := mpd{
+		Xmlns:         "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:      "urn:mpeg:dash:profile:isoff-live:2011",
+		MinBufferTime: formatDuration(2 * m.SegmentDuration),
+		Period: period{
+			ID:    "0",
+			Start: "PT0S",
+			AdaptationSet: adaptationSet{
+				ContentType:      "video",
+				MimeType:         "video/mp4",
+				SegmentAlignment: true,
+				StartWithSAP:     1,
+			},
+		},
+	}
+
+	var total float64
+	for _, r := range m.Representations {
+		var duration float64
+		for _, d := range r.Durations {
+			duration += d
+		}
+		total = math.Max(total, duration)
+
+		doc.Period.AdaptationSet.Representations = append(doc.Period.AdaptationSet.Representations, representation{
+			ID:        r.ID,
+			Bandwidth: r.Bandwidth,
+			Width:     r.Width,
+			Height:    r.Height,
+			Codecs:    r.Codecs,
+			SegmentTemplate: segmentTemplate{
+				Timescale:       timescale,
+				Initialization:  r.Initialization,
+				Media:           r.Media,
+				StartNumber:     r.StartNumber,
+				SegmentTimeline: buildTimeline(r.StartTime, r.Durations),
+			},
+		})
+	}
+
+	if m.Live {
+		doc.Type = "dynamic"
+		doc.AvailabilityStartTime = m.AvailabilityStartTime.UTC().Format(time.RFC3339)
+		doc.PublishTime = time.Now().UTC().Format(time.RFC3339)
+		doc.MinimumUpdatePeriod = formatDuration(m.SegmentDuration)
+		doc.SuggestedPresentationDelay = formatDuration(3 * m.SegmentDuration)
+		if m.TimeShiftBufferDepth > 0 {
+			doc.TimeShiftBufferDepth = formatDuration(m.TimeShiftBufferDepth)
+		}
+	} else {
+		doc.Type = "static"
+		doc.MediaPresentationDuration = formatDuration(total)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MPD: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// buildTimeline сворачивает одинаковые длительности в S@r. start - время первого сегмента
+func buildTimeline(start float64, durations []float64) segmentTimeline {
+	var timeline segmentTimeline
+	t := int64(math.Round(start * timescale))
+
+	for i, d := range durations {
+		ms := int64(math.Round(d * timescale))
+		last := len(timeline.Segments) - 1
+		if last >= 0 && timeline.Segments[last].D == ms {
+			timeline.Segments[last].R++
+		} else {
+			s := timelineSegment{D: ms}
+			if i == 0 {
+				start := t
+				s.T = &start
+			}
+			timeline.Segments = append(timeline.Segments, s)
+		}
+		t += ms
+	}
+
+	return timeline
+}
+
+func formatDuration(seconds float64) string {
+	return fmt.Sprintf("PT%.3fS", seconds)
+}
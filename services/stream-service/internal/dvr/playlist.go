@@ -0,0 +1,224 @@
+// Package dvr - time-shift для live стримов: скользящее окно сегментов в MinIO
+// и плейлисты, начинающиеся с заданного момента эфира
+package dvr
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// programDateTimeLayout - формат EXT-X-PROGRAM-DATE-TIME
+const programDateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Segment - сегмент live плейлиста с привязкой к времени эфира
+type Segment struct {
+	Sequence        int64 // media sequence number
+	URI             string
+	Duration        float64
+	ProgramDateTime time.Time
+	InitURI         string // EXT-X-MAP сегмента ("" - InitURI плейлиста)
+	Discontinuity   bool   // EXT-X-DISCONTINUITY: издатель переподключился, ffmpeg перезапущен
+}
+
+// End возвращает момент окончания сегмента
+func (s Segment) End() time.Time {
+	return s.ProgramDateTime.Add(time.Duration(s.Duration * float64(time.Second)))
+}
+
+// Playlist - media плейлист одного качества
+type Playlist struct {
+	InitURI  string // init последних сегментов
+	Segments []Segment
+	Event    bool // EXT-X-PLAYLIST-TYPE:EVENT - сегменты только добавляются
+	Ended    bool
+
+	// DiscontinuitySequence - сколько EXT-X-DISCONTINUITY ушло из плейлиста вместе со старыми сегментами
+	DiscontinuitySequence int64
+}
+
+// Render формирует m3u8. baseURL добавляется к URI init и сегментов
+// ("" - URI остаются относительными)
+func (p *Playlist) Render(baseURL string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:7\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", p.targetDuration())
+
+	var firstSequence int64
+	if len(p.Segments) > 0 {
+		firstSequence = p.Segments[0].Sequence
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", firstSequence)
+	if p.DiscontinuitySequence > 0 {
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", p.DiscontinuitySequence)
+	}
+	if p.Event {
+		b.WriteString("#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	}
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if len(p.Segments) == 0 && p.InitURI != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", baseURL+p.InitURI)
+	}
+
+	// После переподключения у сегментов новый init: EXT-X-MAP повторяется при смене
+	var currentInit string
+	for _, segment := range p.Segments {
+		if segment.Discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		initURI := segment.InitURI
+		if initURI == "" {
+			initURI = p.InitURI
+		}
+		if initURI != "" && initURI != currentInit {
+			fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", baseURL+initURI)
+			currentInit = initURI
+		}
+		fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.UTC().Format(programDateTimeLayout))
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", segment.Duration, baseURL+segment.URI)
+	}
+
+	if p.Ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return b.String()
+}
+
+func (p *Playlist) targetDuration() int {
+	target := 1.0
+	for _, segment := range p.Segments {
+		target = math.Max(target, segment.Duration)
+	}
+	return int(math.Ceil(target))
+}
+
+// From возвращает плейлист, начинающийся с сегмента, который содержит момент start.
+// Если start раньше начала окна - плейлист начинается с самого старого сегмента
+func (p *Playlist) From(start time.Time) *Playlist {
+	first := len(p.Segments)
+	for i, segment := range p.Segments {
+		if segment.End().After(start) {
+			first = i
+			break
+		}
+	}
+
+	// Момент позже последнего готового сегмента - отдаём live край
+	if first == len(p.Segments) && first > 0 {
+		first--
+	}
+
+	discontinuitySequence := p.DiscontinuitySequence
+	for _, segment := range p.Segments[:first] {
+		if segment.Discontinuity {
+			discontinuitySequence++
+		}
+	}
+
+	return &Playlist{
+		InitURI:               p.InitURI,
+		Segments:              p.Segments[first:],
+		Ended:                 p.Ended,
+		DiscontinuitySequence: discontinuitySequence,
+	}
+}
+
+// Start возвращает время начала самого старого сегмента
+func (p *Playlist) Start() (time.Time, bool) {
+	if len(p.Segments) == 0 {
+		return time.Time{}, false
+	}
+	return p.Segments[0].ProgramDateTime, true
+}
+
+// Parse читает media плейлист. Сегменты без EXT-X-PROGRAM-DATE-TIME получают время
+// конца предыдущего сегмента
+func Parse(data []byte) (*Playlist, error) {
+	playlist := &Playlist{}
+	var sequence int64
+	var programDateTime time.Time
+	duration := -1.0
+	discontinuity := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-DISCONTINUITY-SEQUENCE:"):
+			value, err := strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-DISCONTINUITY-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid discontinuity sequence: %w", err)
+			}
+			playlist.DiscontinuitySequence = value
+		case line == "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			value, err := strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid media sequence: %w", err)
+			}
+			sequence = value
+		case strings.HasPrefix(line, "#EXT-X-PLAYLIST-TYPE:EVENT"):
+			playlist.Event = true
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if _, after, found := strings.Cut(line, `URI="`); found {
+				playlist.InitURI, _, _ = strings.Cut(after, `"`)
+			}
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			value, err := ParseProgramDateTime(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"))
+			if err != nil {
+				return nil, err
+			}
+			programDateTime = value
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			d, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segment duration: %w", err)
+			}
+			duration = d
+		case line == "#EXT-X-ENDLIST":
+			playlist.Ended = true
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if duration < 0 {
+				continue
+			}
+			segment := Segment{
+				Sequence:        sequence,
+				URI:             line,
+				Duration:        duration,
+				ProgramDateTime: programDateTime,
+				InitURI:         playlist.InitURI,
+				Discontinuity:   discontinuity,
+			}
+			playlist.Segments = append(playlist.Segments, segment)
+
+			sequence++
+			programDateTime = segment.End()
+			duration = -1
+			discontinuity = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// ParseProgramDateTime разбирает значение EXT-X-PROGRAM-DATE-TIME
+// (ffmpeg пишет смещение без двоеточия: 2024-01-01T12:00:00.000+0000)
+func ParseProgramDateTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid program date time %q", value)
+}
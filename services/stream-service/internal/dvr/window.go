@@ -0,0 +1,156 @@
+package dvr
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MinWindow = 1 * time.Minute
+	MaxWindow = 24 * time.Hour
+
+	// DeleteDelay - сколько ждать перед удалением сегмента, вышедшего из окна:
+	// плееры на краю окна успевают догрузить то, что было в их копии плейлиста
+	DeleteDelay = 30 * time.Second
+)
+
+// ValidateWindow проверяет DVR окно стрима в секундах (0 - весь эфир)
+func ValidateWindow(seconds int) error {
+	if seconds == 0 {
+		return nil
+	}
+	window := time.Duration(seconds) * time.Second
+	if window < MinWindow || window > MaxWindow {
+		return fmt.Errorf("dvr_window_seconds must be 0 or between %d and %d",
+			int(MinWindow.Seconds()), int(MaxWindow.Seconds()))
+	}
+	return nil
+}
+
+// Window - скользящее окно загруженных сегментов одного качества.
+// Без ограничения (length 0) плейлист только растёт и помечается как EVENT.
+// Не потокобезопасно: вызывающий сериализует доступ
+type Window struct {
+	length   time.Duration
+	initURI  string
+	segments []Segment
+	offset   float64 // суммарная длительность сегментов, вышедших из окна
+
+	discontinuities int64 // EXT-X-DISCONTINUITY, вышедшие из окна
+	discontinue     bool  // следующий сегмент начинается после переподключения
+
+	evicted []evictedSegment
+}
+
+type evictedSegment struct {
+	uri string
+	at  time.Time
+}
+
+func NewWindow(length time.Duration) *Window {
+	return &Window{length: length}
+}
+
+// RestoreWindow восстанавливает окно по плейлисту, загруженному в хранилище.
+// offset - длительность сегментов, уже ушедших из окна (их в плейлисте нет)
+func RestoreWindow(length time.Duration, playlist *Playlist, offset float64) *Window {
+	return &Window{
+		length:          length,
+		initURI:         playlist.InitURI,
+		segments:        append([]Segment(nil), playlist.Segments...),
+		offset:          offset,
+		discontinuities: playlist.DiscontinuitySequence,
+	}
+}
+
+// Limited сообщает, удаляются ли старые сегменты
+func (w *Window) Limited() bool {
+	return w.length > 0
+}
+
+// SetInit запоминает init сегмент (EXT-X-MAP)
+func (w *Window) SetInit(uri string) {
+	w.initURI = uri
+}
+
+// Discontinue помечает следующий сегмент разрывом (EXT-X-DISCONTINUITY):
+// ffmpeg перезапущен после переподключения издателя
+func (w *Window) Discontinue() {
+	w.discontinue = len(w.segments) > 0
+}
+
+// Add добавляет загруженный сегмент и сдвигает окно
+func (w *Window) Add(segment Segment) {
+	if segment.InitURI == "" {
+		segment.InitURI = w.initURI
+	}
+	segment.Discontinuity = segment.Discontinuity || w.discontinue
+	w.discontinue = false
+
+	w.segments = append(w.segments, segment)
+	if !w.Limited() {
+		return
+	}
+
+	var total float64
+	for _, s := range w.segments {
+		total += s.Duration
+	}
+
+	// Последний сегмент остаётся всегда, даже если он длиннее окна
+	now := time.Now()
+	for len(w.segments) > 1 && total-w.segments[0].Duration >= w.length.Seconds() {
+		oldest := w.segments[0]
+		total -= oldest.Duration
+		w.offset += oldest.Duration
+		if oldest.Discontinuity {
+			w.discontinuities++
+		}
+		w.evicted = append(w.evicted, evictedSegment{uri: oldest.URI, at: now})
+		w.segments = w.segments[1:]
+	}
+}
+
+// Expired возвращает URI сегментов, вышедших из окна больше DeleteDelay назад,
+// и забывает о них
+func (w *Window) Expired() []string {
+	deadline := time.Now().Add(-DeleteDelay)
+
+	var expired []string
+	for len(w.evicted) > 0 && w.evicted[0].at.Before(deadline) {
+		expired = append(expired, w.evicted[0].uri)
+		w.evicted = w.evicted[1:]
+	}
+	return expired
+}
+
+// Segments возвращает копию сегментов окна
+func (w *Window) Segments() []Segment {
+	return append([]Segment(nil), w.segments...)
+}
+
+// Offset - время от начала эфира до первого сегмента окна в секундах
+// (SegmentTimeline в manifest.mpd)
+func (w *Window) Offset() float64 {
+	return w.offset
+}
+
+// Duration - длительность эфира в секундах: окно вместе с ушедшими из него сегментами
+func (w *Window) Duration() float64 {
+	duration := w.offset
+	for _, segment := range w.segments {
+		duration += segment.Duration
+	}
+	return duration
+}
+
+// Playlist возвращает плейлист окна для MinIO
+func (w *Window) Playlist(ended bool) *Playlist {
+	return &Playlist{
+		InitURI:               w.initURI,
+		Segments:              w.Segments(),
+		Event:                 !w.Limited(),
+		Ended:                 ended,
+		DiscontinuitySequence: w.discontinuities,
+	}
+}
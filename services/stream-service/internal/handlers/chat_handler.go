@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/chat"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const maxChatHistoryLimit = 100
+
+// ChatHandler подключает WebSocket к чату стрима и отдаёт историю сообщений
+type ChatHandler struct {
+	streamRepo *repository.StreamRepository
+	chatRepo   *repository.ChatRepository
+	hub        *chat.Hub
+	upgrader   websocket.Upgrader
+}
+
+func NewChatHandler(streamRepo *repository.StreamRepository, chatRepo *repository.ChatRepository, hub *chat.Hub) *ChatHandler {
+	return &ChatHandler{
+		streamRepo: streamRepo,
+		chatRepo:   chatRepo,
+		hub:        hub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// Авторизация по JWT, а не cookie: чужой сайт не подключится от имени пользователя
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeWS upgrades the connection to the stream chat. Пользователь приходит из
+// X-User-ID / X-Username (JWT проверен в API Gateway), без них - только чтение
+func (h *ChatHandler) ServeWS(c *gin.Context) {
+	stream, ok := h.lookupStream(c)
+	if !ok {
+		return
+	}
+
+	var user *chat.User
+	if value, exists := c.Get("user_id"); exists {
+		userID := value.(uuid.UUID)
+		username := c.GetHeader("X-Username")
+		if username == "" {
+			username = "user-" + userID.String()[:8]
+		}
+		user = &chat.User{ID: userID, Username: username}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту ошибкой
+		log.Printf("❌ Failed to upgrade chat connection for stream %s: %v", stream.ID, err)
+		return
+	}
+
+	h.hub.Serve(conn, stream, user)
+}
+
+// GetMessages returns chat history before ?before= (RFC3339), oldest first
+func (h *ChatHandler) GetMessages(c *gin.Context) {
+	stream, ok := h.lookupStream(c)
+	if !ok {
+		return
+	}
+
+	var before time.Time
+	if value := c.Query("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid before: expected RFC3339 time"})
+			return
+		}
+		before = parsed
+	}
+
+	limit := chat.HistoryLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxChatHistoryLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := h.chatRepo.GetRecentMessages(stream.ID, before, limit)
+	if err != nil {
+		log.Printf("❌ Failed to get chat messages for stream %s: %v", stream.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get chat messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+func (h *ChatHandler) lookupStream(c *gin.Context) (*models.Stream, bool) {
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return nil, false
+	}
+
+	return stream, true
+}
@@ -1,40 +1,43 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 
+	"github.com/SerKKiT/streaming-platform/shared/storage"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/stream-service/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type CleanupHandler struct {
-	streamRepo   *repository.StreamRepository
-	minioStorage *storage.MinIOStorage
-	outputDir    string
+	streamRepo *repository.StreamRepository
+	storage    storage.Storage
+	outputDir  string
 }
 
 func NewCleanupHandler(
 	streamRepo *repository.StreamRepository,
-	minioStorage *storage.MinIOStorage,
+	storage storage.Storage,
 	outputDir string,
 ) *CleanupHandler {
 	return &CleanupHandler{
-		streamRepo:   streamRepo,
-		minioStorage: minioStorage,
-		outputDir:    outputDir,
+		streamRepo: streamRepo,
+		storage:    storage,
+		outputDir:  outputDir,
 	}
 }
 
 // RecordingCompleteRequest - запрос от recording-service
 type RecordingCompleteRequest struct {
-	StreamKey string `json:"stream_key" binding:"required"`
-	StreamID  string `json:"stream_id" binding:"required"`
-	VideoID   string `json:"video_id"`
-	Success   bool   `json:"success"`
+	StorageKey string `json:"storage_key" binding:"required"` // live-segments/<storage key>/
+	StreamID   string `json:"stream_id" binding:"required"`
+	VideoID    string `json:"video_id"`
+	Success    bool   `json:"success"`
 }
 
 // HandleRecordingComplete обрабатывает webhook после завершения конвертации
@@ -45,31 +48,41 @@ func (h *CleanupHandler) HandleRecordingComplete(c *gin.Context) {
 		return
 	}
 
-	log.Printf("📩 Received recording complete webhook for stream %s (success: %v)", req.StreamKey, req.Success)
+	// Storage key - ID стрима; не даём удалить что-то кроме его сегментов
+	if _, err := uuid.Parse(req.StorageKey); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid storage key"})
+		return
+	}
+
+	log.Printf("📩 Received recording complete webhook for stream %s (success: %v)", req.StorageKey, req.Success)
 
 	if !req.Success {
-		log.Printf("⚠️ Recording failed for stream %s, skipping cleanup", req.StreamKey)
+		log.Printf("⚠️ Recording failed for stream %s, skipping cleanup", req.StorageKey)
 		c.JSON(http.StatusOK, gin.H{"message": "Recording failed, cleanup skipped"})
 		return
 	}
 
-	// 1. Удалить файлы из MinIO
-	if err := h.minioStorage.DeleteStreamSegments(req.StreamKey); err != nil {
-		log.Printf("❌ Failed to delete MinIO segments for %s: %v", req.StreamKey, err)
+	// 1. Удалить сегменты из хранилища
+	prefix := fmt.Sprintf("live-segments/%s/", req.StorageKey)
+	deleted, err := h.storage.DeletePrefix(context.Background(), prefix)
+	if err != nil {
+		log.Printf("❌ Failed to delete segments for %s: %v", req.StorageKey, err)
+	} else {
+		log.Printf("✅ Deleted %d objects for stream %s", deleted, req.StorageKey)
 	}
 
 	// 2. Удалить локальные файлы
-	localPath := filepath.Join(h.outputDir, req.StreamKey)
+	localPath := filepath.Join(h.outputDir, req.StorageKey)
 	if err := os.RemoveAll(localPath); err != nil {
-		log.Printf("❌ Failed to delete local files for %s: %v", req.StreamKey, err)
+		log.Printf("❌ Failed to delete local files for %s: %v", req.StorageKey, err)
 	} else {
 		log.Printf("✅ Deleted local files: %s", localPath)
 	}
 
-	log.Printf("✅ Cleanup completed for stream %s", req.StreamKey)
+	log.Printf("✅ Cleanup completed for stream %s", req.StorageKey)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Cleanup completed",
-		"stream_key": req.StreamKey,
+		"message":     "Cleanup completed",
+		"storage_key": req.StorageKey,
 	})
 }
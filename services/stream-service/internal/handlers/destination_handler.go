@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/restream"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DestinationHandler - выходы рестрима стрима (только владелец).
+// Изменения применяются к идущему эфиру сразу
+type DestinationHandler struct {
+	streamRepo *repository.StreamRepository
+	destRepo   *repository.DestinationRepository
+	restream   *restream.Manager
+}
+
+func NewDestinationHandler(streamRepo *repository.StreamRepository, destRepo *repository.DestinationRepository, restream *restream.Manager) *DestinationHandler {
+	return &DestinationHandler{
+		streamRepo: streamRepo,
+		destRepo:   destRepo,
+		restream:   restream,
+	}
+}
+
+// ListDestinations returns destinations of the stream with their live status
+func (h *DestinationHandler) ListDestinations(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	destinations, err := h.destRepo.ListDestinations(stream.ID)
+	if err != nil {
+		log.Printf("❌ Failed to list destinations of stream %s: %v", stream.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to list destinations"})
+		return
+	}
+
+	h.restream.Describe(destinations...)
+	c.JSON(http.StatusOK, gin.H{"destinations": destinations})
+}
+
+// CreateDestination adds an RTMP/SRT output
+func (h *DestinationHandler) CreateDestination(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateDestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name (1-50 characters) and url are required"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name (1-50 characters) and url are required"})
+		return
+	}
+
+	url := strings.TrimSpace(req.URL)
+	protocol, err := restream.ValidateURL(url)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+
+	destination, err := h.destRepo.CreateDestination(stream.ID, name, protocol, url, enabled)
+	if err != nil {
+		h.destinationError(c, stream.ID, err)
+		return
+	}
+
+	h.restream.Apply(destination)
+	h.restream.Describe(destination)
+
+	log.Printf("📤 Destination %s (%s, %s) created for stream %s", destination.ID, destination.Name, protocol, stream.ID)
+	c.JSON(http.StatusCreated, gin.H{"destination": destination})
+}
+
+// UpdateDestination changes name, url or enabled. A running output restarts with the new settings
+func (h *DestinationHandler) UpdateDestination(c *gin.Context) {
+	destination, ok := h.ownedDestination(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name must be 1-50 characters"})
+			return
+		}
+		destination.Name = name
+	}
+	if req.URL != nil {
+		url := strings.TrimSpace(*req.URL)
+		protocol, err := restream.ValidateURL(url)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		destination.URL, destination.Protocol = url, protocol
+	}
+	if req.Enabled != nil {
+		destination.Enabled = *req.Enabled
+	}
+
+	h.save(c, destination)
+}
+
+// StartDestination enables an output; during a broadcast it connects immediately
+func (h *DestinationHandler) StartDestination(c *gin.Context) {
+	h.setEnabled(c, true)
+}
+
+// StopDestination disables an output and disconnects it from the platform
+func (h *DestinationHandler) StopDestination(c *gin.Context) {
+	h.setEnabled(c, false)
+}
+
+// DeleteDestination removes an output
+func (h *DestinationHandler) DeleteDestination(c *gin.Context) {
+	destination, ok := h.ownedDestination(c)
+	if !ok {
+		return
+	}
+
+	if err := h.destRepo.DeleteDestination(destination.StreamID, destination.ID); err != nil {
+		h.destinationError(c, destination.StreamID, err)
+		return
+	}
+
+	h.restream.Remove(destination.StreamID, destination.ID)
+
+	log.Printf("🗑️ Destination %s of stream %s deleted", destination.ID, destination.StreamID)
+	c.JSON(http.StatusOK, gin.H{"message": "Destination deleted"})
+}
+
+func (h *DestinationHandler) setEnabled(c *gin.Context, enabled bool) {
+	destination, ok := h.ownedDestination(c)
+	if !ok {
+		return
+	}
+
+	destination.Enabled = enabled
+	h.save(c, destination)
+}
+
+// save сохраняет выход и применяет его к идущему эфиру
+func (h *DestinationHandler) save(c *gin.Context, destination *models.Destination) {
+	updated, err := h.destRepo.UpdateDestination(destination)
+	if err != nil {
+		h.destinationError(c, destination.StreamID, err)
+		return
+	}
+
+	h.restream.Apply(updated)
+	h.restream.Describe(updated)
+
+	log.Printf("📤 Destination %s of stream %s updated (enabled: %v)", updated.ID, updated.StreamID, updated.Enabled)
+	c.JSON(http.StatusOK, gin.H{"destination": updated})
+}
+
+// ownedStream загружает стрим из :id и проверяет, что текущий пользователь - владелец
+func (h *DestinationHandler) ownedStream(c *gin.Context) (*models.Stream, bool) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return nil, false
+	}
+
+	if stream.UserID != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not authorized to manage this stream's destinations"})
+		return nil, false
+	}
+
+	return stream, true
+}
+
+func (h *DestinationHandler) ownedDestination(c *gin.Context) (*models.Destination, bool) {
+	destinationID, err := uuid.Parse(c.Param("destination_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid destination ID"})
+		return nil, false
+	}
+
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return nil, false
+	}
+
+	destination, err := h.destRepo.GetDestination(stream.ID, destinationID)
+	if err != nil {
+		h.destinationError(c, stream.ID, err)
+		return nil, false
+	}
+	return destination, true
+}
+
+func (h *DestinationHandler) destinationError(c *gin.Context, streamID uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDestinationNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Destination not found"})
+	case errors.Is(err, repository.ErrTooManyDestinations):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("❌ Destination operation failed for stream %s: %v", streamID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update destinations"})
+	}
+}
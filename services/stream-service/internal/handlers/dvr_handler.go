@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const playlistContentType = "application/vnd.apple.mpegurl"
+
+// DVRHandler раздаёт плейлисты live стрима, начинающиеся с заданного момента эфира.
+// Плейлисты строятся из плейлистов DVR окна в MinIO, сегменты отдаются из MinIO напрямую
+type DVRHandler struct {
+	streamRepo    *repository.StreamRepository
+	storage       storage.Storage
+	publicBaseURL string
+}
+
+func NewDVRHandler(
+	streamRepo *repository.StreamRepository,
+	storage storage.Storage,
+	publicBaseURL string,
+) *DVRHandler {
+	return &DVRHandler{
+		streamRepo:    streamRepo,
+		storage:       storage,
+		publicBaseURL: publicBaseURL,
+	}
+}
+
+// GetMaster returns master playlist whose variants start at ?start= (RFC3339 or unix seconds)
+func (h *DVRHandler) GetMaster(c *gin.Context) {
+	stream, ok := h.lookupLiveStream(c)
+	if !ok {
+		return
+	}
+
+	start, ok := parseDVRStart(c)
+	if !ok {
+		return
+	}
+
+	master, err := h.readObject(c, path.Join("live-segments", stream.StorageKey(), "master.m3u8"))
+	if err != nil {
+		log.Printf("❌ Failed to read master playlist for DVR of stream %s: %v", stream.ID, err)
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream playlist is not ready yet"})
+		return
+	}
+
+	// Варианты ведут на DVR плейлисты качеств с тем же началом
+	query := ""
+	if !start.IsZero() {
+		query = "?" + url.Values{"start": {start.UTC().Format(time.RFC3339)}}.Encode()
+	}
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(string(master)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if quality, found := strings.CutSuffix(line, "/playlist.m3u8"); found && !strings.HasPrefix(line, "#") {
+			line = fmt.Sprintf("dvr/%s/playlist.m3u8%s", quality, query)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, playlistContentType, []byte(b.String()))
+}
+
+// GetPlaylist returns media playlist of one quality starting at ?start=
+func (h *DVRHandler) GetPlaylist(c *gin.Context) {
+	stream, ok := h.lookupLiveStream(c)
+	if !ok {
+		return
+	}
+
+	quality := c.Param("quality")
+	if !containsQuality(stream.AvailableQualities, quality) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Quality not found"})
+		return
+	}
+
+	start, ok := parseDVRStart(c)
+	if !ok {
+		return
+	}
+
+	prefix := path.Join("live-segments", stream.StorageKey(), quality)
+	data, err := h.readObject(c, prefix+"/playlist.m3u8")
+	if err != nil {
+		log.Printf("❌ Failed to read %s playlist for DVR of stream %s: %v", quality, stream.ID, err)
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream playlist is not ready yet"})
+		return
+	}
+
+	playlist, err := dvr.Parse(data)
+	if err != nil {
+		log.Printf("❌ Failed to parse %s playlist of stream %s: %v", quality, stream.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to build DVR playlist"})
+		return
+	}
+
+	// Плейлист отдаётся через API, поэтому сегменты - абсолютными ссылками на MinIO
+	baseURL := fmt.Sprintf("%s/%s/%s/", h.publicBaseURL, h.storage.Bucket(), prefix)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, playlistContentType, []byte(playlist.From(start).Render(baseURL)))
+}
+
+func (h *DVRHandler) lookupLiveStream(c *gin.Context) (*models.Stream, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return nil, false
+	}
+
+	if stream.Status != "live" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Stream is not currently live"})
+		return nil, false
+	}
+
+	return stream, true
+}
+
+func (h *DVRHandler) readObject(c *gin.Context, objectName string) ([]byte, error) {
+	object, err := h.storage.Get(c.Request.Context(), objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return io.ReadAll(object)
+}
+
+// parseDVRStart разбирает ?start= (RFC3339 или unix seconds). Без параметра -
+// нулевое время: плейлист начинается с начала DVR окна
+func parseDVRStart(c *gin.Context) (time.Time, bool) {
+	value := c.Query("start")
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		seconds, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid start: expected RFC3339 time or unix seconds"})
+			return time.Time{}, false
+		}
+		start = time.Unix(seconds, 0)
+	}
+
+	if start.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "start is in the future"})
+		return time.Time{}, false
+	}
+
+	return start, true
+}
+
+func containsQuality(qualities []string, quality string) bool {
+	for _, q := range qualities {
+		if q == quality {
+			return true
+		}
+	}
+	return false
+}
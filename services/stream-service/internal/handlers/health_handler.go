@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/health"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultHealthSamples - час телеметрии при замерах раз в 5 секунд
+	defaultHealthSamples = 720
+	maxHealthSamples     = 5000
+)
+
+// HealthHandler отдаёт владельцу телеметрию эфира: канал ingest и прогресс ffmpeg
+type HealthHandler struct {
+	streamRepo *repository.StreamRepository
+	healthRepo *repository.HealthRepository
+	monitor    *health.Monitor
+}
+
+func NewHealthHandler(streamRepo *repository.StreamRepository, healthRepo *repository.HealthRepository, monitor *health.Monitor) *HealthHandler {
+	return &HealthHandler{
+		streamRepo: streamRepo,
+		healthRepo: healthRepo,
+		monitor:    monitor,
+	}
+}
+
+// GetStreamHealth returns health samples of a broadcast (owner only).
+// ?broadcast_id= - конкретный эфир (по умолчанию последний),
+// ?since= RFC3339 - только новые замеры (для опроса), ?limit= - последние N замеров
+func (h *HealthHandler) GetStreamHealth(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return
+	}
+
+	if stream.UserID != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not authorized to view this stream's health"})
+		return
+	}
+
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid since: expected RFC3339 time"})
+			return
+		}
+		since = parsed
+	}
+
+	limit := defaultHealthSamples
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHealthSamples {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "limit must be between 1 and 5000"})
+			return
+		}
+		limit = parsed
+	}
+
+	response := models.StreamHealth{
+		StreamID: streamID,
+		Live:     stream.Status == models.StatusLive,
+		Samples:  []*models.HealthSample{},
+	}
+
+	latestID, err := h.healthRepo.GetLatestBroadcastID(streamID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to get latest broadcast of stream %s: %v", streamID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stream health"})
+		return
+	}
+
+	broadcastID := latestID
+	if value := c.Query("broadcast_id"); value != "" {
+		broadcastID, err = uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid broadcast ID"})
+			return
+		}
+
+		ok, err := h.healthRepo.BroadcastBelongsToStream(streamID, broadcastID)
+		if err != nil {
+			log.Printf("❌ Failed to check broadcast %s: %v", broadcastID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stream health"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Broadcast not found"})
+			return
+		}
+	}
+	response.BroadcastID = &broadcastID
+
+	// Текущий замер есть только у идущего (последнего) эфира
+	if broadcastID == latestID {
+		if current, ok := h.monitor.Current(streamID); ok {
+			response.Current = current
+		}
+	}
+
+	samples, err := h.healthRepo.GetSamples(broadcastID, since, limit)
+	if err != nil {
+		log.Printf("❌ Failed to get health samples of stream %s: %v", streamID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get stream health"})
+		return
+	}
+	response.Samples = samples
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ScheduleHandler - запланированные эфиры: анонс, перенос, отмена и список предстоящих
+type ScheduleHandler struct {
+	streamRepo *repository.StreamRepository
+}
+
+func NewScheduleHandler(streamRepo *repository.StreamRepository) *ScheduleHandler {
+	return &ScheduleHandler{streamRepo: streamRepo}
+}
+
+// GetUpcomingStreams returns scheduled streams, nearest first
+func (h *ScheduleHandler) GetUpcomingStreams(c *gin.Context) {
+	streams, err := h.streamRepo.GetUpcomingStreams(schedule.UpcomingLimit)
+	if err != nil {
+		log.Printf("❌ Failed to get upcoming streams: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get upcoming streams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"streams": streams})
+}
+
+// ScheduleStream schedules an offline stream or reschedules a scheduled one
+func (h *ScheduleHandler) ScheduleStream(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	var req models.ScheduleStreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := schedule.ValidateStart(req.ScheduledStartAt, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := schedule.ValidateCoverImageURL(req.CoverImageURL); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.streamRepo.ScheduleStream(stream.ID, req.ScheduledStartAt, req.CoverImageURL); err != nil {
+		h.transitionError(c, stream.ID, err)
+		return
+	}
+
+	stream.Status = models.StatusScheduled
+	stream.ScheduledStartAt = &req.ScheduledStartAt
+	stream.CoverImageURL = req.CoverImageURL
+
+	log.Printf("📅 Stream %s scheduled for %s", stream.ID, req.ScheduledStartAt.Format(time.RFC3339))
+	c.JSON(http.StatusOK, gin.H{"stream": stream})
+}
+
+// CancelSchedule returns a scheduled stream to offline
+func (h *ScheduleHandler) CancelSchedule(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	if stream.Status != models.StatusScheduled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Stream is not scheduled"})
+		return
+	}
+
+	if err := h.streamRepo.UpdateStreamStatus(stream.ID, models.StatusOffline); err != nil {
+		h.transitionError(c, stream.ID, err)
+		return
+	}
+
+	log.Printf("📅 Schedule of stream %s cancelled", stream.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule cancelled"})
+}
+
+func (h *ScheduleHandler) ownedStream(c *gin.Context) (*models.Stream, bool) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return nil, false
+	}
+
+	if stream.UserID != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not authorized to schedule this stream"})
+		return nil, false
+	}
+
+	return stream, true
+}
+
+// transitionError - стрим успел сменить статус (например, вышел в эфир): 409
+func (h *ScheduleHandler) transitionError(c *gin.Context, streamID uuid.UUID, err error) {
+	var transitionErr *models.StatusTransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Stream cannot be scheduled while " + transitionErr.From})
+		return
+	}
+
+	log.Printf("❌ Failed to update schedule of stream %s: %v", streamID, err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update stream schedule"})
+}
@@ -6,36 +6,45 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"path/filepath"
+	"path"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/stream-service/internal/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/schedule"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
 	"github.com/SerKKiT/streaming-platform/stream-service/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type StreamHandler struct {
-	streamRepo    *repository.StreamRepository
-	srtServerAddr string
-	minioStorage  *storage.MinIOStorage
-	publicBaseURL string
+	streamRepo     *repository.StreamRepository
+	srtServerAddr  string
+	rtmpServerAddr string
+	rtmpApp        string
+	storage        storage.Storage
+	publicBaseURL  string
 }
 
 // NewStreamHandler - ОБНОВЛЕННАЯ СИГНАТУРА
 func NewStreamHandler(
 	streamRepo *repository.StreamRepository,
 	srtServerAddr string,
-	minioStorage *storage.MinIOStorage,
+	rtmpServerAddr string,
+	rtmpApp string,
+	storage storage.Storage,
 	publicBaseURL string,
 ) *StreamHandler {
 	return &StreamHandler{
-		streamRepo:    streamRepo,
-		srtServerAddr: srtServerAddr,
-		minioStorage:  minioStorage,
-		publicBaseURL: publicBaseURL,
+		streamRepo:     streamRepo,
+		srtServerAddr:  srtServerAddr,
+		rtmpServerAddr: rtmpServerAddr,
+		rtmpApp:        rtmpApp,
+		storage:        storage,
+		publicBaseURL:  publicBaseURL,
 	}
 }
 
@@ -49,13 +58,36 @@ func (h *StreamHandler) CreateStream(c *gin.Context) {
 		return
 	}
 
+	abrPreset, abrLadder, err := transcoder.ResolveLadder(req.ABRPreset, req.Qualities)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := dvr.ValidateWindow(req.DVRWindow); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Стрим можно сразу создать запланированным
+	if req.ScheduledStartAt != nil {
+		if err := schedule.ValidateStart(*req.ScheduledStartAt, time.Now()); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	if err := schedule.ValidateCoverImageURL(req.CoverImageURL); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	streamKey, err := utils.GenerateStreamKey()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate stream key"})
 		return
 	}
 
-	stream, err := h.streamRepo.CreateStream(userID, streamKey, req.Title, req.Description)
+	stream, err := h.streamRepo.CreateStream(userID, streamKey, req.Title, req.Description, abrPreset, abrLadder, req.LowLatency, req.DVRWindow, req.ScheduledStartAt, req.CoverImageURL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -64,7 +96,9 @@ func (h *StreamHandler) CreateStream(c *gin.Context) {
 	response := models.CreateStreamResponse{
 		Stream:    stream,
 		StreamURL: h.buildSRTURL(streamKey),
-		HLSURL:    h.buildMinIOHLSURL(streamKey),
+		RTMPURL:   h.buildRTMPURL(streamKey),
+		WHIPURL:   h.buildWHIPURL(stream.ID),
+		HLSURL:    h.buildMinIOHLSURL(stream.StorageKey()),
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -91,19 +125,30 @@ func (h *StreamHandler) GetStreamPlaybackInfo(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"stream_id":           stream.ID,
 		"title":               stream.Title,
 		"description":         stream.Description,
 		"username":            stream.Username, // ✅ ДОБАВЛЕНО
 		"status":              stream.Status,
-		"hls_url":             h.buildMinIOHLSURL(stream.StreamKey),
+		"hls_url":             h.buildMinIOHLSURL(stream.StorageKey()),
+		"dash_url":            h.buildMinIODASHURL(stream.StorageKey()),
 		"viewer_count":        stream.ViewerCount,
 		"started_at":          stream.StartedAt,
 		"thumbnail_url":       stream.ThumbnailURL,
 		"available_qualities": stream.AvailableQualities,
+		"low_latency":         stream.LowLatency,
+		"dvr_window_seconds":  stream.DVRWindowSeconds,
+		"dvr_url":             h.buildDVRURL(stream.ID),
 		"is_live":             true,
-	})
+	}
+
+	// LL-HLS раздаётся stream-service напрямую (blocking reload), hls_url остаётся для обычных плееров
+	if stream.LowLatency {
+		response["ll_hls_url"] = h.buildLLHLSURL(stream.ID)
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetStream retrieves stream by ID
@@ -154,7 +199,7 @@ func (h *StreamHandler) DeleteStream(c *gin.Context) {
 		return
 	}
 
-	// ✅ Получаем stream перед удалением чтобы узнать stream_key
+	// ✅ Получаем stream перед удалением чтобы проверить владельца
 	stream, err := h.streamRepo.GetStreamByID(streamID)
 	if err != nil {
 		log.Printf("❌ Failed to get stream: %v", err)
@@ -168,7 +213,7 @@ func (h *StreamHandler) DeleteStream(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🗑️  Deleting stream %s (key: %s) for user %s", streamID, stream.StreamKey, userID)
+	log.Printf("🗑️  Deleting stream %s for user %s", streamID, userID)
 
 	// ✅ Удаляем из БД
 	err = h.streamRepo.DeleteStream(streamID, userID)
@@ -181,12 +226,13 @@ func (h *StreamHandler) DeleteStream(c *gin.Context) {
 	// ✅ Удаляем HLS файлы из MinIO (в фоне, не блокируем ответ)
 	go func() {
 		ctx := context.Background()
-		hlsFolder := fmt.Sprintf("live-segments/%s/", stream.StreamKey)
+		hlsFolder := fmt.Sprintf("live-segments/%s/", stream.StorageKey())
 
-		if err := h.minioStorage.DeleteFolder(ctx, hlsFolder); err != nil {
-			log.Printf("❌ Failed to delete HLS files for stream %s: %v", stream.StreamKey, err)
+		deleted, err := h.storage.DeletePrefix(ctx, hlsFolder)
+		if err != nil {
+			log.Printf("❌ Failed to delete HLS files for stream %s: %v", stream.ID, err)
 		} else {
-			log.Printf("✅ Deleted HLS files for stream %s", stream.StreamKey)
+			log.Printf("✅ Deleted %d HLS files for stream %s", deleted, stream.ID)
 		}
 	}()
 
@@ -194,9 +240,15 @@ func (h *StreamHandler) DeleteStream(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Stream deleted successfully"})
 }
 
-// GetLiveStreams returns all live streams
+// GetLiveStreams returns all live streams, most watched first (?sort=recent - newest first)
 func (h *StreamHandler) GetLiveStreams(c *gin.Context) {
-	streams, err := h.streamRepo.GetLiveStreams()
+	order := c.DefaultQuery("sort", repository.LiveOrderPopular)
+	if order != repository.LiveOrderPopular && order != repository.LiveOrderRecent {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "sort must be 'popular' or 'recent'"})
+		return
+	}
+
+	streams, err := h.streamRepo.GetLiveStreams(order)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -229,8 +281,12 @@ func (h *StreamHandler) UpdateStream(c *gin.Context) {
 	}
 
 	var req struct {
-		Title       string `json:"title" binding:"required"`
-		Description string `json:"description"`
+		Title       string   `json:"title" binding:"required"`
+		Description string   `json:"description"`
+		ABRPreset   string   `json:"abr_preset"`
+		Qualities   []string `json:"qualities"`
+		LowLatency  *bool    `json:"low_latency"`
+		DVRWindow   *int     `json:"dvr_window_seconds"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -241,35 +297,53 @@ func (h *StreamHandler) UpdateStream(c *gin.Context) {
 
 	log.Printf("📝 Updating stream %s: title=%s, description=%s", streamID, req.Title, req.Description)
 
-	stream.Title = req.Title
-	stream.Description = req.Description
+	update := repository.StreamUpdate{
+		Title:            req.Title,
+		Description:      req.Description,
+		LowLatency:       req.LowLatency,
+		DVRWindowSeconds: req.DVRWindow,
+	}
 
-	if err := h.streamRepo.UpdateStream(stream); err != nil {
-		log.Printf("❌ Failed to update stream in DB: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update stream"})
-		return
+	// Новый набор качеств применяется со следующего эфира
+	if req.ABRPreset != "" || len(req.Qualities) > 0 {
+		abrPreset, abrLadder, err := transcoder.ResolveLadder(req.ABRPreset, req.Qualities)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		update.ABRPreset = &abrPreset
+		update.ABRLadder = abrLadder
 	}
 
-	log.Printf("✅ Stream %s updated successfully", streamID)
-	c.JSON(http.StatusOK, gin.H{"stream": stream})
-}
+	// DVR окно тоже применяется со следующего эфира
+	if req.DVRWindow != nil {
+		if err := dvr.ValidateWindow(*req.DVRWindow); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
 
-// GetStreamByKey retrieves stream by stream_key
-func (h *StreamHandler) GetStreamByKey(c *gin.Context) {
-	streamKey := c.Param("key")
-	if streamKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Stream key is required"})
+	if err := h.streamRepo.UpdateStream(stream.ID, update); err != nil {
+		log.Printf("❌ Failed to update stream in DB: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update stream"})
 		return
 	}
 
-	stream, err := h.streamRepo.GetStreamByKey(streamKey)
-	if err != nil {
-		log.Printf("❌ Stream not found by key: %s", streamKey)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
-		return
+	stream.Title = update.Title
+	stream.Description = update.Description
+	if update.ABRPreset != nil {
+		stream.ABRPreset = *update.ABRPreset
+		stream.ABRLadder = update.ABRLadder
+	}
+	if update.LowLatency != nil {
+		stream.LowLatency = *update.LowLatency
+	}
+	if update.DVRWindowSeconds != nil {
+		stream.DVRWindowSeconds = *update.DVRWindowSeconds
 	}
 
-	c.JSON(http.StatusOK, stream)
+	log.Printf("✅ Stream %s updated successfully", streamID)
+	c.JSON(http.StatusOK, gin.H{"stream": stream})
 }
 
 // Health check
@@ -297,12 +371,12 @@ func (h *StreamHandler) GetStreamThumbnail(c *gin.Context) {
 		return
 	}
 
-	objectName := filepath.Join("live-segments", stream.StreamKey, "thumbnail.jpg")
+	objectName := path.Join("live-segments", stream.StorageKey(), "thumbnail.jpg")
 
-	log.Printf("✅ Streaming thumbnail from MinIO: %s", objectName)
+	log.Printf("✅ Streaming thumbnail from storage: %s", objectName)
 
 	ctx := c.Request.Context()
-	object, err := h.minioStorage.GetObject(ctx, objectName)
+	object, err := h.storage.Get(ctx, objectName)
 	if err != nil {
 		log.Printf("❌ Failed to get thumbnail: %v", err)
 		c.Status(http.StatusNotFound)
@@ -312,8 +386,8 @@ func (h *StreamHandler) GetStreamThumbnail(c *gin.Context) {
 
 	// ✅ Короткий cache для live thumbnails (30 секунд)
 	c.Header("Content-Type", "image/jpeg")
-	c.Header("Cache-Control", "public, max-age=30")                                    // Обновляется каждые 30 секунд
-	c.Header("ETag", fmt.Sprintf("\"%s-%d\"", stream.StreamKey, time.Now().Unix()/30)) // ETag меняется каждые 30 секунд
+	c.Header("Cache-Control", "public, max-age=30")                             // Обновляется каждые 30 секунд
+	c.Header("ETag", fmt.Sprintf("\"%s-%d\"", stream.ID, time.Now().Unix()/30)) // ETag меняется каждые 30 секунд
 	c.Status(http.StatusOK)
 
 	_, err = io.Copy(c.Writer, object)
@@ -327,13 +401,37 @@ func (h *StreamHandler) buildSRTURL(streamKey string) string {
 	return "srt://" + h.srtServerAddr + "?streamid=" + streamKey
 }
 
-func (h *StreamHandler) buildMinIOHLSURL(streamKey string) string {
+func (h *StreamHandler) buildRTMPURL(streamKey string) string {
+	return "rtmp://" + h.rtmpServerAddr + "/" + h.rtmpApp + "/" + streamKey
+}
+
+// buildWHIPURL - endpoint для публикации из браузера (через API Gateway)
+func (h *StreamHandler) buildWHIPURL(streamID uuid.UUID) string {
+	return fmt.Sprintf("%s/api/streams/%s/whip", h.publicBaseURL, streamID)
+}
+
+func (h *StreamHandler) buildLLHLSURL(streamID uuid.UUID) string {
+	return fmt.Sprintf("%s/api/streams/%s/llhls/master.m3u8", h.publicBaseURL, streamID)
+}
+
+// buildDVRURL - master плейлист с началом в произвольный момент эфира (?start=)
+func (h *StreamHandler) buildDVRURL(streamID uuid.UUID) string {
+	return fmt.Sprintf("%s/api/streams/%s/dvr", h.publicBaseURL, streamID)
+}
+
+func (h *StreamHandler) buildMinIOHLSURL(storageKey string) string {
 	// ✅ Возвращаем master.m3u8 для ABR
 	return fmt.Sprintf("%s/live-streams/live-segments/%s/master.m3u8",
-		h.publicBaseURL, streamKey)
+		h.publicBaseURL, storageKey)
+}
+
+// buildMinIODASHURL - manifest.mpd ссылается на те же CMAF сегменты, что и master.m3u8
+func (h *StreamHandler) buildMinIODASHURL(storageKey string) string {
+	return fmt.Sprintf("%s/live-streams/live-segments/%s/manifest.mpd",
+		h.publicBaseURL, storageKey)
 }
 
-// В stream_handler.go добавить:
+// GetStreamQualities returns qualities produced by the transcoder and the configured ladder
 func (h *StreamHandler) GetStreamQualities(c *gin.Context) {
 	streamID := c.Param("id")
 
@@ -352,6 +450,16 @@ func (h *StreamHandler) GetStreamQualities(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"stream_id":           stream.ID,
 		"available_qualities": stream.AvailableQualities,
+		"abr_preset":          stream.ABRPreset,
+		"abr_ladder":          stream.ABRLadder,
 		"status":              stream.Status,
 	})
 }
+
+// GetABRPresets returns named quality presets available for streams
+func (h *StreamHandler) GetABRPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"presets":        transcoder.ABRPresets,
+		"default_preset": transcoder.DefaultABRPreset,
+	})
+}
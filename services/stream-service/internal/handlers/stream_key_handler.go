@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/ingest"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// StreamKeyHandler - управление ключами публикации стрима (только владелец)
+type StreamKeyHandler struct {
+	streamRepo *repository.StreamRepository
+	keyRepo    *repository.StreamKeyRepository
+	publisher  *ingest.Publisher
+}
+
+func NewStreamKeyHandler(streamRepo *repository.StreamRepository, keyRepo *repository.StreamKeyRepository, publisher *ingest.Publisher) *StreamKeyHandler {
+	return &StreamKeyHandler{
+		streamRepo: streamRepo,
+		keyRepo:    keyRepo,
+		publisher:  publisher,
+	}
+}
+
+// ListKeys returns active and revoked keys of the stream
+func (h *StreamKeyHandler) ListKeys(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.keyRepo.ListKeys(stream.ID)
+	if err != nil {
+		log.Printf("❌ Failed to list keys of stream %s: %v", stream.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to list stream keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// CreateKey adds a named key, e.g. for a backup encoder
+func (h *StreamKeyHandler) CreateKey(c *gin.Context) {
+	stream, ok := h.ownedStream(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateStreamKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name is required (1-50 characters)"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name is required (1-50 characters)"})
+		return
+	}
+
+	secret, err := utils.GenerateStreamKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate stream key"})
+		return
+	}
+
+	key, err := h.keyRepo.CreateKey(stream.ID, name, secret)
+	if err != nil {
+		h.keyError(c, stream.ID, err)
+		return
+	}
+
+	log.Printf("🔑 Stream key %s (%s) created for stream %s", key.ID, key.Name, stream.ID)
+	c.JSON(http.StatusCreated, gin.H{"key": key})
+}
+
+// RotateKey replaces the secret of a key. A publisher using the old secret is disconnected
+func (h *StreamKeyHandler) RotateKey(c *gin.Context) {
+	stream, keyID, ok := h.ownedStreamKey(c)
+	if !ok {
+		return
+	}
+
+	secret, err := utils.GenerateStreamKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate stream key"})
+		return
+	}
+
+	key, err := h.keyRepo.RotateKey(stream.ID, keyID, secret)
+	if err != nil {
+		h.keyError(c, stream.ID, err)
+		return
+	}
+
+	disconnected := h.publisher.DisconnectKey(stream.ID, keyID)
+	log.Printf("🔑 Stream key %s of stream %s rotated (publisher disconnected: %v)", keyID, stream.ID, disconnected)
+
+	c.JSON(http.StatusOK, gin.H{"key": key, "disconnected": disconnected})
+}
+
+// RevokeKey permanently disables a key. A publisher using it is disconnected
+func (h *StreamKeyHandler) RevokeKey(c *gin.Context) {
+	stream, keyID, ok := h.ownedStreamKey(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.keyRepo.RevokeKey(stream.ID, keyID)
+	if err != nil {
+		h.keyError(c, stream.ID, err)
+		return
+	}
+
+	disconnected := h.publisher.DisconnectKey(stream.ID, keyID)
+	log.Printf("🔑 Stream key %s of stream %s revoked (publisher disconnected: %v)", keyID, stream.ID, disconnected)
+
+	c.JSON(http.StatusOK, gin.H{"key": key, "disconnected": disconnected})
+}
+
+// ownedStream загружает стрим из :id и проверяет, что текущий пользователь - владелец
+func (h *StreamKeyHandler) ownedStream(c *gin.Context) (*models.Stream, bool) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return nil, false
+	}
+
+	if stream.UserID != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not authorized to manage this stream's keys"})
+		return nil, false
+	}
+
+	return stream, true
+}
+
+func (h *StreamKeyHandler) ownedStreamKey(c *gin.Context) (*models.Stream, uuid.UUID, bool) {
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid key ID"})
+		return nil, uuid.Nil, false
+	}
+
+	stream, ok := h.ownedStream(c)
+	return stream, keyID, ok
+}
+
+func (h *StreamKeyHandler) keyError(c *gin.Context, streamID uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, repository.ErrStreamKeyNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream key not found or already revoked"})
+	case errors.Is(err, repository.ErrStreamKeyNameTaken):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, repository.ErrTooManyStreamKeys):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("❌ Stream key operation failed for stream %s: %v", streamID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update stream keys"})
+	}
+}
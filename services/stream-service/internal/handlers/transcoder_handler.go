@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// TranscoderHandler - состояние транскодера для операторов (admin, INTERNAL_API_KEY)
+type TranscoderHandler struct {
+	scheduler *transcoder.Scheduler
+	registry  *worker.Registry
+}
+
+func NewTranscoderHandler(scheduler *transcoder.Scheduler, registry *worker.Registry) *TranscoderHandler {
+	return &TranscoderHandler{scheduler: scheduler, registry: registry}
+}
+
+// GetUsage returns CPU slot capacity, usage and the broadcasts holding slots
+func (h *TranscoderHandler) GetUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scheduler.Usage())
+}
+
+// GetWorkers returns live transcode workers with their free slots (STREAM_ROLE=ingest)
+func (h *TranscoderHandler) GetWorkers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"workers": h.registry.Workers()})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/viewers"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// broadcastHistoryLimit - сколько последних эфиров отдаёт GetBroadcasts
+const broadcastHistoryLimit = 50
+
+// ViewerHandler принимает heartbeat'ы плееров и отдаёт статистику эфиров
+type ViewerHandler struct {
+	streamRepo *repository.StreamRepository
+	tracker    *viewers.Tracker
+}
+
+func NewViewerHandler(streamRepo *repository.StreamRepository, tracker *viewers.Tracker) *ViewerHandler {
+	return &ViewerHandler{
+		streamRepo: streamRepo,
+		tracker:    tracker,
+	}
+}
+
+// HeartbeatRequest - плеер присылает раз в несколько секунд, пока стрим открыт.
+// leaving=true при закрытии страницы
+type HeartbeatRequest struct {
+	SessionID string `json:"session_id" binding:"required,uuid"`
+	Leaving   bool   `json:"leaving"`
+}
+
+// Heartbeat отмечает зрителя и возвращает текущее число зрителей стрима
+func (h *ViewerHandler) Heartbeat(c *gin.Context) {
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "session_id (UUID) is required"})
+		return
+	}
+
+	client := viewerClient(c)
+
+	if req.Leaving {
+		c.JSON(http.StatusOK, gin.H{"viewer_count": h.tracker.Leave(streamID, req.SessionID, client)})
+		return
+	}
+
+	count, live := h.tracker.Heartbeat(streamID, req.SessionID, client)
+	if !live {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream is not currently live"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"viewer_count": count})
+}
+
+// viewerClient - кто прислал heartbeat: пользователь по JWT (X-User-ID от API Gateway)
+// или IP зрителя. X-Real-IP выставляет API Gateway, клиентский X-Forwarded-For не используется
+func viewerClient(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return "user:" + userID.(uuid.UUID).String()
+	}
+	if ip := c.GetHeader("X-Real-IP"); ip != "" {
+		return "ip:" + ip
+	}
+	return "ip:" + c.RemoteIP()
+}
+
+// GetBroadcasts returns recent broadcasts of the stream with peak and average viewers (owner only)
+func (h *ViewerHandler) GetBroadcasts(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	streamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return
+	}
+
+	if stream.UserID != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not authorized to view this stream's statistics"})
+		return
+	}
+
+	broadcasts, err := h.streamRepo.GetStreamBroadcasts(streamID, broadcastHistoryLimit)
+	if err != nil {
+		log.Printf("❌ Failed to get broadcasts for stream %s: %v", streamID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to get broadcasts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"broadcasts": broadcasts})
+}
@@ -0,0 +1,139 @@
+// Package health - телеметрия эфира: статистика канала ingest (SRT) и прогресс ffmpeg.
+// Замеры снимаются периодически, последний держится в памяти, все пишутся в БД по эфирам
+package health
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// SampleInterval - период замеров телеметрии
+const SampleInterval = 5 * time.Second
+
+// LinkStatsSource - соединение ingest, умеющее отдавать статистику канала.
+// LinkStats возвращает значения за интервал с предыдущего вызова
+type LinkStatsSource interface {
+	LinkStats() models.LinkStats
+}
+
+// Monitor собирает телеметрию активных публикаций
+type Monitor struct {
+	healthRepo *repository.HealthRepository
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*Session // stream ID → публикация
+}
+
+func NewMonitor(healthRepo *repository.HealthRepository) *Monitor {
+	return &Monitor{
+		healthRepo: healthRepo,
+		sessions:   make(map[uuid.UUID]*Session),
+	}
+}
+
+// Session - телеметрия одной публикации
+type Session struct {
+	monitor  *Monitor
+	streamID uuid.UUID
+	link     LinkStatsSource // nil - протокол без статистики канала
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	encoder *models.EncoderStats
+	latest  *models.HealthSample
+}
+
+// Begin начинает замеры публикации. link может быть nil (RTMP, WHIP)
+func (m *Monitor) Begin(streamID uuid.UUID, link LinkStatsSource) *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &Session{
+		monitor:  m,
+		streamID: streamID,
+		link:     link,
+		cancel:   cancel,
+	}
+
+	m.mu.Lock()
+	m.sessions[streamID] = session
+	m.mu.Unlock()
+
+	go session.run(ctx)
+	return session
+}
+
+// Current возвращает последний замер идущей публикации
+func (m *Monitor) Current(streamID uuid.UUID) (*models.HealthSample, bool) {
+	m.mu.Lock()
+	session, ok := m.sessions[streamID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.latest, session.latest != nil
+}
+
+// ReportProgress запоминает последний прогресс ffmpeg до следующего замера
+func (s *Session) ReportProgress(stats models.EncoderStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder = &stats
+}
+
+// End останавливает замеры публикации
+func (s *Session) End() {
+	s.cancel()
+
+	s.monitor.mu.Lock()
+	defer s.monitor.mu.Unlock()
+	if s.monitor.sessions[s.streamID] == s {
+		delete(s.monitor.sessions, s.streamID)
+	}
+}
+
+func (s *Session) run(ctx context.Context) {
+	ticker := time.NewTicker(SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *Session) sample() {
+	sample := &models.HealthSample{SampledAt: time.Now()}
+	if s.link != nil {
+		link := s.link.LinkStats()
+		link.UpdateLossPercent()
+		sample.Link = &link
+	}
+
+	s.mu.Lock()
+	if s.encoder != nil {
+		encoder := *s.encoder
+		sample.Encoder = &encoder
+	}
+	s.latest = sample
+	s.mu.Unlock()
+
+	if sample.Link == nil && sample.Encoder == nil {
+		return
+	}
+
+	if err := s.monitor.healthRepo.InsertSample(s.streamID, sample); err != nil {
+		log.Printf("⚠️ Failed to store health sample for stream %s: %v", s.streamID, err)
+	}
+}
@@ -0,0 +1,357 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/health"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/restream"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/viewers"
+	"github.com/google/uuid"
+)
+
+// ErrAlreadyPublishing возвращается если для стрима уже есть активный издатель
+var ErrAlreadyPublishing = fmt.Errorf("stream is already being published")
+
+// Transcoder - вывод эфира: локальный ffmpeg (transcoder.FFmpegTranscoder)
+// или transcode workers (worker.Dispatcher)
+type Transcoder interface {
+	TranscodeToHLS(ctx context.Context, input io.Reader, stream *models.Stream, broadcast *transcoder.Broadcast, hooks transcoder.Hooks) error
+	FinishBroadcast(broadcast *transcoder.Broadcast)
+	CanAdmit(streamID uuid.UUID) bool
+}
+
+// Publisher - общий жизненный цикл публикации для всех протоколов ingest (SRT, RTMP, ...):
+// статус стрима, события для recording-service, ABR транскодирование и рестрим на внешние площадки.
+// Эфир переживает обрыв связи: после отключения издателя он ждёт переподключения
+// с тем же ключом reconnectGrace и только потом завершается
+type Publisher struct {
+	streamRepo     *repository.StreamRepository
+	transcoder     Transcoder
+	relay          *outbox.Relay
+	viewers        *viewers.Tracker
+	health         *health.Monitor
+	restream       *restream.Manager
+	reconnectGrace time.Duration
+	active         map[uuid.UUID]*publication  // stream ID → активный издатель
+	interrupted    map[uuid.UUID]*interruption // stream ID → эфир, ждущий переподключения
+	mu             sync.Mutex
+}
+
+// publication - активная публикация стрима
+type publication struct {
+	protocol string
+	keyID    uuid.UUID // uuid.Nil - издатель авторизован JWT владельца (WHIP)
+	cancel   context.CancelFunc
+	finished bool // издатель сам завершил публикацию: переподключения не ждём
+}
+
+// interruption - эфир, издатель которого отключился. Стрим остаётся live,
+// пока издатель не переподключится с тем же ключом или не истечёт timer
+type interruption struct {
+	stream      *models.Stream
+	keyID       uuid.UUID
+	broadcast   *transcoder.Broadcast
+	finalStatus string // статус, если издатель не вернётся
+	timer       *time.Timer
+}
+
+func NewPublisher(streamRepo *repository.StreamRepository, transcoder Transcoder, relay *outbox.Relay, viewers *viewers.Tracker, health *health.Monitor, restream *restream.Manager, reconnectGrace time.Duration) *Publisher {
+	return &Publisher{
+		streamRepo:     streamRepo,
+		transcoder:     transcoder,
+		relay:          relay,
+		viewers:        viewers,
+		health:         health,
+		restream:       restream,
+		reconnectGrace: reconnectGrace,
+		active:         make(map[uuid.UUID]*publication),
+		interrupted:    make(map[uuid.UUID]*interruption),
+	}
+}
+
+// StreamEventPayload - событие stream.started / stream.stopped для recording-service.
+// Ключ публикации в события не попадает: сегменты лежат под storage_key
+type StreamEventPayload struct {
+	StreamID   uuid.UUID `json:"stream_id"`
+	StorageKey string    `json:"storage_key"`
+	Event      string    `json:"event"` // "started" or "stopped"
+	HLSURL     string    `json:"hls_url"`
+	Timestamp  int64     `json:"timestamp"`
+}
+
+// IsPublishing проверяет есть ли у стрима активный издатель.
+// Эфир, ждущий переподключения, активного издателя не имеет
+func (p *Publisher) IsPublishing(streamID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, exists := p.active[streamID]
+	return exists
+}
+
+// DisconnectKey прерывает публикацию стрима, если издатель авторизован ключом keyID.
+// Вызывается при ротации и отзыве ключа, чтобы утёкший ключ не продолжал эфир.
+// Эфир этого ключа, ждущий переподключения, завершается сразу
+func (p *Publisher) DisconnectKey(streamID, keyID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pending, exists := p.interrupted[streamID]; exists && pending.keyID == keyID {
+		log.Printf("🔑 Ending interrupted broadcast of stream %s: stream key %s is no longer valid", streamID, keyID)
+		pending.timer.Stop()
+		delete(p.interrupted, streamID)
+		go p.endBroadcast(pending.stream, pending.broadcast, pending.finalStatus)
+		return true
+	}
+
+	current, exists := p.active[streamID]
+	if !exists || current.keyID != keyID {
+		return false
+	}
+
+	log.Printf("🔑 Disconnecting %s publisher of stream %s: stream key %s is no longer valid", current.protocol, streamID, keyID)
+	current.cancel()
+	return true
+}
+
+// FinishPublishing отмечает, что издатель штатно завершил публикацию (RTMP deleteStream,
+// WHIP DELETE): после отключения эфир завершится сразу, без ожидания переподключения
+func (p *Publisher) FinishPublishing(streamID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if current, exists := p.active[streamID]; exists {
+		current.finished = true
+	}
+}
+
+// HasCapacity проверяет, хватит ли транскодеру слотов на публикацию стрима.
+// Продолжение прерванного эфира всегда помещается: его слоты не освобождались
+func (p *Publisher) HasCapacity(streamID uuid.UUID) bool {
+	return p.transcoder.CanAdmit(streamID)
+}
+
+// Publish запускает транскодирование входящего потока и блокируется до его завершения.
+// Если input реализует health.LinkStatsSource (SRT), в телеметрию попадает статистика канала
+func (p *Publisher) Publish(stream *models.Stream, input io.Reader, protocol string) error {
+	// Start FFmpeg transcoding
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p.mu.Lock()
+	if current, exists := p.active[stream.ID]; exists {
+		p.mu.Unlock()
+		log.Printf("⚠️ Stream %s is already published via %s, rejecting %s", stream.ID, current.protocol, protocol)
+		return ErrAlreadyPublishing
+	}
+	current := &publication{protocol: protocol, keyID: stream.IngestKeyID, cancel: cancel}
+	p.active[stream.ID] = current
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.active, stream.ID)
+		p.mu.Unlock()
+	}()
+
+	storageKey := stream.StorageKey()
+	log.Printf("✅ %s publish accepted for stream %s", protocol, storageKey)
+
+	if stream.IngestKeyID != uuid.Nil {
+		if err := p.streamRepo.MarkStreamKeyUsed(stream.IngestKeyID); err != nil {
+			log.Printf("⚠️ Failed to update stream key usage: %v", err)
+		}
+	}
+
+	// Переподключение с тем же ключом продолжает прерванный эфир: стрим всё ещё live,
+	// событие started уже отправлено
+	broadcast := p.resumeBroadcast(stream)
+	resumed := broadcast != nil
+
+	wentLive := resumed
+	var onStarted func()
+	if resumed {
+		log.Printf("🔁 %s publisher of stream %s reconnected, resuming broadcast", protocol, storageKey)
+	} else {
+		broadcast = transcoder.NewBroadcast()
+
+		// scheduled/offline/error → starting: издатель подключён, источник ещё не распознан
+		if err := p.streamRepo.UpdateStreamStatus(stream.ID, models.StatusStarting); err != nil {
+			return fmt.Errorf("failed to update stream status: %w", err)
+		}
+
+		// starting → live + событие started в той же транзакции, как только ffmpeg запущен
+		onStarted = func() {
+			started, err := newStreamEvent(stream.ID, storageKey, "started", hlsURL(storageKey))
+			if err == nil {
+				err = p.streamRepo.UpdateStreamStatusWithEvents(stream.ID, models.StatusLive, started)
+			}
+			if err != nil {
+				log.Printf("❌ Failed to update stream status, aborting publish: %v", err)
+				cancel()
+				return
+			}
+			wentLive = true
+			p.relay.Notify()
+			p.viewers.StartBroadcast(stream.ID)
+		}
+	}
+
+	// Телеметрия: канал ingest (если протокол умеет) и прогресс ffmpeg
+	link, _ := input.(health.LinkStatsSource)
+	telemetry := p.health.Begin(stream.ID, link)
+
+	// Рестрим получает копию входящего потока параллельно с транскодированием
+	restreaming := p.restream.Begin(stream.ID, protocol)
+	input = io.TeeReader(input, restreaming)
+
+	log.Printf("🎬 Starting transcoding for stream %s", storageKey)
+	transcodeErr := p.transcoder.TranscodeToHLS(ctx, input, stream, broadcast, transcoder.Hooks{
+		OnStarted:  onStarted,
+		OnProgress: telemetry.ReportProgress,
+	})
+	telemetry.End()
+	restreaming.End()
+	if transcodeErr != nil {
+		log.Printf("❌ Transcoding failed for stream %s: %v", storageKey, transcodeErr)
+	}
+
+	// Отключение издателем или ключом (ctx отменён) - штатное завершение
+	finalStatus := models.StatusOffline
+	if transcodeErr != nil && ctx.Err() == nil {
+		finalStatus = models.StatusError
+	}
+
+	if !wentLive {
+		// Эфир так и не начался: событий для recording-service нет
+		p.transcoder.FinishBroadcast(broadcast)
+		if err := p.streamRepo.UpdateStreamStatus(stream.ID, models.StatusError); err != nil {
+			log.Printf("❌ Failed to update stream status: %v", err)
+		}
+		log.Printf("⏹️  Stream %s failed to start", storageKey)
+		return nil
+	}
+
+	// Обрыв связи (ctx не отменён ключом): эфир ждёт переподключения издателя
+	p.mu.Lock()
+	finished := current.finished
+	p.mu.Unlock()
+	if ctx.Err() == nil && !finished && p.reconnectGrace > 0 {
+		p.interruptBroadcast(stream, broadcast, finalStatus)
+		return nil
+	}
+
+	p.endBroadcast(stream, broadcast, finalStatus)
+	return nil
+}
+
+// resumeBroadcast забирает эфир, ждущий переподключения. Эфир другого ключа
+// (или без ключа) не продолжается: он завершается, и публикация начинает новый
+func (p *Publisher) resumeBroadcast(stream *models.Stream) *transcoder.Broadcast {
+	p.mu.Lock()
+	pending, exists := p.interrupted[stream.ID]
+	if exists {
+		pending.timer.Stop()
+		delete(p.interrupted, stream.ID)
+	}
+	p.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+	if pending.keyID == stream.IngestKeyID {
+		return pending.broadcast
+	}
+
+	log.Printf("🔑 Stream %s reconnected with another key, ending interrupted broadcast", stream.ID)
+	p.endBroadcast(pending.stream, pending.broadcast, pending.finalStatus)
+	return nil
+}
+
+// interruptBroadcast оставляет эфир live на reconnectGrace. Если издатель не вернётся,
+// эфир завершается: stopped уходит в recording-service один раз за весь эфир
+func (p *Publisher) interruptBroadcast(stream *models.Stream, broadcast *transcoder.Broadcast, finalStatus string) {
+	pending := &interruption{
+		stream:      stream,
+		keyID:       stream.IngestKeyID,
+		broadcast:   broadcast,
+		finalStatus: finalStatus,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending.timer = time.AfterFunc(p.reconnectGrace, func() {
+		p.mu.Lock()
+		if p.interrupted[stream.ID] != pending {
+			// Издатель успел переподключиться
+			p.mu.Unlock()
+			return
+		}
+		delete(p.interrupted, stream.ID)
+		p.mu.Unlock()
+
+		log.Printf("⌛ Publisher of stream %s did not reconnect within %v", stream.StorageKey(), p.reconnectGrace)
+		p.endBroadcast(stream, broadcast, finalStatus)
+	})
+	p.interrupted[stream.ID] = pending
+
+	log.Printf("⏸️  Publisher of stream %s disconnected, waiting %v for reconnect", stream.StorageKey(), p.reconnectGrace)
+}
+
+// endBroadcast завершает эфир: live → stopping → offline/error + событие stopped
+func (p *Publisher) endBroadcast(stream *models.Stream, broadcast *transcoder.Broadcast, finalStatus string) {
+	storageKey := stream.StorageKey()
+
+	p.viewers.EndBroadcast(stream.ID)
+	if err := p.streamRepo.UpdateStreamStatus(stream.ID, models.StatusStopping); err != nil {
+		log.Printf("❌ Failed to update stream status: %v", err)
+	}
+
+	// Плейлисты закрываются EXT-X-ENDLIST до события stopped
+	p.transcoder.FinishBroadcast(broadcast)
+
+	// ADDED: Update thumbnail URL in database
+	thumbnailURL := fmt.Sprintf("http://localhost:9000/live-streams/live-segments/%s/thumbnail.jpg", storageKey)
+	if err := p.streamRepo.UpdateStreamThumbnail(stream.ID, thumbnailURL); err != nil {
+		log.Printf("⚠️  Failed to update thumbnail URL: %v", err)
+	} else {
+		log.Printf("✅ Updated thumbnail URL for stream %s", storageKey)
+	}
+
+	// stopping → offline/error + событие stopped в той же транзакции
+	stopped, err := newStreamEvent(stream.ID, storageKey, "stopped", hlsURL(storageKey))
+	if err == nil {
+		err = p.streamRepo.UpdateStreamStatusWithEvents(stream.ID, finalStatus, stopped)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to update stream status: %v", err)
+	}
+	p.relay.Notify()
+
+	log.Printf("⏹️  Stream ended: %s (%s)", storageKey, finalStatus)
+}
+
+func hlsURL(storageKey string) string {
+	return fmt.Sprintf("http://localhost/live-streams/live-segments/%s/playlist.m3u8", storageKey)
+}
+
+// newStreamEvent создаёт событие жизненного цикла стрима для recording-service.
+// События одного стрима доставляются по порядку: stopped не обгонит started
+func newStreamEvent(streamID uuid.UUID, storageKey, event, hlsURL string) (outbox.Event, error) {
+	payload := StreamEventPayload{
+		StreamID:   streamID,
+		StorageKey: storageKey,
+		Event:      event,
+		HLSURL:     hlsURL,
+		Timestamp:  time.Now().Unix(),
+	}
+	return outbox.NewEvent("stream."+event, streamID.String(), "recording-service", "/webhook/stream", payload)
+}
@@ -0,0 +1,177 @@
+package llhls
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	playlistContentType = "application/vnd.apple.mpegurl"
+	mediaContentType    = "video/mp4"
+)
+
+// Handler раздаёт LL-HLS плейлисты и части из памяти (blocking playlist reload)
+type Handler struct {
+	registry *Registry
+}
+
+func NewHandler(registry *Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+// GetMaster returns master playlist of a low-latency stream
+func (h *Handler) GetMaster(c *gin.Context) {
+	stream, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, playlistContentType, []byte(stream.MasterPlaylist()))
+}
+
+// GetRenditionFile returns playlist.m3u8, init segments, parts and segments of one quality
+func (h *Handler) GetRenditionFile(c *gin.Context) {
+	stream, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	rendition, ok := stream.Rendition(c.Param("quality"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quality not found"})
+		return
+	}
+	playlist := rendition.Playlist
+
+	file := c.Param("file")
+	switch {
+	case file == "playlist.m3u8":
+		h.servePlaylist(c, playlist)
+
+	case strings.HasPrefix(file, "init"):
+		// init.mp4 - с начала эфира, init_<msn>.mp4 - после переподключения издателя
+		var msn int64
+		if file != "init.mp4" {
+			var err error
+			if msn, err = parseMediaNumber(file, "init_"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid init segment name"})
+				return
+			}
+		}
+		initData, ok := playlist.InitAt(msn)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Init segment not ready"})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.Data(http.StatusOK, mediaContentType, initData)
+
+	case strings.HasPrefix(file, "part_"):
+		seq, err := parseMediaNumber(file, "part_")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid part name"})
+			return
+		}
+
+		// Запрос по EXT-X-PRELOAD-HINT держим до появления части
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 3*playlist.TargetDuration())
+		defer cancel()
+
+		part, ok := playlist.WaitPart(ctx, seq)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Part not found"})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=60")
+		c.Data(http.StatusOK, mediaContentType, part.Data)
+
+	case strings.HasPrefix(file, "segment_"):
+		msn, err := parseMediaNumber(file, "segment_")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid segment name"})
+			return
+		}
+		data, ok := playlist.GetSegment(msn)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=60")
+		c.Data(http.StatusOK, mediaContentType, data)
+
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	}
+}
+
+// servePlaylist поддерживает _HLS_msn / _HLS_part (blocking playlist reload)
+func (h *Handler) servePlaylist(c *gin.Context, playlist *Playlist) {
+	msnParam := c.Query("_HLS_msn")
+	if msnParam == "" {
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, playlistContentType, []byte(playlist.Render()))
+		return
+	}
+
+	msn, err := strconv.ParseInt(msnParam, 10, 64)
+	if err != nil || msn < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid _HLS_msn"})
+		return
+	}
+
+	part := int64(-1)
+	if partParam := c.Query("_HLS_part"); partParam != "" {
+		part, err = strconv.ParseInt(partParam, 10, 64)
+		if err != nil || part < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid _HLS_part"})
+			return
+		}
+	}
+
+	// Слишком далёкое будущее - ошибка клиента (RFC 8216bis, 6.2.5.2)
+	if msn > playlist.LastMSN()+2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "_HLS_msn is too far in the future"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*playlist.TargetDuration())
+	defer cancel()
+
+	if !playlist.Wait(ctx, msn, part) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Playlist update timed out"})
+		return
+	}
+
+	// Ответ на blocking запрос не меняется - его можно кэшировать
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(6*playlist.TargetDuration()/time.Second)))
+	c.Data(http.StatusOK, playlistContentType, []byte(playlist.Render()))
+}
+
+func (h *Handler) lookup(c *gin.Context) (*Stream, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stream ID"})
+		return nil, false
+	}
+
+	stream, ok := h.registry.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Low-latency stream is not live"})
+		return nil, false
+	}
+	return stream, true
+}
+
+// parseMediaNumber извлекает номер из part_12.m4s / segment_00003.m4s / init_40.mp4
+func parseMediaNumber(file, prefix string) (int64, error) {
+	number := strings.TrimSuffix(strings.TrimPrefix(file, prefix), path.Ext(file))
+	return strconv.ParseInt(number, 10, 64)
+}
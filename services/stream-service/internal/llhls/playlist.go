@@ -0,0 +1,414 @@
+package llhls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Part - часть (partial segment) LL-HLS: один fMP4 фрагмент от ffmpeg
+type Part struct {
+	Seq         int64 // сквозной номер части в рендишене (используется в URI)
+	Duration    float64
+	Independent bool
+	Data        []byte
+}
+
+// Segment - полный сегмент, склеенный из частей
+type Segment struct {
+	MSN             int64
+	Duration        float64
+	ProgramDateTime time.Time
+	Parts           []*Part // освобождаются когда сегмент уходит из окна частей
+	Data            []byte  // заполняется при закрытии сегмента
+	Complete        bool
+	InitMSN         int64 // init сегмента (см. InitName)
+	Discontinuity   bool  // первый сегмент после переподключения издателя
+}
+
+// Playlist - live плейлист одного качества в памяти. Части и последние сегменты
+// отдаются напрямую из памяти, полные сегменты дополнительно загружаются в MinIO.
+type Playlist struct {
+	mu sync.Mutex
+
+	segmentTarget float64
+	partTarget    float64
+	window        int // сколько полных сегментов держать в live плейлисте
+	partWindow    int // для скольких последних сегментов показывать части
+
+	inits    map[int64][]byte // MSN первого сегмента → init (после переподключения init новый)
+	initMSN  int64            // init новых сегментов
+	segments []*Segment       // окно: завершённые сегменты + текущий открытый
+	nextMSN  int64
+	nextPart int64
+	ended    bool
+
+	discontinue     bool  // следующий сегмент начинается после переподключения
+	discontinuities int64 // EXT-X-DISCONTINUITY, ушедшие из окна
+
+	// changed закрывается при каждом изменении плейлиста (blocking reload)
+	changed chan struct{}
+}
+
+func NewPlaylist(segmentTarget, partTarget float64, window int) *Playlist {
+	return &Playlist{
+		segmentTarget: segmentTarget,
+		partTarget:    partTarget,
+		window:        window,
+		partWindow:    3,
+		inits:         make(map[int64][]byte),
+		changed:       make(chan struct{}),
+	}
+}
+
+// notify будит всех ожидающих (вызывается под mu)
+func (p *Playlist) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// SetInit сохраняет init сегмент (EXT-X-MAP) для следующих сегментов
+func (p *Playlist) SetInit(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.initMSN = p.nextMSN
+	if p.current() != nil {
+		p.initMSN = p.current().MSN
+	}
+	p.inits[p.initMSN] = data
+	p.notify()
+}
+
+// Init возвращает текущий init сегмент
+func (p *Playlist) Init() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inits[p.initMSN]
+}
+
+// InitAt возвращает init по MSN, с которого он действует
+func (p *Playlist) InitAt(msn int64) ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, ok := p.inits[msn]
+	return data, ok
+}
+
+// Interrupt закрывает текущий сегмент, когда издатель отключился, не завершая плейлист.
+// Следующий сегмент (после переподключения) начнётся с EXT-X-DISCONTINUITY
+func (p *Playlist) Interrupt() *Segment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var completed *Segment
+	if p.current() != nil {
+		completed = p.closeCurrent()
+	}
+	p.discontinue = p.nextMSN > 0
+	p.notify()
+	return completed
+}
+
+// AddPart добавляет часть. Независимая часть (с ключевым кадром) начинает новый
+// сегмент; если при этом закрылся предыдущий - он возвращается для загрузки.
+func (p *Playlist) AddPart(data []byte, duration float64, independent bool) *Segment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var completed *Segment
+	current := p.current()
+	if current != nil && independent {
+		completed = p.closeCurrent()
+		current = nil
+	}
+
+	if current == nil {
+		current = &Segment{
+			MSN:             p.nextMSN,
+			ProgramDateTime: time.Now(),
+			InitMSN:         p.initMSN,
+			Discontinuity:   p.discontinue,
+		}
+		p.discontinue = false
+		p.nextMSN++
+		p.segments = append(p.segments, current)
+	}
+
+	current.Parts = append(current.Parts, &Part{
+		Seq:         p.nextPart,
+		Duration:    duration,
+		Independent: independent,
+		Data:        data,
+	})
+	current.Duration += duration
+	p.nextPart++
+
+	if duration > p.partTarget {
+		p.partTarget = duration
+	}
+
+	p.trim()
+	p.notify()
+	return completed
+}
+
+// End закрывает текущий сегмент и помечает плейлист завершённым (EXT-X-ENDLIST)
+func (p *Playlist) End() *Segment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var completed *Segment
+	if p.current() != nil {
+		completed = p.closeCurrent()
+	}
+	p.ended = true
+	p.notify()
+	return completed
+}
+
+// current возвращает открытый сегмент (вызывается под mu)
+func (p *Playlist) current() *Segment {
+	if len(p.segments) == 0 {
+		return nil
+	}
+	last := p.segments[len(p.segments)-1]
+	if last.Complete {
+		return nil
+	}
+	return last
+}
+
+// closeCurrent склеивает части текущего сегмента (вызывается под mu)
+func (p *Playlist) closeCurrent() *Segment {
+	segment := p.current()
+
+	var buf bytes.Buffer
+	for _, part := range segment.Parts {
+		buf.Write(part.Data)
+	}
+	segment.Data = buf.Bytes()
+	segment.Complete = true
+	return segment
+}
+
+// trim убирает старые сегменты из окна и освобождает части (вызывается под mu)
+func (p *Playlist) trim() {
+	if len(p.segments) > p.window+1 {
+		removed := p.segments[:len(p.segments)-p.window-1]
+		for _, segment := range removed {
+			if segment.Discontinuity {
+				p.discontinuities++
+			}
+		}
+		p.segments = append([]*Segment(nil), p.segments[len(removed):]...)
+
+		// init, на которые больше не ссылается ни один сегмент окна
+		for msn := range p.inits {
+			if msn < p.segments[0].InitMSN {
+				delete(p.inits, msn)
+			}
+		}
+	}
+
+	for i := 0; i < len(p.segments)-p.partWindow; i++ {
+		p.segments[i].Parts = nil
+	}
+}
+
+// Wait блокируется пока в плейлисте не появится часть part сегмента msn
+// (part < 0 - весь сегмент) или плейлист не завершится
+func (p *Playlist) Wait(ctx context.Context, msn, part int64) bool {
+	for {
+		p.mu.Lock()
+		ready := p.ended || p.hasPart(msn, part)
+		changed := p.changed
+		p.mu.Unlock()
+
+		if ready {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// hasPart (вызывается под mu)
+func (p *Playlist) hasPart(msn, part int64) bool {
+	current := p.current()
+	if current == nil || msn < current.MSN {
+		return msn < p.nextMSN
+	}
+	if msn > current.MSN || part < 0 {
+		return false
+	}
+	return int64(len(current.Parts)) > part
+}
+
+// LastMSN возвращает номер последнего сегмента в плейлисте (-1 если пусто)
+func (p *Playlist) LastMSN() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.nextMSN - 1
+}
+
+// TargetDuration возвращает EXT-X-TARGETDURATION в секундах
+func (p *Playlist) TargetDuration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.targetDuration()) * time.Second
+}
+
+// targetDuration (вызывается под mu)
+func (p *Playlist) targetDuration() int {
+	target := p.segmentTarget
+	for _, segment := range p.segments {
+		if segment.Complete && segment.Duration > target {
+			target = segment.Duration
+		}
+	}
+	return int(math.Ceil(target))
+}
+
+// WaitPart блокируется пока не появится часть с номером seq (для EXT-X-PRELOAD-HINT)
+func (p *Playlist) WaitPart(ctx context.Context, seq int64) (*Part, bool) {
+	for {
+		p.mu.Lock()
+		if seq < p.nextPart || p.ended {
+			part := p.findPart(seq)
+			p.mu.Unlock()
+			return part, part != nil
+		}
+		// Ждём только следующую часть, дальше клиент заглядывать не должен
+		if seq > p.nextPart {
+			p.mu.Unlock()
+			return nil, false
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// findPart (вызывается под mu)
+func (p *Playlist) findPart(seq int64) *Part {
+	for i := len(p.segments) - 1; i >= 0; i-- {
+		for _, part := range p.segments[i].Parts {
+			if part.Seq == seq {
+				return part
+			}
+		}
+	}
+	return nil
+}
+
+// GetSegment возвращает полный сегмент из окна
+func (p *Playlist) GetSegment(msn int64) ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, segment := range p.segments {
+		if segment.MSN == msn && segment.Complete {
+			return segment.Data, true
+		}
+	}
+	return nil, false
+}
+
+// LatestSegment возвращает последний полный сегмент (например, для thumbnail)
+func (p *Playlist) LatestSegment() ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.segments) - 1; i >= 0; i-- {
+		if p.segments[i].Complete {
+			return p.segments[i].Data, true
+		}
+	}
+	return nil, false
+}
+
+// Render формирует LL-HLS плейлист (EXT-X-PART, EXT-X-PRELOAD-HINT)
+func (p *Playlist) Render() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:6\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", p.targetDuration())
+	fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", p.partTarget*3)
+	fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", p.partTarget)
+
+	var firstMSN int64
+	if len(p.segments) > 0 {
+		firstMSN = p.segments[0].MSN
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", firstMSN)
+	if p.discontinuities > 0 {
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", p.discontinuities)
+	}
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if len(p.segments) == 0 {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", InitName(p.initMSN))
+	}
+
+	currentInit := int64(-1)
+	for _, segment := range p.segments {
+		if segment.Discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if segment.InitMSN != currentInit {
+			fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", InitName(segment.InitMSN))
+			currentInit = segment.InitMSN
+		}
+		fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.ProgramDateTime.UTC().Format("2006-01-02T15:04:05.000Z"))
+		for _, part := range segment.Parts {
+			fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"%s\"", part.Duration, PartName(part.Seq))
+			if part.Independent {
+				b.WriteString(",INDEPENDENT=YES")
+			}
+			b.WriteString("\n")
+		}
+		if segment.Complete {
+			fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", segment.Duration, SegmentName(segment.MSN))
+		}
+	}
+
+	if p.ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	} else {
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", PartName(p.nextPart))
+	}
+
+	return b.String()
+}
+
+// PartName - URI части в LL-HLS плейлисте
+func PartName(seq int64) string {
+	return fmt.Sprintf("part_%d.m4s", seq)
+}
+
+// InitName - имя init сегмента, действующего с сегмента msn (одинаковое в памяти и в MinIO).
+// Первый init эфира - init.mp4, после переподключений - init_<msn>.mp4
+func InitName(msn int64) string {
+	if msn == 0 {
+		return "init.mp4"
+	}
+	return fmt.Sprintf("init_%d.mp4", msn)
+}
+
+// SegmentName - имя полного сегмента (одинаковое в памяти и в MinIO)
+func SegmentName(msn int64) string {
+	return fmt.Sprintf("segment_%05d.m4s", msn)
+}
@@ -0,0 +1,77 @@
+package llhls
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Rendition - одно качество LL-HLS стрима
+type Rendition struct {
+	Name       string
+	Bandwidth  int
+	Resolution string
+	Playlist   *Playlist
+}
+
+// Stream - активная LL-HLS трансляция
+type Stream struct {
+	Renditions []*Rendition
+}
+
+// Rendition возвращает качество по имени
+func (s *Stream) Rendition(name string) (*Rendition, bool) {
+	for _, r := range s.Renditions {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// MasterPlaylist формирует master.m3u8 со ссылками на <quality>/playlist.m3u8
+func (s *Stream) MasterPlaylist() string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:6\n")
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, r := range s.Renditions {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s\n", r.Bandwidth, r.Resolution)
+		fmt.Fprintf(&b, "%s/playlist.m3u8\n", r.Name)
+	}
+	return b.String()
+}
+
+// Registry хранит активные LL-HLS трансляции, которые раздаёт stream-service
+type Registry struct {
+	streams map[uuid.UUID]*Stream
+	mu      sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{streams: make(map[uuid.UUID]*Stream)}
+}
+
+func (r *Registry) Register(streamID uuid.UUID, stream *Stream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.streams[streamID] = stream
+}
+
+// Unregister удаляет трансляцию, если она не была заменена новой публикацией
+func (r *Registry) Unregister(streamID uuid.UUID, stream *Stream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.streams[streamID] == stream {
+		delete(r.streams, streamID)
+	}
+}
+
+func (r *Registry) Get(streamID uuid.UUID) (*Stream, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stream, ok := r.streams[streamID]
+	return stream, ok
+}
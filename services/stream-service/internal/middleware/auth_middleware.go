@@ -33,7 +33,7 @@ func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
-		claims, err := validateToken(tokenString, jwtSecret)
+		claims, err := ValidateToken(tokenString, jwtSecret)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -47,7 +47,8 @@ func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
-func validateToken(tokenString string, secret string) (*Claims, error) {
+// ValidateToken parses and verifies a JWT issued by auth-service
+func ValidateToken(tokenString string, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
 	})
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatMessage - сообщение live чата стрима
+type ChatMessage struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	StreamID  uuid.UUID `json:"stream_id" db:"stream_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Username  string    `json:"username" db:"username"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChatBan - бан (ExpiresAt == nil) или таймаут пользователя в чате стрима
+type ChatBan struct {
+	StreamID  uuid.UUID  `json:"stream_id" db:"stream_id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedBy uuid.UUID  `json:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Состояния выхода рестрима (в памяти stream-service, не в БД)
+const (
+	DestinationIdle         = "idle"         // Включён, ждёт эфира
+	DestinationConnecting   = "connecting"   // ffmpeg подключается к площадке
+	DestinationLive         = "live"         // Поток уходит на площадку
+	DestinationReconnecting = "reconnecting" // Соединение оборвалось, повтор с задержкой
+	DestinationStopped      = "stopped"      // Выключен владельцем
+)
+
+// Destination - внешний выход рестрима: RTMP ingest другой площадки или SRT caller
+type Destination struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	StreamID  uuid.UUID `json:"stream_id" db:"stream_id"`
+	Name      string    `json:"name" db:"name"`
+	Protocol  string    `json:"protocol" db:"protocol"` // rtmp или srt
+	URL       string    `json:"url" db:"url"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Status    string `json:"status" db:"-"`
+	LastError string `json:"last_error,omitempty" db:"-"`
+}
+
+type CreateDestinationRequest struct {
+	Name    string `json:"name" binding:"required,min=1,max=50"`
+	URL     string `json:"url" binding:"required,max=2048"`
+	Enabled *bool  `json:"enabled"` // по умолчанию true
+}
+
+// UpdateDestinationRequest - nil поля не меняются
+type UpdateDestinationRequest struct {
+	Name    *string `json:"name" binding:"omitempty,min=1,max=50"`
+	URL     *string `json:"url" binding:"omitempty,max=2048"`
+	Enabled *bool   `json:"enabled"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HealthSample - точка телеметрии эфира: канал ingest и прогресс ffmpeg
+type HealthSample struct {
+	SampledAt time.Time     `json:"sampled_at" db:"sampled_at"`
+	Link      *LinkStats    `json:"link,omitempty"`    // nil - протокол без статистики канала (RTMP, WHIP)
+	Encoder   *EncoderStats `json:"encoder,omitempty"` // nil - ffmpeg ещё не сообщил прогресс
+}
+
+// LinkStats - статистика SRT соединения за интервал между замерами
+type LinkStats struct {
+	RTTMs                float64 `json:"rtt_ms" db:"rtt_ms"`
+	BitrateKbps          float64 `json:"bitrate_kbps" db:"bitrate_kbps"`
+	PacketsReceived      int64   `json:"packets_received" db:"packets_received"`
+	PacketsLost          int64   `json:"packets_lost" db:"packets_lost"`
+	PacketsRetransmitted int64   `json:"packets_retransmitted" db:"packets_retransmitted"`
+	PacketsDropped       int64   `json:"packets_dropped" db:"packets_dropped"`
+	PacketLossPercent    float64 `json:"packet_loss_percent" db:"-"` // Вычисляется из packets_lost / packets_received
+}
+
+// UpdateLossPercent пересчитывает PacketLossPercent по счётчикам пакетов
+func (s *LinkStats) UpdateLossPercent() {
+	total := s.PacketsReceived + s.PacketsLost
+	if total == 0 {
+		s.PacketLossPercent = 0
+		return
+	}
+	s.PacketLossPercent = float64(s.PacketsLost) * 100 / float64(total)
+}
+
+// EncoderStats - последний прогресс ffmpeg (-progress)
+type EncoderStats struct {
+	FPS             float64 `json:"fps" db:"fps"`
+	Speed           float64 `json:"speed" db:"speed"` // 1.0 = реальное время
+	DroppedFrames   int64   `json:"dropped_frames" db:"dropped_frames"`
+	DuplicateFrames int64   `json:"duplicate_frames" db:"duplicate_frames"`
+}
+
+// StreamHealth - ответ GET /streams/:id/health
+type StreamHealth struct {
+	StreamID    uuid.UUID       `json:"stream_id"`
+	BroadcastID *uuid.UUID      `json:"broadcast_id,omitempty"` // nil - у стрима ещё не было эфиров
+	Live        bool            `json:"live"`
+	Current     *HealthSample   `json:"current,omitempty"` // Последний замер идущего эфира
+	Samples     []*HealthSample `json:"samples"`
+}
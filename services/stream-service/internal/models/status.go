@@ -0,0 +1,49 @@
+package models
+
+import "fmt"
+
+// Статусы стрима
+const (
+	StatusScheduled = "scheduled" // Запланирован на scheduled_start_at
+	StatusOffline   = "offline"
+	StatusStarting  = "starting" // Издатель подключился, транскодирование запускается
+	StatusLive      = "live"
+	StatusStopping  = "stopping" // Издатель отключился, дописываются последние сегменты
+	StatusError     = "error"    // Эфир прервался аварийно
+)
+
+// statusTransitions - из каких статусов разрешён переход в каждый статус.
+// scheduled → starting → live → stopping → offline/error
+var statusTransitions = map[string][]string{
+	StatusScheduled: {StatusScheduled, StatusOffline, StatusError},
+	StatusStarting:  {StatusScheduled, StatusOffline, StatusError},
+	StatusLive:      {StatusStarting},
+	StatusStopping:  {StatusLive, StatusStarting},
+	StatusOffline:   {StatusStopping, StatusScheduled},
+	StatusError:     {StatusStarting, StatusLive, StatusStopping},
+}
+
+// TransitionSources возвращает статусы, из которых можно перейти в status
+func TransitionSources(status string) []string {
+	return statusTransitions[status]
+}
+
+// CanTransition проверяет допустимость перехода from → to
+func CanTransition(from, to string) bool {
+	for _, source := range statusTransitions[to] {
+		if source == from {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransitionError - недопустимый переход статуса стрима
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("invalid stream status transition %s → %s", e.From, e.To)
+}
@@ -10,29 +10,85 @@ import (
 type Stream struct {
 	ID                 uuid.UUID      `json:"id" db:"id"`
 	UserID             uuid.UUID      `json:"user_id" db:"user_id"`
-	StreamKey          string         `json:"stream_key" db:"stream_key"`
 	Title              string         `json:"title" db:"title"`
 	Description        string         `json:"description" db:"description"`
-	Status             string         `json:"status" db:"status"` // live, offline
+	Status             string         `json:"status" db:"status"` // см. status.go
 	ViewerCount        int            `json:"viewer_count" db:"viewer_count"`
 	StartedAt          *time.Time     `json:"started_at,omitempty" db:"started_at"`
 	EndedAt            *time.Time     `json:"ended_at,omitempty" db:"ended_at"`
 	ThumbnailURL       string         `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
 	HLSURL             string         `json:"hls_url,omitempty" db:"hls_url"`
 	AvailableQualities pq.StringArray `json:"available_qualities" db:"available_qualities"` // ✅ NEW
+	ABRPreset          string         `json:"abr_preset" db:"abr_preset"`
+	ABRLadder          pq.StringArray `json:"abr_ladder" db:"abr_ladder"`                           // Настроенный набор качеств
+	LowLatency         bool           `json:"low_latency" db:"low_latency"`                         // LL-HLS (fMP4 parts, blocking reload)
+	DVRWindowSeconds   int            `json:"dvr_window_seconds" db:"dvr_window_seconds"`           // Сколько секунд эфира можно перемотать (0 = весь эфир)
+	ScheduledStartAt   *time.Time     `json:"scheduled_start_at,omitempty" db:"scheduled_start_at"` // Плановое начало эфира
+	CoverImageURL      string         `json:"cover_image_url,omitempty" db:"cover_image_url"`       // Обложка анонса
 	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt          *time.Time     `json:"updated_at,omitempty" db:"updated_at"`
 	Username           string         `json:"username,omitempty"`
+
+	Keys        []*StreamKey `json:"keys,omitempty" db:"-"` // Только в ответах владельцу
+	IngestKeyID uuid.UUID    `json:"-" db:"-"`              // Ключ, которым авторизован издатель
+}
+
+// StorageKey - префикс объектов стрима в хранилище (live-segments/<storage key>/).
+// Не зависит от ключа публикации: ротация не ломает пути, а URL плейлистов не раскрывают ключ
+func (s *Stream) StorageKey() string {
+	return s.ID.String()
+}
+
+// StreamKey - именованный ключ публикации (SRT/RTMP/WHIP).
+// У стрима может быть несколько ключей, например для основного и резервного энкодера
+type StreamKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	StreamID   uuid.UUID  `json:"stream_id" db:"stream_id"`
+	Name       string     `json:"name" db:"name"`
+	Key        string     `json:"key" db:"key"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+type CreateStreamKeyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+}
+
+// Broadcast - один эфир стрима со статистикой зрителей
+type Broadcast struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	StreamID    uuid.UUID  `json:"stream_id" db:"stream_id"`
+	StartedAt   time.Time  `json:"started_at" db:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	PeakViewers int        `json:"peak_viewers" db:"peak_viewers"`
+	AvgViewers  float64    `json:"avg_viewers" db:"avg_viewers"`
 }
 
 type CreateStreamRequest struct {
-	Title       string `json:"title" binding:"required,min=3,max=255"`
-	Description string `json:"description" binding:"max=1000"`
+	Title       string   `json:"title" binding:"required,min=3,max=255"`
+	Description string   `json:"description" binding:"max=1000"`
+	ABRPreset   string   `json:"abr_preset"` // full, standard, low, minimal
+	Qualities   []string `json:"qualities"`  // Явный список качеств (приоритет над пресетом)
+	LowLatency  bool     `json:"low_latency"`
+	DVRWindow   int      `json:"dvr_window_seconds"` // 0 = весь эфир
+
+	ScheduledStartAt *time.Time `json:"scheduled_start_at"` // Задан - стрим создаётся запланированным
+	CoverImageURL    string     `json:"cover_image_url" binding:"max=2048"`
+}
+
+// ScheduleStreamRequest - запланировать или перенести эфир
+type ScheduleStreamRequest struct {
+	ScheduledStartAt time.Time `json:"scheduled_start_at" binding:"required"`
+	CoverImageURL    string    `json:"cover_image_url" binding:"max=2048"`
 }
 
 type CreateStreamResponse struct {
 	Stream    *Stream `json:"stream"`
-	StreamURL string  `json:"stream_url"`
+	StreamURL string  `json:"stream_url"` // SRT
+	RTMPURL   string  `json:"rtmp_url"`
+	WHIPURL   string  `json:"whip_url"` // WebRTC publish из браузера
 	HLSURL    string  `json:"hls_url"`
 }
 
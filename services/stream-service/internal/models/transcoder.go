@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Режимы транскодирования эфира (admission control)
+const (
+	TranscodeFull        = "full"        // Лестница качеств стрима целиком
+	TranscodeReduced     = "reduced"     // Без верхних качеств: слотов не хватило на всю лестницу
+	TranscodePassthrough = "passthrough" // Исходный поток без перекодирования
+)
+
+// TranscoderUsage - загрузка транскодера: CPU слоты и эфиры, которые их занимают
+type TranscoderUsage struct {
+	CapacitySlots int              `json:"capacity_slots"`
+	UsedSlots     int              `json:"used_slots"`
+	FreeSlots     int              `json:"free_slots"`
+	Jobs          []*TranscoderJob `json:"jobs"`
+}
+
+// TranscoderJob - эфир, занимающий слоты (в том числе ждущий переподключения издателя)
+type TranscoderJob struct {
+	StreamID  uuid.UUID `json:"stream_id"`
+	Mode      string    `json:"mode"`
+	Qualities []string  `json:"qualities"`
+	Slots     int       `json:"slots"`
+	Since     time.Time `json:"since"`
+}
+
+// TranscodeWorker - transcode worker в реестре ingest узла (тело heartbeat'а)
+type TranscodeWorker struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"` // адрес для заданий, например http://10.0.0.5:8090
+	CapacitySlots int       `json:"capacity_slots"`
+	FreeSlots     int       `json:"free_slots"`
+	Jobs          int       `json:"jobs"` // эфиры, занимающие слоты worker'а
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type ChatRepository struct {
+	db *sql.DB
+}
+
+func NewChatRepository(db *sql.DB) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// CreateMessage сохраняет сообщение и заполняет ID и CreatedAt
+func (r *ChatRepository) CreateMessage(message *models.ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (stream_id, user_id, username, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(query, message.StreamID, message.UserID, message.Username, message.Content).
+		Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecentMessages returns up to limit visible messages created before `before`
+// (zero time - latest), oldest first
+func (r *ChatRepository) GetRecentMessages(streamID uuid.UUID, before time.Time, limit int) ([]*models.ChatMessage, error) {
+	if before.IsZero() {
+		before = time.Now().Add(time.Minute)
+	}
+
+	query := `
+		SELECT id, stream_id, user_id, username, content, created_at
+		FROM (
+			SELECT id, stream_id, user_id, username, content, created_at
+			FROM chat_messages
+			WHERE stream_id = $1 AND deleted_at IS NULL AND created_at < $2
+			ORDER BY created_at DESC
+			LIMIT $3
+		) recent
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, streamID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*models.ChatMessage{}
+	for rows.Next() {
+		message := &models.ChatMessage{}
+		if err := rows.Scan(
+			&message.ID,
+			&message.StreamID,
+			&message.UserID,
+			&message.Username,
+			&message.Content,
+			&message.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// DeleteMessage скрывает сообщение. false - сообщения нет или оно уже удалено
+func (r *ChatRepository) DeleteMessage(streamID, messageID, deletedBy uuid.UUID) (bool, error) {
+	query := `
+		UPDATE chat_messages
+		SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $3
+		WHERE id = $1 AND stream_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, messageID, streamID, deletedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete chat message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// DeleteUserMessages скрывает все сообщения пользователя в чате стрима (бан / таймаут)
+func (r *ChatRepository) DeleteUserMessages(streamID, userID, deletedBy uuid.UUID) error {
+	query := `
+		UPDATE chat_messages
+		SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $3
+		WHERE stream_id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, streamID, userID, deletedBy); err != nil {
+		return fmt.Errorf("failed to delete user chat messages: %w", err)
+	}
+
+	return nil
+}
+
+// BanUser банит пользователя (expiresAt == nil) или выдаёт таймаут до expiresAt.
+// Повторный бан заменяет предыдущий
+func (r *ChatRepository) BanUser(streamID, userID uuid.UUID, expiresAt *time.Time, createdBy uuid.UUID) error {
+	query := `
+		INSERT INTO chat_bans (stream_id, user_id, expires_at, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (stream_id, user_id) DO UPDATE
+		SET expires_at = EXCLUDED.expires_at,
+		    created_by = EXCLUDED.created_by,
+		    created_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := r.db.Exec(query, streamID, userID, expiresAt, createdBy); err != nil {
+		return fmt.Errorf("failed to ban chat user: %w", err)
+	}
+
+	return nil
+}
+
+// UnbanUser снимает бан или таймаут
+func (r *ChatRepository) UnbanUser(streamID, userID uuid.UUID) error {
+	query := `DELETE FROM chat_bans WHERE stream_id = $1 AND user_id = $2`
+
+	if _, err := r.db.Exec(query, streamID, userID); err != nil {
+		return fmt.Errorf("failed to unban chat user: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveBans returns bans and timeouts that have not expired yet
+func (r *ChatRepository) GetActiveBans(streamID uuid.UUID) ([]*models.ChatBan, error) {
+	query := `
+		SELECT stream_id, user_id, expires_at, created_by, created_at
+		FROM chat_bans
+		WHERE stream_id = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`
+
+	rows, err := r.db.Query(query, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat bans: %w", err)
+	}
+	defer rows.Close()
+
+	bans := []*models.ChatBan{}
+	for rows.Next() {
+		ban := &models.ChatBan{}
+		var expiresAt sql.NullTime
+
+		if err := rows.Scan(&ban.StreamID, &ban.UserID, &expiresAt, &ban.CreatedBy, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat ban: %w", err)
+		}
+
+		if expiresAt.Valid {
+			ban.ExpiresAt = &expiresAt.Time
+		}
+		bans = append(bans, ban)
+	}
+
+	return bans, rows.Err()
+}
+
+// GetSlowMode returns minimum interval between messages of one user in seconds
+func (r *ChatRepository) GetSlowMode(streamID uuid.UUID) (int, error) {
+	var seconds int
+	query := `SELECT chat_slow_mode_seconds FROM streams WHERE id = $1`
+
+	if err := r.db.QueryRow(query, streamID).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("failed to get chat slow mode: %w", err)
+	}
+
+	return seconds, nil
+}
+
+// SetSlowMode updates slow mode interval (0 - disabled)
+func (r *ChatRepository) SetSlowMode(streamID uuid.UUID, seconds int) error {
+	query := `
+		UPDATE streams
+		SET chat_slow_mode_seconds = $1,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	if _, err := r.db.Exec(query, seconds, streamID); err != nil {
+		return fmt.Errorf("failed to update chat slow mode: %w", err)
+	}
+
+	return nil
+}
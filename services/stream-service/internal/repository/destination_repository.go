@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// MaxDestinations ограничивает число выходов рестрима одного стрима:
+// каждый выход - отдельный ffmpeg и исходящий канал
+const MaxDestinations = 5
+
+var (
+	ErrDestinationNotFound = errors.New("destination not found")
+	ErrTooManyDestinations = fmt.Errorf("stream can have at most %d destinations", MaxDestinations)
+)
+
+const destinationColumns = `id, stream_id, name, protocol, url, enabled, created_at, updated_at`
+
+type DestinationRepository struct {
+	db *sql.DB
+}
+
+func NewDestinationRepository(db *sql.DB) *DestinationRepository {
+	return &DestinationRepository{db: db}
+}
+
+// ListDestinations returns destinations of a stream, oldest first
+func (r *DestinationRepository) ListDestinations(streamID uuid.UUID) ([]*models.Destination, error) {
+	query := `
+		SELECT ` + destinationColumns + `
+		FROM stream_destinations
+		WHERE stream_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list destinations: %w", err)
+	}
+	defer rows.Close()
+
+	destinations := []*models.Destination{}
+	for rows.Next() {
+		destination, err := scanDestination(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan destination: %w", err)
+		}
+		destinations = append(destinations, destination)
+	}
+
+	return destinations, rows.Err()
+}
+
+// GetDestination returns a destination of the stream
+func (r *DestinationRepository) GetDestination(streamID, destinationID uuid.UUID) (*models.Destination, error) {
+	query := `
+		SELECT ` + destinationColumns + `
+		FROM stream_destinations
+		WHERE id = $1 AND stream_id = $2
+	`
+
+	destination, err := scanDestination(r.db.QueryRow(query, destinationID, streamID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination: %w", err)
+	}
+	return destination, nil
+}
+
+// CreateDestination adds an output to a stream
+func (r *DestinationRepository) CreateDestination(streamID uuid.UUID, name, protocol, url string, enabled bool) (*models.Destination, error) {
+	query := `
+		INSERT INTO stream_destinations (stream_id, name, protocol, url, enabled)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (SELECT COUNT(*) FROM stream_destinations WHERE stream_id = $1) < $6
+		RETURNING ` + destinationColumns
+
+	created, err := scanDestination(r.db.QueryRow(query, streamID, name, protocol, url, enabled, MaxDestinations))
+	if err == sql.ErrNoRows {
+		return nil, ErrTooManyDestinations
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateDestination сохраняет изменённый выход (name, protocol, url, enabled)
+func (r *DestinationRepository) UpdateDestination(destination *models.Destination) (*models.Destination, error) {
+	query := `
+		UPDATE stream_destinations
+		SET name = $3, protocol = $4, url = $5, enabled = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND stream_id = $2
+		RETURNING ` + destinationColumns
+
+	updated, err := scanDestination(r.db.QueryRow(query,
+		destination.ID, destination.StreamID,
+		destination.Name, destination.Protocol, destination.URL, destination.Enabled,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update destination: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteDestination removes an output
+func (r *DestinationRepository) DeleteDestination(streamID, destinationID uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM stream_destinations WHERE id = $1 AND stream_id = $2`, destinationID, streamID)
+	if err != nil {
+		return fmt.Errorf("failed to delete destination: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete destination: %w", err)
+	}
+	if rows == 0 {
+		return ErrDestinationNotFound
+	}
+	return nil
+}
+
+func scanDestination(row rowScanner) (*models.Destination, error) {
+	destination := &models.Destination{}
+	err := row.Scan(
+		&destination.ID, &destination.StreamID, &destination.Name, &destination.Protocol,
+		&destination.URL, &destination.Enabled, &destination.CreatedAt, &destination.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepository(db *sql.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+// InsertSample сохраняет замер в текущий эфир стрима.
+// Если эфир не идёт (ещё не live или уже закрыт), замер отбрасывается
+func (r *HealthRepository) InsertSample(streamID uuid.UUID, sample *models.HealthSample) error {
+	// Отсутствующая часть замера сохраняется как NULL
+	var rtt, bitrate, fps, speed sql.NullFloat64
+	var received, lost, retransmitted, dropped, droppedFrames, duplicateFrames sql.NullInt64
+	if link := sample.Link; link != nil {
+		rtt = sql.NullFloat64{Float64: link.RTTMs, Valid: true}
+		bitrate = sql.NullFloat64{Float64: link.BitrateKbps, Valid: true}
+		received = sql.NullInt64{Int64: link.PacketsReceived, Valid: true}
+		lost = sql.NullInt64{Int64: link.PacketsLost, Valid: true}
+		retransmitted = sql.NullInt64{Int64: link.PacketsRetransmitted, Valid: true}
+		dropped = sql.NullInt64{Int64: link.PacketsDropped, Valid: true}
+	}
+	if encoder := sample.Encoder; encoder != nil {
+		fps = sql.NullFloat64{Float64: encoder.FPS, Valid: true}
+		speed = sql.NullFloat64{Float64: encoder.Speed, Valid: true}
+		droppedFrames = sql.NullInt64{Int64: encoder.DroppedFrames, Valid: true}
+		duplicateFrames = sql.NullInt64{Int64: encoder.DuplicateFrames, Valid: true}
+	}
+
+	query := `
+		INSERT INTO stream_health_samples (
+			broadcast_id, sampled_at,
+			rtt_ms, bitrate_kbps, packets_received, packets_lost, packets_retransmitted, packets_dropped,
+			fps, speed, dropped_frames, duplicate_frames
+		)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		FROM stream_broadcasts
+		WHERE stream_id = $1 AND ended_at IS NULL
+	`
+
+	_, err := r.db.Exec(query,
+		streamID, sample.SampledAt,
+		rtt, bitrate, received, lost, retransmitted, dropped,
+		fps, speed, droppedFrames, duplicateFrames,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert health sample: %w", err)
+	}
+	return nil
+}
+
+// GetLatestBroadcastID returns the most recent broadcast of the stream (sql.ErrNoRows - none yet)
+func (r *HealthRepository) GetLatestBroadcastID(streamID uuid.UUID) (uuid.UUID, error) {
+	var broadcastID uuid.UUID
+	err := r.db.QueryRow(`
+		SELECT id FROM stream_broadcasts
+		WHERE stream_id = $1
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, streamID).Scan(&broadcastID)
+	return broadcastID, err
+}
+
+// BroadcastBelongsToStream проверяет, что эфир принадлежит стриму
+func (r *HealthRepository) BroadcastBelongsToStream(streamID, broadcastID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM stream_broadcasts WHERE id = $1 AND stream_id = $2)
+	`, broadcastID, streamID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check broadcast: %w", err)
+	}
+	return exists, nil
+}
+
+// GetSamples returns the last `limit` samples of a broadcast taken after `since`, oldest first
+func (r *HealthRepository) GetSamples(broadcastID uuid.UUID, since time.Time, limit int) ([]*models.HealthSample, error) {
+	query := `
+		SELECT sampled_at,
+			rtt_ms, bitrate_kbps, packets_received, packets_lost, packets_retransmitted, packets_dropped,
+			fps, speed, dropped_frames, duplicate_frames
+		FROM (
+			SELECT *
+			FROM stream_health_samples
+			WHERE broadcast_id = $1 AND sampled_at > $2
+			ORDER BY sampled_at DESC
+			LIMIT $3
+		) recent
+		ORDER BY sampled_at ASC
+	`
+
+	rows, err := r.db.Query(query, broadcastID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health samples: %w", err)
+	}
+	defer rows.Close()
+
+	samples := []*models.HealthSample{}
+	for rows.Next() {
+		sample := &models.HealthSample{}
+		var rtt, bitrate, fps, speed sql.NullFloat64
+		var received, lost, retransmitted, dropped, droppedFrames, duplicateFrames sql.NullInt64
+
+		err := rows.Scan(
+			&sample.SampledAt,
+			&rtt, &bitrate, &received, &lost, &retransmitted, &dropped,
+			&fps, &speed, &droppedFrames, &duplicateFrames,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health sample: %w", err)
+		}
+
+		if rtt.Valid {
+			sample.Link = &models.LinkStats{
+				RTTMs:                rtt.Float64,
+				BitrateKbps:          bitrate.Float64,
+				PacketsReceived:      received.Int64,
+				PacketsLost:          lost.Int64,
+				PacketsRetransmitted: retransmitted.Int64,
+				PacketsDropped:       dropped.Int64,
+			}
+			sample.Link.UpdateLossPercent()
+		}
+		if fps.Valid {
+			sample.Encoder = &models.EncoderStats{
+				FPS:             fps.Float64,
+				Speed:           speed.Float64,
+				DroppedFrames:   droppedFrames.Int64,
+				DuplicateFrames: duplicateFrames.Int64,
+			}
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
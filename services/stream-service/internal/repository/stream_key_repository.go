@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// PrimaryStreamKeyName - имя ключа, создаваемого вместе со стримом
+	PrimaryStreamKeyName = "primary"
+
+	// MaxActiveStreamKeys ограничивает число действующих ключей одного стрима
+	MaxActiveStreamKeys = 10
+)
+
+var (
+	ErrStreamKeyNotFound  = errors.New("stream key not found")
+	ErrStreamKeyNameTaken = errors.New("stream key with this name already exists")
+	ErrTooManyStreamKeys  = fmt.Errorf("stream can have at most %d active keys", MaxActiveStreamKeys)
+)
+
+const streamKeyColumns = `id, stream_id, name, key, created_at, last_used_at, rotated_at, revoked_at`
+
+// queryRower - *sql.DB или *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type StreamKeyRepository struct {
+	db *sql.DB
+}
+
+func NewStreamKeyRepository(db *sql.DB) *StreamKeyRepository {
+	return &StreamKeyRepository{db: db}
+}
+
+// ListKeys returns all keys of a stream: active first, then revoked, oldest first
+func (r *StreamKeyRepository) ListKeys(streamID uuid.UUID) ([]*models.StreamKey, error) {
+	query := `
+		SELECT ` + streamKeyColumns + `
+		FROM stream_keys
+		WHERE stream_id = $1
+		ORDER BY revoked_at IS NOT NULL, created_at
+	`
+
+	rows, err := r.db.Query(query, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stream keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.StreamKey{}
+	for rows.Next() {
+		key, err := scanStreamKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stream key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateKey adds a named key to a stream
+func (r *StreamKeyRepository) CreateKey(streamID uuid.UUID, name, key string) (*models.StreamKey, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка стрима упорядочивает параллельные создания ключей:
+	// иначе оба запроса видят свободное место и превышают лимит
+	var locked uuid.UUID
+	err = tx.QueryRow(`SELECT id FROM streams WHERE id = $1 FOR UPDATE`, streamID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("stream not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock stream: %w", err)
+	}
+
+	query := `
+		INSERT INTO stream_keys (stream_id, name, key)
+		SELECT $1, $2, $3
+		WHERE (SELECT COUNT(*) FROM stream_keys WHERE stream_id = $1 AND revoked_at IS NULL) < $4
+		RETURNING ` + streamKeyColumns
+
+	created, err := scanStreamKey(tx.QueryRow(query, streamID, name, key, MaxActiveStreamKeys))
+	if err == sql.ErrNoRows {
+		return nil, ErrTooManyStreamKeys
+	}
+	if err != nil {
+		return nil, streamKeyError("failed to create stream key", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stream key: %w", err)
+	}
+
+	return created, nil
+}
+
+// RotateKey replaces the secret of an active key, keeping its name and creation time.
+// The old secret stops working immediately
+func (r *StreamKeyRepository) RotateKey(streamID, keyID uuid.UUID, newKey string) (*models.StreamKey, error) {
+	query := `
+		UPDATE stream_keys
+		SET key = $3, rotated_at = CURRENT_TIMESTAMP, last_used_at = NULL
+		WHERE id = $1 AND stream_id = $2 AND revoked_at IS NULL
+		RETURNING ` + streamKeyColumns
+
+	rotated, err := scanStreamKey(r.db.QueryRow(query, keyID, streamID, newKey))
+	if err == sql.ErrNoRows {
+		return nil, ErrStreamKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate stream key: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// RevokeKey permanently disables a key. Revoked keys are kept for audit
+func (r *StreamKeyRepository) RevokeKey(streamID, keyID uuid.UUID) (*models.StreamKey, error) {
+	query := `
+		UPDATE stream_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND stream_id = $2 AND revoked_at IS NULL
+		RETURNING ` + streamKeyColumns
+
+	revoked, err := scanStreamKey(r.db.QueryRow(query, keyID, streamID))
+	if err == sql.ErrNoRows {
+		return nil, ErrStreamKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke stream key: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// insertStreamKey добавляет ключ в транзакции создания стрима
+func insertStreamKey(q queryRower, streamID uuid.UUID, name, key string) (*models.StreamKey, error) {
+	query := `
+		INSERT INTO stream_keys (stream_id, name, key)
+		VALUES ($1, $2, $3)
+		RETURNING ` + streamKeyColumns
+
+	created, err := scanStreamKey(q.QueryRow(query, streamID, name, key))
+	if err != nil {
+		return nil, streamKeyError("failed to create stream key", err)
+	}
+	return created, nil
+}
+
+// streamKeyError переводит нарушение уникальности имени в ErrStreamKeyNameTaken
+func streamKeyError(msg string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_stream_keys_active_name" {
+		return ErrStreamKeyNameTaken
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStreamKey(row rowScanner) (*models.StreamKey, error) {
+	key := &models.StreamKey{}
+	var lastUsedAt, rotatedAt, revokedAt sql.NullTime
+
+	if err := row.Scan(&key.ID, &key.StreamID, &key.Name, &key.Key, &key.CreatedAt, &lastUsedAt, &rotatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if rotatedAt.Valid {
+		key.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
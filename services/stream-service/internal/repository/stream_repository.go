@@ -1,17 +1,22 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 )
 
+// OutboxSource - имя сервиса в outbox_events.source
+const OutboxSource = "stream-service"
+
 type StreamRepository struct {
 	db *sql.DB
 }
@@ -20,47 +25,68 @@ func NewStreamRepository(db *sql.DB) *StreamRepository {
 	return &StreamRepository{db: db}
 }
 
-// CreateStream creates a new stream
-func (r *StreamRepository) CreateStream(userID uuid.UUID, streamKey, title, description string) (*models.Stream, error) {
+// CreateStream creates a new stream with its primary stream key.
+// With scheduledStartAt set the stream is created in 'scheduled' status
+func (r *StreamRepository) CreateStream(userID uuid.UUID, streamKey, title, description, abrPreset string, abrLadder []string, lowLatency bool, dvrWindowSeconds int, scheduledStartAt *time.Time, coverImageURL string) (*models.Stream, error) {
 	stream := &models.Stream{
-		ID:          uuid.New(),
-		UserID:      userID,
-		StreamKey:   streamKey,
-		Title:       title,
-		Description: description,
-		Status:      "offline",
-		ViewerCount: 0,
-		CreatedAt:   time.Now(),
+		ID:               uuid.New(),
+		UserID:           userID,
+		Title:            title,
+		Description:      description,
+		Status:           models.StatusOffline,
+		ViewerCount:      0,
+		ABRPreset:        abrPreset,
+		LowLatency:       lowLatency,
+		DVRWindowSeconds: dvrWindowSeconds,
+		ScheduledStartAt: scheduledStartAt,
+		CoverImageURL:    coverImageURL,
+		CreatedAt:        time.Now(),
+	}
+	if scheduledStartAt != nil {
+		stream.Status = models.StatusScheduled
 	}
 
-	defaultQualities := []string{"360p", "480p", "720p", "1080p"}
+	// До первого эфира доступные качества совпадают с настроенным набором
 	query := `
-		INSERT INTO streams (id, user_id, stream_key, title, description, status, viewer_count, available_qualities, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, user_id, stream_key, title, description, status, viewer_count, available_qualities, created_at
+		INSERT INTO streams (id, user_id, title, description, status, viewer_count, available_qualities, abr_preset, abr_ladder, low_latency, dvr_window_seconds, scheduled_start_at, cover_image_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7, $9, $10, $11, NULLIF($12, ''), $13)
+		RETURNING id, user_id, title, description, status, viewer_count, available_qualities, abr_preset, abr_ladder, low_latency, dvr_window_seconds, created_at
 	`
 
-	var qualities []string
-	err := r.db.QueryRow(
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var qualities, ladder []string
+	err = tx.QueryRow(
 		query,
 		stream.ID,
 		stream.UserID,
-		stream.StreamKey,
 		stream.Title,
 		stream.Description,
 		stream.Status,
 		stream.ViewerCount,
-		pq.Array(defaultQualities),
+		pq.Array(abrLadder),
+		stream.ABRPreset,
+		stream.LowLatency,
+		stream.DVRWindowSeconds,
+		stream.ScheduledStartAt,
+		stream.CoverImageURL,
 		stream.CreatedAt,
 	).Scan(
 		&stream.ID,
 		&stream.UserID,
-		&stream.StreamKey,
 		&stream.Title,
 		&stream.Description,
 		&stream.Status,
 		&stream.ViewerCount,
 		pq.Array(&qualities),
+		&stream.ABRPreset,
+		pq.Array(&ladder),
+		&stream.LowLatency,
+		&stream.DVRWindowSeconds,
 		&stream.CreatedAt,
 	)
 
@@ -68,7 +94,18 @@ func (r *StreamRepository) CreateStream(userID uuid.UUID, streamKey, title, desc
 		return nil, fmt.Errorf("failed to create stream: %w", err)
 	}
 
+	key, err := insertStreamKey(tx, stream.ID, PrimaryStreamKeyName, streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stream: %w", err)
+	}
+
+	stream.Keys = []*models.StreamKey{key}
 	stream.AvailableQualities = pq.StringArray(qualities)
+	stream.ABRLadder = pq.StringArray(ladder)
 	return stream, nil
 }
 
@@ -78,31 +115,36 @@ func (r *StreamRepository) GetStreamByID(streamID uuid.UUID) (*models.Stream, er
 	query := `
 		WITH target_stream AS (
 			SELECT 
-				id, user_id, stream_key, title, description, status, viewer_count,
-				started_at, ended_at, thumbnail_url, hls_url, available_qualities, created_at
+				id, user_id, title, description, status, viewer_count,
+				started_at, ended_at, thumbnail_url, hls_url, available_qualities,
+				abr_preset, abr_ladder, low_latency, dvr_window_seconds, created_at,
+				scheduled_start_at, cover_image_url
 			FROM streams
 			WHERE id = $1
 		)
 		SELECT
-			ts.id, ts.user_id, ts.stream_key, ts.title, ts.description, 
+			ts.id, ts.user_id, ts.title, ts.description, 
 			ts.status, ts.viewer_count, ts.started_at, ts.ended_at, 
-			ts.thumbnail_url, ts.hls_url, ts.available_qualities, ts.created_at,
+			ts.thumbnail_url, ts.hls_url, ts.available_qualities,
+			ts.abr_preset, ts.abr_ladder, ts.low_latency, ts.dvr_window_seconds, ts.created_at,
+			ts.scheduled_start_at, ts.cover_image_url,
 			COALESCE(u.username, 'Unknown') as username
 		FROM target_stream ts
 		LEFT JOIN users u ON ts.user_id = u.id
 	`
 
 	stream := &models.Stream{}
-	var startedAt, endedAt sql.NullTime
-	var thumbnailURL, hlsURL sql.NullString
-	var qualities []string
+	var startedAt, endedAt, scheduledStartAt sql.NullTime
+	var thumbnailURL, hlsURL, coverImageURL sql.NullString
+	var qualities, ladder []string
 	var username string
 
 	err := r.db.QueryRow(query, streamID).Scan(
-		&stream.ID, &stream.UserID, &stream.StreamKey,
+		&stream.ID, &stream.UserID,
 		&stream.Title, &stream.Description, &stream.Status, &stream.ViewerCount,
 		&startedAt, &endedAt, &thumbnailURL, &hlsURL,
-		pq.Array(&qualities), &stream.CreatedAt,
+		pq.Array(&qualities), &stream.ABRPreset, pq.Array(&ladder), &stream.LowLatency, &stream.DVRWindowSeconds, &stream.CreatedAt,
+		&scheduledStartAt, &coverImageURL,
 		&username,
 	)
 
@@ -130,29 +172,42 @@ func (r *StreamRepository) GetStreamByID(streamID uuid.UUID) (*models.Stream, er
 		stream.HLSURL = hlsURL.String
 	}
 
+	if scheduledStartAt.Valid {
+		stream.ScheduledStartAt = &scheduledStartAt.Time
+	}
+
+	if coverImageURL.Valid {
+		stream.CoverImageURL = coverImageURL.String
+	}
+
 	stream.Username = username
 	stream.AvailableQualities = pq.StringArray(qualities)
+	stream.ABRLadder = pq.StringArray(ladder)
 	return stream, nil
 }
 
-// GetStreamByKey retrieves a stream by stream key
-func (r *StreamRepository) GetStreamByKey(streamKey string) (*models.Stream, error) {
+// GetStreamByIngestKey retrieves a stream by one of its active (not revoked) stream keys.
+// IngestKeyID of the result is set to the matched key
+func (r *StreamRepository) GetStreamByIngestKey(streamKey string) (*models.Stream, error) {
 	stream := &models.Stream{}
 	query := `
-		SELECT id, user_id, stream_key, title, description, status, viewer_count,
-		       started_at, ended_at, thumbnail_url, hls_url, available_qualities, created_at
-		FROM streams
-		WHERE stream_key = $1
+		SELECT s.id, s.user_id, s.title, s.description, s.status, s.viewer_count,
+		       s.started_at, s.ended_at, s.thumbnail_url, s.hls_url, s.available_qualities,
+		       s.abr_preset, s.abr_ladder, s.low_latency, s.dvr_window_seconds, s.created_at,
+		       s.scheduled_start_at, s.cover_image_url,
+		       k.id
+		FROM stream_keys k
+		JOIN streams s ON s.id = k.stream_id
+		WHERE k.key = $1 AND k.revoked_at IS NULL
 	`
 
-	var startedAt, endedAt sql.NullTime
-	var thumbnailURL, hlsURL sql.NullString
-	var qualities []string
+	var startedAt, endedAt, scheduledStartAt sql.NullTime
+	var thumbnailURL, hlsURL, coverImageURL sql.NullString
+	var qualities, ladder []string
 
 	err := r.db.QueryRow(query, streamKey).Scan(
 		&stream.ID,
 		&stream.UserID,
-		&stream.StreamKey,
 		&stream.Title,
 		&stream.Description,
 		&stream.Status,
@@ -162,7 +217,14 @@ func (r *StreamRepository) GetStreamByKey(streamKey string) (*models.Stream, err
 		&thumbnailURL,
 		&hlsURL,
 		pq.Array(&qualities),
+		&stream.ABRPreset,
+		pq.Array(&ladder),
+		&stream.LowLatency,
+		&stream.DVRWindowSeconds,
 		&stream.CreatedAt,
+		&scheduledStartAt,
+		&coverImageURL,
+		&stream.IngestKeyID,
 	)
 
 	if err != nil {
@@ -185,25 +247,48 @@ func (r *StreamRepository) GetStreamByKey(streamKey string) (*models.Stream, err
 		stream.HLSURL = hlsURL.String
 	}
 
+	if scheduledStartAt.Valid {
+		stream.ScheduledStartAt = &scheduledStartAt.Time
+	}
+
+	if coverImageURL.Valid {
+		stream.CoverImageURL = coverImageURL.String
+	}
+
 	stream.AvailableQualities = pq.StringArray(qualities)
+	stream.ABRLadder = pq.StringArray(ladder)
 	return stream, nil
 }
 
+// MarkStreamKeyUsed records an accepted publish with the key
+func (r *StreamRepository) MarkStreamKeyUsed(keyID uuid.UUID) error {
+	query := `UPDATE stream_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	if _, err := r.db.Exec(query, keyID); err != nil {
+		return fmt.Errorf("failed to mark stream key as used: %w", err)
+	}
+	return nil
+}
+
 // ✅ ОПТИМИЗИРОВАНО: GetUserStreams с CTE
 func (r *StreamRepository) GetUserStreams(userID uuid.UUID) ([]*models.Stream, error) {
 	query := `
 		WITH filtered_streams AS (
 			SELECT 
-				id, user_id, stream_key, title, description, status, viewer_count,
-				started_at, ended_at, thumbnail_url, hls_url, available_qualities, created_at
+				id, user_id, title, description, status, viewer_count,
+				started_at, ended_at, thumbnail_url, hls_url, available_qualities,
+				abr_preset, abr_ladder, low_latency, dvr_window_seconds, created_at,
+				scheduled_start_at, cover_image_url
 			FROM streams
 			WHERE user_id = $1
 			ORDER BY created_at DESC
 		)
 		SELECT
-			fs.id, fs.user_id, fs.stream_key, fs.title, fs.description, 
+			fs.id, fs.user_id, fs.title, fs.description, 
 			fs.status, fs.viewer_count, fs.started_at, fs.ended_at, 
-			fs.thumbnail_url, fs.hls_url, fs.available_qualities, fs.created_at,
+			fs.thumbnail_url, fs.hls_url, fs.available_qualities,
+			fs.abr_preset, fs.abr_ladder, fs.low_latency, fs.dvr_window_seconds, fs.created_at,
+			fs.scheduled_start_at, fs.cover_image_url,
 			COALESCE(u.username, 'Unknown Streamer') as username
 		FROM filtered_streams fs
 		LEFT JOIN users u ON fs.user_id = u.id
@@ -215,79 +300,50 @@ func (r *StreamRepository) GetUserStreams(userID uuid.UUID) ([]*models.Stream, e
 	}
 
 	defer rows.Close()
-	var streams []*models.Stream
-
-	for rows.Next() {
-		stream := &models.Stream{}
-		var startedAt, endedAt sql.NullTime
-		var thumbnailURL, hlsURL sql.NullString
-		var qualities []string
-		var username string
-
-		err := rows.Scan(
-			&stream.ID,
-			&stream.UserID,
-			&stream.StreamKey,
-			&stream.Title,
-			&stream.Description,
-			&stream.Status,
-			&stream.ViewerCount,
-			&startedAt,
-			&endedAt,
-			&thumbnailURL,
-			&hlsURL,
-			pq.Array(&qualities),
-			&stream.CreatedAt,
-			&username,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan stream: %w", err)
-		}
-
-		if startedAt.Valid {
-			stream.StartedAt = &startedAt.Time
-		}
-
-		if endedAt.Valid {
-			stream.EndedAt = &endedAt.Time
-		}
-
-		if thumbnailURL.Valid {
-			stream.ThumbnailURL = thumbnailURL.String
-		}
-
-		if hlsURL.Valid {
-			stream.HLSURL = hlsURL.String
-		}
+	return scanStreamRows(rows)
+}
 
-		stream.Username = username
-		stream.AvailableQualities = pq.StringArray(qualities)
-		streams = append(streams, stream)
-	}
+// Порядок live стримов
+const (
+	LiveOrderPopular = "popular" // больше зрителей - выше
+	LiveOrderRecent  = "recent"  // недавно начатые - выше
+)
 
-	return streams, nil
+// liveOrderClauses - ORDER BY для CTE (без префикса) и итоговой выборки (fs.)
+var liveOrderClauses = map[string][2]string{
+	LiveOrderPopular: {"viewer_count DESC, started_at DESC", "fs.viewer_count DESC, fs.started_at DESC"},
+	LiveOrderRecent:  {"started_at DESC", "fs.started_at DESC"},
 }
 
 // ✅ ОПТИМИЗИРОВАНО: GetLiveStreams с CTE и LIMIT
-func (r *StreamRepository) GetLiveStreams() ([]*models.Stream, error) {
+func (r *StreamRepository) GetLiveStreams(order string) ([]*models.Stream, error) {
+	clauses, ok := liveOrderClauses[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown live streams order %q", order)
+	}
+
 	query := `
 		WITH filtered_streams AS (
 			SELECT 
-				id, user_id, stream_key, title, description, status, viewer_count,
-				started_at, ended_at, thumbnail_url, hls_url, available_qualities, created_at
+				id, user_id, title, description, status, viewer_count,
+				started_at, ended_at, thumbnail_url, hls_url, available_qualities,
+				abr_preset, abr_ladder, low_latency, dvr_window_seconds, created_at,
+				scheduled_start_at, cover_image_url
 			FROM streams
 			WHERE status = 'live'
-			ORDER BY started_at DESC
+			ORDER BY ` + clauses[0] + `
 			LIMIT 100
 		)
 		SELECT
-			fs.id, fs.user_id, fs.stream_key, fs.title, fs.description, 
+			fs.id, fs.user_id, fs.title, fs.description, 
 			fs.status, fs.viewer_count, fs.started_at, fs.ended_at, 
-			fs.thumbnail_url, fs.hls_url, fs.available_qualities, fs.created_at,
+			fs.thumbnail_url, fs.hls_url, fs.available_qualities,
+			fs.abr_preset, fs.abr_ladder, fs.low_latency, fs.dvr_window_seconds, fs.created_at,
+			fs.scheduled_start_at, fs.cover_image_url,
 			COALESCE(u.username, 'Unknown Streamer') as username
 		FROM filtered_streams fs
 		LEFT JOIN users u ON fs.user_id = u.id
+		ORDER BY ` + clauses[1] + `
 	`
 
 	rows, err := r.db.Query(query)
@@ -296,19 +352,58 @@ func (r *StreamRepository) GetLiveStreams() ([]*models.Stream, error) {
 	}
 
 	defer rows.Close()
+	return scanStreamRows(rows)
+}
+
+// GetUpcomingStreams returns scheduled streams, nearest first
+func (r *StreamRepository) GetUpcomingStreams(limit int) ([]*models.Stream, error) {
+	query := `
+		WITH filtered_streams AS (
+			SELECT 
+				id, user_id, title, description, status, viewer_count,
+				started_at, ended_at, thumbnail_url, hls_url, available_qualities,
+				abr_preset, abr_ladder, low_latency, dvr_window_seconds, created_at,
+				scheduled_start_at, cover_image_url
+			FROM streams
+			WHERE status = 'scheduled'
+			ORDER BY scheduled_start_at ASC
+			LIMIT $1
+		)
+		SELECT
+			fs.id, fs.user_id, fs.title, fs.description, 
+			fs.status, fs.viewer_count, fs.started_at, fs.ended_at, 
+			fs.thumbnail_url, fs.hls_url, fs.available_qualities,
+			fs.abr_preset, fs.abr_ladder, fs.low_latency, fs.dvr_window_seconds, fs.created_at,
+			fs.scheduled_start_at, fs.cover_image_url,
+			COALESCE(u.username, 'Unknown Streamer') as username
+		FROM filtered_streams fs
+		LEFT JOIN users u ON fs.user_id = u.id
+		ORDER BY fs.scheduled_start_at ASC
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upcoming streams: %w", err)
+	}
+
+	defer rows.Close()
+	return scanStreamRows(rows)
+}
+
+// scanStreamRows читает выборку стримов с username (GetUserStreams, GetLiveStreams, GetUpcomingStreams)
+func scanStreamRows(rows *sql.Rows) ([]*models.Stream, error) {
 	var streams []*models.Stream
 
 	for rows.Next() {
 		stream := &models.Stream{}
-		var startedAt, endedAt sql.NullTime
-		var thumbnailURL, hlsURL sql.NullString
-		var qualities []string
+		var startedAt, endedAt, scheduledStartAt sql.NullTime
+		var thumbnailURL, hlsURL, coverImageURL sql.NullString
+		var qualities, ladder []string
 		var username string
 
 		err := rows.Scan(
 			&stream.ID,
 			&stream.UserID,
-			&stream.StreamKey,
 			&stream.Title,
 			&stream.Description,
 			&stream.Status,
@@ -318,7 +413,13 @@ func (r *StreamRepository) GetLiveStreams() ([]*models.Stream, error) {
 			&thumbnailURL,
 			&hlsURL,
 			pq.Array(&qualities),
+			&stream.ABRPreset,
+			pq.Array(&ladder),
+			&stream.LowLatency,
+			&stream.DVRWindowSeconds,
 			&stream.CreatedAt,
+			&scheduledStartAt,
+			&coverImageURL,
 			&username,
 		)
 
@@ -342,23 +443,57 @@ func (r *StreamRepository) GetLiveStreams() ([]*models.Stream, error) {
 			stream.HLSURL = hlsURL.String
 		}
 
+		if scheduledStartAt.Valid {
+			stream.ScheduledStartAt = &scheduledStartAt.Time
+		}
+
+		if coverImageURL.Valid {
+			stream.CoverImageURL = coverImageURL.String
+		}
+
 		stream.Username = username
 		stream.AvailableQualities = pq.StringArray(qualities)
+		stream.ABRLadder = pq.StringArray(ladder)
 		streams = append(streams, stream)
 	}
 
 	return streams, nil
 }
 
-// UpdateStream updates stream title and description
-func (r *StreamRepository) UpdateStream(stream *models.Stream) error {
+// StreamUpdate - fields of a stream the owner can change; nil fields are left as is.
+// ABR, low latency and DVR settings apply to the next broadcast
+type StreamUpdate struct {
+	Title            string
+	Description      string
+	ABRPreset        *string
+	ABRLadder        []string // set together with ABRPreset
+	LowLatency       *bool
+	DVRWindowSeconds *int
+}
+
+// UpdateStream updates stream title, description and broadcast settings in one statement
+func (r *StreamRepository) UpdateStream(streamID uuid.UUID, update StreamUpdate) error {
 	query := `
 		UPDATE streams
-		SET title = $1, description = $2, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $3
+		SET title = $1,
+		    description = $2,
+		    abr_preset = COALESCE($3, abr_preset),
+		    abr_ladder = COALESCE($4::text[], abr_ladder),
+		    low_latency = COALESCE($5, low_latency),
+		    dvr_window_seconds = COALESCE($6, dvr_window_seconds),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
 	`
 
-	_, err := r.db.Exec(query, stream.Title, stream.Description, stream.ID)
+	var abrLadder interface{}
+	if update.ABRPreset != nil {
+		abrLadder = pq.Array(update.ABRLadder)
+	}
+
+	_, err := r.db.Exec(query,
+		update.Title, update.Description, update.ABRPreset, abrLadder,
+		update.LowLatency, update.DVRWindowSeconds, streamID,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to update stream: %w", err)
 	}
@@ -366,58 +501,312 @@ func (r *StreamRepository) UpdateStream(stream *models.Stream) error {
 	return nil
 }
 
-// UpdateStreamStatus updates stream status (live/offline)
+// UpdateStreamQualities updates qualities actually produced by the transcoder
+func (r *StreamRepository) UpdateStreamQualities(streamID uuid.UUID, qualities []string) error {
+	query := `
+		UPDATE streams
+		SET available_qualities = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	_, err := r.db.Exec(query, pq.Array(qualities), streamID)
+	if err != nil {
+		return fmt.Errorf("failed to update available qualities: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateStreamStatus переводит стрим в новый статус.
+// Недопустимый переход возвращает *models.StatusTransitionError
 func (r *StreamRepository) UpdateStreamStatus(streamID uuid.UUID, status string) error {
+	return r.updateStreamStatus(r.db, streamID, status)
+}
+
+// UpdateStreamStatusWithEvents меняет статус и сохраняет события в outbox одной транзакцией
+func (r *StreamRepository) UpdateStreamStatusWithEvents(streamID uuid.UUID, status string, events ...outbox.Event) error {
+	return outbox.WithTx(context.Background(), r.db, OutboxSource, func(tx *sql.Tx) error {
+		return r.updateStreamStatus(tx, streamID, status)
+	}, events...)
+}
+
+func (r *StreamRepository) updateStreamStatus(exec outbox.Execer, streamID uuid.UUID, status string) error {
+	ctx := context.Background()
 	now := time.Now()
+	sources := pq.Array(models.TransitionSources(status))
 
-	if status == "live" {
-		query := `
+	var query string
+	args := []interface{}{status, now, streamID, sources}
+	switch status {
+	case models.StatusLive:
+		query = `
 			UPDATE streams
 			SET status = $1,
 			    started_at = COALESCE(started_at, $2),
 			    ended_at = NULL,
 			    updated_at = CURRENT_TIMESTAMP
-			WHERE id = $3
+			WHERE id = $3 AND status = ANY($4)
 		`
-
-		_, err := r.db.Exec(query, status, now, streamID)
-		if err != nil {
-			return fmt.Errorf("failed to update stream status to live: %w", err)
-		}
-
-		log.Printf("✅ Stream %s status updated to 'live'", streamID)
-	} else if status == "offline" {
-		query := `
+	case models.StatusOffline, models.StatusError:
+		// Истёкшее расписание - не эфир, ended_at не трогаем
+		query = `
 			UPDATE streams
 			SET status = $1,
-			    ended_at = $2,
+			    ended_at = CASE WHEN status = 'scheduled' THEN ended_at ELSE $2 END,
+			    viewer_count = 0,
 			    updated_at = CURRENT_TIMESTAMP
-			WHERE id = $3
+			WHERE id = $3 AND status = ANY($4)
 		`
-
-		_, err := r.db.Exec(query, status, now, streamID)
-		if err != nil {
-			return fmt.Errorf("failed to update stream status to offline: %w", err)
-		}
-
-		log.Printf("✅ Stream %s status updated to 'offline'", streamID)
-	} else {
-		query := `
+	case models.StatusStarting, models.StatusStopping:
+		query = `
 			UPDATE streams
 			SET status = $1,
 			    updated_at = CURRENT_TIMESTAMP
-			WHERE id = $2
+			WHERE id = $2 AND status = ANY($3)
+		`
+		args = []interface{}{status, streamID, sources}
+	default:
+		// scheduled выставляется только вместе со временем начала (ScheduleStream)
+		return fmt.Errorf("unsupported stream status %q", status)
+	}
+
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update stream status to %s: %w", status, err)
+	}
+	if err := r.checkTransition(result, streamID, status); err != nil {
+		return err
+	}
+
+	switch status {
+	case models.StatusLive:
+		// Новый эфир: незакрытый после сбоя эфир закрываем
+		if err := r.closeBroadcast(ctx, exec, streamID, now); err != nil {
+			return err
+		}
+		broadcastQuery := `
+			INSERT INTO stream_broadcasts (stream_id, started_at)
+			VALUES ($1, $2)
 		`
+		if _, err := exec.ExecContext(ctx, broadcastQuery, streamID, now); err != nil {
+			return fmt.Errorf("failed to start broadcast: %w", err)
+		}
+	case models.StatusOffline, models.StatusError:
+		if err := r.closeBroadcast(ctx, exec, streamID, now); err != nil {
+			return err
+		}
+	}
 
-		_, err := r.db.Exec(query, status, streamID)
-		if err != nil {
-			return fmt.Errorf("failed to update stream status: %w", err)
+	log.Printf("✅ Stream %s status updated to '%s'", streamID, status)
+	return nil
+}
+
+// checkTransition превращает UPDATE без затронутых строк в ошибку перехода
+func (r *StreamRepository) checkTransition(result sql.Result, streamID uuid.UUID, to string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var current string
+	err = r.db.QueryRow(`SELECT status FROM streams WHERE id = $1`, streamID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("stream not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stream status: %w", err)
+	}
+	return &models.StatusTransitionError{From: current, To: to}
+}
+
+// ScheduleStream планирует эфир (или переносит уже запланированный) на startAt
+func (r *StreamRepository) ScheduleStream(streamID uuid.UUID, startAt time.Time, coverImageURL string) error {
+	query := `
+		UPDATE streams
+		SET status = $1,
+		    scheduled_start_at = $2,
+		    cover_image_url = NULLIF($3, ''),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = ANY($5)
+	`
+
+	result, err := r.db.Exec(query, models.StatusScheduled, startAt, coverImageURL, streamID, pq.Array(models.TransitionSources(models.StatusScheduled)))
+	if err != nil {
+		return fmt.Errorf("failed to schedule stream: %w", err)
+	}
+	return r.checkTransition(result, streamID, models.StatusScheduled)
+}
+
+// ExpireScheduledStreams переводит в offline стримы, запланированные раньше deadline
+// и так и не начавшиеся. Возвращает ID истёкших стримов
+func (r *StreamRepository) ExpireScheduledStreams(deadline time.Time) ([]uuid.UUID, error) {
+	query := `
+		UPDATE streams
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND scheduled_start_at < $3
+		RETURNING id
+	`
+
+	rows, err := r.db.Query(query, models.StatusOffline, models.StatusScheduled, deadline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire scheduled streams: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stream ID: %w", err)
 		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RecoverInterruptedStreams вызывается при старте сервиса: эфиры, оборванные
+// падением процесса (starting/live/stopping), переводятся в error
+func (r *StreamRepository) RecoverInterruptedStreams() (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE streams
+		SET status = $1,
+		    ended_at = CURRENT_TIMESTAMP,
+		    viewer_count = 0,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE status = ANY($2)
+	`
+	result, err := tx.Exec(query, models.StatusError, pq.Array([]string{models.StatusStarting, models.StatusLive, models.StatusStopping}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover interrupted streams: %w", err)
+	}
+
+	broadcastsQuery := `
+		UPDATE stream_broadcasts b
+		SET ended_at = CURRENT_TIMESTAMP
+		FROM streams s
+		WHERE b.stream_id = s.id AND b.ended_at IS NULL AND s.status = $1
+	`
+	if _, err := tx.Exec(broadcastsQuery, models.StatusError); err != nil {
+		return 0, fmt.Errorf("failed to close interrupted broadcasts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit recovery: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *StreamRepository) closeBroadcast(ctx context.Context, exec outbox.Execer, streamID uuid.UUID, endedAt time.Time) error {
+	query := `
+		UPDATE stream_broadcasts
+		SET ended_at = $2
+		WHERE stream_id = $1 AND ended_at IS NULL
+	`
+
+	if _, err := exec.ExecContext(ctx, query, streamID, endedAt); err != nil {
+		return fmt.Errorf("failed to end broadcast: %w", err)
+	}
+	return nil
+}
+
+// RecordViewerCounts сохраняет текущее число зрителей live стримов и добавляет
+// замер в статистику идущего эфира (пик и среднее)
+func (r *StreamRepository) RecordViewerCounts(counts map[uuid.UUID]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(counts))
+	viewers := make([]int64, 0, len(counts))
+	for id, count := range counts {
+		ids = append(ids, id.String())
+		viewers = append(viewers, int64(count))
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	streamsQuery := `
+		UPDATE streams s
+		SET viewer_count = c.viewers
+		FROM unnest($1::uuid[], $2::int[]) AS c(stream_id, viewers)
+		WHERE s.id = c.stream_id AND s.status = 'live' AND s.viewer_count <> c.viewers
+	`
+	if _, err := tx.Exec(streamsQuery, pq.Array(ids), pq.Array(viewers)); err != nil {
+		return fmt.Errorf("failed to update viewer counts: %w", err)
+	}
+
+	broadcastsQuery := `
+		UPDATE stream_broadcasts b
+		SET peak_viewers = GREATEST(b.peak_viewers, c.viewers),
+		    avg_viewers = (b.avg_viewers * b.viewer_samples + c.viewers) / (b.viewer_samples + 1),
+		    viewer_samples = b.viewer_samples + 1
+		FROM unnest($1::uuid[], $2::int[]) AS c(stream_id, viewers)
+		WHERE b.stream_id = c.stream_id AND b.ended_at IS NULL
+	`
+	if _, err := tx.Exec(broadcastsQuery, pq.Array(ids), pq.Array(viewers)); err != nil {
+		return fmt.Errorf("failed to update broadcast statistics: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit viewer counts: %w", err)
+	}
 	return nil
 }
 
+// GetStreamBroadcasts returns recent broadcasts of a stream with viewer statistics
+func (r *StreamRepository) GetStreamBroadcasts(streamID uuid.UUID, limit int) ([]*models.Broadcast, error) {
+	query := `
+		SELECT id, stream_id, started_at, ended_at, peak_viewers, avg_viewers
+		FROM stream_broadcasts
+		WHERE stream_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(query, streamID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get broadcasts: %w", err)
+	}
+	defer rows.Close()
+
+	broadcasts := []*models.Broadcast{}
+	for rows.Next() {
+		broadcast := &models.Broadcast{}
+		var endedAt sql.NullTime
+
+		if err := rows.Scan(
+			&broadcast.ID,
+			&broadcast.StreamID,
+			&broadcast.StartedAt,
+			&endedAt,
+			&broadcast.PeakViewers,
+			&broadcast.AvgViewers,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast: %w", err)
+		}
+
+		if endedAt.Valid {
+			broadcast.EndedAt = &endedAt.Time
+		}
+		broadcasts = append(broadcasts, broadcast)
+	}
+
+	return broadcasts, rows.Err()
+}
+
 // UpdateStreamThumbnail updates stream thumbnail URL
 func (r *StreamRepository) UpdateStreamThumbnail(streamID uuid.UUID, thumbnailURL string) error {
 	query := `
@@ -0,0 +1,151 @@
+// Package restream - рестрим (simulcast): входящий поток публикации параллельно
+// с локальным транскодированием уходит на внешние площадки по RTMP/SRT.
+// Поток ingest перепаковывается одним relay ffmpeg в MPEG-TS, который раздаётся
+// выходам: к TS можно подключиться с любого места, поэтому выход переподключается
+// посреди эфира независимо от остальных
+package restream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ValidateURL проверяет адрес выхода и возвращает протокол (rtmp или srt)
+func ValidateURL(raw string) (string, error) {
+	invalid := fmt.Errorf("url must be an rtmp://, rtmps:// or srt:// address")
+
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", invalid
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "rtmp", "rtmps":
+		return "rtmp", nil
+	case "srt":
+		if u.Port() == "" {
+			return "", fmt.Errorf("srt url must include a port")
+		}
+		return "srt", nil
+	}
+	return "", invalid
+}
+
+// Manager ведёт рестрим идущих публикаций
+type Manager struct {
+	destRepo *repository.DestinationRepository
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*Session // stream ID → рестрим идущей публикации
+}
+
+func NewManager(destRepo *repository.DestinationRepository) *Manager {
+	return &Manager{
+		destRepo: destRepo,
+		sessions: make(map[uuid.UUID]*Session),
+	}
+}
+
+// Begin начинает рестрим публикации: в Session пишется входящий поток.
+// Relay запускается только если у стрима есть выходы - иначе данные отбрасываются.
+// Выход, добавленный к стриму без выходов посреди эфира, начнёт работу со следующего эфира:
+// relay должен видеть поток с начала (заголовки FLV/WebM)
+func (m *Manager) Begin(streamID uuid.UUID, protocol string) *Session {
+	session := &Session{manager: m, streamID: streamID}
+
+	destinations, err := m.destRepo.ListDestinations(streamID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load restream destinations of stream %s: %v", streamID, err)
+		return session
+	}
+	if len(destinations) == 0 {
+		return session
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session.ctx, session.cancel = ctx, cancel
+	session.targets = make(map[uuid.UUID]*target)
+	session.subscribers = make(map[*subscriber]struct{})
+
+	if err := session.startRelay(ctx, protocol); err != nil {
+		cancel()
+		log.Printf("❌ Failed to start restream relay for stream %s: %v", streamID, err)
+		return &Session{manager: m, streamID: streamID}
+	}
+
+	for _, destination := range destinations {
+		if destination.Enabled {
+			session.startTarget(destination)
+		}
+	}
+
+	m.mu.Lock()
+	m.sessions[streamID] = session
+	m.mu.Unlock()
+
+	log.Printf("📤 Restreaming stream %s to %d destination(s)", streamID, len(session.targets))
+	return session
+}
+
+// Apply применяет изменённый выход к идущему эфиру: включённый (пере)запускается
+// с новыми настройками, выключенный останавливается
+func (m *Manager) Apply(destination *models.Destination) {
+	session, ok := m.session(destination.StreamID)
+	if !ok {
+		return
+	}
+
+	if destination.Enabled {
+		session.startTarget(destination)
+	} else {
+		session.stopTarget(destination.ID)
+	}
+}
+
+// Remove останавливает удалённый выход
+func (m *Manager) Remove(streamID, destinationID uuid.UUID) {
+	if session, ok := m.session(streamID); ok {
+		session.stopTarget(destinationID)
+	}
+}
+
+// Describe заполняет состояние выходов (Status, LastError)
+func (m *Manager) Describe(destinations ...*models.Destination) {
+	for _, destination := range destinations {
+		destination.Status, destination.LastError = m.status(destination)
+	}
+}
+
+func (m *Manager) status(destination *models.Destination) (string, string) {
+	if !destination.Enabled {
+		return models.DestinationStopped, ""
+	}
+
+	session, ok := m.session(destination.StreamID)
+	if !ok {
+		return models.DestinationIdle, ""
+	}
+
+	session.mu.Lock()
+	t, ok := session.targets[destination.ID]
+	session.mu.Unlock()
+	if !ok {
+		return models.DestinationIdle, ""
+	}
+	return t.state()
+}
+
+func (m *Manager) session(streamID uuid.UUID) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[streamID]
+	return session, ok
+}
@@ -0,0 +1,249 @@
+package restream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	feedBuffer     = 512       // чанков ingest в очереди relay
+	targetBuffer   = 256       // чанков MPEG-TS в очереди одного выхода
+	relayChunkSize = 64 * 1024 // чтение MPEG-TS из relay
+)
+
+// Session - рестрим одной публикации. Пишется как io.Writer (копия входящего потока)
+// и никогда не тормозит локальное транскодирование: при переполнении очередей данные теряются
+type Session struct {
+	manager  *Manager
+	streamID uuid.UUID
+	ctx      context.Context
+	cancel   context.CancelFunc // nil - выходов нет, рестрим не идёт
+	feed     chan []byte
+
+	mu          sync.Mutex
+	closed      bool
+	targets     map[uuid.UUID]*target
+	subscribers map[*subscriber]struct{}
+	relayDone   bool
+	dropped     int
+}
+
+// subscriber - очередь MPEG-TS одного подключения выхода
+type subscriber struct {
+	ch chan []byte
+}
+
+// Write передаёт копию входящего потока relay
+func (s *Session) Write(p []byte) (int, error) {
+	if s.cancel == nil {
+		return len(p), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return len(p), nil
+	}
+
+	select {
+	case s.feed <- append([]byte(nil), p...):
+	default:
+		// relay не успевает: теряем кусок, но не задерживаем ingest
+		if s.dropped++; s.dropped == 1 {
+			log.Printf("⚠️ Restream relay of stream %s is falling behind, dropping input", s.streamID)
+		}
+	}
+	return len(p), nil
+}
+
+// End останавливает relay и все выходы публикации
+func (s *Session) End() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.manager.mu.Lock()
+	if s.manager.sessions[s.streamID] == s {
+		delete(s.manager.sessions, s.streamID)
+	}
+	s.manager.mu.Unlock()
+
+	s.mu.Lock()
+	s.closed = true
+	close(s.feed)
+	s.mu.Unlock()
+
+	s.cancel()
+	log.Printf("📤 Restreaming of stream %s stopped", s.streamID)
+}
+
+// startRelay запускает ffmpeg, перепаковывающий входящий поток в MPEG-TS
+func (s *Session) startRelay(ctx context.Context, protocol string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", relayArgs(protocol)...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	s.feed = make(chan []byte, feedBuffer)
+	go func() {
+		failed := false
+		for chunk := range s.feed {
+			// Очередь вычитывается до конца, даже если relay упал
+			if !failed {
+				_, err := stdin.Write(chunk)
+				failed = err != nil
+			}
+		}
+		stdin.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, relayChunkSize)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				s.broadcast(append([]byte(nil), buf[:n]...))
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Printf("❌ Restream relay of stream %s failed: %v", s.streamID, err)
+		}
+		s.closeSubscribers()
+	}()
+
+	return nil
+}
+
+// relayArgs - перепаковка ingest в MPEG-TS. WHIP приходит в WebM (VP8/Opus),
+// а площадки принимают H.264/AAC - его приходится транскодировать
+func relayArgs(protocol string) []string {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+	}
+
+	if protocol == "WHIP" {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-tune", "zerolatency",
+			"-g", "60",
+			"-c:a", "aac",
+			"-b:a", "128k",
+		)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	return append(args, "-f", "mpegts", "pipe:1")
+}
+
+// broadcast раздаёт MPEG-TS выходам. Отставший выход отключается и переподключится
+func (s *Session) broadcast(chunk []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers {
+		select {
+		case sub.ch <- chunk:
+		default:
+			log.Printf("⚠️ Restream destination of stream %s is too slow, reconnecting it", s.streamID)
+			delete(s.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (s *Session) subscribe() (*subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.relayDone {
+		return nil, fmt.Errorf("restream relay stopped")
+	}
+
+	sub := &subscriber{ch: make(chan []byte, targetBuffer)}
+	s.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+func (s *Session) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// closeSubscribers - relay завершился, выходы получают конец потока
+func (s *Session) closeSubscribers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.relayDone = true
+	for sub := range s.subscribers {
+		close(sub.ch)
+	}
+	s.subscribers = make(map[*subscriber]struct{})
+}
+
+// startTarget (пере)запускает выход
+func (s *Session) startTarget(destination *models.Destination) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	if previous, ok := s.targets[destination.ID]; ok {
+		previous.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &target{
+		destination: destination,
+		cancel:      cancel,
+		status:      models.DestinationConnecting,
+	}
+	s.targets[destination.ID] = t
+	go s.runTarget(ctx, t)
+}
+
+func (s *Session) stopTarget(destinationID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.targets[destinationID]; ok {
+		t.cancel()
+		delete(s.targets, destinationID)
+	}
+}
+
+var _ io.Writer = (*Session)(nil)
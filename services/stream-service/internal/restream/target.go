@@ -0,0 +1,189 @@
+package restream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+)
+
+const (
+	reconnectInitial = 2 * time.Second
+	reconnectMax     = 30 * time.Second
+	// stableAfter - после столько времени в эфире выход считается стабильным, задержка переподключения сбрасывается
+	stableAfter = 30 * time.Second
+)
+
+// target - один выход рестрима: свой ffmpeg, своё состояние и переподключение
+type target struct {
+	destination *models.Destination
+	cancel      context.CancelFunc
+
+	mu        sync.Mutex
+	status    string
+	lastError string
+}
+
+func (t *target) state() (string, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status, t.lastError
+}
+
+func (t *target) setState(status, lastError string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = status
+	t.lastError = lastError
+}
+
+// runTarget держит выход подключённым до конца эфира или остановки выхода
+func (s *Session) runTarget(ctx context.Context, t *target) {
+	d := t.destination
+	delay := reconnectInitial
+
+	for {
+		started := time.Now()
+		err := s.push(ctx, t)
+		if ctx.Err() != nil {
+			t.setState(models.DestinationStopped, "")
+			log.Printf("⏹️ Restream to %s (%s) stopped", d.Name, d.Protocol)
+			return
+		}
+
+		if time.Since(started) > stableAfter {
+			delay = reconnectInitial
+		}
+
+		message := "connection closed"
+		if err != nil {
+			message = err.Error()
+		}
+		t.setState(models.DestinationReconnecting, message)
+		log.Printf("🔄 Restream to %s (%s) dropped: %s, reconnecting in %v", d.Name, d.Protocol, message, delay)
+
+		select {
+		case <-ctx.Done():
+			t.setState(models.DestinationStopped, "")
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > reconnectMax {
+			delay = reconnectMax
+		}
+		t.setState(models.DestinationConnecting, message)
+	}
+}
+
+// push - одно подключение выхода: MPEG-TS из relay без перекодирования уходит на площадку
+func (s *Session) push(ctx context.Context, t *target) error {
+	sub, err := s.subscribe()
+	if err != nil {
+		return err
+	}
+	defer s.unsubscribe(sub)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", pushArgs(t.destination)...)
+
+	stderr := &lastLine{}
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	// Первый блок -progress - пакеты пошли на площадку
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		live := false
+		for scanner.Scan() {
+			if !live && strings.HasPrefix(scanner.Text(), "progress=") {
+				live = true
+				t.setState(models.DestinationLive, "")
+				log.Printf("✅ Restreaming to %s (%s)", t.destination.Name, t.destination.Protocol)
+			}
+		}
+	}()
+
+	go func() {
+		defer stdin.Close()
+		for chunk := range sub.ch {
+			if _, err := stdin.Write(chunk); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if line := stderr.String(); line != "" {
+			return fmt.Errorf("%s", line)
+		}
+		return err
+	}
+	return nil
+}
+
+func pushArgs(d *models.Destination) []string {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-progress", "pipe:1",
+		"-f", "mpegts",
+		"-i", "pipe:0",
+		"-c", "copy",
+	}
+
+	if d.Protocol == "srt" {
+		return append(args, "-f", "mpegts", d.URL)
+	}
+	return append(args, "-f", "flv", d.URL)
+}
+
+// lastLine запоминает последнюю непустую строку вывода - причину обрыва для владельца
+type lastLine struct {
+	mu   sync.Mutex
+	buf  []byte
+	last string
+}
+
+func (w *lastLine) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimSpace(string(w.buf[:i])); line != "" {
+			w.last = line
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lastLine) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
@@ -0,0 +1,218 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// AMF0 type markers
+const (
+	amfNumber      = 0x00
+	amfBoolean     = 0x01
+	amfString      = 0x02
+	amfObject      = 0x03
+	amfNull        = 0x05
+	amfUndefined   = 0x06
+	amfECMAArray   = 0x08
+	amfObjectEnd   = 0x09
+	amfStrictArray = 0x0a
+	amfDate        = 0x0b
+	amfLongString  = 0x0c
+)
+
+// maxAMFDepth - предел вложенности объектов и массивов: команды разбираются
+// до проверки ключа стрима, глубокая вложенность не должна исчерпать стек
+const maxAMFDepth = 32
+
+// amfObj - AMF0 объект (порядок ключей для RTMP не важен)
+type amfObj map[string]interface{}
+
+// decodeAMF0 декодирует последовательность AMF0 значений (команды RTMP)
+func decodeAMF0(data []byte) ([]interface{}, error) {
+	r := bytes.NewReader(data)
+	var values []interface{}
+	for r.Len() > 0 {
+		v, err := decodeAMF0Value(r, 0)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func decodeAMF0Value(r *bytes.Reader, depth int) (interface{}, error) {
+	if depth > maxAMFDepth {
+		return nil, fmt.Errorf("amf: nesting deeper than %d", maxAMFDepth)
+	}
+
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch marker {
+	case amfNumber:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+
+	case amfBoolean:
+		b, err := r.ReadByte()
+		return b != 0, err
+
+	case amfString:
+		return readAMFString(r)
+
+	case amfObject:
+		return readAMFObject(r, depth+1)
+
+	case amfNull, amfUndefined:
+		return nil, nil
+
+	case amfECMAArray:
+		// Количество элементов (подсказка), дальше формат как у объекта
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return readAMFObject(r, depth+1)
+
+	case amfStrictArray:
+		var count uint32
+		if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+			return nil, err
+		}
+		if int(count) > r.Len() {
+			return nil, fmt.Errorf("amf: strict array too long: %d", count)
+		}
+		arr := make([]interface{}, 0, count)
+		for i := uint32(0); i < count; i++ {
+			v, err := decodeAMF0Value(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+
+	case amfDate:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		// timezone (2 байта) игнорируется
+		if _, err := r.Seek(2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+
+	case amfLongString:
+		var length uint32
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, err
+		}
+		if int(length) > r.Len() {
+			return nil, fmt.Errorf("amf: long string too long: %d", length)
+		}
+		buf := make([]byte, length)
+		_, err := io.ReadFull(r, buf)
+		return string(buf), err
+	}
+
+	return nil, fmt.Errorf("amf: unsupported type marker 0x%02x", marker)
+}
+
+func readAMFString(r *bytes.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if int(length) > r.Len() {
+		return "", fmt.Errorf("amf: string too long: %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readAMFObject(r *bytes.Reader, depth int) (amfObj, error) {
+	obj := make(amfObj)
+	for {
+		key, err := readAMFString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "" {
+			marker, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if marker == amfObjectEnd {
+				return obj, nil
+			}
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+		}
+
+		v, err := decodeAMF0Value(r, depth)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+	}
+}
+
+// encodeAMF0 кодирует значения в AMF0 (поддерживаются типы, которые отправляет сервер)
+func encodeAMF0(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		writeAMF0Value(&buf, v)
+	}
+	return buf.Bytes()
+}
+
+func writeAMF0Value(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(amfNull)
+	case bool:
+		buf.WriteByte(amfBoolean)
+		if val {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case int:
+		writeAMF0Value(buf, float64(val))
+	case float64:
+		buf.WriteByte(amfNumber)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(val))
+	case string:
+		buf.WriteByte(amfString)
+		writeAMFString(buf, val)
+	case amfObj:
+		buf.WriteByte(amfObject)
+		for key, item := range val {
+			writeAMFString(buf, key)
+			writeAMF0Value(buf, item)
+		}
+		writeAMFString(buf, "")
+		buf.WriteByte(amfObjectEnd)
+	default:
+		buf.WriteByte(amfUndefined)
+	}
+}
+
+func writeAMFString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
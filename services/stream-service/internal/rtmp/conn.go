@@ -0,0 +1,560 @@
+package rtmp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	handshakeSize    = 1536
+	defaultChunkSize = 128
+	outChunkSize     = 4096
+	windowAckSize    = 2500000
+	maxMessageSize   = 16 << 20 // защита от некорректных заголовков
+	publishStreamID  = 1
+
+	// До подтверждения публикации приходят только команды (connect, createStream,
+	// publish): меньший предел не даёт неавторизованному клиенту занять память
+	maxCommandSize = 64 << 10
+
+	// maxChunkStreams - сколько chunk stream держит одно соединение (OBS и ffmpeg используют 3-6)
+	maxChunkStreams = 32
+)
+
+// Типы сообщений RTMP
+const (
+	msgSetChunkSize     = 1
+	msgAbort            = 2
+	msgAck              = 3
+	msgUserControl      = 4
+	msgWindowAckSize    = 5
+	msgSetPeerBandwidth = 6
+	msgAudio            = 8
+	msgVideo            = 9
+	msgDataAMF3         = 15
+	msgCommandAMF3      = 17
+	msgDataAMF0         = 18
+	msgCommandAMF0      = 20
+)
+
+// Chunk stream IDs для исходящих сообщений
+const (
+	csidProtocol = 2
+	csidCommand  = 3
+)
+
+type message struct {
+	typeID    uint8
+	streamID  uint32
+	timestamp uint32
+	payload   []byte
+}
+
+// chunkStream хранит состояние заголовков и недособранное сообщение одного chunk stream
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typeID    uint8
+	streamID  uint32
+	extended  bool
+	buf       []byte
+}
+
+// Conn - серверная сторона RTMP соединения издателя
+type Conn struct {
+	nc          net.Conn
+	br          *bufio.Reader
+	bw          *bufio.Writer
+	inChunkSize uint32
+	maxMessage  uint32 // maxCommandSize до AcceptPublish, затем maxMessageSize
+	streams     map[uint32]*chunkStream
+	received    uint32
+	lastAck     uint32
+	idleTimeout time.Duration
+
+	App       string
+	StreamKey string
+}
+
+func NewConn(nc net.Conn, idleTimeout time.Duration) *Conn {
+	return &Conn{
+		nc:          nc,
+		br:          bufio.NewReaderSize(nc, 64*1024),
+		bw:          bufio.NewWriterSize(nc, 64*1024),
+		inChunkSize: defaultChunkSize,
+		maxMessage:  maxCommandSize,
+		streams:     make(map[uint32]*chunkStream),
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.nc.RemoteAddr()
+}
+
+func (c *Conn) Close() error {
+	return c.nc.Close()
+}
+
+// Handshake выполняет простой (без digest) RTMP handshake
+func (c *Conn) Handshake() error {
+	c.nc.SetDeadline(time.Now().Add(c.idleTimeout))
+	defer c.nc.SetDeadline(time.Time{})
+
+	c0c1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(c.br, c0c1); err != nil {
+		return fmt.Errorf("failed to read C0/C1: %w", err)
+	}
+	if c0c1[0] != 3 {
+		return fmt.Errorf("unsupported RTMP version: %d", c0c1[0])
+	}
+
+	// S0 + S1 (time, zero, random) + S2 (эхо C1)
+	s := make([]byte, 1+2*handshakeSize)
+	s[0] = 3
+	if _, err := rand.Read(s[9 : 1+handshakeSize]); err != nil {
+		return err
+	}
+	copy(s[1+handshakeSize:], c0c1[1:])
+
+	if _, err := c.bw.Write(s); err != nil {
+		return err
+	}
+	if err := c.bw.Flush(); err != nil {
+		return err
+	}
+
+	c2 := make([]byte, handshakeSize)
+	if _, err := io.ReadFull(c.br, c2); err != nil {
+		return fmt.Errorf("failed to read C2: %w", err)
+	}
+
+	return nil
+}
+
+// ReadPublish обрабатывает команды connect/createStream/publish
+// и возвращается когда клиент запросил публикацию
+func (c *Conn) ReadPublish() error {
+	c.nc.SetDeadline(time.Now().Add(c.idleTimeout))
+	defer c.nc.SetDeadline(time.Time{})
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+
+		if msg.typeID != msgCommandAMF0 && msg.typeID != msgCommandAMF3 {
+			continue
+		}
+
+		values, err := decodeCommand(msg)
+		if err != nil {
+			return fmt.Errorf("failed to decode command: %w", err)
+		}
+		if len(values) < 2 {
+			continue
+		}
+
+		name, _ := values[0].(string)
+		txID, _ := values[1].(float64)
+
+		switch name {
+		case "connect":
+			if len(values) > 2 {
+				if obj, ok := values[2].(amfObj); ok {
+					app, _ := obj["app"].(string)
+					c.App = strings.Trim(app, "/")
+				}
+			}
+			if err := c.onConnect(txID); err != nil {
+				return err
+			}
+
+		case "releaseStream", "FCPublish":
+			if err := c.writeCommand(csidCommand, 0, "_result", txID, nil); err != nil {
+				return err
+			}
+
+		case "createStream":
+			if err := c.writeCommand(csidCommand, 0, "_result", txID, nil, publishStreamID); err != nil {
+				return err
+			}
+
+		case "publish":
+			if len(values) < 4 {
+				return fmt.Errorf("publish command without stream name")
+			}
+			name, _ := values[3].(string)
+			// OBS может передавать параметры после "?"
+			if i := strings.Index(name, "?"); i >= 0 {
+				name = name[:i]
+			}
+			c.StreamKey = name
+			return nil
+
+		case "deleteStream", "FCUnpublish", "closeStream":
+			return fmt.Errorf("client closed stream before publishing")
+		}
+	}
+}
+
+// AcceptPublish подтверждает начало публикации: с этого момента принимаются медиа сообщения
+func (c *Conn) AcceptPublish() error {
+	c.maxMessage = maxMessageSize
+
+	// User Control: Stream Begin
+	event := make([]byte, 6)
+	binary.BigEndian.PutUint32(event[2:], publishStreamID)
+	if err := c.writeMessage(csidProtocol, msgUserControl, 0, event); err != nil {
+		return err
+	}
+
+	return c.writeCommand(csidCommand, publishStreamID, "onStatus", 0, nil, amfObj{
+		"level":       "status",
+		"code":        "NetStream.Publish.Start",
+		"description": "Publishing " + c.StreamKey,
+	})
+}
+
+// RejectPublish отклоняет публикацию с заданным кодом статуса
+func (c *Conn) RejectPublish(code, description string) error {
+	return c.writeCommand(csidCommand, publishStreamID, "onStatus", 0, nil, amfObj{
+		"level":       "error",
+		"code":        code,
+		"description": description,
+	})
+}
+
+// ErrUnpublished - издатель завершил публикацию командой (deleteStream), а не потерял связь
+var ErrUnpublished = errors.New("publisher ended the stream")
+
+// ReadMedia читает аудио/видео сообщения и пишет их в w как FLV поток.
+// Возвращает ErrUnpublished когда издатель завершил публикацию.
+func (c *Conn) ReadMedia(w io.Writer) error {
+	if _, err := w.Write(flvHeader); err != nil {
+		return err
+	}
+
+	for {
+		c.nc.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		msg, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+
+		switch msg.typeID {
+		case msgAudio, msgVideo:
+			if err := writeFLVTag(w, msg.typeID, msg.timestamp, msg.payload); err != nil {
+				return err
+			}
+
+		case msgDataAMF0:
+			if err := writeFLVTag(w, flvTagScript, msg.timestamp, stripSetDataFrame(msg.payload)); err != nil {
+				return err
+			}
+
+		case msgCommandAMF0, msgCommandAMF3:
+			values, err := decodeCommand(msg)
+			if err != nil || len(values) == 0 {
+				continue
+			}
+			switch values[0] {
+			case "deleteStream", "FCUnpublish", "closeStream":
+				return ErrUnpublished
+			}
+		}
+	}
+}
+
+func (c *Conn) onConnect(txID float64) error {
+	ackSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(ackSize, windowAckSize)
+	if err := c.writeMessage(csidProtocol, msgWindowAckSize, 0, ackSize); err != nil {
+		return err
+	}
+
+	bandwidth := make([]byte, 5)
+	binary.BigEndian.PutUint32(bandwidth, windowAckSize)
+	bandwidth[4] = 2 // dynamic
+	if err := c.writeMessage(csidProtocol, msgSetPeerBandwidth, 0, bandwidth); err != nil {
+		return err
+	}
+
+	chunkSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(chunkSize, outChunkSize)
+	if err := c.writeMessage(csidProtocol, msgSetChunkSize, 0, chunkSize); err != nil {
+		return err
+	}
+
+	return c.writeCommand(csidCommand, 0, "_result", txID,
+		amfObj{
+			"fmsVer":       "FMS/3,0,1,123",
+			"capabilities": 31,
+		},
+		amfObj{
+			"level":          "status",
+			"code":           "NetConnection.Connect.Success",
+			"description":    "Connection succeeded.",
+			"objectEncoding": 0,
+		},
+	)
+}
+
+// readMessage собирает следующее полное сообщение из chunk потока.
+// Протокольные сообщения (chunk size, abort) обрабатываются внутри.
+func (c *Conn) readMessage() (*message, error) {
+	for {
+		msg, err := c.readChunk()
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			continue
+		}
+
+		switch msg.typeID {
+		case msgSetChunkSize:
+			if len(msg.payload) < 4 {
+				return nil, fmt.Errorf("invalid set chunk size message")
+			}
+			size := binary.BigEndian.Uint32(msg.payload) & 0x7fffffff
+			if size == 0 || size > maxMessageSize {
+				return nil, fmt.Errorf("invalid chunk size: %d", size)
+			}
+			c.inChunkSize = size
+			continue
+
+		case msgAbort:
+			if len(msg.payload) >= 4 {
+				if cs, ok := c.streams[binary.BigEndian.Uint32(msg.payload)]; ok {
+					cs.buf = nil
+				}
+			}
+			continue
+
+		case msgAck, msgUserControl, msgWindowAckSize, msgSetPeerBandwidth:
+			continue
+		}
+
+		return msg, nil
+	}
+}
+
+// readChunk читает один chunk; возвращает сообщение если оно собрано полностью
+func (c *Conn) readChunk() (*message, error) {
+	b, err := c.br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	read := uint32(1)
+
+	format := b >> 6
+	csid := uint32(b & 0x3f)
+	switch csid {
+	case 0:
+		b1, err := c.br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		csid = 64 + uint32(b1)
+		read++
+	case 1:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		csid = 64 + uint32(ext[0]) + uint32(ext[1])*256
+		read += 2
+	}
+
+	cs, ok := c.streams[csid]
+	if !ok {
+		if len(c.streams) >= maxChunkStreams {
+			return nil, fmt.Errorf("too many chunk streams")
+		}
+		cs = &chunkStream{}
+		c.streams[csid] = cs
+	}
+
+	var hdr [11]byte
+	switch format {
+	case 0:
+		if _, err := io.ReadFull(c.br, hdr[:11]); err != nil {
+			return nil, err
+		}
+		read += 11
+		cs.timestamp = uint24(hdr[0:3])
+		cs.length = uint24(hdr[3:6])
+		cs.typeID = hdr[6]
+		cs.streamID = binary.LittleEndian.Uint32(hdr[7:11])
+		cs.delta = 0
+		cs.extended = cs.timestamp == 0xffffff
+		cs.buf = nil
+		if cs.extended {
+			ts, err := c.readExtendedTimestamp()
+			if err != nil {
+				return nil, err
+			}
+			cs.timestamp = ts
+			read += 4
+		}
+
+	case 1, 2:
+		size := 7
+		if format == 2 {
+			size = 3
+		}
+		if _, err := io.ReadFull(c.br, hdr[:size]); err != nil {
+			return nil, err
+		}
+		read += uint32(size)
+		cs.delta = uint24(hdr[0:3])
+		if format == 1 {
+			cs.length = uint24(hdr[3:6])
+			cs.typeID = hdr[6]
+		}
+		cs.extended = cs.delta == 0xffffff
+		if cs.extended {
+			ts, err := c.readExtendedTimestamp()
+			if err != nil {
+				return nil, err
+			}
+			cs.delta = ts
+			read += 4
+		}
+		cs.buf = nil
+		cs.timestamp += cs.delta
+
+	case 3:
+		if cs.extended {
+			if _, err := c.readExtendedTimestamp(); err != nil {
+				return nil, err
+			}
+			read += 4
+		}
+		// Новое сообщение с тем же заголовком
+		if len(cs.buf) == 0 {
+			cs.timestamp += cs.delta
+		}
+	}
+
+	if cs.length > c.maxMessage {
+		return nil, fmt.Errorf("message too large: %d bytes", cs.length)
+	}
+
+	remaining := cs.length - uint32(len(cs.buf))
+	n := remaining
+	if n > c.inChunkSize {
+		n = c.inChunkSize
+	}
+
+	// Буфер растёт по мере прихода данных, а не по длине из заголовка
+	start := len(cs.buf)
+	cs.buf = slices.Grow(cs.buf, int(n))[:start+int(n)]
+	if _, err := io.ReadFull(c.br, cs.buf[start:]); err != nil {
+		return nil, err
+	}
+	read += n
+
+	if err := c.countReceived(read); err != nil {
+		return nil, err
+	}
+
+	if uint32(len(cs.buf)) < cs.length {
+		return nil, nil
+	}
+
+	msg := &message{
+		typeID:    cs.typeID,
+		streamID:  cs.streamID,
+		timestamp: cs.timestamp,
+		payload:   cs.buf,
+	}
+	cs.buf = nil
+	return msg, nil
+}
+
+func (c *Conn) readExtendedTimestamp() (uint32, error) {
+	var ext [4]byte
+	if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(ext[:]), nil
+}
+
+// countReceived отправляет Acknowledgement после каждого окна принятых байт
+func (c *Conn) countReceived(n uint32) error {
+	c.received += n
+	if c.received-c.lastAck < windowAckSize {
+		return nil
+	}
+	c.lastAck = c.received
+
+	ack := make([]byte, 4)
+	binary.BigEndian.PutUint32(ack, c.received)
+	return c.writeMessage(csidProtocol, msgAck, 0, ack)
+}
+
+func (c *Conn) writeCommand(csid, streamID uint32, values ...interface{}) error {
+	return c.writeMessage(csid, msgCommandAMF0, streamID, encodeAMF0(values...))
+}
+
+// writeMessage отправляет сообщение: первый chunk с полным заголовком (fmt 0), остальные fmt 3
+func (c *Conn) writeMessage(csid uint32, typeID uint8, streamID uint32, payload []byte) error {
+	var hdr [12]byte
+	hdr[0] = byte(csid & 0x3f)
+	putUint24(hdr[4:7], uint32(len(payload)))
+	hdr[7] = typeID
+	binary.LittleEndian.PutUint32(hdr[8:12], streamID)
+
+	if _, err := c.bw.Write(hdr[:]); err != nil {
+		return err
+	}
+
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > outChunkSize {
+			n = outChunkSize
+		}
+		if _, err := c.bw.Write(payload[:n]); err != nil {
+			return err
+		}
+		payload = payload[n:]
+		if len(payload) > 0 {
+			if err := c.bw.WriteByte(0xc0 | byte(csid&0x3f)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.bw.Flush()
+}
+
+func decodeCommand(msg *message) ([]interface{}, error) {
+	payload := msg.payload
+	// AMF3 команды начинаются с байта 0 и дальше закодированы в AMF0
+	if msg.typeID == msgCommandAMF3 && len(payload) > 0 {
+		payload = payload[1:]
+	}
+	return decodeAMF0(payload)
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
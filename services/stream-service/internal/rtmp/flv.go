@@ -0,0 +1,37 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const flvTagScript = 18
+
+// flvHeader - заголовок FLV (audio + video) и PreviousTagSize0
+var flvHeader = []byte{'F', 'L', 'V', 0x01, 0x05, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00}
+
+// writeFLVTag пишет один FLV tag вместе с PreviousTagSize
+func writeFLVTag(w io.Writer, tagType uint8, timestamp uint32, data []byte) error {
+	tag := make([]byte, 11+len(data)+4)
+	tag[0] = tagType
+	putUint24(tag[1:4], uint32(len(data)))
+	putUint24(tag[4:7], timestamp&0xffffff)
+	tag[7] = byte(timestamp >> 24)
+	// StreamID (3 байта) всегда 0
+	copy(tag[11:], data)
+	binary.BigEndian.PutUint32(tag[11+len(data):], uint32(11+len(data)))
+
+	_, err := w.Write(tag)
+	return err
+}
+
+// stripSetDataFrame убирает обёртку "@setDataFrame" из metadata сообщения,
+// чтобы в FLV попал обычный onMetaData
+func stripSetDataFrame(payload []byte) []byte {
+	prefix := encodeAMF0("@setDataFrame")
+	if bytes.HasPrefix(payload, prefix) {
+		return payload[len(prefix):]
+	}
+	return payload
+}
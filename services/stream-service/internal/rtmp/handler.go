@@ -0,0 +1,87 @@
+package rtmp
+
+import (
+	"errors"
+	"io"
+	"log"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/ingest"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+)
+
+type Handler struct {
+	streamRepo *repository.StreamRepository
+	publisher  *ingest.Publisher
+}
+
+func NewHandler(streamRepo *repository.StreamRepository, publisher *ingest.Publisher) *Handler {
+	return &Handler{
+		streamRepo: streamRepo,
+		publisher:  publisher,
+	}
+}
+
+// ValidateStreamKey checks if the stream key is an active key of some stream
+func (h *Handler) ValidateStreamKey(streamKey string) bool {
+	stream, err := h.streamRepo.GetStreamByIngestKey(streamKey)
+	if err != nil {
+		log.Printf("❌ Stream key validation failed: %v", err)
+		return false
+	}
+
+	log.Printf("✅ Stream key validated (stream ID: %s, key ID: %s)", stream.ID, stream.IngestKeyID)
+	return true
+}
+
+// HandlePublish handles incoming RTMP stream (remuxed to FLV for ffmpeg)
+func (h *Handler) HandlePublish(conn *Conn) {
+	streamKey := conn.StreamKey
+
+	stream, err := h.streamRepo.GetStreamByIngestKey(streamKey)
+	if err != nil {
+		log.Printf("❌ Invalid stream key")
+		conn.RejectPublish("NetStream.Publish.BadName", "Invalid stream key")
+		return
+	}
+
+	// Reject second publisher for the same stream
+	if h.publisher.IsPublishing(stream.ID) {
+		log.Printf("❌ Stream %s is already live, rejecting RTMP publish", stream.ID)
+		conn.RejectPublish("NetStream.Publish.BadName", "Stream is already live")
+		return
+	}
+
+	if !h.publisher.HasCapacity(stream.ID) {
+		log.Printf("❌ No transcoder capacity for stream %s, rejecting RTMP publish", stream.ID)
+		conn.RejectPublish("NetStream.Publish.Rejected", "Server is overloaded, try again later")
+		return
+	}
+
+	if err := conn.AcceptPublish(); err != nil {
+		log.Printf("❌ Failed to accept RTMP publish: %v", err)
+		return
+	}
+
+	log.Printf("✅ RTMP connection accepted for stream %s", stream.ID)
+
+	// RTMP сообщения → FLV поток → ffmpeg stdin
+	pr, pw := io.Pipe()
+	go func() {
+		err := conn.ReadMedia(pw)
+		if errors.Is(err, ErrUnpublished) {
+			// Издатель закончил эфир, а не потерял связь
+			h.publisher.FinishPublishing(stream.ID)
+		} else if err != nil && err != io.EOF {
+			log.Printf("⚠️ RTMP media read ended for stream %s: %v", stream.ID, err)
+		}
+		pw.CloseWithError(io.EOF)
+	}()
+
+	if err := h.publisher.Publish(stream, pr, "RTMP"); err != nil {
+		log.Printf("❌ RTMP publish failed for stream %s: %v", stream.ID, err)
+	}
+
+	// Останавливаем чтение если ffmpeg завершился раньше издателя
+	pr.Close()
+	conn.Close()
+}
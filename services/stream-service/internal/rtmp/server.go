@@ -0,0 +1,109 @@
+package rtmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+type Server struct {
+	listener net.Listener
+	config   *Config
+	handler  *Handler
+}
+
+type Config struct {
+	Address     string
+	App         string        // Имя приложения в URL (rtmp://host/<app>/<stream_key>)
+	IdleTimeout time.Duration // Таймаут на handshake и отсутствие данных от издателя
+}
+
+func NewServer(cfg *Config, handler *Handler) (*Server, error) {
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = 30 * time.Second
+	}
+
+	ln, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start RTMP listener: %w", err)
+	}
+
+	log.Printf("✅ RTMP listener created on %s", cfg.Address)
+	return &Server{
+		listener: ln,
+		config:   cfg,
+		handler:  handler,
+	}, nil
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	log.Printf("🚀 RTMP server started on %s (app: %s)", s.config.Address, s.config.App)
+
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("⏹️  RTMP server shutting down")
+				return ctx.Err()
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			log.Printf("❌ RTMP accept error: %v", err)
+			continue
+		}
+
+		// Handle connection in separate goroutine
+		go s.handleConnection(nc)
+	}
+}
+
+func (s *Server) handleConnection(nc net.Conn) {
+	conn := NewConn(nc, s.config.IdleTimeout)
+	defer conn.Close()
+
+	log.Printf("📡 New RTMP connection from %s", conn.RemoteAddr())
+
+	if err := conn.Handshake(); err != nil {
+		log.Printf("❌ RTMP handshake failed (%s): %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	if err := conn.ReadPublish(); err != nil {
+		log.Printf("❌ RTMP session ended before publish (%s): %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	log.Printf("📡 RTMP publish request: app=%s", conn.App)
+
+	// Validate app and stream key before accepting
+	if conn.App != s.config.App {
+		log.Printf("❌ Rejecting RTMP publish: unknown app %q", conn.App)
+		conn.RejectPublish("NetStream.Publish.BadName", "Unknown application")
+		return
+	}
+
+	if !s.handler.ValidateStreamKey(conn.StreamKey) {
+		log.Printf("❌ Rejecting RTMP publish: invalid stream key")
+		conn.RejectPublish("NetStream.Publish.BadName", "Invalid stream key")
+		return
+	}
+
+	s.handler.HandlePublish(conn)
+}
+
+func (s *Server) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		log.Println("✅ RTMP listener closed")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package schedule - запланированные эфиры: проверка расписания и
+// автоматическое снятие анонсов, которые так и не вышли в эфир
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+)
+
+const (
+	// MaxLeadTime - насколько заранее можно запланировать эфир
+	MaxLeadTime = 90 * 24 * time.Hour
+
+	// ExpireGrace - сколько ждать издателя после планового начала,
+	// прежде чем вернуть стрим в offline
+	ExpireGrace = 30 * time.Minute
+
+	// ExpireInterval - как часто проверяются просроченные анонсы
+	ExpireInterval = time.Minute
+
+	// UpcomingLimit - максимум стримов в GET /streams/upcoming
+	UpcomingLimit = 100
+)
+
+// ValidateStart проверяет плановое время начала эфира
+func ValidateStart(startAt, now time.Time) error {
+	if !startAt.After(now) {
+		return fmt.Errorf("scheduled_start_at must be in the future")
+	}
+	if startAt.Sub(now) > MaxLeadTime {
+		return fmt.Errorf("scheduled_start_at must be within %d days", int(MaxLeadTime.Hours()/24))
+	}
+	return nil
+}
+
+// ValidateCoverImageURL проверяет ссылку на обложку (пустая - без обложки)
+func ValidateCoverImageURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("cover_image_url must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+// Expirer переводит в offline запланированные стримы, издатель которых
+// не подключился в течение ExpireGrace после планового начала
+type Expirer struct {
+	streamRepo *repository.StreamRepository
+}
+
+func NewExpirer(streamRepo *repository.StreamRepository) *Expirer {
+	return &Expirer{streamRepo: streamRepo}
+}
+
+// Start проверяет просроченные анонсы каждые ExpireInterval до отмены ctx
+func (e *Expirer) Start(ctx context.Context) {
+	ticker := time.NewTicker(ExpireInterval)
+	defer ticker.Stop()
+
+	log.Printf("📅 Schedule expirer started (grace %v)", ExpireGrace)
+
+	for {
+		e.expire()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Expirer) expire() {
+	ids, err := e.streamRepo.ExpireScheduledStreams(time.Now().Add(-ExpireGrace))
+	if err != nil {
+		log.Printf("❌ Failed to expire scheduled streams: %v", err)
+		return
+	}
+	for _, id := range ids {
+		log.Printf("📅 Scheduled stream %s did not start in time, moved to offline", id)
+	}
+}
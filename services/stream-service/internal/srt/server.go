@@ -61,12 +61,11 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) handleConnection(req gosrt.ConnRequest) {
-	streamID := req.StreamId()
-	log.Printf("📡 New SRT connection request, StreamID: %s", streamID)
+	log.Printf("📡 New SRT connection request from %s", req.RemoteAddr())
 
 	// Validate stream key before accepting
-	if !s.handler.ValidateStreamKey(streamID) {
-		log.Printf("❌ Rejecting connection: invalid stream key %s", streamID)
+	if !s.handler.ValidateStreamKey(req.StreamId()) {
+		log.Printf("❌ Rejecting connection from %s: invalid stream key", req.RemoteAddr())
 		req.Reject(gosrt.REJ_PEER)
 		return
 	}
@@ -0,0 +1,34 @@
+package srt
+
+import (
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	gosrt "github.com/datarhei/gosrt"
+)
+
+// statsConn - SRT соединение со статистикой канала для телеметрии эфира
+// (реализует health.LinkStatsSource)
+type statsConn struct {
+	gosrt.Conn
+	stats gosrt.Statistics // Предыдущий замер: gosrt считает интервал относительно него
+}
+
+func newStatsConn(conn gosrt.Conn) *statsConn {
+	c := &statsConn{Conn: conn}
+	conn.Stats(&c.stats)
+	return c
+}
+
+// LinkStats возвращает статистику приёма с предыдущего вызова
+func (c *statsConn) LinkStats() models.LinkStats {
+	c.Conn.Stats(&c.stats)
+
+	interval := c.stats.Interval
+	return models.LinkStats{
+		RTTMs:                c.stats.Instantaneous.MsRTT,
+		BitrateKbps:          interval.MbpsRecvRate * 1024,
+		PacketsReceived:      int64(interval.PktRecv),
+		PacketsLost:          int64(interval.PktRecvLoss),
+		PacketsRetransmitted: int64(interval.PktRecvRetrans),
+		PacketsDropped:       int64(interval.PktRecvDrop),
+	}
+}
@@ -0,0 +1,85 @@
+package transcoder
+
+import "fmt"
+
+// Broadcast - вывод одного эфира, который переживает переподключения издателя.
+// Лестница качеств, DVR окна, нумерация сегментов и временная шкала сохраняются
+// между запусками ffmpeg; сегменты после переподключения идут за EXT-X-DISCONTINUITY
+type Broadcast struct {
+	abrConfig ABRConfig
+	lease     *Lease // CPU слоты эфира, nil - вывод ещё не подготовлен
+	runs      int    // сколько раз запускался ffmpeg
+
+	uploader   *segmentUploader    // обычный HLS
+	lowLatency *lowLatencyPipeline // LL-HLS
+}
+
+func NewBroadcast() *Broadcast {
+	return &Broadcast{}
+}
+
+// Qualities - качества, которые выводит эфир (пусто, пока вывод не подготовлен)
+func (b *Broadcast) Qualities() []string {
+	return b.abrConfig.ProfileNames()
+}
+
+// Mode - режим вывода эфира (models.Transcode*), "" - вывод не подготовлен
+func (b *Broadcast) Mode() string {
+	if b.lease == nil {
+		return ""
+	}
+	return b.lease.Mode()
+}
+
+// Resumed сообщает, что эфир уже выводился до текущего подключения издателя
+func (b *Broadcast) Resumed() bool {
+	return b.runs > 0
+}
+
+// continuation - откуда очередной запуск ffmpeg продолжает эфир
+type continuation struct {
+	startNumber int     // номер первого сегмента (обычный HLS)
+	tsOffset    float64 // секунды эфира, выведенные предыдущими запусками
+}
+
+// initName - init сегмент запуска: init.mp4 с начала эфира, init_<первый сегмент>.mp4
+// после переподключения (новый URI, чтобы плееры не взяли init из кэша)
+func (c continuation) initName() string {
+	if c.startNumber == 0 {
+		return "init.mp4"
+	}
+	return fmt.Sprintf("init_%d.mp4", c.startNumber)
+}
+
+func (b *Broadcast) continuation() continuation {
+	switch {
+	case b.uploader != nil:
+		return continuation{startNumber: b.uploader.nextSegment(), tsOffset: b.uploader.mediaDuration()}
+	case b.lowLatency != nil:
+		// Части ffmpeg нумеруются заново, сегменты нумерует llhls.Playlist
+		return continuation{tsOffset: b.lowLatency.mediaDuration()}
+	}
+	return continuation{}
+}
+
+// AbandonBroadcast освобождает слоты эфира, не закрывая плейлисты:
+// эфир продолжил другой процесс, и этот больше не выводит его
+func (t *FFmpegTranscoder) AbandonBroadcast(broadcast *Broadcast) {
+	if broadcast.lease != nil {
+		broadcast.lease.Release()
+	}
+}
+
+// FinishBroadcast закрывает плейлисты эфира (EXT-X-ENDLIST) и manifest.mpd и освобождает
+// слоты транскодера. Вызывается, когда эфир окончательно завершён: издатель не вернулся за отведённое время
+func (t *FFmpegTranscoder) FinishBroadcast(broadcast *Broadcast) {
+	if broadcast.lease != nil {
+		broadcast.lease.Release()
+	}
+	if broadcast.uploader != nil {
+		broadcast.uploader.finish()
+	}
+	if broadcast.lowLatency != nil {
+		broadcast.lowLatency.finish()
+	}
+}
@@ -0,0 +1,84 @@
+package transcoder
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/cmaf"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dash"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
+)
+
+// dashRepresentation собирает качество для manifest.mpd из DVR окна:
+// в MPD попадают только сегменты, которые уже загружены в MinIO и ещё не удалены.
+// offset - время от начала эфира до первого сегмента окна
+func dashRepresentation(outputPath string, profile Profile, playlist *dvr.Playlist, offset float64) (dash.Representation, bool) {
+	if playlist.InitURI == "" || len(playlist.Segments) == 0 {
+		return dash.Representation{}, false
+	}
+
+	initData, err := os.ReadFile(filepath.Join(outputPath, profile.Name, playlist.InitURI))
+	if err != nil {
+		return dash.Representation{}, false
+	}
+
+	durations := make([]float64, len(playlist.Segments))
+	for i, segment := range playlist.Segments {
+		durations[i] = segment.Duration
+	}
+
+	return dash.Representation{
+		ID:             profile.Name,
+		Bandwidth:      profile.Bandwidth(),
+		Width:          profile.Width,
+		Height:         profile.Height,
+		Codecs:         cmaf.Codecs(initData),
+		Initialization: profile.Name + "/" + playlist.InitURI,
+		Media:          profile.Name + "/segment_$Number%03d$.m4s",
+		StartNumber:    playlist.Segments[0].Sequence,
+		StartTime:      offset,
+		Durations:      durations,
+	}, true
+}
+
+// uploadDASHManifest формирует manifest.mpd поверх тех же CMAF сегментов, что и HLS
+func (t *FFmpegTranscoder) uploadDASHManifest(storageKey, outputPath string, abrConfig ABRConfig, startedAt time.Time, live bool, window func(quality string) (*dvr.Playlist, float64, bool)) {
+	manifest := dash.Manifest{
+		Live:                  live,
+		AvailabilityStartTime: startedAt,
+		SegmentDuration:       float64(abrConfig.SegmentTime),
+		TimeShiftBufferDepth:  abrConfig.DVRWindow.Seconds(),
+	}
+
+	for _, profile := range abrConfig.Profiles {
+		playlist, offset, ok := window(profile.Name)
+		if !ok {
+			continue
+		}
+		if representation, ok := dashRepresentation(outputPath, profile, playlist, offset); ok {
+			manifest.Representations = append(manifest.Representations, representation)
+		}
+	}
+
+	if len(manifest.Representations) == 0 {
+		return
+	}
+
+	t.putDASHManifest(storageKey, &manifest)
+}
+
+func (t *FFmpegTranscoder) putDASHManifest(storageKey string, manifest *dash.Manifest) {
+	data, err := manifest.Render()
+	if err != nil {
+		log.Printf("❌ Failed to render DASH manifest for stream %s: %v", storageKey, err)
+		return
+	}
+
+	objectName := fmt.Sprintf("live-segments/%s/manifest.mpd", storageKey)
+	if err := t.uploadBytes(data, objectName, "application/dash+xml"); err != nil {
+		log.Printf("❌ Failed to upload manifest.mpd for stream %s: %v", storageKey, err)
+	}
+}
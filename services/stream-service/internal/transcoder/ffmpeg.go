@@ -1,6 +1,7 @@
 package transcoder
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,117 +9,261 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/llhls"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
 	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/google/uuid"
 )
 
 type FFmpegTranscoder struct {
 	outputDir     string
-	minioClient   *minio.Client
-	minioBucket   string
+	storage       storage.Storage
 	streamRepo    *repository.StreamRepository
 	publicBaseURL string
 	abrConfig     ABRConfig
+	llRegistry    *llhls.Registry
+	scheduler     *Scheduler
 }
 
 func NewFFmpegTranscoder(
 	outputDir string,
-	minioEndpoint, minioAccessKey, minioSecretKey, minioBucket string,
-	useSSL bool,
+	storage storage.Storage,
 	streamRepo *repository.StreamRepository,
 	publicBaseURL string,
-) (*FFmpegTranscoder, error) {
-	minioClient, err := minio.New(minioEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(minioAccessKey, minioSecretKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
-	}
-
-	ctx := context.Background()
-	exists, err := minioClient.BucketExists(ctx, minioBucket)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check bucket: %w", err)
-	}
-
-	if !exists {
-		err = minioClient.MakeBucket(ctx, minioBucket, minio.MakeBucketOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create bucket: %w", err)
-		}
-	}
-
+	llRegistry *llhls.Registry,
+	scheduler *Scheduler,
+) *FFmpegTranscoder {
 	return &FFmpegTranscoder{
 		outputDir:     outputDir,
-		minioClient:   minioClient,
-		minioBucket:   minioBucket,
+		storage:       storage,
 		streamRepo:    streamRepo,
 		publicBaseURL: publicBaseURL,
 		abrConfig:     DefaultABRConfig,
-	}, nil
+		llRegistry:    llRegistry,
+		scheduler:     scheduler,
+	}
 }
 
-// TranscodeToHLS with Adaptive Bitrate (multiple qualities)
-func (t *FFmpegTranscoder) TranscodeToHLS(ctx context.Context, input io.Reader, streamKey string) error {
-	outputPath := filepath.Join(t.outputDir, streamKey)
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+// CanAdmit проверяет, хватит ли слотов на публикацию стрима (см. Scheduler.CanAdmit)
+func (t *FFmpegTranscoder) CanAdmit(streamID uuid.UUID) bool {
+	return t.scheduler.CanAdmit(streamID)
+}
 
-	// Создаём директории для каждого качества
-	for _, profile := range t.abrConfig.Profiles {
-		qualityPath := filepath.Join(outputPath, profile.Name)
-		if err := os.MkdirAll(qualityPath, 0755); err != nil {
-			return fmt.Errorf("failed to create quality directory %s: %w", profile.Name, err)
+// TranscodeToHLS with Adaptive Bitrate (multiple qualities)
+// Набор качеств берётся из настроек стрима (abr_ladder) и урезается, если транскодеру
+// не хватает CPU слотов (ErrNoCapacity - не хватает даже на исходный поток).
+// broadcast - эфир, который продолжает этот запуск: после переподключения издателя
+// плейлисты и нумерация сегментов продолжаются, а не начинаются заново.
+// Плейлисты закрываются только FinishBroadcast. hooks сообщают о запуске ffmpeg и его прогрессе
+func (t *FFmpegTranscoder) TranscodeToHLS(ctx context.Context, input io.Reader, stream *models.Stream, broadcast *Broadcast, hooks Hooks) error {
+	storageKey := stream.StorageKey()
+	outputPath := filepath.Join(t.outputDir, storageKey)
+
+	resumed := broadcast.Resumed()
+	if resumed {
+		// Лестница качеств не меняется до конца эфира: иначе плейлисты качеств не продолжить
+		log.Printf("🔁 Resuming broadcast of stream %s after publisher reconnect", storageKey)
+		removeFFmpegPlaylists(outputPath, broadcast.abrConfig)
+	} else {
+		abrConfig, replay, lease, err := t.prepareOutput(ctx, input, stream, outputPath)
+		if err != nil {
+			return err
 		}
+		input = replay
+		broadcast.abrConfig = abrConfig
+		broadcast.lease = lease
 	}
+	abrConfig := broadcast.abrConfig
 
 	// Build FFmpeg command for ABR
-	args := t.buildABRCommand(streamKey, outputPath)
+	args := t.buildABRCommand(abrConfig, outputPath, broadcast.continuation())
+	broadcast.runs++
 
 	log.Printf("🎬 Starting ABR transcoding for stream %s with qualities: %v",
-		streamKey, GetProfileNames())
+		storageKey, abrConfig.ProfileNames())
 
 	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 	cmd.Stdin = input
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = newProgressWriter(hooks.OnProgress) // -progress pipe:1
 	cmd.Stderr = os.Stderr
 
+	if abrConfig.LowLatency {
+		if broadcast.lowLatency == nil {
+			broadcast.lowLatency = t.newLowLatencyPipeline(stream, outputPath, abrConfig)
+		}
+		return t.runLowLatency(ctx, cmd, stream, outputPath, broadcast.lowLatency, hooks)
+	}
+
 	// Запускаем генерацию thumbnail через 10 секунд
-	go t.generateThumbnailAfterDelay(ctx, streamKey, outputPath, 10*time.Second)
+	go t.generateThumbnailAfterDelay(ctx, stream, outputPath, func() string {
+		return findFirstPlaylist(outputPath, abrConfig.Profiles)
+	}, 10*time.Second)
+
+	// Загрузка сегментов всех качеств по событиям файловой системы
+	if broadcast.uploader == nil {
+		broadcast.uploader = t.newSegmentUploader(storageKey, outputPath, abrConfig)
+	}
+	if err := broadcast.uploader.start(resumed); err != nil {
+		return err
+	}
+
+	runErr := runCommand(cmd, hooks)
 
-	// Запускаем мониторинг и загрузку сегментов для всех качеств
-	go t.monitorAndUploadABRSegments(ctx, streamKey, outputPath)
+	// Даже при ошибке ffmpeg загружаем всё, что успело записаться
+	broadcast.uploader.stop()
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg ABR failed: %w", err)
+	if runErr != nil {
+		return fmt.Errorf("ffmpeg ABR failed: %w", runErr)
 	}
+	log.Printf("✅ ABR transcoding completed for stream %s", storageKey)
+	return nil
+}
 
-	// После завершения загружаем оставшиеся файлы
-	t.uploadRemainingABRSegments(streamKey, outputPath)
-	log.Printf("✅ ABR transcoding completed for stream %s", streamKey)
+// prepareOutput выбирает лестницу качеств по источнику и свободным слотам транскодера
+// и готовит директории вывода (первое подключение издателя к эфиру)
+func (t *FFmpegTranscoder) prepareOutput(ctx context.Context, input io.Reader, stream *models.Stream, outputPath string) (ABRConfig, io.Reader, *Lease, error) {
+	storageKey := stream.StorageKey()
+	abrConfig := t.abrConfig.WithLadder(stream.ABRLadder).WithLowLatency(stream.LowLatency).WithDVRWindow(stream.DVRWindowSeconds)
+
+	// Определяем параметры источника и убираем качества выше его разрешения
+	input, source, err := t.probeInput(ctx, input, storageKey)
+	if err != nil {
+		log.Printf("⚠️ Failed to probe source for stream %s, using configured ladder: %v", storageKey, err)
+	} else {
+		abrConfig = abrConfig.PruneForSource(*source)
+		log.Printf("🔍 Source for stream %s: %dx%d @ %.2f fps (%s/%s)", storageKey, source.Width, source.Height, source.Framerate, source.VideoCodec, source.AudioCodec)
+	}
+
+	abrConfig, lease, err := t.scheduler.Acquire(stream.ID, abrConfig)
+	if err != nil {
+		return abrConfig, input, nil, err
+	}
+
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		lease.Release()
+		return abrConfig, input, nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Создаём директории для каждого качества
+	for _, profile := range abrConfig.Profiles {
+		qualityPath := filepath.Join(outputPath, profile.Name)
+		if err := os.MkdirAll(qualityPath, 0755); err != nil {
+			lease.Release()
+			return abrConfig, input, nil, fmt.Errorf("failed to create quality directory %s: %w", profile.Name, err)
+		}
+	}
+
+	// Сохраняем фактически производимые качества
+	if err := t.streamRepo.UpdateStreamQualities(stream.ID, abrConfig.ProfileNames()); err != nil {
+		log.Printf("⚠️ Failed to update available qualities for stream %s: %v", storageKey, err)
+	}
+
+	return abrConfig, input, lease, nil
+}
+
+// removeFFmpegPlaylists удаляет рабочие плейлисты прошлого запуска ffmpeg:
+// новый запуск начинает их заново с -start_number, а не дописывает (append_list)
+func removeFFmpegPlaylists(outputPath string, abrConfig ABRConfig) {
+	for _, profile := range abrConfig.Profiles {
+		for _, name := range []string{"playlist.m3u8", "parts.m3u8"} {
+			os.Remove(filepath.Join(outputPath, profile.Name, name))
+		}
+	}
+}
+
+// runLowLatency запускает ffmpeg в LL-HLS режиме: части собираются в плейлисты
+// в памяти, полные сегменты загружаются в MinIO по мере готовности
+func (t *FFmpegTranscoder) runLowLatency(ctx context.Context, cmd *exec.Cmd, stream *models.Stream, outputPath string, pipeline *lowLatencyPipeline, hooks Hooks) error {
+	pipeline.start()
+
+	go t.generateThumbnailAfterDelay(ctx, stream, outputPath, func() string {
+		return pipeline.thumbnailSource(outputPath)
+	}, 10*time.Second)
+
+	runErr := runCommand(cmd, hooks)
+
+	// Даже при ошибке ffmpeg загружаем готовые сегменты
+	pipeline.stop()
+
+	if runErr != nil {
+		return fmt.Errorf("ffmpeg LL-HLS failed: %w", runErr)
+	}
+	log.Printf("✅ LL-HLS transcoding completed for stream %s", stream.StorageKey())
 	return nil
 }
 
-// buildABRCommand создает FFmpeg команду для множественных качеств
-func (t *FFmpegTranscoder) buildABRCommand(streamKey, outputPath string) []string {
-	profiles := t.abrConfig.Profiles
-	numProfiles := len(profiles)
+// runCommand запускает ffmpeg, сообщает о старте и ждёт завершения
+func runCommand(cmd *exec.Cmd, hooks Hooks) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	hooks.started()
+	return cmd.Wait()
+}
 
+// buildABRCommand создает FFmpeg команду для множественных качеств.
+// cont - продолжение эфира после переподключения издателя (нулевое для первого запуска)
+func (t *FFmpegTranscoder) buildABRCommand(abrConfig ABRConfig, outputPath string, cont continuation) []string {
 	args := []string{
 		"-hide_banner",
+		"-progress", "pipe:1", // Прогресс для телеметрии эфира (stdout не занят выводом)
 		"-i", "pipe:0",
+	}
+
+	var varStreamMap []string
+	if abrConfig.Passthrough() {
+		// Исходный поток без перекодирования: сегменты режутся по ключевым кадрам источника
+		args = append(args, "-map", "0:v:0", "-map", "0:a:0", "-c", "copy")
+		varStreamMap = []string{"v:0,a:0,name:" + abrConfig.Profiles[0].Name}
+	} else {
+		var encodeArgs []string
+		encodeArgs, varStreamMap = buildEncodeArgs(abrConfig)
+		args = append(args, encodeArgs...)
+	}
+
+	// Временная шкала продолжается с конца уже выведенного эфира
+	if cont.tsOffset > 0 {
+		args = append(args, "-output_ts_offset", strconv.FormatFloat(cont.tsOffset, 'f', 3, 64))
+	}
+
+	if abrConfig.LowLatency {
+		return append(args, lowLatencyOutputArgs(abrConfig, outputPath, varStreamMap)...)
+	}
+
+	// ✅ ОБНОВЛЕНО: HLS параметры
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", fmt.Sprintf("%d", abrConfig.SegmentTime), // Берется из config (4 сек)
+		"-hls_list_size", fmt.Sprintf("%d", abrConfig.PlaylistSize), // 0 = все сегменты
+		"-hls_flags", "delete_segments+append_list+independent_segments+program_date_time+temp_file", // temp_file: плейлист и сегменты появляются атомарно (rename)
+		"-hls_playlist_type", abrConfig.PlaylistType,
+		"-hls_segment_type", "fmp4", // CMAF сегменты общие для HLS и DASH
+		"-hls_fmp4_init_filename", cont.initName(),
+		"-start_number", strconv.Itoa(cont.startNumber),
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", strings.Join(varStreamMap, " "),
+		"-hls_segment_filename", filepath.Join(outputPath, "%v", "segment_%03d.m4s"),
+		filepath.Join(outputPath, "%v", "playlist.m3u8"),
+	)
+
+	return args
+}
+
+// buildEncodeArgs - кодирование лестницы качеств: split исходного видео,
+// масштабирование и x264/AAC для каждого качества. Возвращает аргументы и var_stream_map
+func buildEncodeArgs(abrConfig ABRConfig) ([]string, []string) {
+	profiles := abrConfig.Profiles
+	numProfiles := len(profiles)
+
+	args := []string{
 		"-c:v", "libx264",
 		"-preset", "veryfast",
 		"-tune", "zerolatency",
-		"-g", "60",
-		"-keyint_min", "60",
 		"-sc_threshold", "0",
 		"-pix_fmt", "yuv420p",
 	}
@@ -133,15 +278,14 @@ func (t *FFmpegTranscoder) buildABRCommand(streamKey, outputPath string) []strin
 		filterComplex += ";"
 
 		for i, profile := range profiles {
-			filterComplex += fmt.Sprintf("[v%d]scale=%d:%d[v%dout]",
-				i, profile.Width, profile.Height, i)
+			filterComplex += fmt.Sprintf("[v%d]%s[v%dout]",
+				i, videoFilter(abrConfig, profile), i)
 			if i < numProfiles-1 {
 				filterComplex += ";"
 			}
 		}
 	} else {
-		filterComplex = fmt.Sprintf("[0:v]scale=%d:%d[v0out]",
-			profiles[0].Width, profiles[0].Height)
+		filterComplex = fmt.Sprintf("[0:v]%s[v0out]", videoFilter(abrConfig, profiles[0]))
 	}
 
 	args = append(args, "-filter_complex", filterComplex)
@@ -155,6 +299,8 @@ func (t *FFmpegTranscoder) buildABRCommand(streamKey, outputPath string) []strin
 		args = append(args, fmt.Sprintf("-b:v:%d", i), profile.VideoBitrate)
 		args = append(args, fmt.Sprintf("-maxrate:v:%d", i), profile.MaxRate)
 		args = append(args, fmt.Sprintf("-bufsize:v:%d", i), profile.BufSize)
+		args = append(args, fmt.Sprintf("-g:v:%d", i), fmt.Sprintf("%d", profile.GOPSize()))
+		args = append(args, fmt.Sprintf("-keyint_min:v:%d", i), fmt.Sprintf("%d", profile.GOPSize()))
 
 		// Audio mapping
 		args = append(args, "-map", "a:0")
@@ -166,207 +312,68 @@ func (t *FFmpegTranscoder) buildABRCommand(streamKey, outputPath string) []strin
 			fmt.Sprintf("v:%d,a:%d,name:%s", i, i, profile.Name))
 	}
 
-	// ✅ ОБНОВЛЕНО: HLS параметры
-	args = append(args,
-		"-f", "hls",
-		"-hls_time", fmt.Sprintf("%d", t.abrConfig.SegmentTime), // Берется из config (4 сек)
-		"-hls_list_size", fmt.Sprintf("%d", t.abrConfig.PlaylistSize), // 0 = все сегменты
-		"-hls_flags", "delete_segments+append_list+independent_segments+program_date_time", // ✅ Добавлен program_date_time
-		"-hls_playlist_type", t.abrConfig.PlaylistType,
-		"-hls_segment_type", "mpegts",
-		"-master_pl_name", "master.m3u8",
-		"-var_stream_map", strings.Join(varStreamMap, " "),
-		"-hls_segment_filename", filepath.Join(outputPath, "%v", "segment_%03d.ts"),
-		filepath.Join(outputPath, "%v", "playlist.m3u8"),
-	)
-
-	return args
+	return args, varStreamMap
 }
 
-// monitorAndUploadABRSegments загружает сегменты всех качеств
-func (t *FFmpegTranscoder) monitorAndUploadABRSegments(ctx context.Context, streamKey, outputPath string) {
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	uploadedSegments := make(map[string]bool)
-	var mu sync.Mutex
-	const maxConcurrentUploads = 10
-	semaphore := make(chan struct{}, maxConcurrentUploads)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			var wg sync.WaitGroup
-
-			// Загружаем сегменты для каждого качества
-			for _, profile := range t.abrConfig.Profiles {
-				qualityPath := filepath.Join(outputPath, profile.Name)
-
-				// Загружаем .ts сегменты
-				files, err := filepath.Glob(filepath.Join(qualityPath, "segment_*.ts"))
-				if err != nil {
-					continue
-				}
-
-				for _, file := range files {
-					fileName := filepath.Base(file)
-					qualityKey := profile.Name + "/" + fileName
-
-					mu.Lock()
-					alreadyUploaded := uploadedSegments[qualityKey]
-					mu.Unlock()
-
-					if alreadyUploaded {
-						continue
-					}
-
-					// Проверяем что файл завершен
-					fileInfo, err := os.Stat(file)
-					if err != nil {
-						continue
-					}
-
-					time.Sleep(300 * time.Millisecond)
-					fileInfo2, err := os.Stat(file)
-					if err != nil || fileInfo.Size() != fileInfo2.Size() {
-						continue
-					}
-
-					semaphore <- struct{}{}
-					wg.Add(1)
-					go func(filePath, quality, fileName string) {
-						defer wg.Done()
-						defer func() { <-semaphore }()
-
-						objectName := fmt.Sprintf("live-segments/%s/%s/%s", streamKey, quality, fileName)
-						err := t.uploadFileToMinIO(filePath, objectName, "video/mp2t")
-						if err != nil {
-							log.Printf("❌ Failed to upload %s/%s: %v", quality, fileName, err)
-							return
-						}
-
-						mu.Lock()
-						uploadedSegments[qualityKey] = true
-						mu.Unlock()
-
-						log.Printf("📦 Uploaded %s/%s", quality, fileName)
-					}(file, profile.Name, fileName)
-				}
-
-				// Загружаем playlist.m3u8 для этого качества
-				playlistPath := filepath.Join(qualityPath, "playlist.m3u8")
-				if _, err := os.Stat(playlistPath); err == nil {
-					objectName := fmt.Sprintf("live-segments/%s/%s/playlist.m3u8", streamKey, profile.Name)
-					_ = t.uploadFileToMinIO(playlistPath, objectName, "application/vnd.apple.mpegurl")
-				}
-			}
-
-			wg.Wait()
-
-			// Загружаем master playlist
-			masterPath := filepath.Join(outputPath, "master.m3u8")
-			if _, err := os.Stat(masterPath); err == nil {
-				objectName := fmt.Sprintf("live-segments/%s/master.m3u8", streamKey)
-				_ = t.uploadFileToMinIO(masterPath, objectName, "application/vnd.apple.mpegurl")
-			}
-		}
+// videoFilter возвращает цепочку фильтров для одного качества.
+// Если источник определён, частота кадров сохраняется как есть,
+// иначе приводится к частоте из профиля.
+func videoFilter(abrConfig ABRConfig, profile Profile) string {
+	filter := fmt.Sprintf("scale=%d:%d", profile.Width, profile.Height)
+	if abrConfig.Source == nil && profile.Framerate > 0 {
+		filter += fmt.Sprintf(",fps=%d", profile.Framerate)
 	}
+	return filter
 }
 
-// uploadFileToMinIO универсальный метод загрузки файла
-func (t *FFmpegTranscoder) uploadFileToMinIO(filePath, objectName, contentType string) error {
-	ctx := context.Background()
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
+// probeInput определяет параметры входящего потока перед запуском ffmpeg
+func (t *FFmpegTranscoder) probeInput(ctx context.Context, input io.Reader, storageKey string) (io.Reader, *SourceInfo, error) {
+	log.Printf("🔍 Probing source for stream %s", storageKey)
+	source, replay, err := ProbeSource(ctx, input)
 	if err != nil {
-		return err
+		return replay, nil, err
 	}
-
-	_, err = t.minioClient.PutObject(ctx, t.minioBucket, objectName, file, fileInfo.Size(), minio.PutObjectOptions{
-		ContentType: contentType,
-	})
-	return err
+	return replay, source, nil
 }
 
-// uploadRemainingABRSegments загружает все оставшиеся файлы
-func (t *FFmpegTranscoder) uploadRemainingABRSegments(streamKey, outputPath string) {
-	log.Printf("📤 Uploading remaining ABR segments for stream %s", streamKey)
+// uploadFile загружает локальный файл в хранилище live сегментов
+func (t *FFmpegTranscoder) uploadFile(filePath, objectName, contentType string) error {
+	return t.storage.PutFile(context.Background(), objectName, filePath, contentType)
+}
 
-	var wg sync.WaitGroup
+// uploadBytes загружает данные из памяти
+func (t *FFmpegTranscoder) uploadBytes(data []byte, objectName, contentType string) error {
+	return t.storage.Put(context.Background(), objectName, bytes.NewReader(data), int64(len(data)), contentType)
+}
 
-	// Загружаем master playlist
-	masterPath := filepath.Join(outputPath, "master.m3u8")
-	if _, err := os.Stat(masterPath); err == nil {
-		objectName := fmt.Sprintf("live-segments/%s/master.m3u8", streamKey)
-		if err := t.uploadFileToMinIO(masterPath, objectName, "application/vnd.apple.mpegurl"); err != nil {
-			log.Printf("❌ Failed to upload master.m3u8: %v", err)
-		} else {
-			log.Printf("✅ Uploaded master.m3u8")
+// deleteSegments удаляет сегменты, вышедшие из DVR окна
+func (t *FFmpegTranscoder) deleteSegments(prefix string, uris []string) {
+	for _, uri := range uris {
+		if err := t.storage.Delete(context.Background(), prefix+"/"+uri); err != nil {
+			log.Printf("⚠️ Failed to delete expired segment %s/%s: %v", prefix, uri, err)
 		}
 	}
-
-	// Загружаем файлы для каждого качества
-	for _, profile := range t.abrConfig.Profiles {
-		qualityPath := filepath.Join(outputPath, profile.Name)
-
-		// Playlist для качества
-		playlistPath := filepath.Join(qualityPath, "playlist.m3u8")
-		if _, err := os.Stat(playlistPath); err == nil {
-			objectName := fmt.Sprintf("live-segments/%s/%s/playlist.m3u8", streamKey, profile.Name)
-			_ = t.uploadFileToMinIO(playlistPath, objectName, "application/vnd.apple.mpegurl")
-		}
-
-		// Все сегменты
-		files, _ := filepath.Glob(filepath.Join(qualityPath, "segment_*.ts"))
-		for _, file := range files {
-			fileName := filepath.Base(file)
-			wg.Add(1)
-			go func(filePath, quality, fileName string) {
-				defer wg.Done()
-				objectName := fmt.Sprintf("live-segments/%s/%s/%s", streamKey, quality, fileName)
-				if err := t.uploadFileToMinIO(filePath, objectName, "video/mp2t"); err != nil {
-					log.Printf("❌ Failed to upload %s: %v", fileName, err)
-				}
-			}(file, profile.Name, fileName)
-		}
+	if len(uris) > 0 {
+		log.Printf("🧹 Removed %d segments outside DVR window from %s", len(uris), prefix)
 	}
-
-	wg.Wait()
-	log.Printf("✅ All ABR segments uploaded for stream %s", streamKey)
 }
 
 // generateThumbnailAfterDelay генерирует thumbnail через заданную задержку
-func (t *FFmpegTranscoder) generateThumbnailAfterDelay(ctx context.Context, streamKey, outputPath string, delay time.Duration) {
-	log.Printf("📸 Will generate thumbnail for stream %s in %v", streamKey, delay)
+func (t *FFmpegTranscoder) generateThumbnailAfterDelay(ctx context.Context, stream *models.Stream, outputPath string, findSource func() string, delay time.Duration) {
+	storageKey := stream.StorageKey()
+	log.Printf("📸 Will generate thumbnail for stream %s in %v", storageKey, delay)
 	select {
 	case <-time.After(delay):
 		// Продолжаем
 	case <-ctx.Done():
-		log.Printf("⚠️ Stream %s ended before thumbnail generation", streamKey)
+		log.Printf("⚠️ Stream %s ended before thumbnail generation", storageKey)
 		return
 	}
 
 	// ✅ Ждем пока появится хотя бы один сегмент в любой из папок качества
 	var firstSegment string
 	for i := 0; i < 20; i++ {
-		// Проверяем все папки качества
-		for _, quality := range []string{"1080p", "720p", "480p", "360p"} {
-			qualityPath := filepath.Join(outputPath, quality)
-			segments, _ := filepath.Glob(filepath.Join(qualityPath, "segment_*.ts"))
-			if len(segments) > 0 {
-				firstSegment = segments[0]
-				log.Printf("✅ Found segment in %s: %s", quality, firstSegment)
-				break
-			}
-		}
-		if firstSegment != "" {
+		if firstSegment = findSource(); firstSegment != "" {
 			break
 		}
 		time.Sleep(1 * time.Second)
@@ -401,27 +408,35 @@ func (t *FFmpegTranscoder) generateThumbnailAfterDelay(ctx context.Context, stre
 	log.Printf("✅ Thumbnail generated: %s", thumbnailPath)
 
 	// Загружаем thumbnail в MinIO
-	if err := t.uploadThumbnailToMinIO(streamKey, thumbnailPath); err != nil {
+	if err := t.uploadThumbnailToMinIO(stream, thumbnailPath); err != nil {
 		log.Printf("❌ Failed to upload thumbnail: %v", err)
 	} else {
-		log.Printf("✅ Thumbnail uploaded to MinIO for stream %s", streamKey)
+		log.Printf("✅ Thumbnail uploaded to MinIO for stream %s", storageKey)
 	}
 }
 
-// uploadThumbnailToMinIO загружает thumbnail и обновляет БД
-func (t *FFmpegTranscoder) uploadThumbnailToMinIO(streamKey, thumbnailPath string) error {
-	objectName := fmt.Sprintf("live-segments/%s/thumbnail.jpg", streamKey)
-	if err := t.uploadFileToMinIO(thumbnailPath, objectName, "image/jpeg"); err != nil {
-		return err
+// findFirstPlaylist ищет качество с готовыми сегментами. fMP4 сегмент без init
+// ffmpeg не прочитает, поэтому для thumbnail отдаём плейлист качества.
+func findFirstPlaylist(outputPath string, profiles []Profile) string {
+	for _, profile := range profiles {
+		qualityPath := filepath.Join(outputPath, profile.Name)
+		segments, _ := filepath.Glob(filepath.Join(qualityPath, "segment_*.m4s"))
+		if len(segments) > 0 {
+			log.Printf("✅ Found segment in %s: %s", profile.Name, segments[0])
+			return filepath.Join(qualityPath, "playlist.m3u8")
+		}
 	}
+	return ""
+}
 
-	log.Printf("✅ Thumbnail uploaded to MinIO: %s", objectName)
-	stream, err := t.streamRepo.GetStreamByKey(streamKey)
-	if err != nil {
-		log.Printf("⚠️ Failed to get stream by key %s: %v", streamKey, err)
+// uploadThumbnailToMinIO загружает thumbnail и обновляет БД
+func (t *FFmpegTranscoder) uploadThumbnailToMinIO(stream *models.Stream, thumbnailPath string) error {
+	objectName := fmt.Sprintf("live-segments/%s/thumbnail.jpg", stream.StorageKey())
+	if err := t.uploadFile(thumbnailPath, objectName, "image/jpeg"); err != nil {
 		return err
 	}
 
+	log.Printf("✅ Thumbnail uploaded to MinIO: %s", objectName)
 	thumbnailURL := fmt.Sprintf("%s/api/streams/%s/thumbnail", t.publicBaseURL, stream.ID)
 	if err := t.streamRepo.UpdateStreamThumbnail(stream.ID, thumbnailURL); err != nil {
 		log.Printf("⚠️ Failed to update thumbnail_url in DB: %v", err)
@@ -0,0 +1,429 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/cmaf"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dash"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/llhls"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	lowLatencyWindow       = 6                      // полных сегментов в LL-HLS плейлисте
+	lowLatencyPollInterval = 100 * time.Millisecond // как часто проверяем новые части ffmpeg
+	lowLatencyFFmpegList   = 20                     // частей в рабочем плейлисте ffmpeg (старые удаляются)
+	lowLatencyLinger       = 30 * time.Second       // сколько раздавать плейлист после окончания эфира
+)
+
+// lowLatencyPipeline собирает fMP4 части ffmpeg в LL-HLS плейлисты (раздаются из памяти)
+// и загружает полные сегменты + плейлисты DVR окна в MinIO. Плейлисты живут весь эфир:
+// при переподключении издателя новый ffmpeg продолжает их после EXT-X-DISCONTINUITY
+type lowLatencyPipeline struct {
+	t          *FFmpegTranscoder
+	streamID   uuid.UUID
+	storageKey string
+	stream     *llhls.Stream
+	renditions []*lowLatencyRendition
+	cancel     context.CancelFunc
+	pollers    sync.WaitGroup
+	uploaders  sync.WaitGroup
+
+	// manifest.mpd обновляется из всех загрузчиков качеств
+	startedAt   time.Time
+	segmentTime float64
+	dvrWindow   time.Duration
+	manifestMu  sync.Mutex
+}
+
+type lowLatencyRendition struct {
+	name         string
+	profile      Profile
+	dir          string
+	playlist     *llhls.Playlist
+	window       *dvr.Window // сегменты, загруженные в MinIO (под manifestMu)
+	initUploaded string      // последний загруженный init
+
+	// Состояние текущего запуска ffmpeg
+	defaults cmaf.TrackDefaults
+	hasInit  bool
+	lastPart int // последний обработанный номер части ffmpeg
+	uploads  chan *llhls.Segment
+}
+
+// ffmpegPlaylistEntry - часть (или сегмент) из рабочего плейлиста ffmpeg
+type ffmpegPlaylistEntry struct {
+	uri             string
+	duration        float64
+	programDateTime time.Time // нулевое, если ffmpeg не пишет EXT-X-PROGRAM-DATE-TIME
+}
+
+// lowLatencyOutputArgs - HLS параметры ffmpeg для LL режима: каждый fMP4 "сегмент"
+// ffmpeg длиной PartTime становится LL-HLS частью, ключевые кадры ровно на границах сегментов
+func lowLatencyOutputArgs(abrConfig ABRConfig, outputPath string, varStreamMap []string) []string {
+	return []string{
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", abrConfig.SegmentTime),
+		"-f", "hls",
+		"-hls_time", strconv.FormatFloat(abrConfig.PartTime, 'f', 3, 64),
+		"-hls_list_size", strconv.Itoa(lowLatencyFFmpegList),
+		"-hls_flags", "split_by_time+delete_segments+temp_file",
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", "init.mp4",
+		"-var_stream_map", strings.Join(varStreamMap, " "),
+		"-hls_segment_filename", filepath.Join(outputPath, "%v", "part_%05d.m4s"),
+		filepath.Join(outputPath, "%v", "parts.m3u8"),
+	}
+}
+
+func (t *FFmpegTranscoder) newLowLatencyPipeline(stream *models.Stream, outputPath string, abrConfig ABRConfig) *lowLatencyPipeline {
+	p := &lowLatencyPipeline{
+		t:          t,
+		streamID:   stream.ID,
+		storageKey: stream.StorageKey(),
+		stream:     &llhls.Stream{},
+
+		startedAt:   time.Now(),
+		segmentTime: float64(abrConfig.SegmentTime),
+		dvrWindow:   abrConfig.DVRWindow,
+	}
+
+	for _, profile := range abrConfig.Profiles {
+		playlist := llhls.NewPlaylist(float64(abrConfig.SegmentTime), abrConfig.PartTime, lowLatencyWindow)
+
+		p.renditions = append(p.renditions, &lowLatencyRendition{
+			name:     profile.Name,
+			profile:  profile,
+			dir:      filepath.Join(outputPath, profile.Name),
+			playlist: playlist,
+			window:   dvr.NewWindow(abrConfig.DVRWindow),
+		})
+
+		p.stream.Renditions = append(p.stream.Renditions, &llhls.Rendition{
+			Name:       profile.Name,
+			Bandwidth:  profile.Bandwidth(),
+			Resolution: fmt.Sprintf("%dx%d", profile.Width, profile.Height),
+			Playlist:   playlist,
+		})
+	}
+
+	t.llRegistry.Register(stream.ID, p.stream)
+
+	// master.m3u8 в MinIO ссылается на обычные плейлисты качеств (плееры без LL-HLS)
+	masterObject := fmt.Sprintf("live-segments/%s/master.m3u8", p.storageKey)
+	if err := t.uploadBytes([]byte(p.stream.MasterPlaylist()), masterObject, "application/vnd.apple.mpegurl"); err != nil {
+		log.Printf("❌ Failed to upload LL-HLS master playlist for stream %s: %v", p.storageKey, err)
+	}
+
+	log.Printf("⚡ LL-HLS pipeline started for stream %s (part %.1fs, segment %ds)",
+		p.storageKey, abrConfig.PartTime, abrConfig.SegmentTime)
+	return p
+}
+
+// start начинает сбор частей очередного запуска ffmpeg (нумерация частей ffmpeg начинается заново)
+func (p *lowLatencyPipeline) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	for _, r := range p.renditions {
+		r.hasInit = false
+		r.lastPart = -1
+		r.uploads = make(chan *llhls.Segment, 16)
+
+		p.pollers.Add(1)
+		go p.poll(ctx, r)
+
+		p.uploaders.Add(1)
+		go p.upload(r)
+	}
+}
+
+// stop забирает последние части после выхода ffmpeg, закрывает текущие сегменты
+// и дожидается их загрузки. Плейлисты остаются открытыми: издатель может переподключиться
+func (p *lowLatencyPipeline) stop() {
+	p.cancel()
+	p.pollers.Wait()
+
+	for _, r := range p.renditions {
+		p.collectParts(r)
+		if segment := r.playlist.Interrupt(); segment != nil {
+			r.uploads <- segment
+		}
+		close(r.uploads)
+	}
+
+	p.uploaders.Wait()
+	log.Printf("✅ LL-HLS segments uploaded for stream %s", p.storageKey)
+}
+
+// finish закрывает плейлисты (EXT-X-ENDLIST) - эфир окончен
+func (p *lowLatencyPipeline) finish() {
+	for _, r := range p.renditions {
+		r.playlist.End() // открытого сегмента нет: его закрыл stop
+		p.uploadPlaylist(r, fmt.Sprintf("live-segments/%s/%s", p.storageKey, r.name), true)
+	}
+
+	p.uploadDASHManifest(false)
+	log.Printf("✅ LL-HLS pipeline finished for stream %s", p.storageKey)
+
+	// Даём плеерам доиграть до EXT-X-ENDLIST
+	time.AfterFunc(lowLatencyLinger, func() {
+		p.t.llRegistry.Unregister(p.streamID, p.stream)
+	})
+}
+
+func (p *lowLatencyPipeline) poll(ctx context.Context, r *lowLatencyRendition) {
+	defer p.pollers.Done()
+
+	ticker := time.NewTicker(lowLatencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.collectParts(r)
+		}
+	}
+}
+
+// collectParts забирает новые части из рабочего плейлиста ffmpeg
+func (p *lowLatencyPipeline) collectParts(r *lowLatencyRendition) {
+	data, err := os.ReadFile(filepath.Join(r.dir, "parts.m3u8"))
+	if err != nil {
+		return
+	}
+
+	initURI, entries := parseFFmpegPlaylist(data)
+
+	for _, entry := range entries {
+		var number int
+		if _, err := fmt.Sscanf(entry.uri, "part_%d.m4s", &number); err != nil || number <= r.lastPart {
+			continue
+		}
+
+		if !r.hasInit {
+			if initURI == "" {
+				return
+			}
+			initData, err := os.ReadFile(filepath.Join(r.dir, initURI))
+			if err != nil {
+				return
+			}
+			defaults, ok := cmaf.ParseInit(initData)
+			if !ok {
+				log.Printf("⚠️ No video track in LL-HLS init segment for %s/%s", p.storageKey, r.name)
+			}
+			r.defaults = defaults
+			r.playlist.SetInit(initData)
+			r.hasInit = true
+		}
+
+		partData, err := os.ReadFile(filepath.Join(r.dir, entry.uri))
+		r.lastPart = number
+		if err != nil {
+			log.Printf("⚠️ LL-HLS part %s/%s is gone: %v", r.name, entry.uri, err)
+			continue
+		}
+
+		independent := cmaf.IsIndependent(partData, r.defaults)
+		if segment := r.playlist.AddPart(partData, entry.duration, independent); segment != nil {
+			r.uploads <- segment
+		}
+	}
+}
+
+// upload загружает init, полные сегменты и плейлист DVR окна качества в MinIO.
+// Плейлист всегда загружается после сегмента, чтобы не ссылаться на несуществующие объекты.
+func (p *lowLatencyPipeline) upload(r *lowLatencyRendition) {
+	defer p.uploaders.Done()
+
+	prefix := fmt.Sprintf("live-segments/%s/%s", p.storageKey, r.name)
+
+	for segment := range r.uploads {
+		// После переподключения у сегментов новый init
+		initName := llhls.InitName(segment.InitMSN)
+		if initData, ok := r.playlist.InitAt(segment.InitMSN); ok && initName != r.initUploaded {
+			err := uploadWithRetry(context.Background(), func() error {
+				return p.t.uploadBytes(initData, prefix+"/"+initName, "video/mp4")
+			})
+			if err != nil {
+				log.Printf("❌ Failed to upload %s/%s: %v", r.name, initName, err)
+			} else {
+				r.initUploaded = initName
+			}
+		}
+
+		name := llhls.SegmentName(segment.MSN)
+		err := uploadWithRetry(context.Background(), func() error {
+			return p.t.uploadBytes(segment.Data, prefix+"/"+name, "video/mp4")
+		})
+		if err != nil {
+			log.Printf("❌ Failed to upload %s/%s: %v", r.name, name, err)
+			continue
+		}
+		log.Printf("📦 Uploaded %s/%s", r.name, name)
+
+		p.manifestMu.Lock()
+		if r.initUploaded != "" {
+			r.window.SetInit(r.initUploaded)
+		}
+		r.window.Add(dvr.Segment{
+			Sequence:        segment.MSN,
+			URI:             name,
+			Duration:        segment.Duration,
+			ProgramDateTime: segment.ProgramDateTime,
+			Discontinuity:   segment.Discontinuity,
+		})
+		p.manifestMu.Unlock()
+
+		p.uploadPlaylist(r, prefix, false)
+		p.uploadDASHManifest(true)
+	}
+}
+
+// mediaDuration - длительность уже загруженного эфира в секундах
+func (p *lowLatencyPipeline) mediaDuration() float64 {
+	p.manifestMu.Lock()
+	defer p.manifestMu.Unlock()
+	return p.renditions[0].window.Duration()
+}
+
+func (p *lowLatencyPipeline) uploadPlaylist(r *lowLatencyRendition, prefix string, ended bool) {
+	p.manifestMu.Lock()
+	playlist := []byte(r.window.Playlist(ended).Render(""))
+	expired := r.window.Expired()
+	p.manifestMu.Unlock()
+
+	if err := p.t.uploadBytes(playlist, prefix+"/playlist.m3u8", "application/vnd.apple.mpegurl"); err != nil {
+		log.Printf("❌ Failed to upload %s/playlist.m3u8: %v", r.name, err)
+		return
+	}
+
+	p.t.deleteSegments(prefix, expired)
+}
+
+// uploadDASHManifest обновляет manifest.mpd по сегментам DVR окна, уже загруженным во всех качествах
+func (p *lowLatencyPipeline) uploadDASHManifest(live bool) {
+	p.manifestMu.Lock()
+	defer p.manifestMu.Unlock()
+
+	// Последний сегмент, который есть во всех качествах
+	available := int64(-1)
+	for i, r := range p.renditions {
+		segments := r.window.Segments()
+		if len(segments) == 0 {
+			return
+		}
+		last := segments[len(segments)-1].Sequence
+		if i == 0 || last < available {
+			available = last
+		}
+	}
+
+	manifest := dash.Manifest{
+		Live:                  live,
+		AvailabilityStartTime: p.startedAt,
+		SegmentDuration:       p.segmentTime,
+		TimeShiftBufferDepth:  p.dvrWindow.Seconds(),
+	}
+
+	for _, r := range p.renditions {
+		segments := r.window.Segments()
+		var durations []float64
+		for _, segment := range segments {
+			if segment.Sequence > available {
+				break
+			}
+			durations = append(durations, segment.Duration)
+		}
+		if len(durations) == 0 {
+			return
+		}
+
+		manifest.Representations = append(manifest.Representations, dash.Representation{
+			ID:             r.name,
+			Bandwidth:      r.profile.Bandwidth(),
+			Width:          r.profile.Width,
+			Height:         r.profile.Height,
+			Codecs:         cmaf.Codecs(r.playlist.Init()),
+			Initialization: r.name + "/" + r.window.Playlist(!live).InitURI,
+			Media:          r.name + "/segment_$Number%05d$.m4s",
+			StartNumber:    segments[0].Sequence,
+			StartTime:      r.window.Offset(),
+			Durations:      durations,
+		})
+	}
+
+	p.t.putDASHManifest(p.storageKey, &manifest)
+}
+
+// thumbnailSource пишет init + последний полный сегмент во временный файл для ffmpeg
+func (p *lowLatencyPipeline) thumbnailSource(outputPath string) string {
+	for _, r := range p.renditions {
+		segment, ok := r.playlist.LatestSegment()
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(outputPath, "thumbnail_source.mp4")
+		data := append(append([]byte(nil), r.playlist.Init()...), segment...)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Printf("❌ Failed to write thumbnail source: %v", err)
+			return ""
+		}
+		log.Printf("✅ Found LL-HLS segment in %s for thumbnail", r.name)
+		return path
+	}
+	return ""
+}
+
+// parseFFmpegPlaylist читает EXT-X-MAP, EXT-X-PROGRAM-DATE-TIME и пары EXTINF/URI из плейлиста ffmpeg
+func parseFFmpegPlaylist(data []byte) (string, []ffmpegPlaylistEntry) {
+	var initURI string
+	var entries []ffmpegPlaylistEntry
+	var programDateTime time.Time
+	duration := -1.0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if _, after, found := strings.Cut(line, `URI="`); found {
+				initURI, _, _ = strings.Cut(after, `"`)
+			}
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			if t, err := dvr.ParseProgramDateTime(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:")); err == nil {
+				programDateTime = t
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if d, err := strconv.ParseFloat(value, 64); err == nil {
+				duration = d
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if duration >= 0 {
+				entries = append(entries, ffmpegPlaylistEntry{uri: line, duration: duration, programDateTime: programDateTime})
+				if !programDateTime.IsZero() {
+					programDateTime = programDateTime.Add(time.Duration(duration * float64(time.Second)))
+				}
+			}
+			duration = -1
+		}
+	}
+
+	return initURI, entries
+}
@@ -0,0 +1,218 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	probeBytes   = 1 << 20 // ~1 MB MPEG-TS - несколько секунд видео
+	probeTimeout = 5 * time.Second
+)
+
+// SourceInfo - параметры входящего потока, полученные через ffprobe
+type SourceInfo struct {
+	Width      int
+	Height     int
+	Framerate  float64
+	VideoCodec string // "h264", "vp8", ...
+	AudioCodec string // "" - аудио нет
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+	} `json:"streams"`
+}
+
+// ProbeSource читает начало входящего потока и определяет разрешение, частоту кадров и кодеки.
+// Возвращает reader, который заново отдаёт прочитанные данные, а затем остаток потока,
+// поэтому его можно сразу передавать в ffmpeg.
+func ProbeSource(ctx context.Context, input io.Reader) (*SourceInfo, io.Reader, error) {
+	buf := make([]byte, 0, probeBytes)
+
+	// Read блокируется, пока издатель молчит, поэтому чтение идёт в горутине,
+	// а probeTimeout отсчитывается таймером
+	reads := make(chan probeRead, 1)
+	readChunk := func() {
+		chunk := make([]byte, 64*1024)
+		n, err := input.Read(chunk)
+		reads <- probeRead{data: chunk[:n], err: err}
+	}
+
+	timer := time.NewTimer(probeTimeout)
+	defer timer.Stop()
+
+	var readErr error
+	pending := false
+probe:
+	for len(buf) < probeBytes {
+		if !pending {
+			go readChunk()
+			pending = true
+		}
+
+		select {
+		case read := <-reads:
+			pending = false
+			buf = append(buf, read.data...)
+			if read.err != nil {
+				readErr = read.err
+				break probe
+			}
+		case <-timer.C:
+			break probe
+		case <-ctx.Done():
+			readErr = ctx.Err()
+			break probe
+		}
+	}
+
+	// Незавершённое чтение отдаёт свои данные следующими, до остатка потока
+	rest := input
+	if pending {
+		rest = io.MultiReader(&pendingRead{reads: reads}, input)
+	}
+	replay := io.MultiReader(bytes.NewReader(buf), rest)
+
+	if readErr != nil && readErr != io.EOF {
+		return nil, replay, fmt.Errorf("failed to read probe data: %w", readErr)
+	}
+
+	if len(buf) == 0 {
+		return nil, replay, fmt.Errorf("no data received for probing")
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(probeCtx, "ffprobe",
+		"-v", "error",
+		"-probesize", strconv.Itoa(len(buf)),
+		"-show_entries", "stream=codec_type,codec_name,width,height,avg_frame_rate,r_frame_rate",
+		"-of", "json",
+		"-i", "pipe:0",
+	)
+	cmd.Stdin = bytes.NewReader(buf)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, replay, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result ffprobeOutput
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, replay, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	var source *SourceInfo
+	audioCodec := ""
+	for _, stream := range result.Streams {
+		switch {
+		case stream.CodecType == "video" && source == nil && stream.Height > 0:
+			framerate := parseFrameRate(stream.AvgFrameRate)
+			if framerate == 0 {
+				framerate = parseFrameRate(stream.RFrameRate)
+			}
+			source = &SourceInfo{
+				Width:      stream.Width,
+				Height:     stream.Height,
+				Framerate:  framerate,
+				VideoCodec: stream.CodecName,
+			}
+		case stream.CodecType == "audio" && audioCodec == "":
+			audioCodec = stream.CodecName
+		}
+	}
+
+	if source == nil {
+		return nil, replay, fmt.Errorf("no video stream found in source")
+	}
+	source.AudioCodec = audioCodec
+
+	return source, replay, nil
+}
+
+type probeRead struct {
+	data []byte
+	err  error
+}
+
+// pendingRead отдаёт результат чтения, начатого во время probe
+type pendingRead struct {
+	reads    <-chan probeRead
+	received bool
+	read     probeRead
+}
+
+func (p *pendingRead) Read(b []byte) (int, error) {
+	if !p.received {
+		p.read = <-p.reads
+		p.received = true
+	}
+	if len(p.read.data) > 0 {
+		n := copy(b, p.read.data)
+		p.read.data = p.read.data[n:]
+		return n, nil
+	}
+	if p.read.err != nil {
+		return 0, p.read.err
+	}
+	return 0, io.EOF
+}
+
+// parseFrameRate разбирает частоту кадров ffprobe ("30000/1001", "25/1")
+func parseFrameRate(value string) float64 {
+	num, den, found := strings.Cut(value, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+// PruneForSource убирает качества выше разрешения источника и выставляет
+// частоту кадров источника всем оставшимся профилям
+func (c ABRConfig) PruneForSource(source SourceInfo) ABRConfig {
+	var profiles []Profile
+	for _, p := range c.Profiles {
+		if p.Height <= source.Height {
+			profiles = append(profiles, p)
+		}
+	}
+
+	// Источник ниже самого маленького профиля - оставляем только его
+	if len(profiles) == 0 && len(c.Profiles) > 0 {
+		profiles = []Profile{c.Profiles[len(c.Profiles)-1]}
+	}
+
+	if fps := int(math.Round(source.Framerate)); fps > 0 {
+		for i := range profiles {
+			profiles[i].Framerate = fps
+		}
+	}
+
+	c.Profiles = profiles
+	c.Source = &source
+	return c
+}
@@ -1,5 +1,14 @@
 package transcoder
 
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Profile представляет конфигурацию одного качества видео
 type Profile struct {
 	Name         string
@@ -11,16 +20,31 @@ type Profile struct {
 	BufSize      string
 	AudioBitrate string
 	Framerate    int
+	Passthrough  bool // Исходный поток без перекодирования (Resolution и битрейт - оценка для плейлистов)
 }
 
+// PassthroughProfile - имя качества исходного потока без перекодирования
+const PassthroughProfile = "source"
+
 // ABRConfig представляет конфигурацию Adaptive Bitrate Streaming
 type ABRConfig struct {
 	Profiles     []Profile
-	SegmentTime  int    // Длительность сегмента в секундах
-	PlaylistSize int    // Размер playlist (0 = все сегменты)
-	PlaylistType string // "event" для live
+	SegmentTime  int           // Длительность сегмента в секундах
+	PlaylistSize int           // Размер playlist (0 = все сегменты)
+	PlaylistType string        // "event" для live
+	Source       *SourceInfo   // Параметры источника (nil если probe не удался)
+	LowLatency   bool          // LL-HLS: fMP4 части вместо MPEG-TS сегментов
+	PartTime     float64       // Длительность LL-HLS части в секундах
+	DVRWindow    time.Duration // Сколько эфира держать в live плейлисте (0 = весь эфир)
 }
 
+// Параметры LL-HLS: сегмент равен GOP (2 сек), части по 0.5 сек
+// дают задержку порядка 2-4 секунд
+const (
+	LowLatencySegmentTime = 2
+	LowLatencyPartTime    = 0.5
+)
+
 // DefaultABRProfiles - набор качеств для адаптивного стриминга
 var DefaultABRProfiles = []Profile{
 	{
@@ -77,11 +101,204 @@ var DefaultABRConfig = ABRConfig{
 	PlaylistType: "event",
 }
 
-// GetProfileNames возвращает список имён профилей
-func GetProfileNames() []string {
-	names := make([]string, len(DefaultABRProfiles))
-	for i, p := range DefaultABRProfiles {
+// DefaultABRPreset - пресет, который используется если при создании стрима ничего не выбрано
+const DefaultABRPreset = "full"
+
+// CustomABRPreset - имя пресета для явно заданного списка качеств
+const CustomABRPreset = "custom"
+
+// ABRPresets - именованные наборы качеств, доступные при создании стрима
+var ABRPresets = map[string][]string{
+	"full":     {"1080p", "720p", "480p", "360p"},
+	"standard": {"720p", "480p", "360p"},
+	"low":      {"480p", "360p"},
+	"minimal":  {"360p"},
+}
+
+// GetProfile возвращает профиль по имени качества
+func GetProfile(name string) (Profile, bool) {
+	for _, p := range DefaultABRProfiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ResolveLadder определяет набор качеств стрима по имени пресета или явному списку.
+// Явный список имеет приоритет над пресетом. Возвращает имя пресета и качества
+// в порядке убывания разрешения.
+func ResolveLadder(preset string, qualities []string) (string, []string, error) {
+	if len(qualities) == 0 {
+		if preset == "" {
+			preset = DefaultABRPreset
+		}
+		ladder, ok := ABRPresets[preset]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown ABR preset: %s", preset)
+		}
+		return preset, append([]string(nil), ladder...), nil
+	}
+
+	seen := make(map[string]bool)
+	var profiles []Profile
+	for _, name := range qualities {
+		profile, ok := GetProfile(name)
+		if !ok {
+			return "", nil, fmt.Errorf("unknown quality: %s", name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		profiles = append(profiles, profile)
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Height > profiles[j].Height
+	})
+
+	ladder := make([]string, len(profiles))
+	for i, p := range profiles {
+		ladder[i] = p.Name
+	}
+	return CustomABRPreset, ladder, nil
+}
+
+// WithLadder возвращает копию конфигурации с профилями из заданного списка качеств.
+// Неизвестные имена пропускаются; пустой результат означает профили по умолчанию.
+func (c ABRConfig) WithLadder(qualities []string) ABRConfig {
+	var profiles []Profile
+	for _, name := range qualities {
+		if profile, ok := GetProfile(name); ok {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	if len(profiles) == 0 {
+		profiles = DefaultABRProfiles
+	}
+
+	c.Profiles = profiles
+	return c
+}
+
+// WithLowLatency включает LL-HLS режим с короткими сегментами и частями
+func (c ABRConfig) WithLowLatency(enabled bool) ABRConfig {
+	if !enabled {
+		return c
+	}
+	c.LowLatency = true
+	c.SegmentTime = LowLatencySegmentTime
+	c.PartTime = LowLatencyPartTime
+	return c
+}
+
+// WithDVRWindow ограничивает live плейлист последними seconds секундами эфира
+func (c ABRConfig) WithDVRWindow(seconds int) ABRConfig {
+	c.DVRWindow = time.Duration(seconds) * time.Second
+	return c
+}
+
+// Slots возвращает стоимость кодирования качества в CPU слотах Scheduler:
+// слот - 360p30, стоимость растёт с числом пикселей в секунду (720p30 - 4, 1080p30 - 9)
+func (p Profile) Slots() int {
+	if p.Passthrough {
+		return PassthroughSlots
+	}
+
+	framerate := p.Framerate
+	if framerate <= 0 {
+		framerate = 30
+	}
+	pixelRate := float64(p.Width*p.Height*framerate) / float64(640*360*30)
+	return int(math.Ceil(pixelRate))
+}
+
+// Slots возвращает стоимость всей лестницы качеств
+func (c ABRConfig) Slots() int {
+	slots := 0
+	for _, p := range c.Profiles {
+		slots += p.Slots()
+	}
+	return slots
+}
+
+// Passthrough сообщает, что эфир выводится без перекодирования
+func (c ABRConfig) Passthrough() bool {
+	return len(c.Profiles) == 1 && c.Profiles[0].Passthrough
+}
+
+// CanPassthrough проверяет, можно ли отдать источник без перекодирования:
+// нужны H.264 + AAC (CMAF сегменты), LL-HLS части требуют ключевых кадров
+// транскодера, поэтому для low latency вывод всегда перекодируется
+func (c ABRConfig) CanPassthrough() bool {
+	return c.Source != nil && !c.LowLatency &&
+		c.Source.VideoCodec == "h264" && c.Source.AudioCodec == "aac"
+}
+
+// passthroughProfile описывает исходный поток как качество. Битрейт берётся
+// у ближайшего профиля не выше источника - это оценка для BANDWIDTH плейлистов
+func passthroughProfile(source SourceInfo) Profile {
+	estimate := DefaultABRProfiles[len(DefaultABRProfiles)-1]
+	for _, p := range DefaultABRProfiles {
+		if p.Height <= source.Height {
+			estimate = p
+			break
+		}
+	}
+
+	return Profile{
+		Name:         PassthroughProfile,
+		Resolution:   fmt.Sprintf("%dx%d", source.Width, source.Height),
+		Width:        source.Width,
+		Height:       source.Height,
+		VideoBitrate: estimate.VideoBitrate,
+		MaxRate:      estimate.MaxRate,
+		BufSize:      estimate.BufSize,
+		AudioBitrate: estimate.AudioBitrate,
+		Framerate:    int(math.Round(source.Framerate)),
+		Passthrough:  true,
+	}
+}
+
+// GOPSize возвращает размер GOP для профиля (ключевой кадр каждые 2 секунды)
+func (p Profile) GOPSize() int {
+	if p.Framerate <= 0 {
+		return 60
+	}
+	return p.Framerate * 2
+}
+
+// ProfileNames возвращает список имён профилей конфигурации
+func (c ABRConfig) ProfileNames() []string {
+	names := make([]string, len(c.Profiles))
+	for i, p := range c.Profiles {
 		names[i] = p.Name
 	}
 	return names
 }
+
+// Bandwidth возвращает пиковый битрейт профиля (видео + аудио) в бит/с для master playlist
+func (p Profile) Bandwidth() int {
+	return parseBitrate(p.MaxRate) + parseBitrate(p.AudioBitrate)
+}
+
+// parseBitrate разбирает битрейт в формате ffmpeg ("5000k", "1M")
+func parseBitrate(value string) int {
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1000
+		value = strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1000000
+		value = strings.TrimSuffix(value, "M")
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n * multiplier
+}
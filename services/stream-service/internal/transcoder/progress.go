@@ -0,0 +1,69 @@
+package transcoder
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+)
+
+// Hooks - обратные вызовы жизненного цикла транскодирования (все необязательны)
+type Hooks struct {
+	OnStarted  func()                    // Источник распознан, ffmpeg запущен
+	OnProgress func(models.EncoderStats) // Очередной блок ffmpeg -progress (~раз в секунду)
+}
+
+func (h Hooks) started() {
+	if h.OnStarted != nil {
+		h.OnStarted()
+	}
+}
+
+// progressWriter разбирает вывод ffmpeg -progress pipe:1: блоки строк key=value,
+// каждый блок завершается строкой progress=continue|end
+type progressWriter struct {
+	onProgress func(models.EncoderStats)
+	buf        []byte
+	current    models.EncoderStats
+}
+
+func newProgressWriter(onProgress func(models.EncoderStats)) *progressWriter {
+	return &progressWriter{onProgress: onProgress}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.parseLine(strings.TrimSpace(string(w.buf[:i])))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) parseLine(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+
+	switch key {
+	case "fps":
+		w.current.FPS, _ = strconv.ParseFloat(value, 64)
+	case "speed":
+		// "1.02x", до первого кадра - "N/A"
+		w.current.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	case "drop_frames":
+		w.current.DroppedFrames, _ = strconv.ParseInt(value, 10, 64)
+	case "dup_frames":
+		w.current.DuplicateFrames, _ = strconv.ParseInt(value, 10, 64)
+	case "progress":
+		if w.onProgress != nil {
+			w.onProgress(w.current)
+		}
+	}
+}
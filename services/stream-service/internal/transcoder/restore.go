@@ -0,0 +1,152 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+)
+
+// RestoreBroadcast восстанавливает эфир, который выводил другой процесс (transcode worker
+// упал посреди эфира): DVR окна качеств читаются из плейлистов в хранилище, init сегменты
+// скачиваются на диск. Следующий TranscodeToHLS продолжает эфир как после переподключения издателя.
+// qualities и mode - вывод эфира до сбоя, лестница не меняется.
+// input == nil - эфир восстанавливается только чтобы закрыть его (FinishBroadcast): без probe и слотов
+func (t *FFmpegTranscoder) RestoreBroadcast(ctx context.Context, input io.Reader, stream *models.Stream, qualities []string, mode string) (io.Reader, *Broadcast, error) {
+	storageKey := stream.StorageKey()
+	outputPath := filepath.Join(t.outputDir, storageKey)
+
+	abrConfig := t.abrConfig.WithDVRWindow(stream.DVRWindowSeconds)
+	abrConfig.Profiles = nil
+
+	var source *SourceInfo
+	if input != nil {
+		replay, probed, err := t.probeInput(ctx, input, storageKey)
+		input = replay
+		if err != nil {
+			log.Printf("⚠️ Failed to probe source for stream %s: %v", storageKey, err)
+		} else {
+			source = probed
+			abrConfig.Source = source
+		}
+	}
+
+	for _, name := range qualities {
+		if name == PassthroughProfile {
+			var info SourceInfo
+			if source != nil {
+				info = *source
+			}
+			abrConfig.Profiles = append(abrConfig.Profiles, passthroughProfile(info))
+			continue
+		}
+
+		profile, ok := GetProfile(name)
+		if !ok {
+			return input, nil, fmt.Errorf("unknown quality %q of broadcast", name)
+		}
+		if source != nil {
+			if fps := int(math.Round(source.Framerate)); fps > 0 {
+				profile.Framerate = fps
+			}
+		}
+		abrConfig.Profiles = append(abrConfig.Profiles, profile)
+	}
+	if len(abrConfig.Profiles) == 0 {
+		return input, nil, fmt.Errorf("broadcast has no qualities to restore")
+	}
+	if abrConfig.Passthrough() && input != nil && source == nil {
+		return input, nil, fmt.Errorf("cannot continue passthrough broadcast without source info")
+	}
+
+	broadcast := &Broadcast{abrConfig: abrConfig, runs: 1}
+	if input != nil {
+		lease, err := t.scheduler.Reserve(stream.ID, abrConfig, mode)
+		if err != nil {
+			return input, nil, err
+		}
+		broadcast.lease = lease
+	}
+
+	uploader, err := t.restoreUploader(ctx, stream, outputPath, abrConfig)
+	if err != nil {
+		t.AbandonBroadcast(broadcast)
+		return input, nil, err
+	}
+	broadcast.uploader = uploader
+
+	log.Printf("♻️ Restored broadcast of stream %s from storage (qualities: %v, next segment %d)",
+		storageKey, abrConfig.ProfileNames(), uploader.nextSegment())
+	return input, broadcast, nil
+}
+
+// restoreUploader поднимает состояние загрузки качеств из плейлистов в хранилище.
+// Качество без плейлиста начинается с нуля: до сбоя у него ничего не загрузилось
+func (t *FFmpegTranscoder) restoreUploader(ctx context.Context, stream *models.Stream, outputPath string, abrConfig ABRConfig) (*segmentUploader, error) {
+	u := t.newSegmentUploader(stream.StorageKey(), outputPath, abrConfig)
+	if stream.StartedAt != nil {
+		u.startedAt = *stream.StartedAt
+	}
+
+	for _, q := range u.qualities {
+		if err := os.MkdirAll(q.dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create quality directory %s: %w", q.name, err)
+		}
+
+		prefix := fmt.Sprintf("live-segments/%s/%s", u.storageKey, q.name)
+		playlist, err := t.loadPlaylist(ctx, prefix+"/playlist.m3u8")
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if playlist.Ended {
+			return nil, fmt.Errorf("broadcast of stream %s is already finished", stream.ID)
+		}
+		if len(playlist.Segments) == 0 {
+			continue
+		}
+
+		// Длительность ушедших из DVR окна сегментов в плейлисте не сохраняется:
+		// оцениваем её по номеру первого сегмента (сегменты режутся по GOP ровно SegmentTime)
+		first := playlist.Segments[0]
+		offset := float64(first.Sequence) * float64(abrConfig.SegmentTime)
+
+		q.window = dvr.RestoreWindow(abrConfig.DVRWindow, playlist, offset)
+		q.uploadedThrough = int(playlist.Segments[len(playlist.Segments)-1].Sequence)
+		q.playlistUploaded = true
+
+		// init нужен на диске для codecs в manifest.mpd
+		if playlist.InitURI != "" {
+			if err := t.storage.Download(ctx, prefix+"/"+playlist.InitURI, filepath.Join(q.dir, playlist.InitURI)); err != nil {
+				return nil, fmt.Errorf("failed to download %s/%s: %w", q.name, playlist.InitURI, err)
+			}
+			q.initUploaded = playlist.InitURI
+		}
+	}
+
+	return u, nil
+}
+
+func (t *FFmpegTranscoder) loadPlaylist(ctx context.Context, key string) (*dvr.Playlist, error) {
+	object, err := t.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return dvr.Parse(data)
+}
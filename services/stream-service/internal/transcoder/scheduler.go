@@ -0,0 +1,183 @@
+package transcoder
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrNoCapacity - свободных слотов не хватает даже на минимальный вывод эфира
+var ErrNoCapacity = errors.New("transcoder capacity exhausted")
+
+// PassthroughSlots - стоимость эфира без перекодирования (только перепаковка)
+const PassthroughSlots = 1
+
+// Scheduler распределяет CPU слоты транскодирования между эфирами.
+// Слот - примерно одно качество 360p30 (x264 veryfast); стоимость качества растёт
+// с числом пикселей в секунду. Когда слотов мало, эфир получает урезанную лестницу
+// или исходный поток без перекодирования, когда их нет совсем - отказ
+type Scheduler struct {
+	capacity int
+
+	mu     sync.Mutex
+	leases map[uuid.UUID]*Lease // stream ID → слоты эфира
+}
+
+func NewScheduler(capacity int) *Scheduler {
+	return &Scheduler{
+		capacity: capacity,
+		leases:   make(map[uuid.UUID]*Lease),
+	}
+}
+
+// Lease - слоты, выделенные эфиру. Держатся до конца эфира, включая ожидание
+// переподключения издателя: продолжение эфира не проходит admission заново
+type Lease struct {
+	scheduler *Scheduler
+	job       models.TranscoderJob
+}
+
+// CanAdmit проверяет, примет ли транскодер публикацию стрима: есть слоты
+// хотя бы на минимальный вывод или стрим продолжает эфир со своими слотами
+func (s *Scheduler) CanAdmit(streamID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.leases[streamID]; ok {
+		return true
+	}
+	return s.free() >= PassthroughSlots
+}
+
+// Acquire выделяет эфиру слоты и возвращает конфигурацию, которую они позволяют:
+// всю лестницу, лестницу без верхних качеств или исходный поток (если кодеки источника
+// подходят для HLS без перекодирования). Не хватает даже на это - ErrNoCapacity
+func (s *Scheduler) Acquire(streamID uuid.UUID, abrConfig ABRConfig) (ABRConfig, *Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	free := s.free()
+	granted, mode, ok := degrade(abrConfig, free)
+	if !ok {
+		log.Printf("❌ No transcoder capacity for stream %s (%d of %d slots free)", streamID, free, s.capacity)
+		return abrConfig, nil, ErrNoCapacity
+	}
+
+	lease := s.lease(streamID, granted, mode)
+	if mode != models.TranscodeFull {
+		log.Printf("⚠️ Transcoder capacity low (%d of %d slots free): stream %s degraded to %s %v",
+			free, s.capacity, streamID, mode, lease.job.Qualities)
+	}
+	log.Printf("🎛️ Stream %s takes %d transcoder slots (%d/%d used)", streamID, lease.job.Slots, s.capacity-free+lease.job.Slots, s.capacity)
+
+	return granted, lease, nil
+}
+
+// Reserve выделяет слоты под заданную конфигурацию без деградации: эфир продолжается
+// на другом процессе с той же лестницей качеств (ErrNoCapacity - не помещается)
+func (s *Scheduler) Reserve(streamID uuid.UUID, abrConfig ABRConfig, mode string) (*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if free := s.free(); abrConfig.Slots() > free {
+		log.Printf("❌ Not enough transcoder slots to continue stream %s (%d needed, %d free)", streamID, abrConfig.Slots(), free)
+		return nil, ErrNoCapacity
+	}
+
+	lease := s.lease(streamID, abrConfig, mode)
+	log.Printf("🎛️ Stream %s takes %d transcoder slots to continue broadcast", streamID, lease.job.Slots)
+	return lease, nil
+}
+
+// lease регистрирует слоты эфира (вызывается под mu)
+func (s *Scheduler) lease(streamID uuid.UUID, abrConfig ABRConfig, mode string) *Lease {
+	lease := &Lease{
+		scheduler: s,
+		job: models.TranscoderJob{
+			StreamID:  streamID,
+			Mode:      mode,
+			Qualities: abrConfig.ProfileNames(),
+			Slots:     abrConfig.Slots(),
+			Since:     time.Now(),
+		},
+	}
+	s.leases[streamID] = lease
+	return lease
+}
+
+// degrade подбирает вывод под free слотов: сначала отбрасываются верхние (самые дорогие) качества
+func degrade(abrConfig ABRConfig, free int) (ABRConfig, string, bool) {
+	for i := range abrConfig.Profiles {
+		ladder := abrConfig
+		ladder.Profiles = abrConfig.Profiles[i:]
+		if ladder.Slots() > free {
+			continue
+		}
+
+		if i == 0 {
+			return ladder, models.TranscodeFull, true
+		}
+		return ladder, models.TranscodeReduced, true
+	}
+
+	if abrConfig.CanPassthrough() && free >= PassthroughSlots {
+		abrConfig.Profiles = []Profile{passthroughProfile(*abrConfig.Source)}
+		return abrConfig, models.TranscodePassthrough, true
+	}
+
+	return abrConfig, "", false
+}
+
+// Usage возвращает текущую загрузку транскодера
+func (s *Scheduler) Usage() models.TranscoderUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*models.TranscoderJob, 0, len(s.leases))
+	for _, lease := range s.leases {
+		job := lease.job
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Since.Before(jobs[j].Since)
+	})
+
+	used := s.capacity - s.free()
+	return models.TranscoderUsage{
+		CapacitySlots: s.capacity,
+		UsedSlots:     used,
+		FreeSlots:     s.capacity - used,
+		Jobs:          jobs,
+	}
+}
+
+// free - свободные слоты (вызывается под mu)
+func (s *Scheduler) free() int {
+	used := 0
+	for _, lease := range s.leases {
+		used += lease.job.Slots
+	}
+	return s.capacity - used
+}
+
+// Mode - режим вывода эфира (models.Transcode*)
+func (l *Lease) Mode() string {
+	return l.job.Mode
+}
+
+// Release возвращает слоты эфира. Повторный вызов ничего не делает
+func (l *Lease) Release() {
+	s := l.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leases[l.job.StreamID] == l {
+		delete(s.leases, l.job.StreamID)
+		log.Printf("🎛️ Stream %s released %d transcoder slots", l.job.StreamID, l.job.Slots)
+	}
+}
@@ -0,0 +1,441 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/dvr"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	uploadMaxAttempts    = 6
+	uploadInitialBackoff = 500 * time.Millisecond
+	uploadMaxBackoff     = 8 * time.Second
+	uploadResyncInterval = 5 * time.Second // страховка на случай потерянных событий fsnotify
+)
+
+// segmentUploader загружает сегменты ABR в MinIO по событиям файловой системы.
+// Сегмент считается готовым, когда на него сослался playlist.m3u8 ffmpeg,
+// и загружается раньше плейлиста, который на него ссылается. Плейлист в MinIO
+// строится из DVR окна загруженных сегментов, а не копируется у ffmpeg.
+// Состояние качеств живёт весь эфир: при переподключении издателя новый ffmpeg
+// продолжает те же плейлисты (start/stop на каждый запуск, finish в конце эфира)
+type segmentUploader struct {
+	t          *FFmpegTranscoder
+	storageKey string
+	outputPath string
+	abrConfig  ABRConfig
+	startedAt  time.Time
+
+	watcher   *fsnotify.Watcher
+	qualities map[string]*qualityUploader
+	cancel    context.CancelFunc
+	done      chan struct{}
+	workers   sync.WaitGroup
+
+	mu             sync.Mutex // прогресс качеств
+	manifestMu     sync.Mutex // manifest.mpd загружается по порядку
+	masterMu       sync.Mutex
+	masterUploaded bool
+}
+
+// qualityUploader - состояние загрузки одного качества. Вместо множества
+// загруженных файлов хранится только номер последнего загруженного сегмента.
+type qualityUploader struct {
+	name    string
+	dir     string
+	changed chan struct{} // сигналы схлопываются: одна перечитка плейлиста на пачку событий
+
+	initUploaded     string
+	uploadedThrough  int         // последний загруженный номер сегмента (-1 - ни одного)
+	window           *dvr.Window // загруженные сегменты в пределах DVR окна
+	playlistUploaded bool
+}
+
+func (t *FFmpegTranscoder) newSegmentUploader(storageKey, outputPath string, abrConfig ABRConfig) *segmentUploader {
+	u := &segmentUploader{
+		t:          t,
+		storageKey: storageKey,
+		outputPath: outputPath,
+		abrConfig:  abrConfig,
+		startedAt:  time.Now(),
+		qualities:  make(map[string]*qualityUploader),
+	}
+
+	for _, profile := range abrConfig.Profiles {
+		u.qualities[profile.Name] = &qualityUploader{
+			name:            profile.Name,
+			dir:             filepath.Join(outputPath, profile.Name),
+			changed:         make(chan struct{}, 1),
+			uploadedThrough: -1,
+			window:          dvr.NewWindow(abrConfig.DVRWindow),
+		}
+	}
+	return u
+}
+
+// start начинает наблюдение за выводом очередного запуска ffmpeg.
+// resumed - издатель переподключился: следующие сегменты идут после разрыва
+func (u *segmentUploader) start(resumed bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create fsnotify watcher: %w", err)
+	}
+
+	if err := watcher.Add(u.outputPath); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", u.outputPath, err)
+	}
+	for _, q := range u.qualities {
+		if err := watcher.Add(q.dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", q.dir, err)
+		}
+	}
+
+	if resumed {
+		u.mu.Lock()
+		for _, q := range u.qualities {
+			q.window.Discontinue()
+		}
+		u.mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.watcher = watcher
+	u.cancel = cancel
+	u.done = make(chan struct{})
+
+	for _, q := range u.qualities {
+		u.workers.Add(1)
+		go u.runQuality(ctx, q)
+	}
+	go u.watch(ctx)
+
+	log.Printf("👀 Watching ABR output of stream %s", u.storageKey)
+	return nil
+}
+
+// stop останавливает наблюдение и загружает всё, что ffmpeg успел записать.
+// Плейлисты остаются открытыми: издатель может переподключиться
+func (u *segmentUploader) stop() {
+	u.cancel()
+	<-u.done
+	u.workers.Wait()
+
+	ctx := context.Background()
+	for _, q := range u.qualities {
+		u.syncQuality(ctx, q)
+	}
+	u.uploadMaster(ctx)
+	log.Printf("✅ All ABR segments uploaded for stream %s", u.storageKey)
+}
+
+// finish закрывает плейлисты EXT-X-ENDLIST и загружает static manifest.mpd - эфир окончен
+func (u *segmentUploader) finish() {
+	ctx := context.Background()
+	for _, q := range u.qualities {
+		u.mu.Lock()
+		uploaded := q.playlistUploaded
+		u.mu.Unlock()
+		if uploaded {
+			u.uploadPlaylist(ctx, q, true)
+		}
+	}
+
+	u.uploadDASHManifest(false)
+	log.Printf("✅ ABR playlists closed for stream %s", u.storageKey)
+}
+
+// nextSegment - номер, с которого продолжает нумерацию ffmpeg после переподключения
+func (u *segmentUploader) nextSegment() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	next := 0
+	for _, q := range u.qualities {
+		if q.uploadedThrough+1 > next {
+			next = q.uploadedThrough + 1
+		}
+	}
+	return next
+}
+
+// mediaDuration - длительность уже загруженного эфира в секундах
+func (u *segmentUploader) mediaDuration() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.qualities[u.abrConfig.Profiles[0].Name].window.Duration()
+}
+
+// watch превращает события fsnotify в сигналы качествам
+func (u *segmentUploader) watch(ctx context.Context) {
+	defer close(u.done)
+	defer u.watcher.Close()
+
+	resync := time.NewTicker(uploadResyncInterval)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-u.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			switch filepath.Base(event.Name) {
+			case "playlist.m3u8":
+				if q, ok := u.qualities[filepath.Base(filepath.Dir(event.Name))]; ok {
+					q.notify()
+				}
+			case "master.m3u8":
+				u.uploadMaster(ctx)
+			}
+
+		case err, ok := <-u.watcher.Errors:
+			if !ok {
+				return
+			}
+			// При переполнении очереди событий просто перечитываем все плейлисты
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("⚠️ fsnotify error for stream %s: %v", u.storageKey, err)
+			}
+			u.notifyAll()
+
+		case <-resync.C:
+			u.notifyAll()
+		}
+	}
+}
+
+func (u *segmentUploader) notifyAll() {
+	for _, q := range u.qualities {
+		q.notify()
+	}
+}
+
+func (q *qualityUploader) notify() {
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+// runQuality последовательно загружает сегменты качества. Пока идёт загрузка,
+// новые события схлопываются в один сигнал - это и есть backpressure.
+func (u *segmentUploader) runQuality(ctx context.Context, q *qualityUploader) {
+	defer u.workers.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.changed:
+			u.syncQuality(ctx, q)
+		}
+	}
+}
+
+// syncQuality загружает init и новые сегменты из снимка плейлиста ffmpeg,
+// затем плейлист DVR окна
+func (u *segmentUploader) syncQuality(ctx context.Context, q *qualityUploader) {
+	data, err := os.ReadFile(filepath.Join(q.dir, "playlist.m3u8"))
+	if err != nil {
+		return
+	}
+
+	initURI, entries := parseFFmpegPlaylist(data)
+	prefix := fmt.Sprintf("live-segments/%s/%s", u.storageKey, q.name)
+
+	if initURI != "" && initURI != u.initUploaded(q) {
+		err := uploadWithRetry(ctx, func() error {
+			return u.t.uploadFile(filepath.Join(q.dir, initURI), prefix+"/"+initURI, "video/mp4")
+		})
+		if err != nil {
+			log.Printf("❌ Failed to upload %s/%s: %v", q.name, initURI, err)
+			return
+		}
+		u.mu.Lock()
+		q.initUploaded = initURI
+		q.window.SetInit(initURI)
+		u.mu.Unlock()
+	}
+
+	for _, entry := range entries {
+		var number int
+		if _, err := fmt.Sscanf(entry.uri, "segment_%d.m4s", &number); err != nil {
+			continue
+		}
+		if number <= u.uploadedThrough(q) {
+			continue
+		}
+
+		err := uploadWithRetry(ctx, func() error {
+			return u.t.uploadFile(filepath.Join(q.dir, entry.uri), prefix+"/"+entry.uri, "video/mp4")
+		})
+		if err != nil {
+			// Плейлист не загружаем - он ссылался бы на отсутствующий сегмент
+			log.Printf("❌ Failed to upload %s/%s: %v", q.name, entry.uri, err)
+			return
+		}
+
+		programDateTime := entry.programDateTime
+		if programDateTime.IsZero() {
+			programDateTime = time.Now().Add(-time.Duration(entry.duration * float64(time.Second)))
+		}
+
+		u.mu.Lock()
+		q.uploadedThrough = number
+		q.window.Add(dvr.Segment{
+			Sequence:        int64(number),
+			URI:             entry.uri,
+			Duration:        entry.duration,
+			ProgramDateTime: programDateTime,
+		})
+		u.mu.Unlock()
+		log.Printf("📦 Uploaded %s/%s", q.name, entry.uri)
+	}
+
+	if u.uploadPlaylist(ctx, q, false) {
+		u.uploadMaster(ctx)
+		u.uploadDASHManifest(true)
+	}
+}
+
+// uploadPlaylist загружает плейлист DVR окна (все его сегменты уже в MinIO)
+// и удаляет вышедшие из окна сегменты. ended - эфир окончен, EXT-X-ENDLIST
+func (u *segmentUploader) uploadPlaylist(ctx context.Context, q *qualityUploader, ended bool) bool {
+	prefix := fmt.Sprintf("live-segments/%s/%s", u.storageKey, q.name)
+
+	u.mu.Lock()
+	playlist := q.window.Playlist(ended).Render("")
+	u.mu.Unlock()
+
+	err := uploadWithRetry(ctx, func() error {
+		return u.t.uploadBytes([]byte(playlist), prefix+"/playlist.m3u8", "application/vnd.apple.mpegurl")
+	})
+	if err != nil {
+		log.Printf("❌ Failed to upload %s/playlist.m3u8: %v", q.name, err)
+		return false
+	}
+
+	u.mu.Lock()
+	q.playlistUploaded = true
+	expired := q.window.Expired()
+	u.mu.Unlock()
+
+	// Вышедшие из окна сегменты больше не нужны ни в MinIO, ни на диске
+	u.t.deleteSegments(prefix, expired)
+	for _, uri := range expired {
+		os.Remove(filepath.Join(q.dir, uri))
+	}
+	return true
+}
+
+// uploadMaster загружает master.m3u8, когда у всех качеств уже есть плейлисты
+func (u *segmentUploader) uploadMaster(ctx context.Context) {
+	u.masterMu.Lock()
+	defer u.masterMu.Unlock()
+
+	if u.masterUploaded || !u.allPlaylistsUploaded() {
+		return
+	}
+
+	masterPath := filepath.Join(u.outputPath, "master.m3u8")
+	if _, err := os.Stat(masterPath); err != nil {
+		return
+	}
+
+	objectName := fmt.Sprintf("live-segments/%s/master.m3u8", u.storageKey)
+	err := uploadWithRetry(ctx, func() error {
+		return u.t.uploadFile(masterPath, objectName, "application/vnd.apple.mpegurl")
+	})
+	if err != nil {
+		log.Printf("❌ Failed to upload master.m3u8: %v", err)
+		return
+	}
+
+	u.masterUploaded = true
+	log.Printf("✅ Uploaded master.m3u8 for stream %s", u.storageKey)
+}
+
+// uploadDASHManifest обновляет manifest.mpd по сегментам DVR окна (все уже загружены)
+func (u *segmentUploader) uploadDASHManifest(live bool) {
+	u.manifestMu.Lock()
+	defer u.manifestMu.Unlock()
+
+	u.t.uploadDASHManifest(u.storageKey, u.outputPath, u.abrConfig, u.startedAt, live, func(quality string) (*dvr.Playlist, float64, bool) {
+		q, ok := u.qualities[quality]
+		if !ok {
+			return nil, 0, false
+		}
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		return q.window.Playlist(!live), q.window.Offset(), true
+	})
+}
+
+func (u *segmentUploader) allPlaylistsUploaded() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, q := range u.qualities {
+		if !q.playlistUploaded {
+			return false
+		}
+	}
+	return true
+}
+
+func (u *segmentUploader) initUploaded(q *qualityUploader) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return q.initUploaded
+}
+
+func (u *segmentUploader) uploadedThrough(q *qualityUploader) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return q.uploadedThrough
+}
+
+// uploadWithRetry повторяет загрузку с экспоненциальной задержкой
+func uploadWithRetry(ctx context.Context, fn func() error) error {
+	backoff := uploadInitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= uploadMaxAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == uploadMaxAttempts {
+			break
+		}
+
+		log.Printf("⚠️ Upload attempt %d/%d failed, retrying in %v: %v", attempt, uploadMaxAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > uploadMaxBackoff {
+			backoff = uploadMaxBackoff
+		}
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+// Package viewers - подсчёт зрителей live стримов по heartbeat'ам плееров.
+// Счётчики живут в памяти и периодически сбрасываются в БД вместе со статистикой эфира
+package viewers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// SessionTTL - зритель считается ушедшим, если heartbeat не приходил дольше
+	SessionTTL = 30 * time.Second
+
+	// FlushInterval - как часто счётчики сохраняются в БД
+	FlushInterval = 10 * time.Second
+
+	// maxSessionsPerStream ограничивает память на один стрим
+	maxSessionsPerStream = 100000
+
+	// maxSessionsPerClient - сколько сессий одного клиента (пользователь или IP) учитывается
+	// в стриме: session_id выбирает сам плеер, без лимита один клиент накручивает счётчик.
+	// Запас на несколько вкладок, устройств и зрителей за одним NAT
+	maxSessionsPerClient = 10
+)
+
+// Tracker считает уникальные сессии просмотра live стримов
+type Tracker struct {
+	streamRepo *repository.StreamRepository
+
+	mu         sync.Mutex
+	broadcasts map[uuid.UUID]*broadcast
+}
+
+// broadcast - сессии одного live стрима
+type broadcast struct {
+	sessions map[string]*session // session ID → сессия
+	clients  map[string]int      // клиент → число его сессий
+}
+
+type session struct {
+	client   string
+	lastSeen time.Time
+}
+
+func NewTracker(streamRepo *repository.StreamRepository) *Tracker {
+	return &Tracker{
+		streamRepo: streamRepo,
+		broadcasts: make(map[uuid.UUID]*broadcast),
+	}
+}
+
+// StartBroadcast начинает приём heartbeat'ов для стрима
+func (t *Tracker) StartBroadcast(streamID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broadcasts[streamID] = &broadcast{
+		sessions: make(map[string]*session),
+		clients:  make(map[string]int),
+	}
+}
+
+// EndBroadcast забывает зрителей завершённого стрима.
+// Счётчик в БД обнуляется при смене статуса на offline
+func (t *Tracker) EndBroadcast(streamID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.broadcasts, streamID)
+}
+
+// Heartbeat отмечает сессию клиента client активной и возвращает текущее число зрителей.
+// Сессии сверх maxSessionsPerClient и чужие сессии не учитываются. false - стрим не в эфире
+func (t *Tracker) Heartbeat(streamID uuid.UUID, sessionID, client string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.broadcasts[streamID]
+	if !ok {
+		return 0, false
+	}
+
+	if s, exists := b.sessions[sessionID]; exists {
+		if s.client == client {
+			s.lastSeen = time.Now()
+		}
+		return len(b.sessions), true
+	}
+
+	if len(b.sessions) >= maxSessionsPerStream || b.clients[client] >= maxSessionsPerClient {
+		return len(b.sessions), true
+	}
+	b.sessions[sessionID] = &session{client: client, lastSeen: time.Now()}
+	b.clients[client]++
+	return len(b.sessions), true
+}
+
+// Leave удаляет сессию клиента сразу, не дожидаясь SessionTTL
+func (t *Tracker) Leave(streamID uuid.UUID, sessionID, client string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.broadcasts[streamID]
+	if !ok {
+		return 0
+	}
+	if s, exists := b.sessions[sessionID]; exists && s.client == client {
+		b.remove(sessionID, s)
+	}
+	return len(b.sessions)
+}
+
+// Count возвращает текущее число зрителей стрима
+func (t *Tracker) Count(streamID uuid.UUID) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.broadcasts[streamID]
+	if !ok {
+		return 0
+	}
+	return len(b.sessions)
+}
+
+func (b *broadcast) remove(sessionID string, s *session) {
+	delete(b.sessions, sessionID)
+	if b.clients[s.client]--; b.clients[s.client] <= 0 {
+		delete(b.clients, s.client)
+	}
+}
+
+// Start периодически удаляет протухшие сессии и сохраняет счётчики в БД
+func (t *Tracker) Start(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	log.Printf("👀 Viewer tracker started (flush every %v, session TTL %v)", FlushInterval, SessionTTL)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.flush()
+		}
+	}
+}
+
+func (t *Tracker) flush() {
+	counts := t.snapshot()
+	if len(counts) == 0 {
+		return
+	}
+
+	if err := t.streamRepo.RecordViewerCounts(counts); err != nil {
+		log.Printf("❌ Failed to flush viewer counts: %v", err)
+	}
+}
+
+// snapshot удаляет протухшие сессии и возвращает число зрителей live стримов
+func (t *Tracker) snapshot() map[uuid.UUID]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deadline := time.Now().Add(-SessionTTL)
+	counts := make(map[uuid.UUID]int, len(t.broadcasts))
+	for streamID, b := range t.broadcasts {
+		for sessionID, s := range b.sessions {
+			if s.lastSeen.Before(deadline) {
+				b.remove(sessionID, s)
+			}
+		}
+		counts[streamID] = len(b.sessions)
+	}
+	return counts
+}
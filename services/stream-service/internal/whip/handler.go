@@ -0,0 +1,300 @@
+package whip
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/ingest"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/middleware"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	maxOfferSize      = 64 * 1024
+	iceGatherTimeout  = 5 * time.Second
+	connectTimeout    = 30 * time.Second
+	sdpContentType    = "application/sdp"
+	whipProtocolLabel = "WHIP"
+)
+
+type Config struct {
+	UDPPort  string // весь ICE трафик идёт через один UDP порт
+	PublicIP string // внешний IP для host кандидатов (если сервис за NAT/docker)
+}
+
+// Handler - WHIP (WebRTC-HTTP Ingestion Protocol) endpoint для публикации из браузера.
+// Принимает VP8 + Opus, пересобирает в WebM и отдаёт в общий pipeline публикации.
+type Handler struct {
+	streamRepo *repository.StreamRepository
+	publisher  *ingest.Publisher
+	jwtSecret  string
+	api        *webrtc.API
+	udpConn    net.PacketConn
+	sessions   map[string]*Session
+	mu         sync.Mutex
+}
+
+func NewHandler(cfg *Config, streamRepo *repository.StreamRepository, publisher *ingest.Publisher, jwtSecret string) (*Handler, error) {
+	mediaEngine := &webrtc.MediaEngine{}
+
+	// Только VP8 + Opus: их умеет WebM, который читает ffmpeg
+	videoFeedback := []webrtc.RTCPFeedback{
+		{Type: webrtc.TypeRTCPFBNACK},
+		{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+		{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"},
+		{Type: webrtc.TypeRTCPFBGoogREMB},
+	}
+	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoFeedback},
+		PayloadType:        96,
+	}, webrtc.RTPCodecTypeVideo); err != nil {
+		return nil, fmt.Errorf("failed to register VP8 codec: %w", err)
+	}
+	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"},
+		PayloadType:        111,
+	}, webrtc.RTPCodecTypeAudio); err != nil {
+		return nil, fmt.Errorf("failed to register Opus codec: %w", err)
+	}
+
+	// NACK, RTCP reports, TWCC
+	interceptors := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(mediaEngine, interceptors); err != nil {
+		return nil, fmt.Errorf("failed to register interceptors: %w", err)
+	}
+
+	udpConn, err := net.ListenPacket("udp", ":"+cfg.UDPPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on WHIP UDP port %s: %w", cfg.UDPPort, err)
+	}
+
+	settingEngine := webrtc.SettingEngine{}
+	settingEngine.SetICEUDPMux(webrtc.NewICEUDPMux(nil, udpConn))
+	if cfg.PublicIP != "" {
+		settingEngine.SetNAT1To1IPs([]string{cfg.PublicIP}, webrtc.ICECandidateTypeHost)
+	}
+
+	log.Printf("📡 WHIP ICE listening on UDP port %s", cfg.UDPPort)
+
+	return &Handler{
+		streamRepo: streamRepo,
+		publisher:  publisher,
+		jwtSecret:  jwtSecret,
+		api: webrtc.NewAPI(
+			webrtc.WithMediaEngine(mediaEngine),
+			webrtc.WithInterceptorRegistry(interceptors),
+			webrtc.WithSettingEngine(settingEngine),
+		),
+		udpConn:  udpConn,
+		sessions: make(map[string]*Session),
+	}, nil
+}
+
+// HandleOffer принимает SDP offer и отвечает SDP answer (POST /streams/:id/whip)
+func (h *Handler) HandleOffer(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	if !strings.HasPrefix(c.ContentType(), sdpContentType) {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{Error: "Content-Type must be application/sdp"})
+		return
+	}
+
+	stream, err := h.streamRepo.GetStreamByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return
+	}
+
+	keyID, ok := h.authorize(c, stream)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid stream key or token"})
+		return
+	}
+	// Издатель запоминает ключ, чтобы его отзыв прервал публикацию
+	stream.IngestKeyID = keyID
+
+	// Reject second publisher for the same stream
+	if h.publisher.IsPublishing(stream.ID) {
+		log.Printf("❌ Stream %s is already live, rejecting WHIP offer", stream.ID)
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Stream is already live"})
+		return
+	}
+
+	if !h.publisher.HasCapacity(stream.ID) {
+		log.Printf("❌ No transcoder capacity for stream %s, rejecting WHIP offer", stream.ID)
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Server is overloaded, try again later"})
+		return
+	}
+
+	offer, err := io.ReadAll(io.LimitReader(c.Request.Body, maxOfferSize))
+	if err != nil || len(offer) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read SDP offer"})
+		return
+	}
+
+	session, answer, err := h.startSession(stream, string(offer))
+	if err != nil {
+		log.Printf("❌ WHIP negotiation failed for stream %s: %v", stream.ID, err)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Относительный Location работает и напрямую, и через API Gateway (/api/streams/...)
+	c.Header("Location", "whip/"+session.ID)
+	c.Data(http.StatusCreated, sdpContentType, []byte(answer))
+}
+
+// HandleDelete завершает WHIP сессию (DELETE /streams/:id/whip/:session)
+func (h *Handler) HandleDelete(c *gin.Context) {
+	h.mu.Lock()
+	session, exists := h.sessions[c.Param("session")]
+	h.mu.Unlock()
+
+	if !exists || session.stream.ID.String() != c.Param("id") {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Session not found"})
+		return
+	}
+
+	if _, ok := h.authorize(c, session.stream); !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid stream key or token"})
+		return
+	}
+
+	// Браузер закончил эфир сам - переподключения не ждём
+	h.publisher.FinishPublishing(session.stream.ID)
+	session.Close()
+	c.Status(http.StatusOK)
+}
+
+// Close завершает все активные сессии и освобождает UDP порт
+func (h *Handler) Close() {
+	h.mu.Lock()
+	sessions := make([]*Session, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+
+	for _, session := range sessions {
+		session.Close()
+	}
+	h.udpConn.Close()
+}
+
+// authorize принимает Bearer stream key или JWT владельца стрима и возвращает ID
+// ключа (uuid.Nil для JWT). stream не изменяется: DELETE передаёт стрим активной сессии
+func (h *Handler) authorize(c *gin.Context, stream *models.Stream) (uuid.UUID, bool) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return uuid.Nil, false
+	}
+
+	// Ключ должен принадлежать именно этому стриму
+	if keyed, err := h.streamRepo.GetStreamByIngestKey(token); err == nil {
+		if keyed.ID != stream.ID {
+			return uuid.Nil, false
+		}
+		return keyed.IngestKeyID, true
+	}
+
+	claims, err := middleware.ValidateToken(token, h.jwtSecret)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return uuid.Nil, claims.UserID == stream.UserID
+}
+
+func (h *Handler) startSession(stream *models.Stream, offer string) (*Session, string, error) {
+	pc, err := h.api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create peer connection: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	session := newSession(uuid.New().String(), stream, pc, pw)
+
+	answer, err := negotiate(pc, offer)
+	if err != nil {
+		session.Close()
+		return nil, "", err
+	}
+
+	h.mu.Lock()
+	h.sessions[session.ID] = session
+	h.mu.Unlock()
+
+	log.Printf("✅ WHIP session %s started for stream %s", session.ID, stream.ID)
+
+	go h.runPublish(session, pr)
+
+	return session, answer, nil
+}
+
+// runPublish отдаёт WebM поток сессии в общий pipeline (статус, webhooks, ABR)
+func (h *Handler) runPublish(session *Session, pr *io.PipeReader) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, session.ID)
+		h.mu.Unlock()
+	}()
+
+	// Стрим становится live только после установки соединения
+	select {
+	case <-session.Connected():
+	case <-session.Done():
+		return
+	case <-time.After(connectTimeout):
+		log.Printf("❌ WHIP session %s did not connect in %s", session.ID, connectTimeout)
+		session.Close()
+		return
+	}
+
+	if err := h.publisher.Publish(session.stream, pr, whipProtocolLabel); err != nil {
+		log.Printf("❌ WHIP publish failed for stream %s: %v", session.stream.ID, err)
+	}
+
+	// ffmpeg завершился - закрываем WebRTC, даже если браузер ещё шлёт медиа
+	pr.Close()
+	session.Close()
+}
+
+// negotiate применяет offer и возвращает answer со всеми ICE кандидатами (WHIP без trickle)
+func negotiate(pc *webrtc.PeerConnection, offer string) (string, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", fmt.Errorf("invalid SDP offer: %w", err)
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create answer: %w", err)
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return "", fmt.Errorf("failed to set local description: %w", err)
+	}
+
+	select {
+	case <-gatherComplete:
+	case <-time.After(iceGatherTimeout):
+		log.Printf("⚠️ ICE gathering timed out, answering with partial candidates")
+	}
+
+	return pc.LocalDescription().SDP, nil
+}
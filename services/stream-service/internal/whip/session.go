@@ -0,0 +1,179 @@
+package whip
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media/samplebuilder"
+)
+
+const (
+	// Сколько пакетов samplebuilder ждёт опоздавшие/переупорядоченные пакеты
+	videoMaxLate = 256
+	audioMaxLate = 32
+
+	// Интервал PLI пока не пришёл первый ключевой кадр
+	keyframeRequestInterval = 2 * time.Second
+)
+
+// Session - одна WHIP публикация: PeerConnection + WebM поток в ffmpeg
+type Session struct {
+	ID     string
+	stream *models.Stream
+	pc     *webrtc.PeerConnection
+	muxer  *webmMuxer
+	pw     *io.PipeWriter
+
+	startedAt     time.Time
+	connected     chan struct{}
+	connectedOnce sync.Once
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newSession(id string, stream *models.Stream, pc *webrtc.PeerConnection, pw *io.PipeWriter) *Session {
+	s := &Session{
+		ID:        id,
+		stream:    stream,
+		pc:        pc,
+		muxer:     newWebMMuxer(pw),
+		pw:        pw,
+		startedAt: time.Now(),
+		connected: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	pc.OnTrack(s.handleTrack)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		log.Printf("🔌 WHIP session %s (stream %s): connection state %s", s.ID, stream.ID, state)
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			s.connectedOnce.Do(func() { close(s.connected) })
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
+			s.Close()
+		}
+	})
+
+	return s
+}
+
+// Connected закрывается когда ICE/DTLS соединение установлено
+func (s *Session) Connected() <-chan struct{} {
+	return s.connected
+}
+
+// Done закрывается при завершении сессии
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close завершает сессию: ffmpeg получает EOF, PeerConnection закрывается
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.pw.CloseWithError(io.EOF)
+		if err := s.pc.Close(); err != nil {
+			log.Printf("⚠️ Failed to close WHIP peer connection %s: %v", s.ID, err)
+		}
+		log.Printf("⏹️  WHIP session %s closed", s.ID)
+	})
+}
+
+// handleTrack читает RTP входящего трека, собирает кадры и пишет их в WebM
+func (s *Session) handleTrack(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+	codec := track.Codec()
+	log.Printf("🎞️  WHIP session %s: track %s (%s)", s.ID, track.Kind(), codec.MimeType)
+
+	var (
+		builder *samplebuilder.SampleBuilder
+		write   func(timestamp int64, frame []byte) error
+	)
+
+	switch {
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeVP8):
+		builder = samplebuilder.New(videoMaxLate, &codecs.VP8Packet{}, codec.ClockRate)
+		write = s.muxer.WriteVideo
+		go s.requestKeyframes(track.SSRC())
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeOpus):
+		builder = samplebuilder.New(audioMaxLate, &codecs.OpusPacket{}, codec.ClockRate)
+		write = s.muxer.WriteAudio
+	default:
+		log.Printf("⚠️ WHIP session %s: unsupported codec %s, ignoring track", s.ID, codec.MimeType)
+		return
+	}
+
+	clock := &trackClock{rate: codec.ClockRate}
+
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("⚠️ WHIP session %s: %s track read ended: %v", s.ID, track.Kind(), err)
+			}
+			return
+		}
+
+		builder.Push(packet)
+		for sample := builder.Pop(); sample != nil; sample = builder.Pop() {
+			timestamp := clock.millis(sample.PacketTimestamp, s.startedAt)
+			if err := write(timestamp, sample.Data); err != nil {
+				log.Printf("⚠️ WHIP session %s: failed to write %s frame: %v", s.ID, track.Kind(), err)
+				s.Close()
+				return
+			}
+		}
+	}
+}
+
+// requestKeyframes шлёт PLI пока muxer не получил первый ключевой кадр
+func (s *Session) requestKeyframes(ssrc webrtc.SSRC) {
+	ticker := time.NewTicker(keyframeRequestInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.muxer.Started() {
+			return
+		}
+
+		if err := s.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(ssrc)}}); err != nil {
+			return
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trackClock переводит RTP timestamp трека в миллисекунды от начала сессии.
+// Треки синхронизируются по времени прихода первого пакета.
+type trackClock struct {
+	rate    uint32
+	started bool
+	offset  int64 // мс от начала сессии до первого пакета
+	last    uint32
+	ticks   int64
+}
+
+func (c *trackClock) millis(timestamp uint32, sessionStart time.Time) int64 {
+	if !c.started {
+		c.started = true
+		c.offset = time.Since(sessionStart).Milliseconds()
+		c.last = timestamp
+	}
+
+	// Разница через int32 корректно обрабатывает переполнение uint32
+	c.ticks += int64(int32(timestamp - c.last))
+	c.last = timestamp
+
+	return c.offset + c.ticks*1000/int64(c.rate)
+}
@@ -0,0 +1,306 @@
+package whip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+)
+
+// Matroska / WebM element IDs (маркерные биты уже включены)
+const (
+	ebmlHeaderID         = 0x1A45DFA3
+	ebmlVersionID        = 0x4286
+	ebmlReadVersionID    = 0x42F7
+	ebmlMaxIDLengthID    = 0x42F2
+	ebmlMaxSizeLengthID  = 0x42F3
+	ebmlDocTypeID        = 0x4282
+	ebmlDocTypeVerID     = 0x4287
+	ebmlDocTypeReadVerID = 0x4285
+
+	segmentID       = 0x18538067
+	infoID          = 0x1549A966
+	timecodeScaleID = 0x2AD7B1
+	muxingAppID     = 0x4D80
+	writingAppID    = 0x5741
+
+	tracksID       = 0x1654AE6B
+	trackEntryID   = 0xAE
+	trackNumberID  = 0xD7
+	trackUIDID     = 0x73C5
+	trackTypeID    = 0x83
+	codecIDID      = 0x86
+	codecPrivateID = 0x63A2
+	videoID        = 0xE0
+	pixelWidthID   = 0xB0
+	pixelHeightID  = 0xBA
+	audioID        = 0xE1
+	samplingFreqID = 0xB5
+	channelsID     = 0x9F
+
+	clusterID     = 0x1F43B675
+	timecodeID    = 0xE7
+	simpleBlockID = 0xA3
+)
+
+const (
+	videoTrackNumber = 1
+	audioTrackNumber = 2
+
+	// Относительный timecode блока - int16, новый кластер открываем с запасом
+	maxClusterSpanMs = 30000
+)
+
+// unknownSize - размер Segment/Cluster для live записи (длина заранее неизвестна)
+var unknownSize = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// webmMuxer пишет live WebM (VP8 + Opus) для передачи в ffmpeg через stdin.
+// Заголовок пишется на первом ключевом кадре видео (из него берётся разрешение),
+// всё что пришло раньше отбрасывается - ffmpeg должен начать с декодируемого кадра.
+type webmMuxer struct {
+	w             io.Writer
+	mu            sync.Mutex
+	headerWritten bool
+	clusterOpen   bool
+	clusterTime   int64 // мс
+}
+
+func newWebMMuxer(w io.Writer) *webmMuxer {
+	return &webmMuxer{w: w}
+}
+
+// Started сообщает записан ли уже первый ключевой кадр
+func (m *webmMuxer) Started() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.headerWritten
+}
+
+// WriteVideo пишет VP8 кадр с таймстемпом в миллисекундах
+func (m *webmMuxer) WriteVideo(timestamp int64, frame []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keyframe := isVP8Keyframe(frame)
+
+	if !m.headerWritten {
+		if !keyframe {
+			return nil
+		}
+		width, height := vp8Dimensions(frame)
+		if err := m.writeHeader(width, height); err != nil {
+			return err
+		}
+		m.headerWritten = true
+	}
+
+	// Каждый ключевой кадр открывает новый кластер
+	if keyframe || m.clusterExpired(timestamp) {
+		if err := m.startCluster(timestamp); err != nil {
+			return err
+		}
+	}
+
+	return m.writeBlock(videoTrackNumber, timestamp, keyframe, frame)
+}
+
+// WriteAudio пишет Opus пакет с таймстемпом в миллисекундах
+func (m *webmMuxer) WriteAudio(timestamp int64, frame []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.headerWritten {
+		return nil
+	}
+
+	if m.clusterExpired(timestamp) {
+		if err := m.startCluster(timestamp); err != nil {
+			return err
+		}
+	}
+
+	return m.writeBlock(audioTrackNumber, timestamp, true, frame)
+}
+
+func (m *webmMuxer) clusterExpired(timestamp int64) bool {
+	if !m.clusterOpen {
+		return true
+	}
+	delta := timestamp - m.clusterTime
+	return delta > maxClusterSpanMs || delta < -maxClusterSpanMs
+}
+
+func (m *webmMuxer) writeHeader(width, height uint16) error {
+	var buf bytes.Buffer
+
+	buf.Write(ebmlElement(ebmlHeaderID, concat(
+		ebmlUint(ebmlVersionID, 1),
+		ebmlUint(ebmlReadVersionID, 1),
+		ebmlUint(ebmlMaxIDLengthID, 4),
+		ebmlUint(ebmlMaxSizeLengthID, 8),
+		ebmlString(ebmlDocTypeID, "webm"),
+		ebmlUint(ebmlDocTypeVerID, 4),
+		ebmlUint(ebmlDocTypeReadVerID, 2),
+	)))
+
+	buf.Write(ebmlID(segmentID))
+	buf.Write(unknownSize)
+
+	buf.Write(ebmlElement(infoID, concat(
+		ebmlUint(timecodeScaleID, 1000000), // 1 мс
+		ebmlString(muxingAppID, "stream-service"),
+		ebmlString(writingAppID, "stream-service-whip"),
+	)))
+
+	videoTrack := ebmlElement(trackEntryID, concat(
+		ebmlUint(trackNumberID, videoTrackNumber),
+		ebmlUint(trackUIDID, videoTrackNumber),
+		ebmlUint(trackTypeID, 1),
+		ebmlString(codecIDID, "V_VP8"),
+		ebmlElement(videoID, concat(
+			ebmlUint(pixelWidthID, uint64(width)),
+			ebmlUint(pixelHeightID, uint64(height)),
+		)),
+	))
+
+	audioTrack := ebmlElement(trackEntryID, concat(
+		ebmlUint(trackNumberID, audioTrackNumber),
+		ebmlUint(trackUIDID, audioTrackNumber),
+		ebmlUint(trackTypeID, 2),
+		ebmlString(codecIDID, "A_OPUS"),
+		ebmlElement(codecPrivateID, opusHead()),
+		ebmlElement(audioID, concat(
+			ebmlFloat(samplingFreqID, 48000),
+			ebmlUint(channelsID, 2),
+		)),
+	))
+
+	buf.Write(ebmlElement(tracksID, concat(videoTrack, audioTrack)))
+
+	_, err := m.w.Write(buf.Bytes())
+	return err
+}
+
+func (m *webmMuxer) startCluster(timestamp int64) error {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+
+	var buf bytes.Buffer
+	buf.Write(ebmlID(clusterID))
+	buf.Write(unknownSize)
+	buf.Write(ebmlUint(timecodeID, uint64(timestamp)))
+
+	if _, err := m.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	m.clusterOpen = true
+	m.clusterTime = timestamp
+	return nil
+}
+
+func (m *webmMuxer) writeBlock(track uint64, timestamp int64, keyframe bool, frame []byte) error {
+	block := make([]byte, 0, len(frame)+4)
+	block = append(block, ebmlSize(track)...)
+	block = binary.BigEndian.AppendUint16(block, uint16(int16(timestamp-m.clusterTime)))
+
+	var flags byte
+	if keyframe {
+		flags |= 0x80
+	}
+	block = append(block, flags)
+	block = append(block, frame...)
+
+	_, err := m.w.Write(ebmlElement(simpleBlockID, block))
+	return err
+}
+
+// isVP8Keyframe - бит P в заголовке VP8 кадра равен 0 для ключевых кадров
+func isVP8Keyframe(frame []byte) bool {
+	return len(frame) > 0 && frame[0]&0x01 == 0
+}
+
+// vp8Dimensions читает разрешение из заголовка ключевого кадра VP8
+func vp8Dimensions(frame []byte) (uint16, uint16) {
+	if len(frame) < 10 || frame[3] != 0x9d || frame[4] != 0x01 || frame[5] != 0x2a {
+		return 0, 0
+	}
+	width := binary.LittleEndian.Uint16(frame[6:8]) & 0x3fff
+	height := binary.LittleEndian.Uint16(frame[8:10]) & 0x3fff
+	return width, height
+}
+
+// opusHead - CodecPrivate для A_OPUS (RFC 7845, stereo 48kHz)
+func opusHead() []byte {
+	head := []byte("OpusHead")
+	head = append(head, 1, 2)                            // version, channels
+	head = binary.LittleEndian.AppendUint16(head, 312)   // pre-skip
+	head = binary.LittleEndian.AppendUint32(head, 48000) // input sample rate
+	head = binary.LittleEndian.AppendUint16(head, 0)     // output gain
+	head = append(head, 0)                               // channel mapping family
+	return head
+}
+
+// EBML helpers
+
+func ebmlID(id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFFFF:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFF:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+// ebmlSize кодирует размер как EBML vint минимальной длины
+func ebmlSize(size uint64) []byte {
+	length := 1
+	for length < 8 && size >= (uint64(1)<<(7*length))-1 {
+		length++
+	}
+
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		buf[i] = byte(size)
+		size >>= 8
+	}
+	buf[0] |= 0x80 >> (length - 1)
+	return buf
+}
+
+func ebmlElement(id uint32, payload []byte) []byte {
+	out := ebmlID(id)
+	out = append(out, ebmlSize(uint64(len(payload)))...)
+	return append(out, payload...)
+}
+
+func ebmlUint(id uint32, value uint64) []byte {
+	var payload []byte
+	for value > 0 {
+		payload = append([]byte{byte(value)}, payload...)
+		value >>= 8
+	}
+	if len(payload) == 0 {
+		payload = []byte{0}
+	}
+	return ebmlElement(id, payload)
+}
+
+func ebmlFloat(id uint32, value float64) []byte {
+	payload := binary.BigEndian.AppendUint64(nil, math.Float64bits(value))
+	return ebmlElement(id, payload)
+}
+
+func ebmlString(id uint32, value string) []byte {
+	return ebmlElement(id, []byte(value))
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
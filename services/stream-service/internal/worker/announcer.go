@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+)
+
+// Announcer регистрирует worker'а на ingest узле и сообщает его загрузку heartbeat'ами
+type Announcer struct {
+	id        string
+	url       string // адрес worker'а, по которому ingest шлёт задания
+	ingestURL string
+	apiKey    string
+	scheduler *transcoder.Scheduler
+	client    *http.Client
+}
+
+func NewAnnouncer(id, url, ingestURL, apiKey string, scheduler *transcoder.Scheduler) *Announcer {
+	return &Announcer{
+		id:        id,
+		url:       url,
+		ingestURL: ingestURL,
+		apiKey:    apiKey,
+		scheduler: scheduler,
+		client:    &http.Client{Timeout: HeartbeatInterval},
+	}
+}
+
+// Run шлёт heartbeat'ы, пока не отменён ctx
+func (a *Announcer) Run(ctx context.Context) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	registered := false
+	for {
+		if err := a.heartbeat(ctx); err != nil {
+			if registered {
+				log.Printf("⚠️ Heartbeat to ingest %s failed: %v", a.ingestURL, err)
+			}
+			registered = false
+		} else if !registered {
+			log.Printf("✅ Worker %s registered at ingest %s as %s", a.id, a.ingestURL, a.url)
+			registered = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Announcer) heartbeat(ctx context.Context) error {
+	usage := a.scheduler.Usage()
+	payload, err := json.Marshal(models.TranscodeWorker{
+		ID:            a.id,
+		URL:           a.url,
+		CapacitySlots: usage.CapacitySlots,
+		FreeSlots:     usage.FreeSlots,
+		Jobs:          len(usage.Jobs),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.ingestURL+"/internal/workers/heartbeat", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, a.apiKey)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// AdvertiseURL - адрес worker'а, видимый с ingest узла: IP интерфейса, через который
+// идёт трафик к ingest (в docker у каждой реплики свой)
+func AdvertiseURL(ingestURL, port string) (string, error) {
+	parsed, err := url.Parse(ingestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid ingest URL: %w", err)
+	}
+
+	host := parsed.Host
+	if parsed.Port() == "" {
+		host = net.JoinHostPort(parsed.Hostname(), "80")
+	}
+
+	// UDP "соединение" ничего не отправляет, только выбирает маршрут
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve route to ingest: %w", err)
+	}
+	defer conn.Close()
+
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	return "http://" + net.JoinHostPort(ip.String(), port), nil
+}
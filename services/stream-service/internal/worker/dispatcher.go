@@ -0,0 +1,397 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/google/uuid"
+)
+
+var (
+	// errWorkerLost - соединение с worker'ом оборвалось или он перестал слать heartbeat'ы
+	errWorkerLost = errors.New("transcode worker lost")
+	// errWorkerBusy - у worker'а не хватило слотов на задание
+	errWorkerBusy = errors.New("transcode worker is busy")
+)
+
+// finishTimeout - закрытие плейлистов эфира на worker'е
+const finishTimeout = 30 * time.Second
+
+// Dispatcher - вывод эфиров на transcode worker'ах (ingest.Transcoder ingest узла).
+// LL-HLS эфиры и WHIP (WebM) транскодируются локально: части LL-HLS раздаются из памяти
+// процесса, который их производит, а WebM, в отличие от MPEG-TS, нельзя подхватить с середины
+type Dispatcher struct {
+	local    *transcoder.FFmpegTranscoder
+	registry *Registry
+	apiKey   string
+	client   *http.Client // без таймаута: задание живёт весь эфир
+
+	mu   sync.Mutex
+	jobs map[*transcoder.Broadcast]*remoteBroadcast
+}
+
+// remoteBroadcast - эфир с точки зрения ingest узла: где он выводится и что продолжать
+type remoteBroadcast struct {
+	streamID  uuid.UUID
+	local     bool   // выводится локальным ffmpeg
+	workerID  string // последний worker эфира: у него состояние эфира в памяти
+	started   bool   // worker начал вывод - следующее задание продолжает эфир
+	qualities []string
+	mode      string
+}
+
+func NewDispatcher(local *transcoder.FFmpegTranscoder, registry *Registry, apiKey string) *Dispatcher {
+	return &Dispatcher{
+		local:    local,
+		registry: registry,
+		apiKey:   apiKey,
+		client:   &http.Client{},
+		jobs:     make(map[*transcoder.Broadcast]*remoteBroadcast),
+	}
+}
+
+// CanAdmit - стрим продолжает свой эфир или где-то есть свободные слоты
+func (d *Dispatcher) CanAdmit(streamID uuid.UUID) bool {
+	d.mu.Lock()
+	for _, job := range d.jobs {
+		if job.streamID == streamID {
+			d.mu.Unlock()
+			return true
+		}
+	}
+	d.mu.Unlock()
+
+	return d.registry.HasCapacity() || d.local.CanAdmit(streamID)
+}
+
+// TranscodeToHLS выводит публикацию на worker'е, а если он пропадает посреди эфира -
+// продолжает эфир на другом. Блокируется до конца публикации
+func (d *Dispatcher) TranscodeToHLS(ctx context.Context, input io.Reader, stream *models.Stream, broadcast *transcoder.Broadcast, hooks transcoder.Hooks) error {
+	job := d.job(broadcast, stream.ID)
+
+	reader := bufio.NewReaderSize(input, 64*1024)
+	format := sniffFormat(reader)
+
+	d.mu.Lock()
+	if !job.started && (stream.LowLatency || format == formatWebM || format == formatUnknown) {
+		job.local = true
+	}
+	local, started := job.local, job.started
+	d.mu.Unlock()
+
+	if local {
+		return d.local.TranscodeToHLS(ctx, reader, stream, broadcast, hooks)
+	}
+	if started && format == formatWebM {
+		return fmt.Errorf("cannot continue broadcast of stream %s on a worker with WebM input", stream.ID)
+	}
+
+	var ts io.Reader = reader
+	if format == formatFLV {
+		// RTMP: FLV перепаковывается в MPEG-TS без перекодирования
+		remuxCtx, cancel := context.WithCancel(ctx)
+		remuxed, wait, err := remuxToMPEGTS(remuxCtx, reader)
+		if err != nil {
+			cancel()
+			return err
+		}
+		defer func() {
+			cancel()
+			wait()
+		}()
+		ts = remuxed
+	}
+
+	return d.dispatch(ctx, ts, stream, job, hooks)
+}
+
+// FinishBroadcast закрывает плейлисты эфира на worker'е: последнем, если он жив,
+// иначе на любом - тот восстановит плейлисты из хранилища
+func (d *Dispatcher) FinishBroadcast(broadcast *transcoder.Broadcast) {
+	d.mu.Lock()
+	job, ok := d.jobs[broadcast]
+	delete(d.jobs, broadcast)
+	d.mu.Unlock()
+
+	if !ok || job.local || !job.started {
+		d.local.FinishBroadcast(broadcast)
+		return
+	}
+
+	request := FinishRequest{Qualities: job.qualities, Mode: job.mode}
+	candidates := d.registry.Workers()
+	if w, ok := d.registry.Get(job.workerID); ok {
+		candidates = append([]*models.TranscodeWorker{w}, candidates...)
+	}
+
+	tried := make(map[string]bool)
+	for _, w := range candidates {
+		if tried[w.ID] {
+			continue
+		}
+		tried[w.ID] = true
+
+		if err := d.finish(w, job.streamID, request); err != nil {
+			log.Printf("⚠️ Transcode worker %s failed to finish stream %s: %v", w.ID, job.streamID, err)
+			continue
+		}
+		log.Printf("✅ Broadcast of stream %s finished on worker %s", job.streamID, w.ID)
+		return
+	}
+
+	log.Printf("❌ No transcode worker could finish broadcast of stream %s, playlists stay open", job.streamID)
+}
+
+func (d *Dispatcher) job(broadcast *transcoder.Broadcast, streamID uuid.UUID) *remoteBroadcast {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	job, ok := d.jobs[broadcast]
+	if !ok {
+		job = &remoteBroadcast{streamID: streamID}
+		d.jobs[broadcast] = job
+	}
+	return job
+}
+
+// dispatch отправляет задание worker'ам, пока эфир не закончится или не останется живых
+func (d *Dispatcher) dispatch(ctx context.Context, input io.Reader, stream *models.Stream, job *remoteBroadcast, hooks transcoder.Hooks) error {
+	exclude := make(map[string]bool)
+	var started bool // OnStarted - один раз за публикацию, даже после failover
+
+	for {
+		d.mu.Lock()
+		preferred, resume := job.workerID, job.started
+		d.mu.Unlock()
+
+		w, ok := d.registry.Pick(preferred, exclude)
+		if !ok {
+			if resume {
+				return fmt.Errorf("no transcode worker available to continue stream %s", stream.ID)
+			}
+			return transcoder.ErrNoCapacity
+		}
+
+		err := d.run(ctx, w, input, job, hooks, &started)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !errors.Is(err, errWorkerLost) && !errors.Is(err, errWorkerBusy) {
+			return err
+		}
+
+		log.Printf("🔀 Stream %s: %v, moving to another transcode worker", stream.ID, err)
+		exclude[w.ID] = true
+	}
+}
+
+// run - одно задание на worker'е. Возвращает nil, когда публикация закончилась
+// и worker всё загрузил
+func (d *Dispatcher) run(ctx context.Context, w *models.TranscodeWorker, input io.Reader, job *remoteBroadcast, hooks transcoder.Hooks, started *bool) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d.mu.Lock()
+	spec, err := json.Marshal(Job{Resume: job.started, Qualities: job.qualities, Mode: job.mode})
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	body, bodyWriter := io.Pipe()
+	req, err := http.NewRequestWithContext(runCtx, http.MethodPost, fmt.Sprintf("%s/jobs/%s", w.URL, job.streamID), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "video/mp2t")
+	req.Header.Set(jobHeader, string(spec))
+	req.Header.Set(apiKeyHeader, d.apiKey)
+
+	// Тело запроса - публикация. Конец публикации штатно закрывает тело,
+	// обрыв связи с worker'ом останавливает копирование: остаток потока получит следующий
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		io.Copy(bodyWriter, input)
+		bodyWriter.Close()
+	}()
+	defer func() {
+		body.CloseWithError(errWorkerLost)
+		<-copied
+	}()
+
+	// Worker без heartbeat'ов считается мёртвым, даже если соединение ещё открыто
+	var silent atomic.Bool
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if !d.registry.Alive(w.ID) {
+					silent.Store(true)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: worker %s: %v", errWorkerLost, w.ID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusServiceUnavailable:
+		return fmt.Errorf("%w: worker %s", errWorkerBusy, w.ID)
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("transcode worker %s rejected stream %s: %s %s", w.ID, job.streamID, resp.Status, bytes.TrimSpace(message))
+	}
+
+	log.Printf("🛠️ Stream %s is transcoded by worker %s", job.streamID, w.ID)
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if silent.Load() {
+				return fmt.Errorf("%w: worker %s stopped sending heartbeats", errWorkerLost, w.ID)
+			}
+			return fmt.Errorf("%w: worker %s: %v", errWorkerLost, w.ID, err)
+		}
+
+		switch event.Event {
+		case EventStarted:
+			d.mu.Lock()
+			job.started = true
+			job.workerID = w.ID
+			job.qualities = event.Qualities
+			job.mode = event.Mode
+			d.mu.Unlock()
+
+			if !*started {
+				*started = true
+				if hooks.OnStarted != nil {
+					hooks.OnStarted()
+				}
+			}
+
+		case EventProgress:
+			if hooks.OnProgress != nil && event.Progress != nil {
+				hooks.OnProgress(*event.Progress)
+			}
+
+		case EventCompleted:
+			return nil
+
+		case EventFailed:
+			if event.Busy {
+				return fmt.Errorf("%w: worker %s: %s", errWorkerBusy, w.ID, event.Error)
+			}
+			return fmt.Errorf("transcode worker %s: %s", w.ID, event.Error)
+		}
+	}
+}
+
+func (d *Dispatcher) finish(w *models.TranscodeWorker, streamID uuid.UUID, request FinishRequest) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/jobs/%s/finish", w.URL, streamID), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, d.apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// Форматы входящего потока ingest
+const (
+	formatUnknown = iota
+	formatMPEGTS  // SRT
+	formatFLV     // RTMP
+	formatWebM    // WHIP
+)
+
+// sniffFormat определяет контейнер по первым байтам, не забирая их из reader
+func sniffFormat(reader *bufio.Reader) int {
+	head, _ := reader.Peek(4)
+	switch {
+	case len(head) >= 1 && head[0] == 0x47:
+		return formatMPEGTS
+	case bytes.HasPrefix(head, []byte("FLV")):
+		return formatFLV
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return formatWebM
+	}
+	return formatUnknown
+}
+
+// remuxToMPEGTS перепаковывает FLV в MPEG-TS (-c copy). wait ждёт завершения ffmpeg
+func remuxToMPEGTS(ctx context.Context, input io.Reader) (io.Reader, func(), error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "flv",
+		"-i", "pipe:0",
+		"-map", "0",
+		"-c", "copy",
+		"-f", "mpegts",
+		"pipe:1",
+	)
+	cmd.Stdin = input
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start FLV remux: %w", err)
+	}
+
+	return stdout, func() { cmd.Wait() }, nil
+}
@@ -0,0 +1,60 @@
+// Package worker - распределённое транскодирование: ingest узел (SRT/RTMP/WHIP, API)
+// пересылает MPEG-TS публикации на transcode worker, выбранный по свободным слотам.
+// Worker - тот же stream-service в роли worker: он регистрируется heartbeat'ами,
+// принимает задания по HTTP и пишет сегменты в общее хранилище.
+//
+// Задание - POST {worker}/jobs/:stream_id: тело запроса - MPEG-TS публикации,
+// ответ - поток событий NDJSON (started, progress, completed/failed), пока идёт тело.
+// Если worker пропал посреди эфира, ingest отправляет продолжение другому worker'у:
+// тот восстанавливает плейлисты из хранилища и продолжает нумерацию сегментов
+package worker
+
+import (
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+)
+
+const (
+	// HeartbeatInterval - период heartbeat'ов worker'а
+	HeartbeatInterval = 5 * time.Second
+	// WorkerTimeout - worker без heartbeat'ов дольше этого считается мёртвым
+	WorkerTimeout = 3 * HeartbeatInterval
+
+	// jobHeader - параметры задания (JSON Job) в заголовке запроса: тело занято MPEG-TS
+	jobHeader = "X-Transcode-Job"
+	// apiKeyHeader - запросы между ingest и worker'ами (INTERNAL_API_KEY)
+	apiKeyHeader = "X-Internal-API-Key"
+)
+
+// Job - задание worker'у. Resume - продолжение эфира, начатого другим worker'ом
+// (или этим же до переподключения издателя): лестница качеств и режим не меняются
+type Job struct {
+	Resume    bool     `json:"resume"`
+	Qualities []string `json:"qualities,omitempty"`
+	Mode      string   `json:"mode,omitempty"`
+}
+
+// События задания (поле Event)
+const (
+	EventStarted   = "started"   // ffmpeg запущен; Qualities и Mode - вывод эфира
+	EventProgress  = "progress"  // прогресс ffmpeg для телеметрии
+	EventCompleted = "completed" // тело запроса закончилось, всё загружено
+	EventFailed    = "failed"    // ffmpeg завершился с ошибкой
+)
+
+// Event - строка NDJSON ответа на задание
+type Event struct {
+	Event     string               `json:"event"`
+	Qualities []string             `json:"qualities,omitempty"`
+	Mode      string               `json:"mode,omitempty"`
+	Progress  *models.EncoderStats `json:"progress,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	Busy      bool                 `json:"busy,omitempty"` // failed: у worker'а не хватило слотов, можно взять другого
+}
+
+// FinishRequest - закрыть плейлисты эфира (POST {worker}/jobs/:stream_id/finish)
+type FinishRequest struct {
+	Qualities []string `json:"qualities"`
+	Mode      string   `json:"mode"`
+}
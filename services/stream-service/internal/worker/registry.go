@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Registry - живые transcode worker'ы ingest узла по их heartbeat'ам
+type Registry struct {
+	mu      sync.Mutex
+	workers map[string]*models.TranscodeWorker // worker ID → последний heartbeat
+}
+
+func NewRegistry() *Registry {
+	return &Registry{workers: make(map[string]*models.TranscodeWorker)}
+}
+
+// HandleHeartbeat регистрирует worker'а или обновляет его загрузку
+// (POST /internal/workers/heartbeat, INTERNAL_API_KEY)
+func (r *Registry) HandleHeartbeat(c *gin.Context) {
+	var heartbeat models.TranscodeWorker
+	if err := c.ShouldBindJSON(&heartbeat); err != nil || heartbeat.ID == "" || heartbeat.URL == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "id and url are required"})
+		return
+	}
+	heartbeat.LastHeartbeat = time.Now()
+
+	r.mu.Lock()
+	previous, known := r.workers[heartbeat.ID]
+	r.workers[heartbeat.ID] = &heartbeat
+	r.mu.Unlock()
+
+	if !known || !alive(previous) {
+		log.Printf("🛠️ Transcode worker %s registered at %s (%d slots)", heartbeat.ID, heartbeat.URL, heartbeat.CapacitySlots)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Pick выбирает worker'а для задания: preferred, если он жив (у него в памяти
+// состояние эфира), иначе живого с наибольшим числом свободных слотов
+func (r *Registry) Pick(preferred string, exclude map[string]bool) (*models.TranscodeWorker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w, ok := r.workers[preferred]; ok && alive(w) && !exclude[w.ID] {
+		worker := *w
+		return &worker, true
+	}
+
+	var best *models.TranscodeWorker
+	for _, w := range r.workers {
+		if !alive(w) || exclude[w.ID] || w.FreeSlots <= 0 {
+			continue
+		}
+		if best == nil || w.FreeSlots > best.FreeSlots {
+			best = w
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	worker := *best
+	return &worker, true
+}
+
+// Get возвращает живого worker'а по ID
+func (r *Registry) Get(id string) (*models.TranscodeWorker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[id]
+	if !ok || !alive(w) {
+		return nil, false
+	}
+	worker := *w
+	return &worker, true
+}
+
+// Alive сообщает, присылает ли worker heartbeat'ы
+func (r *Registry) Alive(id string) bool {
+	_, ok := r.Get(id)
+	return ok
+}
+
+// HasCapacity - есть живой worker со свободными слотами
+func (r *Registry) HasCapacity() bool {
+	_, ok := r.Pick("", nil)
+	return ok
+}
+
+// Workers возвращает живых worker'ов и забывает тех, кто пропал
+func (r *Registry) Workers() []*models.TranscodeWorker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workers := make([]*models.TranscodeWorker, 0, len(r.workers))
+	for id, w := range r.workers {
+		if !alive(w) {
+			log.Printf("⚠️ Transcode worker %s stopped sending heartbeats", id)
+			delete(r.workers, id)
+			continue
+		}
+		worker := *w
+		workers = append(workers, &worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	return workers
+}
+
+func alive(w *models.TranscodeWorker) bool {
+	return time.Since(w.LastHeartbeat) < WorkerTimeout
+}
@@ -0,0 +1,239 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/stream-service/internal/transcoder"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// abandonAfter - эфир без заданий дольше этого отпускается: ingest продолжил его
+// на другом worker'е или сам пропал, не завершив эфир
+const abandonAfter = 5 * time.Minute
+
+// Server - сторона worker'а: принимает задания ingest узла и выводит эфиры локальным ffmpeg
+type Server struct {
+	transcoder *transcoder.FFmpegTranscoder
+	streamRepo *repository.StreamRepository
+
+	mu         sync.Mutex
+	broadcasts map[uuid.UUID]*hostedBroadcast // stream ID → эфир, который выводит этот worker
+}
+
+type hostedBroadcast struct {
+	broadcast *transcoder.Broadcast
+	active    bool      // задание идёт прямо сейчас
+	idleSince time.Time // конец последнего задания
+}
+
+func NewServer(ffmpegTranscoder *transcoder.FFmpegTranscoder, streamRepo *repository.StreamRepository) *Server {
+	return &Server{
+		transcoder: ffmpegTranscoder,
+		streamRepo: streamRepo,
+		broadcasts: make(map[uuid.UUID]*hostedBroadcast),
+	}
+}
+
+// Run отпускает эфиры, заданий которых давно не было
+func (s *Server) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			for streamID, hosted := range s.broadcasts {
+				if hosted.active || time.Since(hosted.idleSince) < abandonAfter {
+					continue
+				}
+				s.transcoder.AbandonBroadcast(hosted.broadcast)
+				delete(s.broadcasts, streamID)
+				log.Printf("🧹 Released idle broadcast of stream %s", streamID)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// HandleJob выводит публикацию из тела запроса и отвечает потоком событий NDJSON
+// (POST /jobs/:stream_id, заголовок X-Transcode-Job)
+func (s *Server) HandleJob(c *gin.Context) {
+	streamID, err := uuid.Parse(c.Param("stream_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	var job Job
+	if value := c.GetHeader(jobHeader); value != "" {
+		if err := json.Unmarshal([]byte(value), &job); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid " + jobHeader + " header"})
+			return
+		}
+	}
+
+	stream, err := s.streamRepo.GetStreamByID(streamID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var input io.Reader = c.Request.Body
+
+	s.mu.Lock()
+	hosted, ok := s.broadcasts[streamID]
+	if ok && hosted.active {
+		s.mu.Unlock()
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Stream is already transcoded by this worker"})
+		return
+	}
+	if ok && !job.Resume {
+		// Ingest начал новый эфир: старый здесь больше не продолжится
+		s.transcoder.AbandonBroadcast(hosted.broadcast)
+		ok = false
+	}
+	if !ok {
+		hosted = &hostedBroadcast{}
+	}
+	hosted.active = true
+	s.broadcasts[streamID] = hosted
+	s.mu.Unlock()
+
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		hosted.active = false
+		hosted.idleSince = time.Now()
+		if hosted.broadcast == nil || len(hosted.broadcast.Qualities()) == 0 {
+			delete(s.broadcasts, streamID)
+		}
+	}
+
+	if hosted.broadcast == nil {
+		if job.Resume {
+			// Эфир начинал другой worker: плейлисты и нумерация сегментов - из хранилища
+			replay, broadcast, err := s.transcoder.RestoreBroadcast(ctx, input, stream, job.Qualities, job.Mode)
+			if err != nil {
+				release()
+				status := http.StatusInternalServerError
+				if errors.Is(err, transcoder.ErrNoCapacity) {
+					status = http.StatusServiceUnavailable
+				}
+				log.Printf("❌ Failed to continue broadcast of stream %s: %v", streamID, err)
+				c.JSON(status, models.ErrorResponse{Error: err.Error()})
+				return
+			}
+			hosted.broadcast = broadcast
+			input = replay
+		} else {
+			if !s.transcoder.CanAdmit(streamID) {
+				release()
+				c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Worker is overloaded"})
+				return
+			}
+			hosted.broadcast = transcoder.NewBroadcast()
+		}
+	}
+	defer release()
+
+	// Тело запроса (публикация) и ответ (события) идут одновременно
+	if err := http.NewResponseController(c.Writer).EnableFullDuplex(); err != nil {
+		log.Printf("⚠️ Full duplex is not supported for job of stream %s: %v", streamID, err)
+	}
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	var sendMu sync.Mutex
+	encoder := json.NewEncoder(c.Writer)
+	send := func(event Event) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if err := encoder.Encode(event); err == nil {
+			c.Writer.Flush()
+		}
+	}
+
+	broadcast := hosted.broadcast
+	hooks := transcoder.Hooks{
+		OnStarted: func() {
+			send(Event{Event: EventStarted, Qualities: broadcast.Qualities(), Mode: broadcast.Mode()})
+		},
+		OnProgress: func(stats models.EncoderStats) {
+			send(Event{Event: EventProgress, Progress: &stats})
+		},
+	}
+
+	log.Printf("🛠️ Transcode job for stream %s started (resume: %v)", streamID, job.Resume)
+
+	if err := s.transcoder.TranscodeToHLS(ctx, input, stream, broadcast, hooks); err != nil {
+		log.Printf("❌ Transcode job for stream %s failed: %v", streamID, err)
+		send(Event{Event: EventFailed, Error: err.Error(), Busy: errors.Is(err, transcoder.ErrNoCapacity)})
+		return
+	}
+
+	log.Printf("✅ Transcode job for stream %s completed", streamID)
+	send(Event{Event: EventCompleted})
+}
+
+// HandleFinish закрывает плейлисты эфира (POST /jobs/:stream_id/finish).
+// Эфир, который этот worker не выводил, восстанавливается из хранилища
+func (s *Server) HandleFinish(c *gin.Context) {
+	streamID, err := uuid.Parse(c.Param("stream_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid stream ID"})
+		return
+	}
+
+	var request FinishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	s.mu.Lock()
+	hosted, ok := s.broadcasts[streamID]
+	if ok && hosted.active {
+		s.mu.Unlock()
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Stream is still transcoded by this worker"})
+		return
+	}
+	delete(s.broadcasts, streamID)
+	s.mu.Unlock()
+
+	var broadcast *transcoder.Broadcast
+	if ok {
+		broadcast = hosted.broadcast
+	} else {
+		stream, err := s.streamRepo.GetStreamByID(streamID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Stream not found"})
+			return
+		}
+
+		_, broadcast, err = s.transcoder.RestoreBroadcast(c.Request.Context(), nil, stream, request.Qualities, request.Mode)
+		if err != nil {
+			log.Printf("❌ Failed to restore broadcast of stream %s to finish it: %v", streamID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	s.transcoder.FinishBroadcast(broadcast)
+	log.Printf("🏁 Broadcast of stream %s finished", streamID)
+	c.Status(http.StatusNoContent)
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/outbox"
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/clips"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/config"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/editor"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/handlers"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/middleware"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/packager"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/vod-service/internal/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/uploads"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
@@ -40,30 +46,82 @@ func main() {
 
 	log.Println("✅ Connected to vod_db successfully")
 
-	// Initialize MinIO storage
-	minioStorage, err := storage.NewMinIOStorage(
-		cfg.MinioEndpoint,
-		cfg.MinioAccessKey,
-		cfg.MinioSecretKey,
-		cfg.MinioBucket,
-		cfg.MinioUseSSL,
-	)
+	// Initialize storage
+	storageConfig := storage.Config{
+		Backend:   cfg.StorageBackend,
+		Endpoint:  cfg.MinioEndpoint,
+		AccessKey: cfg.MinioAccessKey,
+		SecretKey: cfg.MinioSecretKey,
+		UseSSL:    cfg.MinioUseSSL,
+		LocalPath: cfg.StoragePath,
+	}
+
+	videoStorage, err := storage.Open(storageConfig, cfg.MinioBucket, storage.Options{})
 	if err != nil {
-		log.Fatal("❌ Failed to initialize MinIO storage:", err)
+		log.Fatal("❌ Failed to initialize video storage:", err)
+	}
+
+	// recordings bucket - источник для импорта записей
+	recordingStorage, err := storage.Open(storageConfig, "recordings", storage.Options{})
+	if err != nil {
+		log.Fatal("❌ Failed to initialize recordings storage:", err)
+	}
+
+	// live-streams bucket - недавние сегменты эфиров для клипов
+	segmentStorage, err := storage.Open(storageConfig, "live-streams", storage.Options{PublicRead: true})
+	if err != nil {
+		log.Fatal("❌ Failed to initialize live-streams storage:", err)
 	}
 
 	// Initialize repository
 	videoRepo := repository.NewVideoRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	editRepo := repository.NewEditRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	packageRepo := repository.NewPackageRepository(db)
+
+	// Повторные доставки recording.import от recording-service отбрасываются по ID,
+	// чтобы одна запись не импортировалась дважды
+	inbox := outbox.NewInbox(db, "vod-service")
+	go inbox.Start(context.Background())
+
+	// HLS ABR пакеты: ready видео упаковываются в фоне, по одному - это полное перекодирование
+	videoPackager := packager.NewPackager(packageRepo, videoStorage, cfg.PackagesWorkDir)
+	videoPackager.Start(context.Background(), 1)
 
 	// Initialize handlers
 	videoHandler := handlers.NewVideoHandler(
 		videoRepo,
-		minioStorage,
+		editRepo,
+		packageRepo,
+		videoStorage,
+		recordingStorage,
 		cfg.RecordingServiceURL,
-		"recordings", // recording bucket для копирования
-		"vod-videos", // vod bucket для хранения и стриминга
+		packager.NewSigner(cfg.PlaybackSigningKey),
 	)
 
+	commentHandler := handlers.NewCommentHandler(videoRepo, commentRepo)
+
+	// Клипы режутся в фоне: не больше двух ffmpeg одновременно
+	clipProcessor := clips.NewProcessor(videoRepo, videoStorage, segmentStorage, cfg.ClipsWorkDir)
+	clipProcessor.Start(context.Background(), 2)
+	clipHandler := handlers.NewClipHandler(videoRepo, segmentStorage, clipProcessor)
+
+	// Обрезка видео: одна за раз, многочасовая запись может перекодироваться долго
+	trimmer := editor.NewTrimmer(editRepo, videoStorage, cfg.EditsWorkDir)
+	trimmer.Start(context.Background(), 1)
+	editHandler := handlers.NewEditHandler(videoRepo, editRepo, trimmer)
+
+	// Загрузки пользователей: приём байт по частям, проверка и нормализация в фоне
+	uploadProcessor := uploads.NewProcessor(uploadRepo, videoStorage, cfg.UploadsDir, uploads.Limits{
+		MaxSize:     cfg.MaxUploadSize,
+		MaxDuration: cfg.MaxUploadDuration,
+	})
+	if err := uploadProcessor.Start(context.Background(), 1); err != nil {
+		log.Fatal("❌ Failed to start upload processor:", err)
+	}
+	uploadHandler := handlers.NewUploadHandler(videoRepo, uploadRepo, uploadProcessor)
+
 	// Setup router
 	router := gin.Default()
 
@@ -94,14 +152,18 @@ func main() {
 		optionalAuth.GET("/videos/:id/stream", videoHandler.GetStreamURL)
 		optionalAuth.GET("/videos/:id/play", videoHandler.StreamVideoFile)
 		optionalAuth.GET("/videos/:id/thumbnail", videoHandler.StreamThumbnail)
+		optionalAuth.GET("/videos/:id/cmaf/:file", videoHandler.GetCMAFFile)
+		optionalAuth.GET("/videos/:id/hls/*path", videoHandler.GetHLSFile)
 		optionalAuth.POST("/videos/:id/view", videoHandler.IncrementView)
+		optionalAuth.GET("/videos/:id/comments", commentHandler.ListComments)
+		optionalAuth.GET("/videos/:id/chapters.vtt", editHandler.GetChaptersVTT)
 	}
 
 	// ✅ Internal service-to-service routes (require INTERNAL_API_KEY)
 	internal := router.Group("/")
 	internal.Use(middleware.InternalAuth())
 	{
-		internal.POST("/videos/import-recording", videoHandler.ImportRecording)
+		internal.POST("/videos/import-recording", inbox.Middleware(), videoHandler.ImportRecording)
 	}
 
 	// ✅ Protected routes (require auth via cookie/header)
@@ -112,10 +174,21 @@ func main() {
 		protected.PUT("/videos/:id", videoHandler.UpdateVideo)
 		protected.DELETE("/videos/:id", videoHandler.DeleteVideo)
 		protected.POST("/videos/:id/like", videoHandler.LikeVideo)
+		protected.GET("/videos/:id/edit", editHandler.GetVideoEdit)
+		protected.PUT("/videos/:id/edit", editHandler.EditVideo)
+		protected.POST("/videos/:id/comments", commentHandler.CreateComment)
+		protected.PUT("/videos/:id/comments/:comment_id", commentHandler.UpdateComment)
+		protected.DELETE("/videos/:id/comments/:comment_id", commentHandler.DeleteComment)
+		protected.POST("/clips", clipHandler.CreateClip)
+		protected.POST("/videos/uploads", uploadHandler.CreateUpload)
+		protected.GET("/videos/uploads/:id", uploadHandler.GetUpload)
+		protected.HEAD("/videos/uploads/:id", uploadHandler.GetUpload)
+		protected.PATCH("/videos/uploads/:id", uploadHandler.PatchUpload)
+		protected.DELETE("/videos/uploads/:id", uploadHandler.CancelUpload)
 	}
 
 	log.Printf("✅ VOD Service running on port %s", cfg.Port)
-	log.Printf("📦 Using MinIO buckets: recordings (source), vod-videos (storage)")
+	log.Printf("📦 Using %s storage: recordings (source), %s (storage)", cfg.StorageBackend, cfg.MinioBucket)
 	log.Println("🔒 Authentication: Cookie (video playback) + JWT Header (API calls) + Internal Key (service-to-service)")
 
 	if err := router.Run(":" + cfg.Port); err != nil {
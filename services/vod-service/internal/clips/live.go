@@ -0,0 +1,174 @@
+package clips
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/google/uuid"
+)
+
+// ErrRangeUnavailable - сегментов за запрошенный отрезок эфира уже (или ещё) нет в хранилище
+var ErrRangeUnavailable = errors.New("clip range is not available")
+
+// qualityPriority - клип режется из лучшего качества эфира (как запись в recording-service).
+// source - эфир без перекодирования
+var qualityPriority = []string{"1080p", "720p", "480p", "360p", "source"}
+
+// liveEdgeTolerance - насколько конец клипа может быть позже последнего загруженного
+// сегмента: "клип последних N секунд" с end_at = now упирается в задержку загрузки
+const liveEdgeTolerance = 30 * time.Second
+
+// liveSegment - сегмент live плейлиста в хранилище
+type liveSegment struct {
+	key             string // live-segments/<key>/<quality>/segment_N.m4s
+	initKey         string // init сегмент запуска транскодера ("" - MPEG-TS)
+	duration        float64
+	programDateTime time.Time
+}
+
+func (s liveSegment) end() time.Time {
+	return s.programDateTime.Add(time.Duration(s.duration * float64(time.Second)))
+}
+
+// LiveRange - сегменты эфира, покрывающие отрезок клипа
+type LiveRange struct {
+	Quality  string
+	segments []liveSegment
+	Offset   float64 // начало клипа от начала первого сегмента, секунды
+	Duration float64
+}
+
+// SelectLiveRange находит в live-segments/<stream id>/ сегменты лучшего качества,
+// покрывающие [start, end] эфира. Сегменты за пределами DVR окна уже удалены.
+// Отрезок, заходящий за live край не больше чем на liveEdgeTolerance, сдвигается к краю
+func SelectLiveRange(ctx context.Context, segments storage.Storage, streamID uuid.UUID, start, end time.Time) (*LiveRange, error) {
+	prefix := path.Join("live-segments", streamID.String())
+
+	master, err := readObject(ctx, segments, path.Join(prefix, "master.m3u8"))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: stream has no live segments", ErrRangeUnavailable)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master playlist: %w", err)
+	}
+
+	quality := ""
+	for _, q := range qualityPriority {
+		if bytes.Contains(master, []byte(q+"/playlist.m3u8")) {
+			quality = q
+			break
+		}
+	}
+	if quality == "" {
+		return nil, fmt.Errorf("%w: stream has no known qualities", ErrRangeUnavailable)
+	}
+
+	data, err := readObject(ctx, segments, path.Join(prefix, quality, "playlist.m3u8"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s playlist: %w", quality, err)
+	}
+
+	playlist, err := parseMediaPlaylist(data, path.Join(prefix, quality))
+	if err != nil {
+		return nil, err
+	}
+	if len(playlist) == 0 {
+		return nil, fmt.Errorf("%w: stream has no live segments", ErrRangeUnavailable)
+	}
+
+	first, last := playlist[0], playlist[len(playlist)-1]
+	if late := end.Sub(last.end()); late > 0 && late <= liveEdgeTolerance {
+		start, end = start.Add(-late), end.Add(-late)
+	}
+	if start.Before(first.programDateTime) || end.After(last.end()) {
+		return nil, fmt.Errorf("%w: segments cover %s - %s", ErrRangeUnavailable,
+			first.programDateTime.UTC().Format(time.RFC3339), last.end().UTC().Format(time.RFC3339))
+	}
+
+	var selected []liveSegment
+	for _, segment := range playlist {
+		if segment.end().After(start) && segment.programDateTime.Before(end) {
+			selected = append(selected, segment)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: no segments in range", ErrRangeUnavailable)
+	}
+
+	return &LiveRange{
+		Quality:  quality,
+		segments: selected,
+		Offset:   start.Sub(selected[0].programDateTime).Seconds(),
+		Duration: end.Sub(start).Seconds(),
+	}, nil
+}
+
+// parseMediaPlaylist читает сегменты плейлиста DVR окна stream-service:
+// EXT-X-MAP, EXT-X-PROGRAM-DATE-TIME у каждого сегмента, относительные URI
+func parseMediaPlaylist(data []byte, prefix string) ([]liveSegment, error) {
+	var segments []liveSegment
+	var initKey string
+	var programDateTime time.Time
+	duration := -1.0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if _, after, found := strings.Cut(line, `URI="`); found {
+				uri, _, _ := strings.Cut(after, `"`)
+				initKey = path.Join(prefix, uri)
+			}
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			value := strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:")
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid program date time %q", value)
+			}
+			programDateTime = t
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			d, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segment duration: %w", err)
+			}
+			duration = d
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if duration < 0 {
+				continue
+			}
+			if programDateTime.IsZero() && len(segments) > 0 {
+				programDateTime = segments[len(segments)-1].end()
+			}
+			segments = append(segments, liveSegment{
+				key:             path.Join(prefix, line),
+				initKey:         initKey,
+				duration:        duration,
+				programDateTime: programDateTime,
+			})
+			programDateTime = time.Time{}
+			duration = -1
+		}
+	}
+
+	return segments, scanner.Err()
+}
+
+func readObject(ctx context.Context, store storage.Storage, key string) ([]byte, error) {
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return io.ReadAll(object)
+}
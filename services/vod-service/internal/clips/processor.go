@@ -0,0 +1,319 @@
+// Package clips - короткие клипы из недавних сегментов live стрима или из видео.
+// Клип - обычное видео с source "clip": запись создаётся сразу в статусе pending,
+// ffmpeg режет его асинхронно, затем клип получает свой thumbnail и становится ready
+package clips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+)
+
+const (
+	// Допустимая длина клипа, секунды
+	MinSeconds = 5
+	MaxSeconds = 60
+
+	queueSize  = 32
+	jobTimeout = 10 * time.Minute
+)
+
+// ErrQueueFull - очередь нарезки переполнена, клип стоит запросить позже
+var ErrQueueFull = errors.New("clip queue is full")
+
+// Job - нарезка одного клипа: из сегментов эфира (Live) или из файла видео (SourcePath)
+type Job struct {
+	Clip *models.Video
+
+	Live *LiveRange
+
+	SourcePath   string // file_path видео в vod-videos
+	StartSeconds float64
+	Duration     float64
+}
+
+// Processor режет клипы в фоне ограниченным числом ffmpeg
+type Processor struct {
+	repo     *repository.VideoRepository
+	videos   storage.Storage // vod-videos: исходные видео и готовые клипы
+	segments storage.Storage // live-streams: сегменты эфиров
+	workDir  string
+	jobs     chan Job
+}
+
+func NewProcessor(repo *repository.VideoRepository, videos, segments storage.Storage, workDir string) *Processor {
+	return &Processor{
+		repo:     repo,
+		videos:   videos,
+		segments: segments,
+		workDir:  workDir,
+		jobs:     make(chan Job, queueSize),
+	}
+}
+
+// Start запускает workers параллельных нарезок. Клипы, оборванные рестартом, помечаются failed
+func (p *Processor) Start(ctx context.Context, workers int) {
+	if failed, err := p.repo.FailPendingClips(); err != nil {
+		log.Printf("⚠️ Failed to recover interrupted clips: %v", err)
+	} else if failed > 0 {
+		log.Printf("⚠️ Marked %d interrupted clips as failed", failed)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					p.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue ставит клип в очередь нарезки
+func (p *Processor) Enqueue(job Job) error {
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (p *Processor) process(ctx context.Context, job Job) {
+	clip := job.Clip
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	log.Printf("✂️ Cutting clip %s", clip.ID)
+
+	if err := p.cut(ctx, job); err != nil {
+		log.Printf("❌ Failed to cut clip %s: %v", clip.ID, err)
+		if err := p.repo.FailClip(clip.ID); err != nil {
+			log.Printf("❌ Failed to mark clip %s as failed: %v", clip.ID, err)
+		}
+		return
+	}
+
+	log.Printf("✅ Clip %s is ready", clip.ID)
+}
+
+func (p *Processor) cut(ctx context.Context, job Job) error {
+	clip := job.Clip
+	dir := filepath.Join(p.workDir, clip.ID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Точная нарезка: ffmpeg декодирует от ключевого кадра до начала клипа
+	var inputArgs []string
+	var offset, duration float64
+	if job.Live != nil {
+		list, err := p.prepareLive(ctx, job.Live, dir)
+		if err != nil {
+			return err
+		}
+		inputArgs = []string{"-f", "concat", "-safe", "0", "-i", list}
+		offset, duration = job.Live.Offset, job.Live.Duration
+	} else {
+		source := filepath.Join(dir, "source.mp4")
+		if err := p.videos.Download(ctx, job.SourcePath, source); err != nil {
+			return fmt.Errorf("failed to download source video: %w", err)
+		}
+		inputArgs = []string{"-ss", formatSeconds(job.StartSeconds), "-i", source}
+		duration = job.Duration
+	}
+
+	output := filepath.Join(dir, "clip.mp4")
+	if err := encode(ctx, inputArgs, offset, duration, output); err != nil {
+		return err
+	}
+
+	thumbnail := filepath.Join(dir, "thumbnail.jpg")
+	if err := extractThumbnail(ctx, output, math.Min(1, duration/2), thumbnail); err != nil {
+		return err
+	}
+
+	if err := p.videos.PutFile(ctx, clip.FilePath, output, "video/mp4"); err != nil {
+		return fmt.Errorf("failed to upload clip: %w", err)
+	}
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+
+	thumbnailPath := fmt.Sprintf("%s.jpg", clip.ID)
+	if err := p.videos.PutFile(ctx, thumbnailPath, thumbnail, "image/jpeg"); err != nil {
+		log.Printf("⚠️ Failed to upload thumbnail of clip %s (non-critical): %v", clip.ID, err)
+		thumbnailPath = ""
+	}
+
+	return p.repo.CompleteClip(clip.ID, int(math.Round(duration)), info.Size(), thumbnailPath)
+}
+
+// prepareLive скачивает сегменты отрезка и возвращает список concat demuxer.
+// fMP4 сегменты склеиваются со своим init в части: после переподключения издателя
+// у сегментов новый init, каждая такая часть - отдельный вход concat
+func (p *Processor) prepareLive(ctx context.Context, r *LiveRange, dir string) (string, error) {
+	var parts []string
+	currentInit := ""
+	var part *os.File
+
+	closePart := func() error {
+		if part == nil {
+			return nil
+		}
+		err := part.Close()
+		part = nil
+		return err
+	}
+
+	for i, segment := range r.segments {
+		local := filepath.Join(dir, fmt.Sprintf("segment_%d%s", i, filepath.Ext(segment.key)))
+		if err := p.segments.Download(ctx, segment.key, local); err != nil {
+			closePart()
+			return "", fmt.Errorf("failed to download segment %s: %w", segment.key, err)
+		}
+
+		// MPEG-TS сегменты самодостаточны
+		if segment.initKey == "" {
+			if err := closePart(); err != nil {
+				return "", err
+			}
+			parts = append(parts, local)
+			continue
+		}
+
+		if part == nil || segment.initKey != currentInit {
+			if err := closePart(); err != nil {
+				return "", err
+			}
+			partPath := filepath.Join(dir, fmt.Sprintf("part_%d.mp4", len(parts)))
+			initPath := filepath.Join(dir, fmt.Sprintf("init_%d.mp4", len(parts)))
+			if err := p.segments.Download(ctx, segment.initKey, initPath); err != nil {
+				return "", fmt.Errorf("failed to download init %s: %w", segment.initKey, err)
+			}
+
+			created, err := os.Create(partPath)
+			if err != nil {
+				return "", err
+			}
+			part = created
+			currentInit = segment.initKey
+			parts = append(parts, partPath)
+
+			if err := appendFile(part, initPath); err != nil {
+				closePart()
+				return "", err
+			}
+		}
+
+		if err := appendFile(part, local); err != nil {
+			closePart()
+			return "", err
+		}
+	}
+	if err := closePart(); err != nil {
+		return "", err
+	}
+
+	list := filepath.Join(dir, "parts.txt")
+	file, err := os.Create(list)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	for _, part := range parts {
+		if _, err := fmt.Fprintf(file, "file '%s'\n", part); err != nil {
+			return "", err
+		}
+	}
+	return list, nil
+}
+
+// encode перекодирует отрезок во фрагментированный MP4 с sidx, как записи стримов:
+// клип раздаётся и файлом, и как HLS/DASH
+func encode(ctx context.Context, inputArgs []string, offset, duration float64, output string) error {
+	args := append([]string{"-hide_banner"}, inputArgs...)
+	if offset > 0 {
+		args = append(args, "-ss", formatSeconds(offset))
+	}
+	args = append(args,
+		"-t", formatSeconds(duration),
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "21",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+frag_keyframe+empty_moov+default_base_moof+global_sidx",
+		"-y",
+		output,
+	)
+
+	log.Printf("🎬 Encoding clip: ffmpeg %v", args)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg clip encoding failed: %w", err)
+	}
+	return nil
+}
+
+// extractThumbnail сохраняет кадр клипа в момент at (секунды)
+func extractThumbnail(ctx context.Context, clipPath string, at float64, output string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", formatSeconds(at),
+		"-i", clipPath,
+		"-frames:v", "1",
+		"-vf", "scale=640:-2",
+		"-y",
+		output,
+	)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg thumbnail failed: %w", err)
+	}
+	return nil
+}
+
+func appendFile(dst *os.File, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to append %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
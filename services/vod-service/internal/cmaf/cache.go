@@ -0,0 +1,71 @@
+package cmaf
+
+import (
+	"container/list"
+	"sync"
+)
+
+// IndexCache - LRU кэш разметки файлов по ключу в хранилище. Хранит и nil
+// (файл без sidx), чтобы не перечитывать такие файлы на каждый запрос
+type IndexCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // от недавно использованных к давним
+	entries  map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key   string
+	index *Index
+}
+
+func NewIndexCache(capacity int) *IndexCache {
+	return &IndexCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get возвращает разметку файла key; false - файл ещё не размечен
+func (c *IndexCache) Get(key string) (*Index, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).index, true
+}
+
+// Add сохраняет разметку файла key, вытесняя давно не использованные файлы
+func (c *IndexCache) Add(key string, index *Index) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).index = index
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, index: index})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Remove забывает разметку файла key (файл удалён)
+func (c *IndexCache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,288 @@
+package cmaf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Максимальный размер moov/sidx, который читаем в память
+const maxHeaderBoxSize = 16 << 20
+
+// ErrNotFragmented - файл не фрагментирован или без sidx (старые записи, загрузки)
+var ErrNotFragmented = errors.New("mp4 file has no sidx index")
+
+// Fragment - moof+mdat фрагмент, отдаётся как HLS/DASH сегмент
+type Fragment struct {
+	Offset   int64
+	Size     int64
+	Duration float64 // секунды
+}
+
+// Index - разметка фрагментированного MP4: init (ftyp+moov) и фрагменты из sidx
+type Index struct {
+	InitSize  int64 // init сегмент = байты [0, InitSize)
+	Codecs    string
+	Width     int
+	Height    int
+	Fragments []Fragment
+}
+
+// Duration возвращает общую длительность в секундах
+func (i *Index) Duration() float64 {
+	var total float64
+	for _, f := range i.Fragments {
+		total += f.Duration
+	}
+	return total
+}
+
+// Bandwidth возвращает средний битрейт в битах в секунду
+func (i *Index) Bandwidth() int {
+	duration := i.Duration()
+	if duration <= 0 {
+		return 0
+	}
+	var size int64
+	for _, f := range i.Fragments {
+		size += f.Size
+	}
+	return int(float64(size*8) / duration)
+}
+
+// ReadIndex читает заголовок файла до первого moof: ftyp, moov и глобальный sidx
+func ReadIndex(r io.ReaderAt, size int64) (*Index, error) {
+	index := &Index{}
+	var sidx []byte
+	var sidxEnd int64
+
+	offset := int64(0)
+	for offset+8 <= size {
+		header := make([]byte, 16)
+		n, err := r.ReadAt(header, offset)
+		if n < 8 {
+			return nil, fmt.Errorf("failed to read box header at %d: %w", offset, err)
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if n < 16 {
+				return nil, fmt.Errorf("truncated largesize box at %d", offset)
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || offset+boxSize > size {
+			return nil, fmt.Errorf("invalid %q box size at %d", kind, offset)
+		}
+
+		if kind == "moof" || kind == "mdat" {
+			break
+		}
+
+		if kind == "moov" || kind == "sidx" {
+			if boxSize > maxHeaderBoxSize {
+				return nil, fmt.Errorf("%s box is too large: %d bytes", kind, boxSize)
+			}
+			payload := make([]byte, boxSize-headerSize)
+			if _, err := r.ReadAt(payload, offset+headerSize); err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+			}
+
+			if kind == "moov" {
+				index.InitSize = offset + boxSize
+				index.Codecs = codecs(payload)
+				index.Width, index.Height = dimensions(payload)
+			} else if sidx == nil {
+				sidx = payload
+				sidxEnd = offset + boxSize
+			}
+		}
+
+		offset += boxSize
+	}
+
+	if index.InitSize == 0 || sidx == nil {
+		return nil, ErrNotFragmented
+	}
+
+	fragments, err := parseSidx(sidx, sidxEnd)
+	if err != nil {
+		return nil, err
+	}
+	index.Fragments = fragments
+	return index, nil
+}
+
+// parseSidx разбирает ссылки sidx; смещения считаются от конца бокса (anchor point)
+func parseSidx(sidx []byte, anchor int64) ([]Fragment, error) {
+	if len(sidx) < 12 {
+		return nil, errors.New("truncated sidx")
+	}
+
+	version := sidx[0]
+	timescale := binary.BigEndian.Uint32(sidx[8:12])
+	if timescale == 0 {
+		return nil, errors.New("sidx timescale is zero")
+	}
+
+	pos := 12
+	var firstOffset uint64
+	if version == 0 {
+		if len(sidx) < pos+8 {
+			return nil, errors.New("truncated sidx")
+		}
+		firstOffset = uint64(binary.BigEndian.Uint32(sidx[pos+4 : pos+8]))
+		pos += 8
+	} else {
+		if len(sidx) < pos+16 {
+			return nil, errors.New("truncated sidx")
+		}
+		firstOffset = binary.BigEndian.Uint64(sidx[pos+8 : pos+16])
+		pos += 16
+	}
+
+	if len(sidx) < pos+4 {
+		return nil, errors.New("truncated sidx")
+	}
+	count := int(binary.BigEndian.Uint16(sidx[pos+2 : pos+4]))
+	pos += 4
+
+	if len(sidx) < pos+count*12 {
+		return nil, errors.New("truncated sidx references")
+	}
+
+	fragments := make([]Fragment, 0, count)
+	offset := anchor + int64(firstOffset)
+	for i := 0; i < count; i++ {
+		ref := sidx[pos : pos+12]
+		pos += 12
+
+		if ref[0]&0x80 != 0 {
+			return nil, errors.New("hierarchical sidx is not supported")
+		}
+		size := int64(binary.BigEndian.Uint32(ref[0:4]) & 0x7fffffff)
+		duration := binary.BigEndian.Uint32(ref[4:8])
+
+		fragments = append(fragments, Fragment{
+			Offset:   offset,
+			Size:     size,
+			Duration: float64(duration) / float64(timescale),
+		})
+		offset += size
+	}
+
+	return fragments, nil
+}
+
+// box - MP4 бокс и его содержимое
+type box struct {
+	kind    string
+	payload []byte
+}
+
+func readBoxes(data []byte) []box {
+	var boxes []box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		kind := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+
+		if size < header || size > uint64(len(data)) {
+			return boxes
+		}
+
+		boxes = append(boxes, box{kind: kind, payload: data[header:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func findBox(data []byte, kind string) []byte {
+	for _, b := range readBoxes(data) {
+		if b.kind == kind {
+			return b.payload
+		}
+	}
+	return nil
+}
+
+// codecs возвращает строку кодеков треков (RFC 6381) из moov
+func codecs(moov []byte) string {
+	var result []string
+	for _, b := range readBoxes(moov) {
+		if b.kind != "trak" {
+			continue
+		}
+		stbl := findBox(findBox(findBox(b.payload, "mdia"), "minf"), "stbl")
+		stsd := findBox(stbl, "stsd")
+		if len(stsd) < 8 {
+			continue
+		}
+		entries := readBoxes(stsd[8:])
+		if len(entries) == 0 {
+			continue
+		}
+		result = append(result, sampleEntryCodec(entries[0]))
+	}
+	return strings.Join(result, ",")
+}
+
+func sampleEntryCodec(entry box) string {
+	switch entry.kind {
+	case "avc1", "avc3":
+		// VisualSampleEntry занимает 78 байт до дочерних боксов
+		if len(entry.payload) < 78 {
+			return entry.kind
+		}
+		avcC := findBox(entry.payload[78:], "avcC")
+		if len(avcC) < 4 {
+			return entry.kind
+		}
+		return fmt.Sprintf("%s.%02x%02x%02x", entry.kind, avcC[1], avcC[2], avcC[3])
+	case "mp4a":
+		return "mp4a.40.2"
+	case "Opus":
+		return "opus"
+	}
+	return entry.kind
+}
+
+// dimensions возвращает размер кадра видео трека из tkhd (16.16 fixed point)
+func dimensions(moov []byte) (int, int) {
+	for _, b := range readBoxes(moov) {
+		if b.kind != "trak" {
+			continue
+		}
+		hdlr := findBox(findBox(b.payload, "mdia"), "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			continue
+		}
+		tkhd := findBox(b.payload, "tkhd")
+		if len(tkhd) < 8 {
+			continue
+		}
+		width := binary.BigEndian.Uint32(tkhd[len(tkhd)-8 : len(tkhd)-4])
+		height := binary.BigEndian.Uint32(tkhd[len(tkhd)-4:])
+		return int(width >> 16), int(height >> 16)
+	}
+	return 0, 0
+}
@@ -0,0 +1,69 @@
+package cmaf
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// SegmentName - имя фрагмента в манифестах
+func SegmentName(n int) string {
+	return fmt.Sprintf("segment_%d.m4s", n)
+}
+
+// Playlist формирует HLS VOD плейлист поверх фрагментов файла.
+// query (например "?v=1") добавляется к URL init и сегментов
+func (i *Index) Playlist(query string) string {
+	target := 0.0
+	for _, f := range i.Fragments {
+		target = math.Max(target, f.Duration)
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:7\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target)))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"init.mp4%s\"\n", query)
+	for n, f := range i.Fragments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s%s\n", f.Duration, SegmentName(n), query)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+// MPD формирует static DASH манифест с теми же сегментами, что и HLS плейлист
+func (i *Index) MPD(query string) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<MPD xmlns=\"urn:mpeg:dash:schema:mpd:2011\" profiles=\"urn:mpeg:dash:profile:isoff-live:2011\" type=\"static\" mediaPresentationDuration=\"PT%.3fS\" minBufferTime=\"PT4.000S\">\n", i.Duration())
+	b.WriteString("  <Period id=\"0\" start=\"PT0S\">\n")
+	b.WriteString("    <AdaptationSet contentType=\"video\" mimeType=\"video/mp4\" segmentAlignment=\"true\" startWithSAP=\"1\">\n")
+	fmt.Fprintf(&b, "      <Representation id=\"source\" bandwidth=\"%d\" width=\"%d\" height=\"%d\" codecs=\"%s\">\n",
+		i.Bandwidth(), i.Width, i.Height, i.Codecs)
+	fmt.Fprintf(&b, "        <SegmentTemplate timescale=\"1000\" initialization=\"init.mp4%s\" media=\"segment_$Number$.m4s%s\" startNumber=\"0\">\n",
+		html.EscapeString(query), html.EscapeString(query))
+	b.WriteString("          <SegmentTimeline>\n")
+
+	var t int64
+	for n, f := range i.Fragments {
+		d := int64(math.Round(f.Duration * 1000))
+		if n == 0 {
+			fmt.Fprintf(&b, "            <S t=\"%d\" d=\"%d\"/>\n", t, d)
+		} else {
+			fmt.Fprintf(&b, "            <S d=\"%d\"/>\n", d)
+		}
+		t += d
+	}
+
+	b.WriteString("          </SegmentTimeline>\n")
+	b.WriteString("        </SegmentTemplate>\n")
+	b.WriteString("      </Representation>\n")
+	b.WriteString("    </AdaptationSet>\n")
+	b.WriteString("  </Period>\n")
+	b.WriteString("</MPD>\n")
+	return b.String()
+}
@@ -1,13 +1,21 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
 	Port                string
 	DatabaseURL         string
+	StorageBackend      string // minio или local
+	StoragePath         string // корень local хранилища (общий с recording-service)
 	MinioEndpoint       string
 	MinioAccessKey      string
 	MinioSecretKey      string
@@ -15,6 +23,13 @@ type Config struct {
 	MinioBucket         string
 	RecordingServiceURL string
 	JWTSecret           string
+	ClipsWorkDir        string // временные файлы нарезки клипов
+	EditsWorkDir        string // временные файлы обрезки видео
+	PackagesWorkDir     string // временные файлы упаковки видео в HLS
+	PlaybackSigningKey  string // подпись URL сегментов HLS пакетов
+	UploadsDir          string // part файлы загрузок, должен переживать рестарт
+	MaxUploadSize       int64
+	MaxUploadDuration   time.Duration
 }
 
 func Load() (*Config, error) {
@@ -43,24 +58,35 @@ func Load() (*Config, error) {
 		)
 	}
 
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = "minio"
+	}
+
+	storagePath := os.Getenv("STORAGE_PATH")
+	if storagePath == "" {
+		storagePath = "/var/lib/streaming/storage"
+	}
+
 	minioEndpoint := os.Getenv("MINIO_ENDPOINT")
 	if minioEndpoint == "" {
 		minioEndpoint = "minio:9000"
 	}
 
+	// Ключи MinIO нужны только для backend minio
 	minioAccessKey := os.Getenv("MINIO_ACCESS_KEY")
-	if minioAccessKey == "" {
+	if minioAccessKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_ACCESS_KEY is required")
 	}
 
 	minioSecretKey := os.Getenv("MINIO_SECRET_KEY")
-	if minioSecretKey == "" {
+	if minioSecretKey == "" && storageBackend == "minio" {
 		return nil, fmt.Errorf("MINIO_SECRET_KEY is required")
 	}
 
 	minioBucket := os.Getenv("MINIO_BUCKET")
 	if minioBucket == "" {
-		minioBucket = "recordings" // По умолчанию тот же bucket что у Recording Service
+		minioBucket = "vod-videos"
 	}
 
 	recordingServiceURL := os.Getenv("RECORDING_SERVICE_URL")
@@ -73,9 +99,61 @@ func Load() (*Config, error) {
 		jwtSecret = "change-me-in-production"
 	}
 
+	clipsWorkDir := os.Getenv("CLIPS_WORK_DIR")
+	if clipsWorkDir == "" {
+		clipsWorkDir = "/tmp/clips"
+	}
+
+	editsWorkDir := os.Getenv("EDITS_WORK_DIR")
+	if editsWorkDir == "" {
+		editsWorkDir = "/tmp/edits"
+	}
+
+	packagesWorkDir := os.Getenv("PACKAGES_WORK_DIR")
+	if packagesWorkDir == "" {
+		packagesWorkDir = "/tmp/packages"
+	}
+
+	// Без отдельного ключа он выводится из секрета JWT (HKDF с собственной меткой),
+	// чтобы подпись URL не совпадала ни с одной подписью JWT
+	playbackSigningKey := os.Getenv("PLAYBACK_SIGNING_KEY")
+	if playbackSigningKey == "" {
+		key, err := hkdf.Key(sha256.New, []byte(jwtSecret), nil, "vod-service playback url signing", 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive playback signing key: %w", err)
+		}
+		playbackSigningKey = hex.EncodeToString(key)
+		log.Printf("⚠️ PLAYBACK_SIGNING_KEY is not set: deriving it from JWT_SECRET, set a dedicated key in production")
+	}
+
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "/var/lib/vod/uploads"
+	}
+
+	maxUploadSize := int64(4096) << 20 // default: 4 GB
+	if value := os.Getenv("MAX_UPLOAD_SIZE_MB"); value != "" {
+		megabytes, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || megabytes < 1 {
+			return nil, fmt.Errorf("MAX_UPLOAD_SIZE_MB must be a positive number of megabytes")
+		}
+		maxUploadSize = megabytes << 20
+	}
+
+	maxUploadDuration := 4 * time.Hour
+	if value := os.Getenv("MAX_UPLOAD_DURATION_MINUTES"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 1 {
+			return nil, fmt.Errorf("MAX_UPLOAD_DURATION_MINUTES must be a positive number of minutes")
+		}
+		maxUploadDuration = time.Duration(minutes) * time.Minute
+	}
+
 	return &Config{
 		Port:                port,
 		DatabaseURL:         dbURL,
+		StorageBackend:      storageBackend,
+		StoragePath:         storagePath,
 		MinioEndpoint:       minioEndpoint,
 		MinioAccessKey:      minioAccessKey,
 		MinioSecretKey:      minioSecretKey,
@@ -83,5 +161,12 @@ func Load() (*Config, error) {
 		MinioBucket:         minioBucket,
 		RecordingServiceURL: recordingServiceURL,
 		JWTSecret:           jwtSecret,
+		ClipsWorkDir:        clipsWorkDir,
+		EditsWorkDir:        editsWorkDir,
+		PackagesWorkDir:     packagesWorkDir,
+		PlaybackSigningKey:  playbackSigningKey,
+		UploadsDir:          uploadsDir,
+		MaxUploadSize:       maxUploadSize,
+		MaxUploadDuration:   maxUploadDuration,
 	}, nil
 }
@@ -0,0 +1,55 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+)
+
+// ShiftChapters переносит главы (по возрастанию начала) на таймлайн после обрезки
+// до [start, end): глава, идущая в момент start, начинается с нуля, главы вне отрезка отбрасываются
+func ShiftChapters(chapters []models.Chapter, start, end float64) []models.Chapter {
+	shifted := []models.Chapter{}
+	for i, chapter := range chapters {
+		if chapter.StartSeconds >= end {
+			break
+		}
+		if chapter.StartSeconds <= start {
+			// Перекрыта следующей главой, тоже начавшейся до start
+			if i+1 < len(chapters) && chapters[i+1].StartSeconds <= start {
+				continue
+			}
+			shifted = append(shifted, models.Chapter{StartSeconds: 0, Title: chapter.Title})
+			continue
+		}
+		shifted = append(shifted, models.Chapter{StartSeconds: chapter.StartSeconds - start, Title: chapter.Title})
+	}
+	return shifted
+}
+
+// WebVTT формирует WebVTT главы: каждая глава длится до начала следующей, последняя - до конца видео
+func WebVTT(chapters []models.Chapter, duration float64) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+
+	for i, chapter := range chapters {
+		end := duration
+		if i+1 < len(chapters) {
+			end = chapters[i+1].StartSeconds
+		}
+		if end <= chapter.StartSeconds {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n", i+1, vttTimestamp(chapter.StartSeconds), vttTimestamp(end), chapter.Title)
+	}
+	return b.String()
+}
+
+// vttTimestamp - hh:mm:ss.ttt
+func vttTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,282 @@
+// Package editor - обрезка видео владельцем и главы. Обрезанный файл собирается в фоне
+// под новым ключом и подменяет файл видео одной транзакцией: до этого зрители смотрят прежний
+package editor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	queueSize = 16
+
+	// Перекодирование многочасовой записи занимает время
+	jobTimeout = 2 * time.Hour
+
+	// keyframeTolerance - насколько раньше точки начала может стоять ключевой кадр,
+	// чтобы обрезать без потерь (копированием потоков с этого кадра)
+	keyframeTolerance = 2.0
+)
+
+// ErrQueueFull - очередь обрезки переполнена, обрезку стоит запросить позже
+var ErrQueueFull = errors.New("edit queue is full")
+
+// Job - обрезка видео до [StartSeconds, EndSeconds) исходного файла
+type Job struct {
+	Video        *models.Video
+	StartSeconds float64
+	EndSeconds   float64
+	Chapters     []models.Chapter // главы на таймлайне после обрезки
+}
+
+// Trimmer обрезает видео в фоне
+type Trimmer struct {
+	repo    *repository.EditRepository
+	videos  storage.Storage
+	workDir string
+	jobs    chan Job
+}
+
+func NewTrimmer(repo *repository.EditRepository, videos storage.Storage, workDir string) *Trimmer {
+	return &Trimmer{
+		repo:    repo,
+		videos:  videos,
+		workDir: workDir,
+		jobs:    make(chan Job, queueSize),
+	}
+}
+
+// Start запускает workers параллельных обрезок. Обрезки, оборванные рестартом, помечаются failed
+func (t *Trimmer) Start(ctx context.Context, workers int) {
+	if failed, err := t.repo.FailInterruptedEdits(); err != nil {
+		log.Printf("⚠️ Failed to recover interrupted trims: %v", err)
+	} else if failed > 0 {
+		log.Printf("⚠️ Marked %d interrupted trims as failed", failed)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-t.jobs:
+					t.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue ставит обрезку в очередь
+func (t *Trimmer) Enqueue(job Job) error {
+	select {
+	case t.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (t *Trimmer) process(ctx context.Context, job Job) {
+	video := job.Video
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	log.Printf("✂️ Trimming video %s to [%s, %s)", video.ID, formatSeconds(job.StartSeconds), formatSeconds(job.EndSeconds))
+
+	if err := t.trim(ctx, job); err != nil {
+		log.Printf("❌ Failed to trim video %s: %v", video.ID, err)
+		if err := t.repo.FailEdit(video.ID, err.Error()); err != nil {
+			log.Printf("❌ Failed to mark trim of video %s as failed: %v", video.ID, err)
+		}
+		return
+	}
+
+	log.Printf("✅ Video %s trimmed", video.ID)
+}
+
+func (t *Trimmer) trim(ctx context.Context, job Job) error {
+	video := job.Video
+	dir := filepath.Join(t.workDir, video.ID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source.mp4")
+	if err := t.videos.Download(ctx, video.FilePath, source); err != nil {
+		return fmt.Errorf("failed to download video: %w", err)
+	}
+
+	start, lossless := job.StartSeconds, true
+	if start > 0 {
+		keyframe, found, err := keyframeBefore(ctx, source, start)
+		if err != nil {
+			return err
+		}
+		if found {
+			start = keyframe
+		} else {
+			lossless = false
+		}
+	}
+	if err := t.repo.SetLossless(video.ID, lossless); err != nil {
+		log.Printf("⚠️ Failed to store trim mode of video %s: %v", video.ID, err)
+	}
+
+	output := filepath.Join(dir, "trimmed.mp4")
+	if err := cut(ctx, source, start, job.EndSeconds-start, lossless, output); err != nil {
+		return err
+	}
+
+	duration, err := probeDuration(ctx, output)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+
+	// Новый ключ: по старому файл может ещё читаться, а ответы /play кэшируются
+	filePath := fmt.Sprintf("%s_%d.mp4", video.ID, time.Now().Unix())
+	if err := t.videos.PutFile(ctx, filePath, output, "video/mp4"); err != nil {
+		return fmt.Errorf("failed to upload trimmed video: %w", err)
+	}
+
+	err = t.repo.ApplyTrim(video.ID, repository.TrimResult{
+		PreviousPath: video.FilePath,
+		FilePath:     filePath,
+		Duration:     int(math.Round(duration)),
+		FileSize:     info.Size(),
+		StartSeconds: start,
+	}, job.Chapters)
+	if err != nil {
+		t.deleteObject(video.ID, filePath)
+		return err
+	}
+
+	t.deleteObject(video.ID, video.FilePath)
+	return nil
+}
+
+func (t *Trimmer) deleteObject(videoID uuid.UUID, key string) {
+	if err := t.videos.Delete(context.Background(), key); err != nil {
+		log.Printf("⚠️ Failed to delete %s of video %s: %v", key, videoID, err)
+	}
+}
+
+// keyframeBefore ищет ключевой кадр видео не раньше keyframeTolerance до at
+func keyframeBefore(ctx context.Context, source string, at float64) (float64, bool, error) {
+	from := math.Max(0, at-keyframeTolerance-1)
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-skip_frame", "nokey",
+		"-show_entries", "frame=pts_time",
+		"-of", "csv=p=0",
+		"-read_intervals", fmt.Sprintf("%s%%+%s", formatSeconds(from), formatSeconds(at-from+1)),
+		source,
+	)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, false, fmt.Errorf("ffprobe keyframes failed: %w", err)
+	}
+
+	best, found := 0.0, false
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		pts, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(line, ",")), 64)
+		if err != nil || pts > at || at-pts > keyframeTolerance {
+			continue
+		}
+		if !found || pts > best {
+			best, found = pts, true
+		}
+	}
+	return best, found, nil
+}
+
+// cut вырезает отрезок во фрагментированный MP4 с sidx, как записи стримов.
+// lossless - копирование потоков с ключевого кадра start, иначе точная нарезка с перекодированием
+func cut(ctx context.Context, source string, start, duration float64, lossless bool, output string) error {
+	args := []string{"-hide_banner"}
+	if start > 0 {
+		// Точное значение pts: при округлении вниз ffmpeg начал бы с предыдущего ключевого кадра
+		args = append(args, "-ss", strconv.FormatFloat(start, 'f', -1, 64))
+	}
+	args = append(args,
+		"-i", source,
+		"-t", formatSeconds(duration),
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+	)
+	if lossless {
+		args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+	} else {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "128k",
+		)
+	}
+	args = append(args,
+		"-movflags", "+frag_keyframe+empty_moov+default_base_moof+global_sidx",
+		"-y",
+		output,
+	)
+
+	log.Printf("🎬 Trimming: ffmpeg %v", args)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg trimming failed: %w", err)
+	}
+	return nil
+}
+
+// probeDuration возвращает длительность файла в секундах
+func probeDuration(ctx context.Context, path string) (float64, error) {
+	output, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe duration failed: %w", err)
+	}
+
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", strings.TrimSpace(string(output)), err)
+	}
+	return duration, nil
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/clips"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ClipHandler struct {
+	repo      *repository.VideoRepository
+	segments  storage.Storage // live-streams: сегменты эфиров
+	processor *clips.Processor
+}
+
+func NewClipHandler(repo *repository.VideoRepository, segments storage.Storage, processor *clips.Processor) *ClipHandler {
+	return &ClipHandler{
+		repo:      repo,
+		segments:  segments,
+		processor: processor,
+	}
+}
+
+// CreateClip создаёт клип из live стрима или видео и ставит его нарезку в очередь.
+// Клип принадлежит тому, кто его создал (зритель или владелец), и появляется
+// в статусе pending; ready/failed - по завершении нарезки (GET /videos/:id)
+func (h *ClipHandler) CreateClip(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.CreateClipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len([]rune(req.Title)) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 3 characters"})
+		return
+	}
+
+	if (req.StreamID == "") == (req.VideoID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of stream_id or video_id is required"})
+		return
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+	if visibility != "public" && visibility != "private" && visibility != "unlisted" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private or unlisted"})
+		return
+	}
+
+	now := time.Now()
+	clipID := uuid.New()
+	clip := &models.Video{
+		ID:          clipID,
+		UserID:      userUUID,
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        []string{},
+		Source:      "clip",
+		Status:      "pending",
+		Visibility:  visibility,
+		FilePath:    fmt.Sprintf("%s.mp4", clipID),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	job := clips.Job{Clip: clip}
+
+	if req.StreamID != "" {
+		streamID, err := uuid.Parse(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stream ID"})
+			return
+		}
+		if req.StartAt == nil || req.EndAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_at and end_at are required for stream clips"})
+			return
+		}
+		if !validClipLength(req.EndAt.Sub(*req.StartAt).Seconds()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": clipLengthError})
+			return
+		}
+
+		liveRange, err := clips.SelectLiveRange(c.Request.Context(), h.segments, streamID, *req.StartAt, *req.EndAt)
+		if errors.Is(err, clips.ErrRangeUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Failed to select segments of stream %s for clip: %v", streamID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create clip"})
+			return
+		}
+
+		clip.StreamID = &streamID
+		job.Live = liveRange
+	} else {
+		videoID, err := uuid.Parse(req.VideoID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+			return
+		}
+		if req.StartSeconds == nil || req.EndSeconds == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_seconds and end_seconds are required for video clips"})
+			return
+		}
+		start, end := *req.StartSeconds, *req.EndSeconds
+		if start < 0 || !validClipLength(end-start) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": clipLengthError})
+			return
+		}
+
+		parent, err := h.repo.GetByID(videoID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		if parent.Visibility == "private" && parent.UserID != userUUID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if parent.Status != "ready" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Video is not ready yet"})
+			return
+		}
+		if parent.Duration > 0 && end > float64(parent.Duration) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Clip range exceeds video duration (%d seconds)", parent.Duration)})
+			return
+		}
+
+		clip.ParentVideoID = &parent.ID
+		clip.StreamID = parent.StreamID
+		job.SourcePath = parent.FilePath
+		job.StartSeconds = start
+		job.Duration = end - start
+	}
+
+	if err := h.repo.Create(clip); err != nil {
+		log.Printf("❌ Failed to create clip: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create clip"})
+		return
+	}
+
+	if err := h.processor.Enqueue(job); err != nil {
+		log.Printf("⚠️ Clip %s rejected: %v", clip.ID, err)
+		if err := h.repo.Delete(clip.ID); err != nil {
+			log.Printf("❌ Failed to delete rejected clip %s: %v", clip.ID, err)
+		}
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many clips are being processed, try again later"})
+		return
+	}
+
+	log.Printf("✂️ Clip %s queued by user %s", clip.ID, userID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"clip":    clip,
+		"message": "Clip is being processed",
+	})
+}
+
+var clipLengthError = fmt.Sprintf("Clip must be %d-%d seconds long", clips.MinSeconds, clips.MaxSeconds)
+
+func validClipLength(seconds float64) bool {
+	return seconds >= clips.MinSeconds && seconds <= clips.MaxSeconds
+}
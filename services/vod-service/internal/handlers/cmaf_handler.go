@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/cmaf"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// cmafIndexCacheSize - сколько разметок файлов держим в памяти. Файл после обрезки
+// получает новый file_path, разметка прежнего вытесняется из кэша
+const cmafIndexCacheSize = 1024
+
+// GetCMAFFile отдаёт HLS/DASH манифесты и сегменты фрагментированного MP4:
+// playlist.m3u8, manifest.mpd, init.mp4 и segment_N.m4s (byte range из файла в хранилище)
+func (h *VideoHandler) GetCMAFFile(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	video, err := h.repo.GetByID(videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	if video.Visibility == "private" {
+		userID := getUserID(c)
+		if userID == "" || userID != video.UserID.String() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	index, err := h.getCMAFIndex(ctx, video)
+	if err != nil {
+		if errors.Is(err, cmaf.ErrNotFragmented) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Adaptive playback is not available for this video"})
+			return
+		}
+		log.Printf("❌ Failed to index video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read video"})
+		return
+	}
+
+	// Версия файла из URL манифеста переносится в URL сегментов
+	query := ""
+	if version := c.Query("v"); version != "" {
+		query = "?v=" + url.QueryEscape(version)
+	}
+
+	file := c.Param("file")
+	switch {
+	case file == "playlist.m3u8":
+		c.Header("Cache-Control", cacheControl(video, 3600))
+		c.Data(http.StatusOK, "application/vnd.apple.mpegurl", []byte(index.Playlist(query)))
+
+	case file == "manifest.mpd":
+		c.Header("Cache-Control", cacheControl(video, 3600))
+		c.Data(http.StatusOK, "application/dash+xml", []byte(index.MPD(query)))
+
+	case file == "init.mp4":
+		h.serveRange(c, video, 0, index.InitSize)
+
+	case strings.HasPrefix(file, "segment_"):
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(file, "segment_"), ".m4s"))
+		if err != nil || n < 0 || n >= len(index.Fragments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Segment not found"})
+			return
+		}
+		fragment := index.Fragments[n]
+		h.serveRange(c, video, fragment.Offset, fragment.Size)
+
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	}
+}
+
+// serveRange отдаёт часть файла видео из хранилища
+func (h *VideoHandler) serveRange(c *gin.Context, video *models.Video, offset, size int64) {
+	object, err := h.videos.Get(c.Request.Context(), video.FilePath)
+	if err != nil {
+		log.Printf("❌ Failed to get object from storage: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stream video"})
+		return
+	}
+	defer object.Close()
+
+	c.Header("Cache-Control", cacheControl(video, 31536000))
+	c.DataFromReader(http.StatusOK, size, "video/mp4", io.NewSectionReader(object, offset, size), nil)
+}
+
+// getCMAFIndex читает (и кэширует) разметку фрагментов файла видео
+func (h *VideoHandler) getCMAFIndex(ctx context.Context, video *models.Video) (*cmaf.Index, error) {
+	index, cached := h.cmafIndexes.Get(video.FilePath)
+	if cached {
+		if index == nil {
+			return nil, cmaf.ErrNotFragmented
+		}
+		return index, nil
+	}
+
+	object, err := h.videos.Get(ctx, video.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	index, err = cmaf.ReadIndex(object, object.Info().Size)
+	if err != nil && !errors.Is(err, cmaf.ErrNotFragmented) {
+		return nil, err
+	}
+
+	h.cmafIndexes.Add(video.FilePath, index)
+
+	if index == nil {
+		return nil, cmaf.ErrNotFragmented
+	}
+	log.Printf("✅ Indexed video %s: %d fragments", video.ID, len(index.Fragments))
+	return index, nil
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxCommentLength = 2000
+
+type CommentHandler struct {
+	videoRepo   *repository.VideoRepository
+	commentRepo *repository.CommentRepository
+}
+
+func NewCommentHandler(videoRepo *repository.VideoRepository, commentRepo *repository.CommentRepository) *CommentHandler {
+	return &CommentHandler{
+		videoRepo:   videoRepo,
+		commentRepo: commentRepo,
+	}
+}
+
+// ListComments возвращает ветки комментариев. ?from=&to= (секунды) - только комментарии
+// к этому отрезку видео в порядке таймлайна, для отметок в плеере
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	video, ok := h.lookupVideo(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.CommentFilter{}
+	for name, target := range map[string]**float64{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be non-negative seconds"})
+			return
+		}
+		*target = &seconds
+	}
+	if filter.From != nil && filter.To != nil && *filter.From > *filter.To {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be greater than to"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	filter.Limit = limit
+	filter.Offset = offset
+
+	comments, total, err := h.commentRepo.ListThreads(video.ID, filter)
+	if err != nil {
+		log.Printf("❌ Failed to get comments for video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CommentListResponse{
+		Comments: comments,
+		Total:    total,
+		Page:     offset/limit + 1,
+		Limit:    limit,
+	})
+}
+
+// CreateComment добавляет комментарий (с моментом видео) или ответ (parent_id)
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userID, ok := requireUserUUID(c)
+	if !ok {
+		return
+	}
+
+	video, ok := h.lookupVideo(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content, ok := validateCommentContent(c, req.Content)
+	if !ok {
+		return
+	}
+
+	comment := &models.Comment{
+		VideoID: video.ID,
+		UserID:  userID,
+		Content: content,
+	}
+
+	if req.ParentID != "" {
+		parentID, err := uuid.Parse(req.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent_id"})
+			return
+		}
+
+		parent, err := h.commentRepo.GetByID(video.ID, parentID)
+		if err != nil || parent.Deleted {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		}
+
+		// Ответ на ответ попадает в ту же ветку
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		if req.TimestampSeconds != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replies cannot have timestamp_seconds"})
+			return
+		}
+		comment.ParentID = &parentID
+	}
+
+	if ts := req.TimestampSeconds; ts != nil {
+		if *ts < 0 || (video.Duration > 0 && *ts > float64(video.Duration)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timestamp_seconds must be within the video duration"})
+			return
+		}
+		comment.TimestampSeconds = ts
+	}
+
+	if err := h.commentRepo.Create(comment); err != nil {
+		log.Printf("❌ Failed to create comment on video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	log.Printf("💬 Comment %s added to video %s by %s", comment.ID, video.ID, userID)
+	c.JSON(http.StatusCreated, gin.H{"comment": comment})
+}
+
+// UpdateComment меняет текст комментария (только автор)
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userID, ok := requireUserUUID(c)
+	if !ok {
+		return
+	}
+
+	video, ok := h.lookupVideo(c)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.lookupComment(c, video)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this comment"})
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content, ok := validateCommentContent(c, req.Content)
+	if !ok {
+		return
+	}
+
+	if err := h.commentRepo.UpdateContent(comment.ID, content); err != nil {
+		log.Printf("❌ Failed to update comment %s: %v", comment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+// DeleteComment удаляет комментарий: автор или владелец видео (модерация)
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userID, ok := requireUserUUID(c)
+	if !ok {
+		return
+	}
+
+	video, ok := h.lookupVideo(c)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.lookupComment(c, video)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID && video.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		return
+	}
+
+	if err := h.commentRepo.Delete(comment.ID, userID); err != nil {
+		log.Printf("❌ Failed to delete comment %s: %v", comment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	if comment.UserID != userID {
+		log.Printf("🛡️ Comment %s on video %s removed by video owner", comment.ID, video.ID)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// lookupVideo загружает видео и проверяет доступ по тем же правилам, что GetVideo:
+// приватное видео и его комментарии видит только владелец
+func (h *CommentHandler) lookupVideo(c *gin.Context) (*models.Video, bool) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return nil, false
+	}
+
+	video, err := h.videoRepo.GetByID(videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return nil, false
+	}
+
+	if video.Visibility == "private" {
+		userID := getUserID(c)
+		if userID == "" || userID != video.UserID.String() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "This video is private",
+				"message": "Only the owner can view this video",
+			})
+			return nil, false
+		}
+	}
+
+	return video, true
+}
+
+func (h *CommentHandler) lookupComment(c *gin.Context, video *models.Video) (*models.Comment, bool) {
+	commentID, err := uuid.Parse(c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return nil, false
+	}
+
+	comment, err := h.commentRepo.GetByID(video.ID, commentID)
+	if err != nil || comment.Deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+
+	return comment, true
+}
+
+func requireUserUUID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func validateCommentContent(c *gin.Context, content string) (string, bool) {
+	content = strings.TrimSpace(content)
+	if content == "" || utf8.RuneCountInString(content) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment must be between 1 and 2000 characters"})
+		return "", false
+	}
+	return content, true
+}
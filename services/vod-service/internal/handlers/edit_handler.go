@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/editor"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxChapters            = 100
+	maxChapterTitleLength  = 100
+	minTrimmedVideoSeconds = 1
+)
+
+type EditHandler struct {
+	videoRepo *repository.VideoRepository
+	editRepo  *repository.EditRepository
+	trimmer   *editor.Trimmer
+}
+
+func NewEditHandler(videoRepo *repository.VideoRepository, editRepo *repository.EditRepository, trimmer *editor.Trimmer) *EditHandler {
+	return &EditHandler{
+		videoRepo: videoRepo,
+		editRepo:  editRepo,
+		trimmer:   trimmer,
+	}
+}
+
+// EditVideo задаёт точки обрезки и/или главы видео (только владелец).
+// Главы без обрезки сохраняются сразу (200). Обрезка выполняется в фоне (202):
+// файл, длительность и главы меняются вместе, когда обрезанный файл готов
+func (h *EditHandler) EditVideo(c *gin.Context) {
+	video, ok := h.lookupOwnVideo(c)
+	if !ok {
+		return
+	}
+
+	if video.Status != "ready" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only ready videos can be edited"})
+		return
+	}
+
+	var req models.EditVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	trimming := req.TrimStartSeconds != nil || req.TrimEndSeconds != nil
+	if !trimming && req.Chapters == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to edit: set trim points or chapters"})
+		return
+	}
+
+	edit, err := h.editRepo.GetEdit(video.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get edit of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit video"})
+		return
+	}
+	if edit != nil && edit.Status == models.EditStatusProcessing {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is being trimmed, try again when trimming is finished"})
+		return
+	}
+
+	duration := float64(video.Duration)
+	start, end := 0.0, duration
+	if req.TrimStartSeconds != nil {
+		start = *req.TrimStartSeconds
+	}
+	if req.TrimEndSeconds != nil {
+		end = *req.TrimEndSeconds
+	}
+	if trimming {
+		if start < 0 || end > duration || end-start < minTrimmedVideoSeconds {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Trim points must satisfy 0 <= trim_start_seconds < trim_end_seconds <= duration",
+			})
+			return
+		}
+		if start == 0 && end == duration {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Trim points cover the whole video"})
+			return
+		}
+	}
+
+	var chapters []models.Chapter
+	if req.Chapters != nil {
+		chapters, ok = validateChapters(c, req.Chapters, end-start)
+		if !ok {
+			return
+		}
+	} else {
+		current, err := h.editRepo.GetChapters(video.ID)
+		if err != nil {
+			log.Printf("❌ Failed to get chapters of video %s: %v", video.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit video"})
+			return
+		}
+		chapters = editor.ShiftChapters(current, start, end)
+	}
+
+	if !trimming {
+		if err := h.editRepo.ReplaceChapters(video.ID, chapters); err != nil {
+			log.Printf("❌ Failed to save chapters of video %s: %v", video.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save chapters"})
+			return
+		}
+
+		log.Printf("✅ Saved %d chapters of video %s", len(chapters), video.ID)
+		c.JSON(http.StatusOK, gin.H{"chapters": chapters, "message": "Chapters saved"})
+		return
+	}
+
+	started, err := h.editRepo.StartEdit(video.ID, start, end)
+	if err != nil {
+		log.Printf("❌ Failed to start trim of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit video"})
+		return
+	}
+	if !started {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is being trimmed, try again when trimming is finished"})
+		return
+	}
+
+	job := editor.Job{Video: video, StartSeconds: start, EndSeconds: end, Chapters: chapters}
+	if err := h.trimmer.Enqueue(job); err != nil {
+		if err := h.editRepo.FailEdit(video.ID, err.Error()); err != nil {
+			log.Printf("⚠️ Failed to release trim of video %s: %v", video.ID, err)
+		}
+		if errors.Is(err, editor.ErrQueueFull) {
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many videos are being trimmed, try again later"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit video"})
+		return
+	}
+
+	edit, err = h.editRepo.GetEdit(video.ID)
+	if err != nil {
+		log.Printf("⚠️ Failed to reload edit of video %s: %v", video.ID, err)
+	}
+
+	log.Printf("✂️ Trim of video %s queued: [%.3f, %.3f)", video.ID, start, end)
+	c.JSON(http.StatusAccepted, gin.H{
+		"edit":    edit,
+		"message": "Trimming started. The video keeps playing untrimmed until it is finished",
+	})
+}
+
+// GetVideoEdit возвращает последнюю обрезку и главы видео (только владелец)
+func (h *EditHandler) GetVideoEdit(c *gin.Context) {
+	video, ok := h.lookupOwnVideo(c)
+	if !ok {
+		return
+	}
+
+	edit, err := h.editRepo.GetEdit(video.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get edit of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video edit"})
+		return
+	}
+
+	chapters, err := h.editRepo.GetChapters(video.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get chapters of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video edit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VideoEditResponse{Edit: edit, Chapters: chapters})
+}
+
+// GetChaptersVTT отдаёт главы видео как WebVTT (track kind="chapters")
+func (h *EditHandler) GetChaptersVTT(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	video, err := h.videoRepo.GetByID(videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	if video.Visibility == "private" {
+		userID := getUserID(c)
+		if userID == "" || userID != video.UserID.String() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+	}
+
+	chapters, err := h.editRepo.GetChapters(video.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get chapters of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chapters"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/vtt; charset=utf-8", []byte(editor.WebVTT(chapters, float64(video.Duration))))
+}
+
+func (h *EditHandler) lookupOwnVideo(c *gin.Context) (*models.Video, bool) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return nil, false
+	}
+
+	video, err := h.videoRepo.GetByID(videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return nil, false
+	}
+
+	if video.UserID.String() != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		return nil, false
+	}
+
+	return video, true
+}
+
+// validateChapters проверяет главы на таймлайне длиной duration и сортирует их по началу
+func validateChapters(c *gin.Context, chapters []models.Chapter, duration float64) ([]models.Chapter, bool) {
+	if len(chapters) > maxChapters {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A video can have at most 100 chapters"})
+		return nil, false
+	}
+
+	result := make([]models.Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		// Перевод строки оборвал бы WebVTT cue
+		title := strings.Join(strings.Fields(chapter.Title), " ")
+		if title == "" || utf8.RuneCountInString(title) > maxChapterTitleLength || strings.Contains(title, "-->") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Chapter title must be between 1 and 100 characters"})
+			return nil, false
+		}
+		if chapter.StartSeconds < 0 || chapter.StartSeconds >= duration {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Chapter start_seconds must be within the (trimmed) video"})
+			return nil, false
+		}
+		// Начало хранится с точностью до миллисекунды
+		start := math.Round(chapter.StartSeconds*1000) / 1000
+		result = append(result, models.Chapter{StartSeconds: start, Title: title})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].StartSeconds < result[j].StartSeconds })
+	for i := 1; i < len(result); i++ {
+		if result[i].StartSeconds == result[i-1].StartSeconds {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Chapters must start at different times"})
+			return nil, false
+		}
+	}
+
+	return result, true
+}
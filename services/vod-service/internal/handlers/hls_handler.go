@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/packager"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetHLSFile отдаёт HLS ABR пакет видео: master.m3u8 проверяет доступ к видео и
+// подписывает URL плейлистов качеств, а /<version>/<file>?token= отдаются по подписи
+func (h *VideoHandler) GetHLSFile(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	file := strings.TrimPrefix(c.Param("path"), "/")
+	if file == "master.m3u8" {
+		h.serveHLSMaster(c, videoID)
+		return
+	}
+
+	version, name, found := strings.Cut(file, "/")
+	if !found || version == "" || strings.Contains(name, "/") || strings.HasPrefix(name, ".") || name == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	hlsPath := "hls/" + videoID.String() + "/" + version
+	token := c.Query("token")
+	expires, ok := h.signer.Verify(hlsPath, token, time.Now())
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired playback token"})
+		return
+	}
+
+	object, err := h.videos.Get(c.Request.Context(), hlsPath+"/"+name)
+	if err != nil {
+		// Прежний пакет удаляется, когда готов пакет обрезанного файла
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer object.Close()
+
+	// URL подписан, поэтому ответ кэшируется, пока действует подпись
+	maxAge := int(time.Until(expires).Seconds())
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(maxAge))
+
+	if strings.HasSuffix(name, ".m3u8") {
+		playlist, err := io.ReadAll(object)
+		if err != nil {
+			log.Printf("❌ Failed to read playlist %s/%s: %v", hlsPath, name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read playlist"})
+			return
+		}
+		c.Data(http.StatusOK, packager.ContentType(name), packager.SignPlaylist(playlist, "", "?token="+url.QueryEscape(token)))
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, object.Info().Size, packager.ContentType(name), object, nil)
+}
+
+// serveHLSMaster отдаёт master.m3u8 с подписанными URL плейлистов качеств
+func (h *VideoHandler) serveHLSMaster(c *gin.Context, videoID uuid.UUID) {
+	video, err := h.repo.GetByID(videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	if video.Visibility == "private" {
+		userID := getUserID(c)
+		if userID == "" || userID != video.UserID.String() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+	}
+
+	pkg, err := h.packageRepo.GetByVideoID(videoID)
+	if err != nil {
+		log.Printf("❌ Failed to get HLS package of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read video"})
+		return
+	}
+	if !pkg.Playable(video) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Adaptive playback is not available for this video"})
+		return
+	}
+
+	object, err := h.videos.Get(c.Request.Context(), pkg.HLSPath+"/master.m3u8")
+	if err != nil {
+		log.Printf("❌ Failed to get master playlist of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read video"})
+		return
+	}
+	defer object.Close()
+
+	master, err := io.ReadAll(object)
+	if err != nil {
+		log.Printf("❌ Failed to read master playlist of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read video"})
+		return
+	}
+
+	// Плейлисты качеств лежат в /hls/<version>/ относительно master.m3u8
+	token := h.signer.Sign(pkg.HLSPath, time.Now())
+	prefix := path.Base(pkg.HLSPath) + "/"
+
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, packager.ContentType("master.m3u8"), packager.SignPlaylist(master, prefix, "?token="+url.QueryEscape(token)))
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/uploads"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Протокол загрузки повторяет tus 1.0 (core): HEAD - текущий offset,
+// PATCH с Upload-Offset и телом application/offset+octet-stream - очередная порция
+const (
+	tusVersion        = "1.0.0"
+	uploadContentType = "application/offset+octet-stream"
+)
+
+type UploadHandler struct {
+	videoRepo  *repository.VideoRepository
+	uploadRepo *repository.UploadRepository
+	processor  *uploads.Processor
+}
+
+func NewUploadHandler(videoRepo *repository.VideoRepository, uploadRepo *repository.UploadRepository, processor *uploads.Processor) *UploadHandler {
+	return &UploadHandler{
+		videoRepo:  videoRepo,
+		uploadRepo: uploadRepo,
+		processor:  processor,
+	}
+}
+
+// CreateUpload создаёт видео в статусе pending и загрузку его файла.
+// Байты отправляются PATCH запросами на upload_url (Location)
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	userUUID, ok := requireUserUUID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len([]rune(req.Title)) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 3 characters"})
+		return
+	}
+
+	filename := filepath.Base(req.Filename)
+	if len(filename) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is too long"})
+		return
+	}
+
+	limits := h.processor.Limits()
+	if req.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a positive number of bytes"})
+		return
+	}
+	if req.Size > limits.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("File is too large: maximum upload size is %d MB", limits.MaxSize>>20),
+		})
+		return
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+	if visibility != "public" && visibility != "private" && visibility != "unlisted" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be public, private or unlisted"})
+		return
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	now := time.Now()
+	videoID := uuid.New()
+	video := &models.Video{
+		ID:          videoID,
+		UserID:      userUUID,
+		Title:       req.Title,
+		Description: req.Description,
+		Category:    req.Category,
+		Tags:        tags,
+		Source:      "upload",
+		Status:      "pending",
+		Visibility:  visibility,
+		FilePath:    fmt.Sprintf("%s.mp4", videoID),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := h.videoRepo.Create(video); err != nil {
+		log.Printf("❌ Failed to create video for upload: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	upload := &models.Upload{
+		ID:        uuid.New(),
+		VideoID:   videoID,
+		UserID:    userUUID,
+		Filename:  filename,
+		Size:      req.Size,
+		Status:    models.UploadStatusUploading,
+		ExpiresAt: now.Add(uploads.TTL),
+	}
+	if err := h.uploadRepo.Create(upload); err != nil {
+		log.Printf("❌ Failed to create upload: %v", err)
+		if err := h.videoRepo.Delete(videoID); err != nil {
+			log.Printf("❌ Failed to delete video %s of failed upload: %v", videoID, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	uploadURL := fmt.Sprintf("http://localhost/api/videos/uploads/%s", upload.ID)
+	log.Printf("📤 Upload %s of video %s created by user %s (%d bytes)", upload.ID, videoID, userUUID, upload.Size)
+
+	setUploadHeaders(c, upload)
+	c.Header("Location", uploadURL)
+	c.JSON(http.StatusCreated, gin.H{
+		"upload":     upload,
+		"video":      video,
+		"upload_url": uploadURL,
+		"chunk_size": uploads.ChunkSize,
+	})
+}
+
+// GetUpload возвращает состояние загрузки: offset для продолжения после обрыва
+// и статус обработки. HEAD отдаёт только заголовки Upload-Offset/Upload-Length
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	upload, ok := h.lookupOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Cache-Control", "no-store")
+	if c.Request.Method == http.MethodHead {
+		c.Status(http.StatusOK)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upload": upload})
+}
+
+// PatchUpload принимает очередную порцию файла с offset из заголовка Upload-Offset
+func (h *UploadHandler) PatchUpload(c *gin.Context) {
+	upload, ok := h.lookupOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Tus-Resumable", tusVersion)
+	if !strings.HasPrefix(c.GetHeader("Content-Type"), uploadContentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + uploadContentType})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset header must be a non-negative number"})
+		return
+	}
+	if c.Request.ContentLength > upload.Size-offset {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk exceeds the declared upload size"})
+		return
+	}
+
+	newOffset, err := h.processor.Receive(upload.ID, offset, c.Request.Body)
+	switch {
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the upload", "offset": newOffset})
+		return
+	case errors.Is(err, uploads.ErrUploadBusy):
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is being written by another request"})
+		return
+	case errors.Is(err, uploads.ErrNotUploading):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is already complete"})
+		return
+	case err != nil && newOffset <= offset:
+		log.Printf("❌ Failed to receive chunk of upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload chunk"})
+		return
+	case err != nil:
+		// Клиент оборвал запрос: полученная часть сохранена, он продолжит с newOffset
+		log.Printf("⚠️ Upload %s chunk interrupted at offset %d: %v", upload.ID, newOffset, err)
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CancelUpload отменяет незавершённую загрузку и удаляет её видео
+func (h *UploadHandler) CancelUpload(c *gin.Context) {
+	upload, ok := h.lookupOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.processor.Cancel(upload)
+	if errors.Is(err, uploads.ErrUploadBusy) {
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is being written by another request"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to cancel upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upload"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is already complete, delete the video instead"})
+		return
+	}
+
+	log.Printf("🗑️ Upload %s cancelled, video %s deleted", upload.ID, upload.VideoID)
+	c.Header("Tus-Resumable", tusVersion)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *UploadHandler) lookupOwnUpload(c *gin.Context) (*models.Upload, bool) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	uploadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return nil, false
+	}
+
+	upload, err := h.uploadRepo.GetByID(uploadID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+
+	if upload.UserID.String() != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		return nil, false
+	}
+
+	return upload, true
+}
+
+func setUploadHeaders(c *gin.Context, upload *models.Upload) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+}
@@ -8,36 +8,49 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/cmaf"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/packager"
 	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
-	"github.com/SerKKiT/streaming-platform/vod-service/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type VideoHandler struct {
 	repo                *repository.VideoRepository
-	storage             *storage.MinIOStorage
+	editRepo            *repository.EditRepository
+	packageRepo         *repository.PackageRepository
+	videos              storage.Storage // vod-videos: хранение и стриминг
+	recordings          storage.Storage // recordings: источник импорта
 	recordingServiceURL string
-	recordingBucket     string
-	vodBucket           string
+	signer              *packager.Signer // подпись URL HLS пакетов
+
+	// Разметка фрагментированных MP4 по file_path (nil - файл без sidx)
+	cmafIndexes *cmaf.IndexCache
 }
 
 func NewVideoHandler(
 	repo *repository.VideoRepository,
-	storage *storage.MinIOStorage,
+	editRepo *repository.EditRepository,
+	packageRepo *repository.PackageRepository,
+	videos storage.Storage,
+	recordings storage.Storage,
 	recordingServiceURL string,
-	recordingBucket string,
-	vodBucket string,
+	signer *packager.Signer,
 ) *VideoHandler {
 	return &VideoHandler{
 		repo:                repo,
-		storage:             storage,
+		editRepo:            editRepo,
+		packageRepo:         packageRepo,
+		videos:              videos,
+		recordings:          recordings,
 		recordingServiceURL: recordingServiceURL,
-		recordingBucket:     recordingBucket,
-		vodBucket:           vodBucket,
+		signer:              signer,
+		cmafIndexes:         cmaf.NewIndexCache(cmafIndexCacheSize),
 	}
 }
 
@@ -116,7 +129,7 @@ func (h *VideoHandler) ImportRecording(c *gin.Context) {
 
 	// Копируем видео из recordings в vod-videos bucket
 	ctx := context.Background()
-	if err := h.storage.CopyFromRecordings(ctx, h.recordingBucket, recording.FilePath, videoFileName); err != nil {
+	if err := h.videos.Copy(ctx, h.recordings, recording.FilePath, videoFileName); err != nil {
 		log.Printf("❌ Failed to copy video: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy recording"})
 		return
@@ -130,7 +143,7 @@ func (h *VideoHandler) ImportRecording(c *gin.Context) {
 		thumbnailFileName = fmt.Sprintf("%s.jpg", videoID.String())
 		log.Printf("📋 Copying thumbnail from recordings/%s to vod-videos/%s", recording.ThumbnailPath, thumbnailFileName)
 
-		if err := h.storage.CopyFromRecordings(ctx, h.recordingBucket, recording.ThumbnailPath, thumbnailFileName); err != nil {
+		if err := h.videos.Copy(ctx, h.recordings, recording.ThumbnailPath, thumbnailFileName); err != nil {
 			log.Printf("⚠️ Failed to copy thumbnail (non-critical): %v", err)
 			thumbnailFileName = ""
 		} else {
@@ -268,6 +281,12 @@ func (h *VideoHandler) GetVideo(c *gin.Context) {
 		log.Printf("✅ Owner access granted to private video %s", videoID)
 	}
 
+	chapters, err := h.editRepo.GetChapters(videoID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get chapters of video %s: %v", videoID, err)
+	}
+	video.Chapters = chapters
+
 	log.Printf("✅ Returning video %s (visibility=%s)", videoID, video.Visibility)
 	c.JSON(http.StatusOK, gin.H{"video": video})
 }
@@ -354,19 +373,25 @@ func (h *VideoHandler) DeleteVideo(c *gin.Context) {
 		return
 	}
 
-	// Удаляем файл из MinIO
+	// Удаляем файл из хранилища
 	ctx := context.Background()
-	if err := h.storage.DeleteObject(ctx, video.FilePath); err != nil {
-		log.Printf("⚠️ Failed to delete file from MinIO: %v", err)
+	if err := h.videos.Delete(ctx, video.FilePath); err != nil {
+		log.Printf("⚠️ Failed to delete file from storage: %v", err)
 	}
+	h.cmafIndexes.Remove(video.FilePath)
 
 	// Удаляем thumbnail
 	if video.ThumbnailPath != "" {
-		if err := h.storage.DeleteObject(ctx, video.ThumbnailPath); err != nil {
-			log.Printf("⚠️ Failed to delete thumbnail from MinIO: %v", err)
+		if err := h.videos.Delete(ctx, video.ThumbnailPath); err != nil {
+			log.Printf("⚠️ Failed to delete thumbnail from storage: %v", err)
 		}
 	}
 
+	// Удаляем HLS пакеты
+	if _, err := h.videos.DeletePrefix(ctx, fmt.Sprintf("hls/%s/", videoID)); err != nil {
+		log.Printf("⚠️ Failed to delete HLS packages from storage: %v", err)
+	}
+
 	// Удаляем из БД
 	if err := h.repo.Delete(videoID); err != nil {
 		log.Printf("❌ Failed to delete video: %v", err)
@@ -433,16 +458,41 @@ func (h *VideoHandler) GetStreamURL(c *gin.Context) {
 		}
 	}
 
-	// Возвращаем URL эндпоинтов
-	videoURL := fmt.Sprintf("http://localhost/api/videos/%s/play", video.ID.String())
+	// Возвращаем URL эндпоинтов. Файл и сегменты кэшируются надолго, а обрезка
+	// подменяет файл: версия в URL отличает новый файл от закэшированного
+	version := fileVersion(video)
+	videoURL := fmt.Sprintf("http://localhost/api/videos/%s/play?v=%s", video.ID.String(), version)
 	thumbnailURL := ""
 	if video.ThumbnailPath != "" {
 		thumbnailURL = fmt.Sprintf("http://localhost/api/videos/%s/thumbnail", video.ID.String())
 	}
 
+	// HLS - лестница качеств (master.m3u8), когда видео упаковано; до этого HLS и DASH
+	// доступны одним качеством для фрагментированных MP4 (записи стримов)
+	pkg, err := h.packageRepo.GetByVideoID(video.ID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get HLS package of video %s: %v", video.ID, err)
+	}
+	abr := pkg.Playable(video)
+	renditions := []string{}
+	hlsURL, dashURL := "", ""
+	if _, err := h.getCMAFIndex(c.Request.Context(), video); err == nil {
+		hlsURL = fmt.Sprintf("http://localhost/api/videos/%s/cmaf/playlist.m3u8?v=%s", video.ID.String(), version)
+		dashURL = fmt.Sprintf("http://localhost/api/videos/%s/cmaf/manifest.mpd?v=%s", video.ID.String(), version)
+	}
+	if abr {
+		hlsURL = fmt.Sprintf("http://localhost/api/videos/%s/hls/master.m3u8", video.ID.String())
+		renditions = pkg.Renditions
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"video_url":     videoURL,
+		"hls_url":       hlsURL,
+		"abr":           abr,
+		"renditions":    renditions,
+		"dash_url":      dashURL,
 		"thumbnail_url": thumbnailURL,
+		"chapters_url":  fmt.Sprintf("http://localhost/api/videos/%s/chapters.vtt", video.ID.String()),
 		"video": gin.H{
 			"id":          video.ID,
 			"title":       video.Title,
@@ -458,6 +508,21 @@ func (h *VideoHandler) GetStreamURL(c *gin.Context) {
 	})
 }
 
+// fileVersion - версия файла видео для URL с долгим кэшем
+func fileVersion(video *models.Video) string {
+	return strconv.FormatInt(video.UpdatedAt.Unix(), 10)
+}
+
+// cacheControl - Cache-Control файла видео: общие кэши (CDN, прокси) хранят только
+// публичные видео, ответы по unlisted и private видео кэширует лишь браузер
+func cacheControl(video *models.Video, maxAge int) string {
+	scope := "private"
+	if video.Visibility == "public" {
+		scope = "public"
+	}
+	return scope + ", max-age=" + strconv.Itoa(maxAge)
+}
+
 // StreamVideoFile streams video file directly with auth check
 func (h *VideoHandler) StreamVideoFile(c *gin.Context) {
 	videoID, err := uuid.Parse(c.Param("id"))
@@ -492,41 +557,25 @@ func (h *VideoHandler) StreamVideoFile(c *gin.Context) {
 		log.Printf("✅ Access granted to owner %s for private video %s", userID, videoID)
 	}
 
-	// ✅ Stream file directly from MinIO
+	// ✅ Stream file directly from storage
 	ctx := c.Request.Context()
-	object, err := h.storage.GetObject(ctx, h.vodBucket, video.FilePath) // ✅ Передаем bucket
+	object, err := h.videos.Get(ctx, video.FilePath)
 	if err != nil {
-		log.Printf("❌ Failed to get object from MinIO: %v", err)
+		log.Printf("❌ Failed to get object from storage: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stream video"})
 		return
 	}
 	defer object.Close()
 
-	// Get file info
-	stat, err := object.Stat()
-	if err != nil {
-		log.Printf("❌ Failed to stat object: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video info"})
-		return
-	}
-
-	log.Printf("✅ Streaming video %s directly (size: %d bytes)", videoID, stat.Size)
+	log.Printf("✅ Streaming video %s directly (size: %d bytes, range: %q)", videoID, object.Info().Size, c.GetHeader("Range"))
 
-	// Set headers for video streaming
-	c.Header("Content-Type", "video/mp4")
-	c.Header("Content-Length", fmt.Sprintf("%d", stat.Size))
-	c.Header("Accept-Ranges", "bytes")
-	c.Header("Cache-Control", "public, max-age=31536000")
+	c.Header("Cache-Control", cacheControl(video, 31536000))
 
 	// CORS for video element
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Credentials", "true")
 
-	// Stream to client
-	_, err = io.Copy(c.Writer, object)
-	if err != nil {
-		log.Printf("⚠️ Error streaming video: %v", err)
-	}
+	// Перемотка и Safari запрашивают части файла (206)
+	serveObject(c, object, "video/mp4")
 }
 
 // StreamThumbnail streams thumbnail file directly with auth check
@@ -567,40 +616,42 @@ func (h *VideoHandler) StreamThumbnail(c *gin.Context) {
 		log.Printf("✅ Access granted to owner %s for private video thumbnail %s", userID, videoID)
 	}
 
-	// ✅ Stream thumbnail directly from MinIO
+	// ✅ Stream thumbnail directly from storage
 	ctx := c.Request.Context()
-	object, err := h.storage.GetObject(ctx, h.vodBucket, video.ThumbnailPath)
+	object, err := h.videos.Get(ctx, video.ThumbnailPath)
 	if err != nil {
-		log.Printf("❌ Failed to get thumbnail from MinIO: %v", err)
+		log.Printf("❌ Failed to get thumbnail from storage: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stream thumbnail"})
 		return
 	}
 	defer object.Close()
 
-	// Get file info
-	stat, err := object.Stat()
-	if err != nil {
-		log.Printf("❌ Failed to stat thumbnail: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get thumbnail info"})
-		return
-	}
-
-	log.Printf("✅ Streaming thumbnail for video %s (size: %d bytes)", videoID, stat.Size)
+	log.Printf("✅ Streaming thumbnail for video %s (size: %d bytes)", videoID, object.Info().Size)
 
-	// Set headers for image streaming
-	c.Header("Content-Type", "image/jpeg")
-	c.Header("Content-Length", fmt.Sprintf("%d", stat.Size))
-	c.Header("Cache-Control", "public, max-age=31536000")
+	// Thumbnail не версионируется в URL: браузер перепроверяет его по ETag (304)
+	c.Header("Cache-Control", cacheControl(video, 86400))
 
 	// CORS for image element
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Credentials", "true")
 
-	// Stream to client
-	_, err = io.Copy(c.Writer, object)
-	if err != nil {
-		log.Printf("⚠️ Error streaming thumbnail: %v", err)
+	serveObject(c, object, "image/jpeg")
+}
+
+// serveObject отдаёт объект хранилища с поддержкой HTTP Range (один или несколько
+// диапазонов, 206/416), If-Range и условных запросов по ETag и Last-Modified объекта (304)
+func serveObject(c *gin.Context, object storage.Object, contentType string) {
+	info := object.Info()
+	if info.ETag != "" {
+		etag := info.ETag
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		c.Header("ETag", etag)
 	}
+	// Content-Type задан заранее, иначе ServeContent читает начало файла для определения типа
+	c.Header("Content-Type", contentType)
+
+	http.ServeContent(c.Writer, c.Request, "", info.LastModified, object)
 }
 
 // getRecordingInfo получает информацию о recording
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment - комментарий к видео. TimestampSeconds привязывает его к моменту видео
+// (только верхний уровень), ответы лежат в Replies
+type Comment struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	VideoID          uuid.UUID  `json:"video_id" db:"video_id"`
+	UserID           uuid.UUID  `json:"user_id" db:"user_id"`
+	Username         string     `json:"username" db:"username"`
+	ParentID         *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	TimestampSeconds *float64   `json:"timestamp_seconds,omitempty" db:"timestamp_seconds"`
+	Content          string     `json:"content" db:"content"`
+	Deleted          bool       `json:"deleted"` // удалён, но остался ради ответов
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	EditedAt         *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+	Replies          []*Comment `json:"replies,omitempty"`
+}
+
+// DTOs
+type CreateCommentRequest struct {
+	Content          string   `json:"content" binding:"required"`
+	TimestampSeconds *float64 `json:"timestamp_seconds"`
+	ParentID         string   `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+type CommentListResponse struct {
+	Comments []*Comment `json:"comments"`
+	Total    int        `json:"total"`
+	Page     int        `json:"page"`
+	Limit    int        `json:"limit"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы обрезки видео
+const (
+	EditStatusProcessing = "processing"
+	EditStatusDone       = "done"
+	EditStatusFailed     = "failed"
+)
+
+// Chapter - именованная глава видео, заканчивается началом следующей главы
+type Chapter struct {
+	StartSeconds float64 `json:"start_seconds"`
+	Title        string  `json:"title"`
+}
+
+// VideoEdit - последняя обрезка видео. Точки обрезки - на таймлайне файла до обрезки
+type VideoEdit struct {
+	VideoID          uuid.UUID `json:"video_id"`
+	Status           string    `json:"status"` // "processing", "done", "failed"
+	TrimStartSeconds float64   `json:"trim_start_seconds"`
+	TrimEndSeconds   float64   `json:"trim_end_seconds"`
+	Lossless         *bool     `json:"lossless,omitempty"` // nil - ещё не решено
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// EditVideoRequest - обрезка и/или главы видео.
+// Без trim_* видео не обрезается; trim_end_seconds по умолчанию - конец видео.
+// chapters заменяют все главы (пустой список удаляет их) и задаются на таймлайне
+// после обрезки; без chapters существующие главы сдвигаются вместе с обрезкой
+type EditVideoRequest struct {
+	TrimStartSeconds *float64  `json:"trim_start_seconds"`
+	TrimEndSeconds   *float64  `json:"trim_end_seconds"`
+	Chapters         []Chapter `json:"chapters"`
+}
+
+type VideoEditResponse struct {
+	Edit     *VideoEdit `json:"edit"` // nil - видео не обрезалось
+	Chapters []Chapter  `json:"chapters"`
+}
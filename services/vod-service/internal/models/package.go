@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы упаковки видео в HLS
+const (
+	PackageStatusPending    = "pending" // прерванная рестартом упаковка ждёт повтора
+	PackageStatusProcessing = "processing"
+	PackageStatusReady      = "ready"
+	PackageStatusFailed     = "failed"
+)
+
+// VideoPackage - HLS ABR пакет видео. Пока новый пакет собирается (после обрезки),
+// отдаётся прежний, если он сделан из текущего файла видео
+type VideoPackage struct {
+	VideoID       uuid.UUID `json:"video_id"`
+	Status        string    `json:"status"`
+	SourcePath    string    `json:"-"` // файл видео последней упаковки
+	HLSPath       string    `json:"-"` // префикс готового пакета в хранилище
+	HLSSourcePath string    `json:"-"` // файл видео, из которого собран готовый пакет
+	Renditions    []string  `json:"renditions"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Playable - готовый пакет собран из текущего файла видео
+func (p *VideoPackage) Playable(video *Video) bool {
+	return p != nil && p.HLSPath != "" && p.HLSSourcePath == video.FilePath
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы загрузки видео
+const (
+	UploadStatusUploading  = "uploading"  // принимаются байты
+	UploadStatusUploaded   = "uploaded"   // файл получен целиком, ждёт обработки
+	UploadStatusProcessing = "processing" // проверка и нормализация
+	UploadStatusCompleted  = "completed"
+	UploadStatusFailed     = "failed"
+)
+
+// Upload - возобновляемая загрузка видео пользователем (source "upload").
+// Offset - сколько байт уже получено, с этого места клиент продолжает загрузку
+type Upload struct {
+	ID        uuid.UUID `json:"id"`
+	VideoID   uuid.UUID `json:"video_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateUploadRequest - начало загрузки: размер файла и метаданные будущего видео
+type CreateUploadRequest struct {
+	Filename    string   `json:"filename" binding:"required"`
+	Size        int64    `json:"size" binding:"required"`
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+	Visibility  string   `json:"visibility"` // default: "public"
+}
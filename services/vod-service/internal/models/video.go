@@ -12,12 +12,13 @@ type Video struct {
 	Username      string     `json:"username" db:"username"` // ✅ ДОБАВЛЕНО: username владельца
 	RecordingID   *uuid.UUID `json:"recording_id,omitempty" db:"recording_id"`
 	StreamID      *uuid.UUID `json:"stream_id,omitempty" db:"stream_id"`
+	ParentVideoID *uuid.UUID `json:"parent_video_id,omitempty" db:"parent_video_id"` // клип: видео, из которого он вырезан
 	Title         string     `json:"title" db:"title"`
 	Description   string     `json:"description" db:"description"`
 	Category      string     `json:"category" db:"category"`
 	Tags          []string   `json:"tags" db:"tags"`
-	Source        string     `json:"source" db:"source"`         // "recording", "upload"
-	Status        string     `json:"status" db:"status"`         // "ready", "processing", "failed"
+	Source        string     `json:"source" db:"source"`         // "recording", "upload", "clip"
+	Status        string     `json:"status" db:"status"`         // "ready", "pending", "failed"
 	Visibility    string     `json:"visibility" db:"visibility"` // "public", "private", "unlisted"
 	FilePath      string     `json:"file_path" db:"file_path"`
 	ThumbnailPath string     `json:"thumbnail_path" db:"thumbnail_path"`
@@ -28,6 +29,8 @@ type Video struct {
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 	PublishedAt   *time.Time `json:"published_at,omitempty" db:"published_at"`
+
+	Chapters []Chapter `json:"chapters,omitempty" db:"-"` // заполняется только для одного видео
 }
 
 // DTOs
@@ -40,6 +43,20 @@ type ImportRecordingRequest struct {
 	Visibility  string   `json:"visibility"` // default: "public"
 }
 
+// CreateClipRequest - клип из live стрима (stream_id, моменты эфира start_at/end_at)
+// или из видео (video_id, позиции start_seconds/end_seconds)
+type CreateClipRequest struct {
+	StreamID     string     `json:"stream_id"`
+	VideoID      string     `json:"video_id"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	StartSeconds *float64   `json:"start_seconds"`
+	EndSeconds   *float64   `json:"end_seconds"`
+	Title        string     `json:"title" binding:"required"`
+	Description  string     `json:"description"`
+	Visibility   string     `json:"visibility"` // default: "public"
+}
+
 type UpdateVideoRequest struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
@@ -0,0 +1,305 @@
+// Package packager - упаковка VOD видео в HLS лестницу качеств (ABR) с fMP4 сегментами.
+// Пакет складывается в vod-videos под hls/<video_id>/<version>/: master.m3u8, плейлист,
+// init и сегменты каждого качества (720p.m3u8, 720p_init.mp4, 720p_00001.m4s).
+// Очередь - ready видео без актуального пакета, поэтому импорты, загрузки, клипы,
+// обрезанные и старые видео подхватываются без отдельных вызовов
+package packager
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/uploads"
+)
+
+const (
+	// segmentTime - длительность сегмента, ключевые кадры всех качеств выровнены по ней
+	segmentTime = 4
+
+	pollInterval = 15 * time.Second
+	jobTimeout   = 4 * time.Hour
+)
+
+// Rendition - качество лестницы. Битрейты совпадают с live ABR профилями stream-service
+type Rendition struct {
+	Name         string
+	Height       int
+	VideoBitrate string
+	MaxRate      string
+	BufSize      string
+	AudioBitrate string
+}
+
+// Ladder - качества от большего к меньшему; выше исходного видео не поднимаемся
+var Ladder = []Rendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: "5000k", MaxRate: "5500k", BufSize: "11000k", AudioBitrate: "192k"},
+	{Name: "720p", Height: 720, VideoBitrate: "2800k", MaxRate: "3080k", BufSize: "5600k", AudioBitrate: "128k"},
+	{Name: "480p", Height: 480, VideoBitrate: "1400k", MaxRate: "1540k", BufSize: "2800k", AudioBitrate: "128k"},
+	{Name: "360p", Height: 360, VideoBitrate: "800k", MaxRate: "880k", BufSize: "1600k", AudioBitrate: "96k"},
+}
+
+// Packager упаковывает видео в фоне
+type Packager struct {
+	repo    *repository.PackageRepository
+	videos  storage.Storage // vod-videos: исходные файлы и пакеты
+	workDir string
+}
+
+func NewPackager(repo *repository.PackageRepository, videos storage.Storage, workDir string) *Packager {
+	return &Packager{
+		repo:    repo,
+		videos:  videos,
+		workDir: workDir,
+	}
+}
+
+// Start запускает workers. Упаковка, оборванная рестартом, начинается заново
+func (p *Packager) Start(ctx context.Context, workers int) {
+	if requeued, err := p.repo.RequeueInterrupted(); err != nil {
+		log.Printf("⚠️ Failed to requeue interrupted packaging: %v", err)
+	} else if requeued > 0 {
+		log.Printf("🔁 Requeued %d interrupted packaging jobs", requeued)
+	}
+
+	for i := 0; i < workers; i++ {
+		go p.work(ctx)
+	}
+}
+
+// RenditionsFor возвращает качества лестницы для видео высотой height.
+// Видео ниже 360p упаковывается в одно качество своей высоты
+func RenditionsFor(height int) []Rendition {
+	var renditions []Rendition
+	for _, r := range Ladder {
+		if r.Height <= height {
+			renditions = append(renditions, r)
+		}
+	}
+	if len(renditions) == 0 {
+		lowest := Ladder[len(Ladder)-1]
+		lowest.Height = height &^ 1
+		lowest.Name = fmt.Sprintf("%dp", lowest.Height)
+		renditions = append(renditions, lowest)
+	}
+	return renditions
+}
+
+func (p *Packager) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		pkg, err := p.repo.ClaimNext()
+		if err != nil {
+			log.Printf("⚠️ Failed to claim video for packaging: %v", err)
+		}
+		if pkg != nil {
+			p.process(ctx, pkg)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Packager) process(parent context.Context, pkg *models.VideoPackage) {
+	ctx, cancel := context.WithTimeout(parent, jobTimeout)
+	defer cancel()
+
+	log.Printf("📦 Packaging video %s (%s) into HLS", pkg.VideoID, pkg.SourcePath)
+
+	err := p.pack(ctx, pkg)
+	if err != nil && parent.Err() != nil {
+		log.Printf("⚠️ Packaging of video %s interrupted", pkg.VideoID)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to package video %s: %v", pkg.VideoID, err)
+		if err := p.repo.Fail(pkg.VideoID, "packaging failed"); err != nil {
+			log.Printf("❌ Failed to mark packaging of video %s as failed: %v", pkg.VideoID, err)
+		}
+	}
+}
+
+func (p *Packager) pack(ctx context.Context, pkg *models.VideoPackage) error {
+	dir := filepath.Join(p.workDir, pkg.VideoID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source.mp4")
+	if err := p.videos.Download(ctx, pkg.SourcePath, source); err != nil {
+		return fmt.Errorf("failed to download video: %w", err)
+	}
+
+	probe, err := uploads.ProbeFile(ctx, source)
+	if err != nil {
+		return err
+	}
+	if probe.VideoCodec == "" || probe.Height <= 0 {
+		return fmt.Errorf("video has no video stream")
+	}
+
+	renditions := RenditionsFor(probe.Height)
+	output := filepath.Join(dir, "hls")
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := transcode(ctx, source, renditions, probe.AudioCodec != "", output); err != nil {
+		return err
+	}
+
+	// Новая версия пакета не пересекается с прежней: её сегменты могли быть закэшированы
+	hlsPath := fmt.Sprintf("hls/%s/%d", pkg.VideoID, time.Now().Unix())
+	if err := p.upload(ctx, output, hlsPath); err != nil {
+		p.discard(hlsPath)
+		return err
+	}
+
+	names := make([]string, len(renditions))
+	for i, r := range renditions {
+		names[i] = r.Name
+	}
+
+	previous, err := p.repo.Complete(pkg, hlsPath, names)
+	if err != nil {
+		p.discard(hlsPath)
+		return err
+	}
+	if previous != "" {
+		p.discard(previous)
+	}
+
+	log.Printf("✅ Video %s packaged: %s (%s)", pkg.VideoID, hlsPath, strings.Join(names, ", "))
+	return nil
+}
+
+// upload загружает файлы пакета из dir под префиксом hlsPath
+func (p *Packager) upload(ctx context.Context, dir, hlsPath string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := hlsPath + "/" + filepath.ToSlash(rel)
+		if err := p.videos.PutFile(ctx, key, path, ContentType(path)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// discard удаляет пакет из хранилища
+func (p *Packager) discard(hlsPath string) {
+	if _, err := p.videos.DeletePrefix(context.Background(), hlsPath+"/"); err != nil {
+		log.Printf("⚠️ Failed to delete HLS package %s: %v", hlsPath, err)
+	}
+}
+
+// ContentType - MIME тип файла пакета
+func ContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".m4s":
+		return "video/iso.segment"
+	default:
+		return "video/mp4"
+	}
+}
+
+// transcode собирает HLS пакет в output одним проходом ffmpeg: split на качества,
+// ключевые кадры каждые segmentTime секунд во всех качествах, чтобы плеер
+// переключался между ними на границе любого сегмента. ffmpeg запускается в output,
+// поэтому все URI в плейлистах - имена файлов рядом с плейлистом
+func transcode(ctx context.Context, source string, renditions []Rendition, hasAudio bool, output string) error {
+	filter := fmt.Sprintf("[0:v]split=%d", len(renditions))
+	for i := range renditions {
+		filter += fmt.Sprintf("[v%d]", i)
+	}
+	for i, r := range renditions {
+		filter += fmt.Sprintf(";[v%d]scale=-2:%d[v%dout]", i, r.Height, i)
+	}
+
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-i", source,
+		"-filter_complex", filter,
+	}
+
+	var streamMap []string
+	for i, r := range renditions {
+		args = append(args,
+			"-map", fmt.Sprintf("[v%dout]", i),
+			fmt.Sprintf("-c:v:%d", i), "libx264",
+			fmt.Sprintf("-b:v:%d", i), r.VideoBitrate,
+			fmt.Sprintf("-maxrate:v:%d", i), r.MaxRate,
+			fmt.Sprintf("-bufsize:v:%d", i), r.BufSize,
+		)
+		if hasAudio {
+			args = append(args,
+				"-map", "0:a:0",
+				fmt.Sprintf("-c:a:%d", i), "aac",
+				fmt.Sprintf("-b:a:%d", i), r.AudioBitrate,
+			)
+			streamMap = append(streamMap, fmt.Sprintf("v:%d,a:%d,name:%s", i, i, r.Name))
+		} else {
+			streamMap = append(streamMap, fmt.Sprintf("v:%d,name:%s", i, r.Name))
+		}
+	}
+	if hasAudio {
+		args = append(args, "-ar", "48000", "-ac", "2")
+	}
+
+	args = append(args,
+		"-preset", "veryfast",
+		"-pix_fmt", "yuv420p",
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentTime),
+		"-f", "hls",
+		"-hls_time", fmt.Sprintf("%d", segmentTime),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_type", "fmp4",
+		"-hls_flags", "independent_segments",
+		"-hls_fmp4_init_filename", "%v_init.mp4",
+		"-hls_segment_filename", "%v_%05d.m4s",
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-y",
+		"%v.m3u8",
+	)
+
+	log.Printf("🎬 Packaging: ffmpeg %v", args)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Dir = output
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg packaging failed: %w", err)
+	}
+	return nil
+}
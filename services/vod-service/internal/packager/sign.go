@@ -0,0 +1,80 @@
+package packager
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenTTL - минимальный срок подписи URL пакета. Смена видимости видео
+// не отзывает уже выданные подписи: они истекают сами
+const TokenTTL = 6 * time.Hour
+
+var uriAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// Signer подписывает URL плейлистов и сегментов пакета: проверка доступа к видео
+// делается один раз при выдаче master.m3u8, сегменты проверяются по подписи без БД
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key string) *Signer {
+	return &Signer{key: []byte(key)}
+}
+
+// Sign возвращает токен доступа к пакету hlsPath. Срок округляется вверх до часа:
+// в течение часа зрители получают одинаковые URL и сегменты кэшируются
+func (s *Signer) Sign(hlsPath string, now time.Time) string {
+	expires := now.Add(TokenTTL).Truncate(time.Hour).Add(time.Hour).Unix()
+	return fmt.Sprintf("%d.%s", expires, s.mac(hlsPath, expires))
+}
+
+// Verify проверяет токен пакета hlsPath и возвращает срок его действия
+func (s *Signer) Verify(hlsPath, token string, now time.Time) (time.Time, bool) {
+	value, mac, found := strings.Cut(token, ".")
+	if !found {
+		return time.Time{}, false
+	}
+	expires, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return time.Time{}, false
+	}
+	if !hmac.Equal([]byte(mac), []byte(s.mac(hlsPath, expires))) {
+		return time.Time{}, false
+	}
+	return time.Unix(expires, 0), true
+}
+
+func (s *Signer) mac(hlsPath string, expires int64) string {
+	h := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(h, "%s\n%d", hlsPath, expires)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SignPlaylist переписывает относительные URI плейлиста (строки сегментов и
+// плейлистов, атрибуты URI="...") в prefix+URI+query
+func SignPlaylist(playlist []byte, prefix, query string) []byte {
+	var b bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(string(playlist), "\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			line = uriAttribute.ReplaceAllStringFunc(line, func(attribute string) string {
+				uri := uriAttribute.FindStringSubmatch(attribute)[1]
+				return fmt.Sprintf(`URI="%s%s%s"`, prefix, uri, query)
+			})
+		default:
+			line = prefix + line + query
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type CommentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// CommentFilter - выборка веток комментариев. From/To (секунды) оставляют только
+// комментарии, привязанные к этому отрезку видео, в порядке таймлайна
+type CommentFilter struct {
+	From   *float64
+	To     *float64
+	Limit  int
+	Offset int
+}
+
+func (f CommentFilter) timeline() bool {
+	return f.From != nil || f.To != nil
+}
+
+// commentColumns - колонки для scanComment, c - video_comments, u - users
+const commentColumns = `
+	c.id, c.video_id, c.user_id, c.parent_id, c.timestamp_seconds,
+	c.content, c.deleted_at IS NOT NULL, c.created_at, c.edited_at,
+	COALESCE(u.username, 'Unknown')
+`
+
+// Create creates a comment and fills ID, CreatedAt and Username
+func (r *CommentRepository) Create(comment *models.Comment) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO video_comments (video_id, user_id, parent_id, timestamp_seconds, content)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, user_id, created_at
+		)
+		SELECT i.id, i.created_at, COALESCE(u.username, 'Unknown')
+		FROM inserted i
+		LEFT JOIN users u ON i.user_id = u.id
+	`
+
+	err := r.db.QueryRow(query,
+		comment.VideoID, comment.UserID, comment.ParentID, comment.TimestampSeconds, comment.Content,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.Username)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID returns a comment of the video, including deleted ones
+func (r *CommentRepository) GetByID(videoID, commentID uuid.UUID) (*models.Comment, error) {
+	query := `
+		SELECT` + commentColumns + `
+		FROM video_comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.id = $1 AND c.video_id = $2
+	`
+
+	comment, err := scanComment(r.db.QueryRow(query, commentID, videoID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// UpdateContent меняет текст комментария и отмечает его отредактированным
+func (r *CommentRepository) UpdateContent(commentID uuid.UUID, content string) error {
+	query := `
+		UPDATE video_comments
+		SET content = $1,
+		    edited_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, content, commentID); err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	return nil
+}
+
+// Delete скрывает комментарий. Ответы остаются видимыми
+func (r *CommentRepository) Delete(commentID, deletedBy uuid.UUID) error {
+	query := `
+		UPDATE video_comments
+		SET deleted_at = CURRENT_TIMESTAMP,
+		    deleted_by = $2,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, commentID, deletedBy); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	return nil
+}
+
+// ListThreads returns top-level comments with their replies and the total number of threads.
+// Удалённый комментарий верхнего уровня возвращается без текста, пока у него есть ответы
+func (r *CommentRepository) ListThreads(videoID uuid.UUID, filter CommentFilter) ([]*models.Comment, int, error) {
+	where := `
+		c.video_id = $1 AND c.parent_id IS NULL
+		AND (c.deleted_at IS NULL OR EXISTS (
+			SELECT 1 FROM video_comments r WHERE r.parent_id = c.id AND r.deleted_at IS NULL
+		))
+	`
+	order := "c.created_at DESC"
+
+	args := []interface{}{videoID}
+	if filter.timeline() {
+		where += " AND c.timestamp_seconds IS NOT NULL"
+		if filter.From != nil {
+			args = append(args, *filter.From)
+			where += fmt.Sprintf(" AND c.timestamp_seconds >= $%d", len(args))
+		}
+		if filter.To != nil {
+			args = append(args, *filter.To)
+			where += fmt.Sprintf(" AND c.timestamp_seconds <= $%d", len(args))
+		}
+		order = "c.timestamp_seconds ASC, c.created_at ASC"
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM video_comments c WHERE ` + where
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM video_comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, commentColumns, where, order, len(args)-1, len(args))
+
+	threads, err := r.queryComments(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.attachReplies(threads); err != nil {
+		return nil, 0, err
+	}
+
+	return threads, total, nil
+}
+
+// attachReplies загружает видимые ответы веток одним запросом
+func (r *CommentRepository) attachReplies(threads []*models.Comment) error {
+	if len(threads) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(threads))
+	byID := make(map[uuid.UUID]*models.Comment, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID.String()
+		byID[thread.ID] = thread
+	}
+
+	query := `
+		SELECT` + commentColumns + `
+		FROM video_comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.parent_id = ANY($1::uuid[]) AND c.deleted_at IS NULL
+		ORDER BY c.created_at ASC
+	`
+
+	replies, err := r.queryComments(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	for _, reply := range replies {
+		if thread, ok := byID[*reply.ParentID]; ok {
+			thread.Replies = append(thread.Replies, reply)
+		}
+	}
+
+	return nil
+}
+
+func (r *CommentRepository) queryComments(query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	comment := &models.Comment{}
+	var parentID uuid.NullUUID
+	var timestamp sql.NullFloat64
+	var editedAt sql.NullTime
+
+	err := row.Scan(
+		&comment.ID, &comment.VideoID, &comment.UserID, &parentID, &timestamp,
+		&comment.Content, &comment.Deleted, &comment.CreatedAt, &editedAt,
+		&comment.Username,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		comment.ParentID = &parentID.UUID
+	}
+	if timestamp.Valid {
+		comment.TimestampSeconds = &timestamp.Float64
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	// Текст удалённого комментария не отдаётся
+	if comment.Deleted {
+		comment.Content = ""
+	}
+
+	return comment, nil
+}
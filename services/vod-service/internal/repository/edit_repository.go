@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EditRepository - главы видео и обрезка (video_chapters, video_edits)
+type EditRepository struct {
+	db *sql.DB
+}
+
+func NewEditRepository(db *sql.DB) *EditRepository {
+	return &EditRepository{db: db}
+}
+
+// TrimResult - обрезанный файл, заменяющий файл видео
+type TrimResult struct {
+	PreviousPath string // file_path до обрезки: замена не применяется, если файл успели сменить
+	FilePath     string
+	Duration     int // секунды
+	FileSize     int64
+	StartSeconds float64 // точка начала на таймлайне исходного файла
+}
+
+// GetChapters returns chapters of the video in timeline order
+func (r *EditRepository) GetChapters(videoID uuid.UUID) ([]models.Chapter, error) {
+	rows, err := r.db.Query(`
+		SELECT start_seconds, title
+		FROM video_chapters
+		WHERE video_id = $1
+		ORDER BY start_seconds
+	`, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapters: %w", err)
+	}
+	defer rows.Close()
+
+	chapters := []models.Chapter{}
+	for rows.Next() {
+		var chapter models.Chapter
+		if err := rows.Scan(&chapter.StartSeconds, &chapter.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan chapter: %w", err)
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	return chapters, rows.Err()
+}
+
+// ReplaceChapters заменяет все главы видео
+func (r *EditRepository) ReplaceChapters(videoID uuid.UUID, chapters []models.Chapter) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceChapters(tx, videoID, chapters); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetEdit returns the latest trim of the video (nil - the video has never been trimmed)
+func (r *EditRepository) GetEdit(videoID uuid.UUID) (*models.VideoEdit, error) {
+	edit := &models.VideoEdit{}
+	var lossless sql.NullBool
+	var reason sql.NullString
+
+	err := r.db.QueryRow(`
+		SELECT video_id, status, trim_start_seconds, trim_end_seconds, lossless, error, created_at, updated_at
+		FROM video_edits
+		WHERE video_id = $1
+	`, videoID).Scan(
+		&edit.VideoID, &edit.Status, &edit.TrimStartSeconds, &edit.TrimEndSeconds,
+		&lossless, &reason, &edit.CreatedAt, &edit.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video edit: %w", err)
+	}
+
+	if lossless.Valid {
+		edit.Lossless = &lossless.Bool
+	}
+	edit.Error = reason.String
+	return edit, nil
+}
+
+// StartEdit регистрирует новую обрезку видео. false - предыдущая обрезка ещё идёт
+func (r *EditRepository) StartEdit(videoID uuid.UUID, start, end float64) (bool, error) {
+	query := `
+		INSERT INTO video_edits (video_id, status, trim_start_seconds, trim_end_seconds)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (video_id) DO UPDATE
+		SET status = EXCLUDED.status,
+		    trim_start_seconds = EXCLUDED.trim_start_seconds,
+		    trim_end_seconds = EXCLUDED.trim_end_seconds,
+		    lossless = NULL,
+		    error = NULL,
+		    created_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE video_edits.status <> $2
+	`
+
+	result, err := r.db.Exec(query, videoID, models.EditStatusProcessing, start, end)
+	if err != nil {
+		return false, fmt.Errorf("failed to start video edit: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// SetLossless запоминает способ обрезки: копирование потоков или перекодирование
+func (r *EditRepository) SetLossless(videoID uuid.UUID, lossless bool) error {
+	_, err := r.db.Exec(`
+		UPDATE video_edits SET lossless = $1, updated_at = CURRENT_TIMESTAMP WHERE video_id = $2
+	`, lossless, videoID)
+	return err
+}
+
+// FailEdit помечает обрезку, которую не удалось выполнить. Файл видео не меняется
+func (r *EditRepository) FailEdit(videoID uuid.UUID, reason string) error {
+	_, err := r.db.Exec(`
+		UPDATE video_edits SET status = $1, error = $2, updated_at = CURRENT_TIMESTAMP WHERE video_id = $3
+	`, models.EditStatusFailed, reason, videoID)
+	return err
+}
+
+// FailInterruptedEdits помечает failed обрезки, оборванные рестартом сервиса
+func (r *EditRepository) FailInterruptedEdits() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE video_edits
+		SET status = $1, error = 'interrupted by service restart', updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2
+	`, models.EditStatusFailed, models.EditStatusProcessing)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ApplyTrim одной транзакцией подменяет файл видео обрезанным, заменяет главы
+// и сдвигает привязку комментариев к таймлайну (вышедшие за обрезку теряют привязку)
+func (r *EditRepository) ApplyTrim(videoID uuid.UUID, trim TrimResult, chapters []models.Chapter) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE videos
+		SET file_path = $1, duration = $2, file_size = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND file_path = $5
+	`, trim.FilePath, trim.Duration, trim.FileSize, videoID, trim.PreviousPath)
+	if err != nil {
+		return fmt.Errorf("failed to replace video file: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("video was deleted or its file changed during trimming")
+	}
+
+	if err := replaceChapters(tx, videoID, chapters); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE video_comments
+		SET timestamp_seconds = CASE
+			WHEN timestamp_seconds - $2 BETWEEN 0 AND $3 THEN timestamp_seconds - $2
+		END
+		WHERE video_id = $1 AND timestamp_seconds IS NOT NULL
+	`, videoID, trim.StartSeconds, trim.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to shift comment timestamps: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE video_edits SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE video_id = $2
+	`, models.EditStatusDone, videoID)
+	if err != nil {
+		return fmt.Errorf("failed to complete video edit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trim: %w", err)
+	}
+	return nil
+}
+
+func replaceChapters(tx *sql.Tx, videoID uuid.UUID, chapters []models.Chapter) error {
+	if _, err := tx.Exec(`DELETE FROM video_chapters WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("failed to delete chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	starts := make([]float64, len(chapters))
+	titles := make([]string, len(chapters))
+	for i, chapter := range chapters {
+		starts[i] = chapter.StartSeconds
+		titles[i] = chapter.Title
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO video_chapters (video_id, start_seconds, title)
+		SELECT $1, c.start_seconds, c.title
+		FROM unnest($2::numeric[], $3::text[]) AS c(start_seconds, title)
+	`, videoID, pq.Array(starts), pq.Array(titles))
+	if err != nil {
+		return fmt.Errorf("failed to insert chapters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PackageRepository struct {
+	db *sql.DB
+}
+
+func NewPackageRepository(db *sql.DB) *PackageRepository {
+	return &PackageRepository{db: db}
+}
+
+// GetByVideoID returns the HLS package of a video (nil - the video was never packaged)
+func (r *PackageRepository) GetByVideoID(videoID uuid.UUID) (*models.VideoPackage, error) {
+	pkg := &models.VideoPackage{}
+	var hlsPath, hlsSourcePath, reason sql.NullString
+	var renditions pq.StringArray
+
+	err := r.db.QueryRow(`
+		SELECT video_id, status, source_path, hls_path, hls_source_path, renditions, error, created_at, updated_at
+		FROM video_packages
+		WHERE video_id = $1
+	`, videoID).Scan(
+		&pkg.VideoID, &pkg.Status, &pkg.SourcePath, &hlsPath, &hlsSourcePath, &renditions,
+		&reason, &pkg.CreatedAt, &pkg.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video package: %w", err)
+	}
+
+	pkg.HLSPath = hlsPath.String
+	pkg.HLSSourcePath = hlsSourcePath.String
+	pkg.Renditions = renditions
+	pkg.Error = reason.String
+	return pkg, nil
+}
+
+// ClaimNext забирает в упаковку ready видео без пакета или с файлом, изменившимся
+// после упаковки (обрезка). Первыми идут недавно изменённые видео: новые импорты
+// и загрузки не ждут упаковки старых видео. nil - упаковывать нечего
+func (r *PackageRepository) ClaimNext() (*models.VideoPackage, error) {
+	query := `
+		WITH next AS (
+			SELECT v.id, v.file_path
+			FROM videos v
+			LEFT JOIN video_packages p ON p.video_id = v.id
+			WHERE v.status = 'ready'
+			  AND (
+			      p.video_id IS NULL
+			      OR p.status = $2
+			      OR (p.status <> $1 AND p.source_path <> v.file_path)
+			  )
+			ORDER BY v.updated_at DESC
+			LIMIT 1
+			FOR UPDATE OF v SKIP LOCKED
+		)
+		INSERT INTO video_packages (video_id, status, source_path)
+		SELECT id, $1, file_path FROM next
+		ON CONFLICT (video_id) DO UPDATE
+		SET status = EXCLUDED.status,
+		    source_path = EXCLUDED.source_path,
+		    error = NULL,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING video_id, source_path
+	`
+
+	pkg := &models.VideoPackage{Status: models.PackageStatusProcessing}
+	err := r.db.QueryRow(query, models.PackageStatusProcessing, models.PackageStatusPending).Scan(&pkg.VideoID, &pkg.SourcePath)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim video for packaging: %w", err)
+	}
+	return pkg, nil
+}
+
+// RequeueInterrupted возвращает в очередь упаковки, оборванные рестартом сервиса
+func (r *PackageRepository) RequeueInterrupted() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE video_packages SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE status = $2
+	`, models.PackageStatusPending, models.PackageStatusProcessing)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Complete делает пакет hlsPath готовым пакетом видео и возвращает префикс прежнего пакета
+// ("" - его не было), который больше не нужен
+func (r *PackageRepository) Complete(pkg *models.VideoPackage, hlsPath string, renditions []string) (string, error) {
+	query := `
+		WITH previous AS (
+			SELECT hls_path FROM video_packages WHERE video_id = $1 FOR UPDATE
+		)
+		UPDATE video_packages
+		SET status = $2, hls_path = $3, hls_source_path = $4, renditions = $5,
+		    error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE video_id = $1
+		RETURNING (SELECT COALESCE(hls_path, '') FROM previous)
+	`
+
+	var previous string
+	err := r.db.QueryRow(query,
+		pkg.VideoID, models.PackageStatusReady, hlsPath, pkg.SourcePath, pq.Array(renditions),
+	).Scan(&previous)
+	if err == sql.ErrNoRows {
+		// Видео удалили во время упаковки
+		return "", fmt.Errorf("video package not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to complete video package: %w", err)
+	}
+	return previous, nil
+}
+
+// Fail помечает упаковку failed. Прежний готовый пакет остаётся, повтор - после смены файла
+func (r *PackageRepository) Fail(videoID uuid.UUID, reason string) error {
+	_, err := r.db.Exec(`
+		UPDATE video_packages
+		SET status = $1, error = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE video_id = $3
+	`, models.PackageStatusFailed, reason, videoID)
+	if err != nil {
+		return fmt.Errorf("failed to fail video package: %w", err)
+	}
+	return nil
+}
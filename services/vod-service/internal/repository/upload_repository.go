@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type UploadRepository struct {
+	db *sql.DB
+}
+
+func NewUploadRepository(db *sql.DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+const uploadColumns = `
+	id, video_id, user_id, filename, size, received_bytes, status,
+	COALESCE(error, ''), expires_at, created_at, updated_at
+`
+
+// Create creates an upload of an already created pending video
+func (r *UploadRepository) Create(upload *models.Upload) error {
+	query := `
+		INSERT INTO video_uploads (id, video_id, user_id, filename, size, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(query,
+		upload.ID, upload.VideoID, upload.UserID, upload.Filename, upload.Size, upload.Status, upload.ExpiresAt,
+	).Scan(&upload.CreatedAt, &upload.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns an upload
+func (r *UploadRepository) GetByID(id uuid.UUID) (*models.Upload, error) {
+	upload, err := scanUpload(r.db.QueryRow(`SELECT`+uploadColumns+`FROM video_uploads WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("upload not found")
+	}
+	return upload, err
+}
+
+// AdvanceOffset сдвигает offset загрузки с from на to и продлевает срок загрузки.
+// Получив последний байт, загрузка встаёт в очередь обработки
+func (r *UploadRepository) AdvanceOffset(id uuid.UUID, from, to int64, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE video_uploads
+		SET received_bytes = $3,
+		    status = CASE WHEN $3 = size THEN $4 ELSE status END,
+		    expires_at = $6,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND received_bytes = $2 AND status = $5
+	`
+
+	result, err := r.db.Exec(query, id, from, to, models.UploadStatusUploaded, models.UploadStatusUploading, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance upload offset: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// ClaimNext забирает в обработку самую давнюю полученную загрузку (nil - очередь пуста)
+func (r *UploadRepository) ClaimNext() (*models.Upload, error) {
+	query := `
+		UPDATE video_uploads
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM video_uploads
+			WHERE status = $2
+			ORDER BY updated_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + uploadColumns
+
+	upload, err := scanUpload(r.db.QueryRow(query, models.UploadStatusProcessing, models.UploadStatusUploaded))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim upload: %w", err)
+	}
+	return upload, nil
+}
+
+// RequeueInterrupted возвращает в очередь загрузки, обработка которых оборвалась рестартом сервиса
+func (r *UploadRepository) RequeueInterrupted() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE video_uploads SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE status = $2
+	`, models.UploadStatusUploaded, models.UploadStatusProcessing)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Complete переводит видео загрузки в ready с параметрами нормализованного файла
+func (r *UploadRepository) Complete(upload *models.Upload, duration int, fileSize int64, thumbnailPath string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE videos
+		SET status = 'ready', duration = $1, file_size = $2, thumbnail_path = $3,
+		    updated_at = CURRENT_TIMESTAMP, published_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`, duration, fileSize, thumbnailPath, upload.VideoID)
+	if err != nil {
+		return fmt.Errorf("failed to complete uploaded video: %w", err)
+	}
+
+	if err := setUploadStatus(tx, upload.ID, models.UploadStatusCompleted, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Fail помечает загрузку и её видео failed
+func (r *UploadRepository) Fail(upload *models.Upload, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE videos SET status = 'failed', updated_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, upload.VideoID)
+	if err != nil {
+		return fmt.Errorf("failed to fail uploaded video: %w", err)
+	}
+
+	if err := setUploadStatus(tx, upload.ID, models.UploadStatusFailed, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Cancel удаляет незавершённую загрузку вместе с её видео. false - загрузка уже получена
+func (r *UploadRepository) Cancel(upload *models.Upload) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM videos
+		WHERE id = $1 AND EXISTS (
+			SELECT 1 FROM video_uploads WHERE id = $2 AND status = $3
+		)
+	`, upload.VideoID, upload.ID, models.UploadStatusUploading)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel upload: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// DeleteExpired удаляет брошенные загрузки с истёкшим сроком вместе с их видео
+// и возвращает ID удалённых загрузок
+func (r *UploadRepository) DeleteExpired() ([]uuid.UUID, error) {
+	query := `
+		WITH expired AS (
+			SELECT id, video_id FROM video_uploads
+			WHERE status = $1 AND expires_at < CURRENT_TIMESTAMP
+		), deleted AS (
+			DELETE FROM videos WHERE id IN (SELECT video_id FROM expired)
+		)
+		SELECT id FROM expired
+	`
+
+	rows, err := r.db.Query(query, models.UploadStatusUploading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func setUploadStatus(tx *sql.Tx, id uuid.UUID, status, reason string) error {
+	_, err := tx.Exec(`
+		UPDATE video_uploads
+		SET status = $1, error = NULLIF($2, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`, status, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to update upload status: %w", err)
+	}
+	return nil
+}
+
+func scanUpload(row *sql.Row) (*models.Upload, error) {
+	upload := &models.Upload{}
+	err := row.Scan(
+		&upload.ID, &upload.VideoID, &upload.UserID, &upload.Filename, &upload.Size, &upload.Offset, &upload.Status,
+		&upload.Error, &upload.ExpiresAt, &upload.CreatedAt, &upload.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
@@ -23,19 +23,19 @@ func NewVideoRepository(db *sql.DB) *VideoRepository {
 func (r *VideoRepository) Create(video *models.Video) error {
 	query := `
 		INSERT INTO videos (
-			id, user_id, recording_id, stream_id,
+			id, user_id, recording_id, stream_id, parent_video_id,
 			title, description, category, tags,
 			source, status, visibility,
 			file_path, thumbnail_path, duration, file_size,
 			view_count, like_count,
 			created_at, updated_at, published_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
 		)
 	`
 
 	_, err := r.db.Exec(query,
-		video.ID, video.UserID, video.RecordingID, video.StreamID,
+		video.ID, video.UserID, video.RecordingID, video.StreamID, video.ParentVideoID,
 		video.Title, video.Description, video.Category, pq.Array(video.Tags),
 		video.Source, video.Status, video.Visibility,
 		video.FilePath, video.ThumbnailPath, video.Duration, video.FileSize,
@@ -51,14 +51,14 @@ func (r *VideoRepository) GetByID(id uuid.UUID) (*models.Video, error) {
 	query := `
 		WITH target_video AS (
 			SELECT 
-				id, user_id, recording_id, stream_id, title, description, category, tags,
+				id, user_id, recording_id, stream_id, parent_video_id, title, description, category, tags,
 				source, status, visibility, file_path, thumbnail_path, duration, file_size,
 				view_count, like_count, created_at, updated_at, published_at
 			FROM videos
 			WHERE id = $1
 		)
 		SELECT
-			tv.id, tv.user_id, tv.recording_id, tv.stream_id,
+			tv.id, tv.user_id, tv.recording_id, tv.stream_id, tv.parent_video_id,
 			tv.title, tv.description, tv.category, tv.tags,
 			tv.source, tv.status, tv.visibility,
 			tv.file_path, tv.thumbnail_path, tv.duration, tv.file_size,
@@ -73,7 +73,7 @@ func (r *VideoRepository) GetByID(id uuid.UUID) (*models.Video, error) {
 	var tags pq.StringArray
 
 	err := r.db.QueryRow(query, id).Scan(
-		&video.ID, &video.UserID, &video.RecordingID, &video.StreamID,
+		&video.ID, &video.UserID, &video.RecordingID, &video.StreamID, &video.ParentVideoID,
 		&video.Title, &video.Description, &video.Category, &tags,
 		&video.Source, &video.Status, &video.Visibility,
 		&video.FilePath, &video.ThumbnailPath, &video.Duration, &video.FileSize,
@@ -107,7 +107,7 @@ func (r *VideoRepository) ListUserVideos(userID uuid.UUID, limit, offset int) ([
 	query := `
 		WITH filtered_videos AS (
 			SELECT 
-				id, user_id, recording_id, stream_id, title, description, category, tags,
+				id, user_id, recording_id, stream_id, parent_video_id, title, description, category, tags,
 				source, status, visibility, file_path, thumbnail_path, duration, file_size,
 				view_count, like_count, created_at, updated_at, published_at
 			FROM videos
@@ -116,7 +116,7 @@ func (r *VideoRepository) ListUserVideos(userID uuid.UUID, limit, offset int) ([
 			LIMIT $2 OFFSET $3
 		)
 		SELECT
-			fv.id, fv.user_id, fv.recording_id, fv.stream_id,
+			fv.id, fv.user_id, fv.recording_id, fv.stream_id, fv.parent_video_id,
 			fv.title, fv.description, fv.category, fv.tags,
 			fv.source, fv.status, fv.visibility,
 			fv.file_path, fv.thumbnail_path, fv.duration, fv.file_size,
@@ -140,7 +140,7 @@ func (r *VideoRepository) ListUserVideos(userID uuid.UUID, limit, offset int) ([
 		var tags pq.StringArray
 
 		err := rows.Scan(
-			&video.ID, &video.UserID, &video.RecordingID, &video.StreamID,
+			&video.ID, &video.UserID, &video.RecordingID, &video.StreamID, &video.ParentVideoID,
 			&video.Title, &video.Description, &video.Category, &tags,
 			&video.Source, &video.Status, &video.Visibility,
 			&video.FilePath, &video.ThumbnailPath, &video.Duration, &video.FileSize,
@@ -193,6 +193,34 @@ func (r *VideoRepository) Delete(id uuid.UUID) error {
 	return nil
 }
 
+// CompleteClip сохраняет результат нарезки клипа и переводит его в ready
+func (r *VideoRepository) CompleteClip(id uuid.UUID, duration int, fileSize int64, thumbnailPath string) error {
+	query := `
+		UPDATE videos
+		SET status = 'ready', duration = $1, file_size = $2, thumbnail_path = $3, updated_at = $4
+		WHERE id = $5 AND source = 'clip'
+	`
+	_, err := r.db.Exec(query, duration, fileSize, thumbnailPath, time.Now(), id)
+	return err
+}
+
+// FailClip помечает клип, который не удалось нарезать
+func (r *VideoRepository) FailClip(id uuid.UUID) error {
+	query := `UPDATE videos SET status = 'failed', updated_at = $1 WHERE id = $2 AND source = 'clip'`
+	_, err := r.db.Exec(query, time.Now(), id)
+	return err
+}
+
+// FailPendingClips помечает failed клипы, нарезка которых оборвалась рестартом сервиса
+func (r *VideoRepository) FailPendingClips() (int64, error) {
+	query := `UPDATE videos SET status = 'failed', updated_at = $1 WHERE source = 'clip' AND status = 'pending'`
+	result, err := r.db.Exec(query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // IncrementViewCount increments view count
 func (r *VideoRepository) IncrementViewCount(id uuid.UUID) error {
 	query := `UPDATE videos SET view_count = view_count + 1 WHERE id = $1`
@@ -211,7 +239,7 @@ func (r *VideoRepository) IncrementLikeCount(id uuid.UUID) error {
 func (r *VideoRepository) GetByRecordingID(recordingID uuid.UUID) (*models.Video, error) {
 	query := `
 		SELECT 
-			v.id, v.user_id, v.recording_id, v.stream_id,
+			v.id, v.user_id, v.recording_id, v.stream_id, v.parent_video_id,
 			v.title, v.description, v.category, v.tags,
 			v.source, v.status, v.visibility,
 			v.file_path, v.thumbnail_path, v.duration, v.file_size,
@@ -226,7 +254,7 @@ func (r *VideoRepository) GetByRecordingID(recordingID uuid.UUID) (*models.Video
 	video := &models.Video{}
 	var tags pq.StringArray
 	err := r.db.QueryRow(query, recordingID).Scan(
-		&video.ID, &video.UserID, &video.RecordingID, &video.StreamID,
+		&video.ID, &video.UserID, &video.RecordingID, &video.StreamID, &video.ParentVideoID,
 		&video.Title, &video.Description, &video.Category, &tags,
 		&video.Source, &video.Status, &video.Visibility,
 		&video.FilePath, &video.ThumbnailPath, &video.Duration, &video.FileSize,
@@ -248,6 +276,7 @@ func (r *VideoRepository) GetByRecordingID(recordingID uuid.UUID) (*models.Video
 }
 
 // ✅ ОПТИМИЗИРОВАНО: ListAllVideos с CTE
+// Чужие видео попадают в список только готовыми (клипы нарезаются асинхронно)
 func (r *VideoRepository) ListAllVideos(userID *uuid.UUID, limit, offset int) ([]*models.Video, int, error) {
 	var videos []*models.Video
 	var total int
@@ -257,10 +286,10 @@ func (r *VideoRepository) ListAllVideos(userID *uuid.UUID, limit, offset int) ([
 	var countArgs []interface{}
 
 	if userID != nil {
-		countQuery = `SELECT COUNT(*) FROM videos WHERE visibility = 'public' OR user_id = $1`
+		countQuery = `SELECT COUNT(*) FROM videos WHERE (visibility = 'public' AND status = 'ready') OR user_id = $1`
 		countArgs = []interface{}{userID}
 	} else {
-		countQuery = `SELECT COUNT(*) FROM videos WHERE visibility = 'public'`
+		countQuery = `SELECT COUNT(*) FROM videos WHERE visibility = 'public' AND status = 'ready'`
 	}
 
 	if err := r.db.QueryRow(countQuery, countArgs...).Scan(&total); err != nil {
@@ -276,16 +305,16 @@ func (r *VideoRepository) ListAllVideos(userID *uuid.UUID, limit, offset int) ([
 		query = `
 			WITH filtered_videos AS (
 				SELECT 
-					id, user_id, recording_id, stream_id, title, description, category, tags,
+					id, user_id, recording_id, stream_id, parent_video_id, title, description, category, tags,
 					source, status, visibility, file_path, thumbnail_path, duration, file_size,
 					view_count, like_count, created_at, updated_at, published_at
 				FROM videos
-				WHERE visibility = 'public' OR user_id = $1
+				WHERE (visibility = 'public' AND status = 'ready') OR user_id = $1
 				ORDER BY created_at DESC
 				LIMIT $2 OFFSET $3
 			)
 			SELECT
-				fv.id, fv.user_id, fv.recording_id, fv.stream_id,
+				fv.id, fv.user_id, fv.recording_id, fv.stream_id, fv.parent_video_id,
 				fv.title, fv.description, fv.category, fv.tags,
 				fv.source, fv.status, fv.visibility,
 				fv.file_path, fv.thumbnail_path, fv.duration, fv.file_size,
@@ -300,16 +329,16 @@ func (r *VideoRepository) ListAllVideos(userID *uuid.UUID, limit, offset int) ([
 		query = `
 			WITH filtered_videos AS (
 				SELECT 
-					id, user_id, recording_id, stream_id, title, description, category, tags,
+					id, user_id, recording_id, stream_id, parent_video_id, title, description, category, tags,
 					source, status, visibility, file_path, thumbnail_path, duration, file_size,
 					view_count, like_count, created_at, updated_at, published_at
 				FROM videos
-				WHERE visibility = 'public'
+				WHERE visibility = 'public' AND status = 'ready'
 				ORDER BY created_at DESC
 				LIMIT $1 OFFSET $2
 			)
 			SELECT
-				fv.id, fv.user_id, fv.recording_id, fv.stream_id,
+				fv.id, fv.user_id, fv.recording_id, fv.stream_id, fv.parent_video_id,
 				fv.title, fv.description, fv.category, fv.tags,
 				fv.source, fv.status, fv.visibility,
 				fv.file_path, fv.thumbnail_path, fv.duration, fv.file_size,
@@ -335,7 +364,7 @@ func (r *VideoRepository) ListAllVideos(userID *uuid.UUID, limit, offset int) ([
 		var tags pq.StringArray
 
 		err := rows.Scan(
-			&video.ID, &video.UserID, &video.RecordingID, &video.StreamID,
+			&video.ID, &video.UserID, &video.RecordingID, &video.StreamID, &video.ParentVideoID,
 			&video.Title, &video.Description, &video.Category, &tags,
 			&video.Source, &video.Status, &video.Visibility,
 			&video.FilePath, &video.ThumbnailPath, &video.Duration, &video.FileSize,
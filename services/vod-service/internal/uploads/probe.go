@@ -0,0 +1,111 @@
+package uploads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMedia - загруженный файл не подходит: причина показывается владельцу
+var ErrInvalidMedia = errors.New("invalid media")
+
+// Контейнеры (ffprobe format_name) и видеокодеки, которые принимаются к загрузке
+var (
+	allowedFormats     = []string{"mov", "mp4", "matroska", "webm", "avi", "mpegts", "flv"}
+	allowedVideoCodecs = []string{"h264", "hevc", "vp8", "vp9", "av1", "mpeg4", "mpeg2video", "prores"}
+)
+
+// Probe - то, что ffprobe знает о файле
+type Probe struct {
+	Format     string // format_name, например "mov,mp4,m4a,3gp,3g2,mj2"
+	Duration   float64
+	VideoCodec string
+	PixFmt     string
+	Height     int
+	AudioCodec string // "" - без звука
+}
+
+// ProbeFile читает контейнер, первые видео и аудио потоки и длительность файла
+func ProbeFile(ctx context.Context, path string) (*Probe, error) {
+	output, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=format_name,duration:stream=codec_type,codec_name,pix_fmt,height",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		// ffprobe не смог разобрать файл - это не видео
+		return nil, fmt.Errorf("%w: file is not a readable video", ErrInvalidMedia)
+	}
+
+	var result struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			PixFmt    string `json:"pix_fmt"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	probe := &Probe{Format: result.Format.FormatName}
+	probe.Duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	for _, stream := range result.Streams {
+		switch {
+		case stream.CodecType == "video" && probe.VideoCodec == "":
+			probe.VideoCodec = stream.CodecName
+			probe.PixFmt = stream.PixFmt
+			probe.Height = stream.Height
+		case stream.CodecType == "audio" && probe.AudioCodec == "":
+			probe.AudioCodec = stream.CodecName
+		}
+	}
+	return probe, nil
+}
+
+// Validate проверяет контейнер, видеокодек и длительность
+func (p *Probe) Validate(maxDuration time.Duration) error {
+	if !containsAny(strings.Split(p.Format, ","), allowedFormats) {
+		return fmt.Errorf("%w: unsupported container %q", ErrInvalidMedia, p.Format)
+	}
+	if p.VideoCodec == "" {
+		return fmt.Errorf("%w: file has no video stream", ErrInvalidMedia)
+	}
+	if !containsAny([]string{p.VideoCodec}, allowedVideoCodecs) {
+		return fmt.Errorf("%w: unsupported video codec %q", ErrInvalidMedia, p.VideoCodec)
+	}
+	if p.Duration <= 0 {
+		return fmt.Errorf("%w: could not determine video duration", ErrInvalidMedia)
+	}
+	if p.Duration > maxDuration.Seconds() {
+		return fmt.Errorf("%w: video is longer than %s", ErrInvalidMedia, maxDuration)
+	}
+	return nil
+}
+
+// Passthrough - видео уже в формате платформы (H.264 yuv420p, не выше 1080p) и копируется без перекодирования
+func (p *Probe) Passthrough() bool {
+	return p.VideoCodec == "h264" && p.PixFmt == "yuv420p" && p.Height <= maxHeight
+}
+
+func containsAny(values, allowed []string) bool {
+	for _, value := range values {
+		for _, a := range allowed {
+			if value == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,403 @@
+// Package uploads - возобновляемая загрузка видео пользователями (в духе tus).
+// Полученные байты дописываются в part файл на томе сервиса, offset хранится в БД.
+// Полностью полученный файл проверяется ffprobe и приводится к формату платформы:
+// H.264/AAC во фрагментированном MP4 с sidx, как записи стримов
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SerKKiT/streaming-platform/shared/storage"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/models"
+	"github.com/SerKKiT/streaming-platform/vod-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// TTL - срок незавершённой загрузки, продлевается каждой порцией байт
+	TTL = 24 * time.Hour
+
+	// ChunkSize - рекомендуемый размер порции (PATCH) для клиентов
+	ChunkSize = 32 << 20
+
+	// maxHeight - видео выше 1080p уменьшается при нормализации
+	maxHeight = 1080
+
+	pollInterval   = 30 * time.Second
+	expireInterval = 10 * time.Minute
+	jobTimeout     = 2 * time.Hour
+)
+
+var (
+	// ErrOffsetMismatch - offset клиента не совпадает с полученным сервером
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrUploadBusy - в загрузку уже пишет другой запрос
+	ErrUploadBusy = errors.New("upload is being written by another request")
+	// ErrNotUploading - загрузка уже получена целиком или завершена
+	ErrNotUploading = errors.New("upload is not accepting data")
+)
+
+// Limits - ограничения загружаемых файлов
+type Limits struct {
+	MaxSize     int64
+	MaxDuration time.Duration
+}
+
+// Processor принимает байты загрузок и обрабатывает полученные файлы в фоне.
+// Очередь обработки - в БД (status uploaded), поэтому переживает рестарт
+type Processor struct {
+	repo   *repository.UploadRepository
+	videos storage.Storage // vod-videos: нормализованные видео и thumbnails
+	dir    string
+	limits Limits
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	writing map[uuid.UUID]bool
+}
+
+func NewProcessor(repo *repository.UploadRepository, videos storage.Storage, dir string, limits Limits) *Processor {
+	return &Processor{
+		repo:    repo,
+		videos:  videos,
+		dir:     dir,
+		limits:  limits,
+		wake:    make(chan struct{}, 1),
+		writing: make(map[uuid.UUID]bool),
+	}
+}
+
+// Limits возвращает ограничения загрузок
+func (p *Processor) Limits() Limits {
+	return p.limits
+}
+
+// Start запускает workers обработчиков и удаление брошенных загрузок.
+// Обработка, оборванная рестартом, начинается заново: файл остался на томе
+func (p *Processor) Start(ctx context.Context, workers int) error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+
+	if requeued, err := p.repo.RequeueInterrupted(); err != nil {
+		log.Printf("⚠️ Failed to requeue interrupted uploads: %v", err)
+	} else if requeued > 0 {
+		log.Printf("🔁 Requeued %d interrupted uploads", requeued)
+	}
+
+	for i := 0; i < workers; i++ {
+		go p.work(ctx)
+	}
+	go p.expire(ctx)
+	return nil
+}
+
+// Cancel удаляет незавершённую загрузку с её видео и part файлом.
+// false - загрузка уже получена целиком
+func (p *Processor) Cancel(upload *models.Upload) (bool, error) {
+	if !p.lock(upload.ID) {
+		return false, ErrUploadBusy
+	}
+	defer p.unlock(upload.ID)
+
+	cancelled, err := p.repo.Cancel(upload)
+	if err != nil || !cancelled {
+		return false, err
+	}
+	p.Discard(upload.ID)
+	return true, nil
+}
+
+// Receive дописывает тело PATCH запроса в загрузку с offset клиента и возвращает новый offset.
+// Оборванный запрос тоже засчитывается: клиент продолжит с полученного сервером места
+func (p *Processor) Receive(uploadID uuid.UUID, offset int64, body io.Reader) (int64, error) {
+	if !p.lock(uploadID) {
+		return 0, ErrUploadBusy
+	}
+	defer p.unlock(uploadID)
+
+	// Offset перечитывается под блокировкой: другой запрос мог его сдвинуть
+	upload, err := p.repo.GetByID(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if upload.Status != models.UploadStatusUploading {
+		return upload.Offset, ErrNotUploading
+	}
+	if offset != upload.Offset {
+		return upload.Offset, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(p.partPath(uploadID), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return upload.Offset, err
+	}
+	// Байты после offset остались от запроса, оборвавшегося до записи offset в БД
+	if err := file.Truncate(upload.Offset); err != nil {
+		file.Close()
+		return upload.Offset, err
+	}
+	if _, err := file.Seek(upload.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return upload.Offset, err
+	}
+
+	// Лишние байты сверх объявленного размера отбрасываются
+	written, copyErr := io.Copy(file, io.LimitReader(body, upload.Size-upload.Offset))
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return upload.Offset, err
+	}
+	if err := file.Close(); err != nil {
+		return upload.Offset, err
+	}
+	if written == 0 {
+		return upload.Offset, copyErr
+	}
+
+	newOffset := upload.Offset + written
+	if _, err := p.repo.AdvanceOffset(uploadID, upload.Offset, newOffset, time.Now().Add(TTL)); err != nil {
+		return upload.Offset, err
+	}
+
+	if newOffset == upload.Size {
+		log.Printf("📥 Upload %s received (%d bytes), queued for processing", uploadID, upload.Size)
+		p.notify()
+	}
+	return newOffset, copyErr
+}
+
+// Discard удаляет part файл отменённой или истёкшей загрузки
+func (p *Processor) Discard(uploadID uuid.UUID) {
+	if err := os.Remove(p.partPath(uploadID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️ Failed to remove part file of upload %s: %v", uploadID, err)
+	}
+}
+
+// lock занимает загрузку для одного запроса: запись и отмена не идут параллельно
+func (p *Processor) lock(uploadID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.writing[uploadID] {
+		return false
+	}
+	p.writing[uploadID] = true
+	return true
+}
+
+func (p *Processor) unlock(uploadID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.writing, uploadID)
+}
+
+func (p *Processor) partPath(uploadID uuid.UUID) string {
+	return filepath.Join(p.dir, uploadID.String()+".part")
+}
+
+func (p *Processor) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Processor) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		upload, err := p.repo.ClaimNext()
+		if err != nil {
+			log.Printf("⚠️ Failed to claim upload: %v", err)
+		}
+		if upload != nil {
+			p.process(ctx, upload)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Processor) expire(ctx context.Context) {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := p.repo.DeleteExpired()
+			if err != nil {
+				log.Printf("⚠️ Failed to delete expired uploads: %v", err)
+				continue
+			}
+			for _, id := range ids {
+				p.Discard(id)
+			}
+			if len(ids) > 0 {
+				log.Printf("🧹 Deleted %d expired uploads", len(ids))
+			}
+		}
+	}
+}
+
+func (p *Processor) process(parent context.Context, upload *models.Upload) {
+	ctx, cancel := context.WithTimeout(parent, jobTimeout)
+	defer cancel()
+
+	log.Printf("🎬 Processing upload %s of video %s", upload.ID, upload.VideoID)
+
+	err := p.normalize(ctx, upload)
+	if err != nil && parent.Err() != nil {
+		// Остановка сервиса: файл остаётся, обработка начнётся заново после рестарта
+		log.Printf("⚠️ Processing of upload %s interrupted", upload.ID)
+		return
+	}
+
+	if err != nil {
+		log.Printf("❌ Failed to process upload %s: %v", upload.ID, err)
+		reason := "processing failed"
+		if errors.Is(err, ErrInvalidMedia) {
+			reason = err.Error()
+		}
+		if err := p.repo.Fail(upload, reason); err != nil {
+			log.Printf("❌ Failed to mark upload %s as failed: %v", upload.ID, err)
+		}
+	} else {
+		log.Printf("✅ Uploaded video %s is ready", upload.VideoID)
+	}
+	p.Discard(upload.ID)
+}
+
+func (p *Processor) normalize(ctx context.Context, upload *models.Upload) error {
+	source := p.partPath(upload.ID)
+
+	probe, err := ProbeFile(ctx, source)
+	if err != nil {
+		return err
+	}
+	if err := probe.Validate(p.limits.MaxDuration); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(p.dir, upload.ID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "video.mp4")
+	if err := transcode(ctx, source, probe, output); err != nil {
+		return err
+	}
+
+	normalized, err := ProbeFile(ctx, output)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+
+	thumbnail := filepath.Join(dir, "thumbnail.jpg")
+	thumbnailPath := fmt.Sprintf("%s.jpg", upload.VideoID)
+	if err := extractThumbnail(ctx, output, math.Min(5, normalized.Duration/2), thumbnail); err != nil {
+		log.Printf("⚠️ Failed to extract thumbnail of video %s (non-critical): %v", upload.VideoID, err)
+		thumbnailPath = ""
+	}
+
+	filePath := fmt.Sprintf("%s.mp4", upload.VideoID)
+	if err := p.videos.PutFile(ctx, filePath, output, "video/mp4"); err != nil {
+		return fmt.Errorf("failed to upload video: %w", err)
+	}
+	if thumbnailPath != "" {
+		if err := p.videos.PutFile(ctx, thumbnailPath, thumbnail, "image/jpeg"); err != nil {
+			log.Printf("⚠️ Failed to upload thumbnail of video %s (non-critical): %v", upload.VideoID, err)
+			thumbnailPath = ""
+		}
+	}
+
+	return p.repo.Complete(upload, int(math.Round(normalized.Duration)), info.Size(), thumbnailPath)
+}
+
+// transcode приводит файл к формату платформы. H.264 и AAC копируются как есть,
+// остальное перекодируется; видео выше 1080p уменьшается
+func transcode(ctx context.Context, source string, probe *Probe, output string) error {
+	args := []string{
+		"-hide_banner",
+		"-i", source,
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+	}
+	if probe.Passthrough() {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-crf", "21",
+			"-pix_fmt", "yuv420p",
+			"-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", maxHeight),
+		)
+	}
+	if probe.AudioCodec == "aac" {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", "aac", "-b:a", "128k")
+	}
+	args = append(args,
+		"-movflags", "+frag_keyframe+empty_moov+default_base_moof+global_sidx",
+		"-y",
+		output,
+	)
+
+	log.Printf("🎬 Normalizing upload: ffmpeg %v", args)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg normalization failed: %w", err)
+	}
+	return nil
+}
+
+// extractThumbnail сохраняет кадр видео в момент at (секунды)
+func extractThumbnail(ctx context.Context, videoPath string, at float64, output string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", strconv.FormatFloat(at, 'f', 3, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-vf", "scale=640:-2",
+		"-y",
+		output,
+	)
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg thumbnail failed: %w", err)
+	}
+	return nil
+}